@@ -17,24 +17,36 @@ import (
 	"github.com/shashank-sharma/backend/internal/routes"
 	"github.com/shashank-sharma/backend/internal/services"
 	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/services/assistant"
 	"github.com/shashank-sharma/backend/internal/services/calendar"
 	"github.com/shashank-sharma/backend/internal/services/feed"
 	"github.com/shashank-sharma/backend/internal/services/fold"
 	"github.com/shashank-sharma/backend/internal/services/mail"
 	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/services/search"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
 	"github.com/shashank-sharma/backend/internal/services/workflow"
 	"github.com/shashank-sharma/backend/internal/store"
 )
 
 type Application struct {
-	Server          *http.Server
-	Pb              *pocketbase.PocketBase
-	FoldService     *fold.FoldService
-	CalendarService *calendar.CalendarService
-	MailService     *mail.MailService
-	WorkflowEngine  *workflow.WorkflowEngine
-	FeedService     *services.FeedService
-	postInitHooks   []func()
+	Server            *http.Server
+	Pb                *pocketbase.PocketBase
+	FoldService       *fold.FoldService
+	CalendarService   *calendar.CalendarService
+	MailService       *mail.MailService
+	WorkflowEngine    *workflow.WorkflowEngine
+	FeedService       *services.FeedService
+	FeedScheduler     *feed.FeedScheduler
+	DigestService     *feed.DigestService
+	FeedArchiver      *feed.Archiver
+	WebSubManager     *feed.WebSubManager
+	EpisodeDownloader *feed.EpisodeDownloader
+	ItemStatsTracker  *feed.ItemStatsTracker
+	SemanticService   *semantic.Service
+	AssistantService  *assistant.Service
+	AIMeter           *ai.Meter
+	postInitHooks     []func()
 }
 
 func New(configFlags config.ConfigFlags) *Application {
@@ -59,6 +71,11 @@ func New(configFlags config.ConfigFlags) *Application {
 		logger.LogInfo("This is an example post-initialization hook - app is fully ready")
 	})
 
+	// Backfill the search index for records created before it existed
+	app.AddPostInitHook(func() {
+		go search.EnsureIndexed(app.Pb)
+	})
+
 	pb.OnServe().BindFunc(func(e *core.ServeEvent) error {
 		// STAGE 1: Initialize base services that don't depend on application services
 		logger.InitLog(pb)
@@ -104,14 +121,39 @@ func (app *Application) initializeServices() {
 			logger.LogInfo("AI client initialized")
 		}
 	}
+
+	// Every AI request is recorded, counted against the budgets and, for
+	// summaries and tags, cached
+	app.AIMeter = ai.NewMeter(aiConfig)
+	if aiClient != nil {
+		aiClient = ai.NewMeteredClient(aiClient, aiConfig.Model, app.AIMeter)
+	}
 	
+	feedConfig := config.GetFeedConfig()
+	providers.SetHostRequestsPerMinute(feedConfig.HostRequestsPerMin)
+	providers.SetHostRates(feedConfig.HostRateLimits)
+
 	// Initialize the feed processor with AI client
 	processor := feed.NewFeedProcessor(aiClient)
-	feedService := feed.NewFeedService(processor)
+	ranker := feed.NewFeedRanker(aiClient)
+	feedService := feed.NewFeedService(processor, ranker, feed.NewRulesEngine(app.WorkflowEngine))
 	feedService.RegisterProvider(providers.NewRSSProvider())
 	feedService.RegisterProvider(providers.NewHackerNewsProvider())
+	feedService.RegisterProvider(providers.NewRedditProvider())
+	feedService.RegisterProvider(providers.NewGithubProvider())
+	feedService.RegisterProvider(providers.NewYoutubeProvider())
+	feedService.RegisterProvider(providers.NewCustomProvider())
 	
 	app.FeedService = &feedService
+	app.FeedScheduler = feed.NewFeedScheduler(feedService, feedConfig)
+	app.DigestService = feed.NewDigestService(processor, ranker)
+	app.FeedArchiver = feed.NewArchiver(feedConfig)
+	app.WebSubManager = feed.NewWebSubManager(feedService, feedConfig)
+	app.EpisodeDownloader = feed.NewEpisodeDownloader(feedConfig)
+	app.ItemStatsTracker = feed.NewItemStatsTracker(feedService)
+	app.SemanticService = semantic.NewService(app.Pb, ai.NewEmbedder(aiConfig, aiClient))
+	chatter, _ := aiClient.(ai.Chatter)
+	app.AssistantService = assistant.NewService(app.Pb, chatter, app.SemanticService)
 	
 	logger.LogInfo("All services initialized successfully")
 }
@@ -122,6 +164,14 @@ func (app *Application) configureRoutes(e *core.ServeEvent) {
 
 	routes.RegisterWorkflowRoutes(apiRouter, "/workflows", app.WorkflowEngine)
 	routes.RegisterFeedRoutes(apiRouter, "/feeds", *app.FeedService)
+	routes.RegisterFeedDigestRoutes(apiRouter, "/feeds/digests", app.DigestService)
+	routes.RegisterFeedPublicationRoutes(apiRouter, "/feeds/publications")
+	routes.RegisterFeedArchiveRoutes(apiRouter, "/feeds/archive", app.FeedArchiver)
+	routes.RegisterFeedHighlightRoutes(apiRouter, "/feeds/highlights")
+	routes.RegisterFeedRetentionRoutes(apiRouter, "/feeds/retention")
+	routes.RegisterFeedBulkRoutes(apiRouter, "/feeds/items/bulk")
+	routes.RegisterFeedWebSubRoutes(apiRouter, "/feeds/websub", app.WebSubManager)
+	routes.RegisterFeedPodcastRoutes(apiRouter, "/feeds/podcasts", app.EpisodeDownloader)
 	routes.RegisterCredentialRoutes(e)
 
 	routes.RegisterTrackRoutes(apiRouter, "/track")
@@ -129,6 +179,10 @@ func (app *Application) configureRoutes(e *core.ServeEvent) {
 	routes.RegisterMailRoutes(apiRouter, "/mail", app.MailService)
 	routes.RegisterFoldRoutes(apiRouter, "/fold", app.FoldService)
 	routes.RegisterSSHRoutes(apiRouter, "/ssh")
+	routes.RegisterSearchRoutes(apiRouter, "/search", app.SemanticService)
+	routes.RegisterFeedRelatedRoutes(apiRouter, "/feeds/items", app.SemanticService)
+	routes.RegisterAssistantRoutes(apiRouter, "/assistant", app.AssistantService)
+	routes.RegisterAIRoutes(apiRouter, "/ai", app.AIMeter)
 	
 	logger.LogInfo("All routes registered successfully")
 }
@@ -143,6 +197,78 @@ func (app *Application) InitCronjobs() error {
 			},
 			IsActive: true,
 		},
+		{
+			Name:     "feed-update",
+			Interval: "*/1 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedUpdateJob(app.Pb, app.FeedScheduler)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "feed-digest",
+			Interval: "*/1 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedDigestJob(app.Pb, app.DigestService)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "feed-archive",
+			Interval: "*/1 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedArchiveJob(app.Pb, app.FeedArchiver)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "feed-retention",
+			Interval: "15 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedRetentionJob(app.Pb)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "feed-websub",
+			Interval: "*/5 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedWebSubJob(app.Pb, app.WebSubManager)
+			},
+			IsActive: app.WebSubManager.Enabled(),
+		},
+		{
+			Name:     "feed-episode-download",
+			Interval: "*/5 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedEpisodeDownloadJob(app.Pb, app.EpisodeDownloader)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "feed-item-stats",
+			Interval: "*/30 * * * *",
+			JobFunc: func() {
+				cronjobs.FeedItemStatsJob(app.Pb, app.ItemStatsTracker)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "semantic-index",
+			Interval: "*/10 * * * *",
+			JobFunc: func() {
+				cronjobs.SemanticIndexJob(app.Pb, app.SemanticService)
+			},
+			IsActive: true,
+		},
+		{
+			Name:     "ai-usage-prune",
+			Interval: "30 3 * * *",
+			JobFunc: func() {
+				cronjobs.AIUsagePruneJob(app.Pb, app.AIMeter)
+			},
+			IsActive: true,
+		},
 	}
 
 	cronjobs.Run(cronJobs)
@@ -7,6 +7,9 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/security"
 	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/services/search"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
 )
 
 func (app *Application) registerHooks() {
@@ -79,6 +82,20 @@ func (app *Application) registerHooks() {
 		return e.Next()
 	})
 
+	// Reading one copy of a story marks the copies from other sources read
+	feed.RegisterClusterHooks(app.Pb)
+
+	// Saving an item queues an offline snapshot of the linked page
+	feed.RegisterArchiveHooks(app.Pb)
+
+	// Keep the full-text search index in sync with feed items, mail, events
+	// and archived articles
+	search.RegisterHooks(app.Pb)
+
+	// Drop the vectors of deleted records, new ones are embedded by the
+	// semantic-index job
+	semantic.RegisterHooks(app.Pb)
+
 	app.Pb.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
 		logger.LogInfo("Application shutting down...")
 		logger.Cleanup()
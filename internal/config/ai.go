@@ -2,28 +2,84 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 )
 
 const (
 	AIServiceOpenAI = "openai"
 	AIServiceClaude = "claude"
+	AIServiceLocal  = "local"
 	AIServiceNone   = "none"
 	
-	EnvAIService         = "AI_SERVICE"
-	EnvAIAPIKey          = "AI_API_KEY"
-	EnvAIModel           = "AI_MODEL"
-	EnvAIAnthropicAPIKey = "AI_ANTHROPIC_API_KEY"
+	EnvAIService            = "AI_SERVICE"
+	EnvAIAPIKey             = "AI_API_KEY"
+	EnvAIModel              = "AI_MODEL"
+	EnvAIAnthropicAPIKey    = "AI_ANTHROPIC_API_KEY"
+	EnvAIEmbeddingModel     = "AI_EMBEDDING_MODEL"
+	EnvAILocalURL           = "AI_LOCAL_URL"
+	EnvAILocalAPI           = "AI_LOCAL_API"
+	EnvAILocalContextWindow = "AI_LOCAL_CONTEXT_WINDOW"
+	EnvAILocalTimeout       = "AI_LOCAL_TIMEOUT"
+	EnvAILocalAllowRemote   = "AI_LOCAL_ALLOW_REMOTE"
+	EnvAIMonthlyBudget      = "AI_MONTHLY_BUDGET"
+	EnvAIUserMonthlyBudget  = "AI_USER_MONTHLY_BUDGET"
+	EnvAIInputPrice         = "AI_INPUT_PRICE"
+	EnvAIOutputPrice        = "AI_OUTPUT_PRICE"
+	EnvAIEmbeddingPrice     = "AI_EMBEDDING_PRICE"
+	EnvAICacheDays          = "AI_CACHE_DAYS"
 	
 	DefaultAIService = AIServiceNone
 	DefaultOpenAIModel = "gpt-3.5-turbo"
 	DefaultClaudeModel = "claude-3-sonnet-20240229"
+	DefaultLocalModel  = "llama3.1"
+
+	DefaultOpenAIEmbeddingModel = "text-embedding-ada-002"
+	DefaultLocalEmbeddingModel  = "nomic-embed-text"
+	// Selects the built-in hashing embeddings whatever the AI service
+	EmbeddingModelHashing = "hashing"
+
+	// APIs a self-hosted model server can speak
+	LocalAPIOllama = "ollama"
+	LocalAPIOpenAI = "openai"
+
+	DefaultLocalAPI           = LocalAPIOllama
+	DefaultOllamaURL          = "http://localhost:11434"
+	DefaultLocalOpenAIURL     = "http://localhost:8000/v1"
+	DefaultLocalContextWindow = 4096 // tokens
+	DefaultLocalTimeout       = 120  // seconds
+
+	DefaultAICacheDays = 30
 )
 
 type AIConfig struct {
-	Service       string
-	APIKey        string
-	AnthropicKey  string
-	Model         string
+	Service        string
+	APIKey         string
+	AnthropicKey   string
+	Model          string
+	EmbeddingModel string // Model of the vectors used for semantic search
+	Local          LocalAIConfig
+	Usage          AIUsageConfig
+}
+
+// LocalAIConfig configures a self-hosted model server
+type LocalAIConfig struct {
+	API           string // ollama or openai for any OpenAI compatible server
+	BaseURL       string
+	ContextWindow int  // Tokens the model accepts, longer input is truncated
+	TimeoutSecs   int  // Timeout of a single request, local models can be slow
+	AllowRemote   bool // Allow a server outside loopback and private networks
+}
+
+// AIUsageConfig limits what the AI service may cost. Budgets and prices are
+// in USD, prices per million tokens.
+type AIUsageConfig struct {
+	MonthlyBudget     float64 // All users together, unlimited when 0
+	UserMonthlyBudget float64 // Each user, unlimited when 0
+	InputPrice        float64 // Overrides the known price of the model when not negative
+	OutputPrice       float64 // Overrides the known price of the model when not negative
+	EmbeddingPrice    float64 // Overrides the known price of the embedding model when not negative
+	CacheDays         int     // Cached responses unused for longer are dropped
 }
 
 func GetAIConfig() AIConfig {
@@ -34,7 +90,9 @@ func GetAIConfig() AIConfig {
 	
 	if service == AIServiceNone {
 		return AIConfig{
-			Service: AIServiceNone,
+			Service:        AIServiceNone,
+			EmbeddingModel: EmbeddingModelHashing,
+			Usage:          getAIUsageConfig(),
 		}
 	}
 	
@@ -50,15 +108,75 @@ func GetAIConfig() AIConfig {
 		switch service {
 		case AIServiceClaude:
 			model = DefaultClaudeModel
+		case AIServiceLocal:
+			model = DefaultLocalModel
 		default:
 			model = DefaultOpenAIModel
 		}
 	}
 	
+	embeddingModel := os.Getenv(EnvAIEmbeddingModel)
+	if embeddingModel == "" {
+		switch service {
+		case AIServiceOpenAI:
+			embeddingModel = DefaultOpenAIEmbeddingModel
+		case AIServiceLocal:
+			embeddingModel = DefaultLocalEmbeddingModel
+		default:
+			embeddingModel = EmbeddingModelHashing
+		}
+	}
+	
 	return AIConfig{
-		Service:      service,
-		APIKey:       apiKey,
-		AnthropicKey: anthropicKey,
-		Model:        model,
+		Service:        service,
+		APIKey:         apiKey,
+		AnthropicKey:   anthropicKey,
+		Model:          model,
+		EmbeddingModel: embeddingModel,
+		Local:          getLocalAIConfig(),
+		Usage:          getAIUsageConfig(),
 	}
-} 
\ No newline at end of file
+}
+
+func getLocalAIConfig() LocalAIConfig {
+	api := strings.ToLower(os.Getenv(EnvAILocalAPI))
+	if api == "" {
+		api = DefaultLocalAPI
+	}
+
+	baseURL := strings.TrimRight(os.Getenv(EnvAILocalURL), "/")
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+		if api == LocalAPIOpenAI {
+			baseURL = DefaultLocalOpenAIURL
+		}
+	}
+
+	return LocalAIConfig{
+		API:           api,
+		BaseURL:       baseURL,
+		ContextWindow: getEnvInt(EnvAILocalContextWindow, DefaultLocalContextWindow),
+		TimeoutSecs:   getEnvInt(EnvAILocalTimeout, DefaultLocalTimeout),
+		AllowRemote:   os.Getenv(EnvAILocalAllowRemote) == "true",
+	}
+} 
+
+func getAIUsageConfig() AIUsageConfig {
+	return AIUsageConfig{
+		MonthlyBudget:     getEnvFloat(EnvAIMonthlyBudget, 0),
+		UserMonthlyBudget: getEnvFloat(EnvAIUserMonthlyBudget, 0),
+		InputPrice:        getEnvFloat(EnvAIInputPrice, -1),
+		OutputPrice:       getEnvFloat(EnvAIOutputPrice, -1),
+		EmbeddingPrice:    getEnvFloat(EnvAIEmbeddingPrice, -1),
+		CacheDays:         getEnvInt(EnvAICacheDays, DefaultAICacheDays),
+	}
+}
+
+// getEnvFloat reads a non-negative number, defaultValue when unset or invalid
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
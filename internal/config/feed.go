@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	EnvFeedSchedulerConcurrency = "FEED_SCHEDULER_CONCURRENCY"
+	EnvFeedMaxFailures          = "FEED_MAX_CONSECUTIVE_FAILURES"
+	EnvFeedDefaultRefreshRate   = "FEED_DEFAULT_REFRESH_RATE"
+	EnvFeedHostRequestsPerMin   = "FEED_HOST_REQUESTS_PER_MINUTE"
+	EnvFeedHostRateLimits       = "FEED_HOST_RATE_LIMITS"
+	EnvFeedArchiveQuotaMB       = "FEED_ARCHIVE_QUOTA_MB"
+	EnvFeedWebSubBaseURL        = "FEED_WEBSUB_BASE_URL"
+	EnvFeedEpisodeMaxMB         = "FEED_EPISODE_MAX_MB"
+	EnvFeedEpisodeQuotaMB       = "FEED_EPISODE_QUOTA_MB"
+
+	DefaultFeedSchedulerConcurrency = 5
+	DefaultFeedMaxFailures          = 10
+	DefaultFeedRefreshRate          = 60 // minutes
+	DefaultFeedHostRequestsPerMin   = 60
+	DefaultFeedArchiveQuotaMB       = 500
+	DefaultFeedEpisodeMaxMB         = 300
+	DefaultFeedEpisodeQuotaMB       = 2048
+)
+
+type FeedConfig struct {
+	Concurrency        int            // Maximum number of sources fetched at the same time
+	MaxFailures        int            // Consecutive failures before a source is deactivated
+	DefaultRefreshRate int            // Refresh rate in minutes for sources without one
+	HostRequestsPerMin int            // Requests per minute allowed to a single host
+	HostRateLimits     map[string]int // Requests per minute of hosts with their own limit, 0 for none
+	ArchiveQuotaMB     int            // Storage each user may use for archived articles
+	WebSubBaseURL      string         // Public URL of this server for WebSub callbacks, WebSub is off when empty
+	EpisodeMaxMB       int            // Largest podcast episode that is downloaded
+	EpisodeQuotaMB     int            // Storage each user may use for downloaded episodes
+}
+
+func GetFeedConfig() FeedConfig {
+	return FeedConfig{
+		Concurrency:        getEnvInt(EnvFeedSchedulerConcurrency, DefaultFeedSchedulerConcurrency),
+		MaxFailures:        getEnvInt(EnvFeedMaxFailures, DefaultFeedMaxFailures),
+		DefaultRefreshRate: getEnvInt(EnvFeedDefaultRefreshRate, DefaultFeedRefreshRate),
+		HostRequestsPerMin: getEnvInt(EnvFeedHostRequestsPerMin, DefaultFeedHostRequestsPerMin),
+		HostRateLimits:     getEnvHostRates(EnvFeedHostRateLimits),
+		ArchiveQuotaMB:     getEnvInt(EnvFeedArchiveQuotaMB, DefaultFeedArchiveQuotaMB),
+		WebSubBaseURL:      strings.TrimRight(os.Getenv(EnvFeedWebSubBaseURL), "/"),
+		EpisodeMaxMB:       getEnvInt(EnvFeedEpisodeMaxMB, DefaultFeedEpisodeMaxMB),
+		EpisodeQuotaMB:     getEnvInt(EnvFeedEpisodeQuotaMB, DefaultFeedEpisodeQuotaMB),
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvHostRates parses per-host request rates given as
+// "host=requests,host=requests", e.g. "youtube.com=120,example.org=10"
+func getEnvHostRates(key string) map[string]int {
+	rates := map[string]int{}
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		host, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(host) == "" {
+			continue
+		}
+		rate, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || rate < 0 {
+			continue
+		}
+		rates[strings.ToLower(strings.TrimSpace(host))] = rate
+	}
+	return rates
+}
@@ -0,0 +1,24 @@
+package cronjobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+)
+
+// AIUsagePruneJob drops stale cached AI responses and old usage records
+func AIUsagePruneJob(app *pocketbase.PocketBase, meter *ai.Meter) error {
+	cached, usage, err := meter.Prune(time.Now())
+	if cached > 0 || usage > 0 {
+		logger.LogInfo(fmt.Sprintf("Pruned %d cached AI responses and %d AI usage records", cached, usage))
+	}
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error pruning AI usage: %v", err))
+		return err
+	}
+
+	return nil
+}
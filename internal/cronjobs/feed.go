@@ -7,29 +7,105 @@ import (
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/shashank-sharma/backend/internal/logger"
-	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/feed"
 )
 
 // FeedUpdateJob runs scheduled feed updates
-func FeedUpdateJob(app *pocketbase.PocketBase, feedService services.FeedService) error {
+func FeedUpdateJob(app *pocketbase.PocketBase, scheduler *feed.FeedScheduler) error {
 	logger.LogInfo("Starting feed update job")
 
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	// Fetch from all sources
-	if err := feedService.FetchAllSources(ctx); err != nil {
-		logger.LogError(fmt.Sprintf("Error fetching feeds: %v", err))
+	// Fetch due sources and process the new items
+	if err := scheduler.Run(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error running feed scheduler: %v", err))
 		return err
 	}
 
-	// Process new items
-	if err := feedService.ProcessNewItems(ctx); err != nil {
-		logger.LogError(fmt.Sprintf("Error processing feed items: %v", err))
+	logger.LogInfo("Feed update job completed")
+	return nil
+}
+
+// FeedDigestJob sends the feed digests that are due
+func FeedDigestJob(app *pocketbase.PocketBase, digestService *feed.DigestService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := digestService.RunDue(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error running feed digests: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// FeedArchiveJob downloads the pages of newly saved feed items
+func FeedArchiveJob(app *pocketbase.PocketBase, archiver *feed.Archiver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := archiver.RunPending(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error archiving feed items: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// FeedRetentionJob deletes the feed items that expired under the users'
+// retention policies
+func FeedRetentionJob(app *pocketbase.PocketBase) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := feed.RunRetention(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error applying feed retention: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// FeedWebSubJob subscribes feed sources to their WebSub hubs and renews
+// leases before they expire
+func FeedWebSubJob(app *pocketbase.PocketBase, manager *feed.WebSubManager) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := manager.Sync(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error syncing WebSub subscriptions: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// FeedEpisodeDownloadJob downloads the podcast episodes queued for offline
+// listening
+func FeedEpisodeDownloadJob(app *pocketbase.PocketBase, downloader *feed.EpisodeDownloader) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	if err := downloader.RunPending(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error downloading podcast episodes: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// FeedItemStatsJob re-polls the score and comment count of recent items so
+// ranking can follow their trajectory
+func FeedItemStatsJob(app *pocketbase.PocketBase, tracker *feed.ItemStatsTracker) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+	defer cancel()
+
+	if err := tracker.Run(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error tracking feed item stats: %v", err))
 		return err
 	}
 
-	logger.LogInfo("Feed update job completed")
 	return nil
 }
@@ -0,0 +1,35 @@
+package cronjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
+)
+
+// SemanticIndexJob embeds new and changed records for semantic search
+func SemanticIndexJob(app *pocketbase.PocketBase, service *semantic.Service) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 9*time.Minute)
+	defer cancel()
+
+	count, err := service.Run(ctx)
+	if count > 0 {
+		logger.LogInfo(fmt.Sprintf("Embedded %d records for semantic search", count))
+	}
+	if errors.Is(err, ai.ErrBudgetExceeded) {
+		// Picked up again once the budget allows
+		logger.LogDebug("Semantic index paused, the monthly AI budget is used up")
+		return nil
+	}
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error updating semantic index: %v", err))
+		return err
+	}
+
+	return nil
+}
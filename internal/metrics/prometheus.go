@@ -45,6 +45,46 @@ var (
 		},
 		[]string{"job_name"},
 	)
+
+	AIRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pocketbase_ai_requests_total",
+			Help: "Total number of AI requests by outcome: ok, error, cached or over_budget",
+		},
+		[]string{"feature", "operation", "model", "status"},
+	)
+
+	AITokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pocketbase_ai_tokens_total",
+			Help: "Total number of tokens sent to and generated by AI models",
+		},
+		[]string{"feature", "model", "direction"},
+	)
+
+	AICost = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "pocketbase_ai_cost_usd_total",
+			Help: "Total cost of AI requests in USD",
+		},
+		[]string{"feature", "model"},
+	)
+
+	AIRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "pocketbase_ai_request_duration_seconds",
+			Help:    "AI request duration in seconds",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		},
+		[]string{"operation", "model"},
+	)
+
+	AIMonthlySpend = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "pocketbase_ai_monthly_spend_usd",
+			Help: "Cost of all AI requests in the current month in USD",
+		},
+	)
 )
 
 // Initialize metrics with default values so they show up in Prometheus
@@ -147,6 +187,28 @@ func TrackCronJobExecution(jobName string, duration time.Duration) {
 	CronJobDuration.WithLabelValues(jobName).Observe(duration.Seconds())
 }
 
+// TrackAIRequest records the outcome, tokens, cost and duration of an AI request
+func TrackAIRequest(feature, operation, model, status string, inputTokens, outputTokens int, cost float64, duration time.Duration) {
+	AIRequests.WithLabelValues(feature, operation, model, status).Inc()
+	if inputTokens > 0 {
+		AITokens.WithLabelValues(feature, model, "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		AITokens.WithLabelValues(feature, model, "output").Add(float64(outputTokens))
+	}
+	if cost > 0 {
+		AICost.WithLabelValues(feature, model).Add(cost)
+	}
+	if duration > 0 {
+		AIRequestDuration.WithLabelValues(operation, model).Observe(duration.Seconds())
+	}
+}
+
+// UpdateAIMonthlySpend sets the AI spend of the current month
+func UpdateAIMonthlySpend(cost float64) {
+	AIMonthlySpend.Set(cost)
+}
+
 // UpdateActiveSessions updates the active sessions gauge
 func UpdateActiveSessions(count int) {
 	ActiveSessions.Set(float64(count))
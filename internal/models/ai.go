@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*AIUsage)(nil)
+var _ core.Model = (*AICacheEntry)(nil)
+
+// AIUsage is a single call to the AI service
+type AIUsage struct {
+	BaseModel
+
+	User         string  `db:"user" json:"user"`           // Empty for work done for all users, like indexing
+	Feature      string  `db:"feature" json:"feature"`     // What the call was made for, e.g. feed_processing
+	Operation    string  `db:"operation" json:"operation"` // summarize, tag, classify, recommend, embed or chat
+	Model        string  `db:"model" json:"model"`
+	InputTokens  int     `db:"input_tokens" json:"input_tokens"`
+	OutputTokens int     `db:"output_tokens" json:"output_tokens"`
+	Cost         float64 `db:"cost" json:"cost"` // USD
+	LatencyMs    int     `db:"latency_ms" json:"latency_ms"`
+	Cached       bool    `db:"cached" json:"cached"`       // Answered from the cache without calling the model
+	Estimated    bool    `db:"estimated" json:"estimated"` // Tokens counted from the text, the API reported none
+	Error        string  `db:"error" json:"error"`
+}
+
+func (m *AIUsage) TableName() string {
+	return "ai_usage"
+}
+
+// AICacheEntry is a stored AI response, reused for identical requests
+type AICacheEntry struct {
+	BaseModel
+
+	Hash      string         `db:"hash" json:"hash"` // SHA-256 of the operation, model and request
+	Operation string         `db:"operation" json:"operation"`
+	Model     string         `db:"model" json:"model"`
+	Response  types.JSONRaw  `db:"response" json:"response"`
+	Hits      int            `db:"hits" json:"hits"`
+	LastUsed  types.DateTime `db:"last_used" json:"last_used"`
+}
+
+func (m *AICacheEntry) TableName() string {
+	return "ai_cache"
+}
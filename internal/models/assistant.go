@@ -0,0 +1,47 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*AssistantConversation)(nil)
+var _ core.Model = (*AssistantMessage)(nil)
+
+// Assistant message roles
+const (
+	AssistantRoleUser      = "user"
+	AssistantRoleAssistant = "assistant"
+)
+
+// AssistantConversation is a conversation of a user with the dashboard
+// assistant
+type AssistantConversation struct {
+	BaseModel
+
+	User          string         `db:"user" json:"user"`
+	Title         string         `db:"title" json:"title"` // The opening question, shortened
+	LastMessageAt types.DateTime `db:"last_message_at" json:"last_message_at"`
+}
+
+func (m *AssistantConversation) TableName() string {
+	return "assistant_conversations"
+}
+
+// AssistantMessage is a question or an answer in an assistant conversation
+type AssistantMessage struct {
+	BaseModel
+
+	User         string        `db:"user" json:"user"`
+	Conversation string        `db:"conversation" json:"conversation"`
+	Role         string        `db:"role" json:"role"` // user, assistant
+	Content      string        `db:"content" json:"content"`
+	Citations    types.JSONRaw `db:"citations" json:"citations"` // Records the answer is based on, referenced as [n] in the content
+	Tools        types.JSONRaw `db:"tools" json:"tools"`         // Lookups run to answer
+	Model        string        `db:"model" json:"model"`         // Empty when answered without AI
+	Error        string        `db:"error" json:"error"`
+}
+
+func (m *AssistantMessage) TableName() string {
+	return "assistant_messages"
+}
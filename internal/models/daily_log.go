@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*DailyLog)(nil)
+
+// DailyLog is the journal entry of a user for a day
+type DailyLog struct {
+	BaseModel
+
+	User    string         `db:"user" json:"user"`
+	Summary string         `db:"summary" json:"summary"` // Editor HTML
+	Score   float64        `db:"score" json:"score"`
+	Date    types.DateTime `db:"date" json:"date"`
+	Bath    bool           `db:"bath" json:"bath"`
+	Feeling string         `db:"feeling" json:"feeling"`
+}
+
+func (m *DailyLog) TableName() string {
+	return "daily_log"
+}
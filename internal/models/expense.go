@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*Expense)(nil)
+
+// Expense is money a user spent
+type Expense struct {
+	BaseModel
+
+	User     string         `db:"user" json:"user"`
+	Amount   float64        `db:"amount" json:"amount"`
+	Currency string         `db:"currency" json:"currency"` // ISO 4217 code, e.g. INR
+	Category string         `db:"category" json:"category"`
+	Merchant string         `db:"merchant" json:"merchant"`
+	Note     string         `db:"note" json:"note"`
+	SpentAt  types.DateTime `db:"spent_at" json:"spent_at"`
+}
+
+func (m *Expense) TableName() string {
+	return "expenses"
+}
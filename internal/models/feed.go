@@ -40,17 +40,20 @@ var _ core.Model = (*FeedCategory)(nil)
 type FeedSource struct {
 	BaseModel
 
-	User        string                  `db:"user" json:"user"`
-	Name        string                  `db:"name" json:"name"`
-	Type        string                  `db:"type" json:"type"`
-	URL         string                  `db:"url" json:"url"`
-	Config      string                  `db:"config" json:"config"`
-	CategoryIDs types.JSONArray[string] `db:"category_ids" json:"category_ids"`
-	RefreshRate int                     `db:"refresh_rate" json:"refresh_rate"`
-	IsActive    bool                    `db:"is_active" json:"is_active"`
-	LastFetched types.DateTime          `db:"last_fetched" json:"last_fetched"`
-	ErrorCount  int                     `db:"error_count" json:"error_count"`
-	LastError   string                  `db:"last_error" json:"last_error"`
+	User         string                  `db:"user" json:"user"`
+	Name         string                  `db:"name" json:"name"`
+	Type         string                  `db:"type" json:"type"`
+	URL          string                  `db:"url" json:"url"`
+	Config       string                  `db:"config" json:"config"`
+	CategoryIDs  types.JSONArray[string] `db:"category_ids" json:"category_ids"`
+	RefreshRate  int                     `db:"refresh_rate" json:"refresh_rate"`
+	IsActive     bool                    `db:"is_active" json:"is_active"`
+	LastFetched  types.DateTime          `db:"last_fetched" json:"last_fetched"`
+	ErrorCount   int                     `db:"error_count" json:"error_count"`
+	LastError    string                  `db:"last_error" json:"last_error"`
+	ETag         string                  `db:"etag" json:"etag"`                   // HTTP cache validator from the last fetch
+	LastModified string                  `db:"last_modified" json:"last_modified"` // HTTP cache validator from the last fetch
+	RetryAfter   types.DateTime          `db:"retry_after" json:"retry_after"`     // Set when the publisher rate limited us
 }
 
 // GetConfigMap converts the Config string to a map[string]interface{}
@@ -87,22 +90,40 @@ func (m *FeedSource) SetConfigMap(configMap map[string]interface{}) error {
 type FeedItem struct {
 	BaseModel
 
-	User        string                  `db:"user" json:"user"`
-	SourceID    string                  `db:"source_id" json:"source_id"`
-	ExternalID  string                  `db:"external_id" json:"external_id"`
-	Title       string                  `db:"title" json:"title"`
-	Content     string                  `db:"content" json:"content"`
-	URL         string                  `db:"url" json:"url"`
-	Author      string                  `db:"author" json:"author"`
-	PublishedAt types.DateTime          `db:"published_at" json:"published_at"`
-	FetchedAt   types.DateTime          `db:"fetched_at" json:"fetched_at"`
-	Status      string                  `db:"status" json:"status"`             // unread, read, saved, dismissed
-	Rating      int                     `db:"rating" json:"rating"`             // User rating (1-5)
-	Tags        types.JSONArray[string] `db:"tags" json:"tags"`                 // Tags for the feed item (many-to-many)
-	CategoryIDs types.JSONArray[string] `db:"category_ids" json:"category_ids"` // Categories this item belongs to
-	Summary     string                  `db:"summary" json:"summary"`           // AI-generated summary
-	Metadata    string                  `db:"metadata" json:"metadata"`         // Additional source-specific data
-	IsProcessed bool                    `db:"is_processed" json:"is_processed"` // Whether AI processing is complete
+	User             string                  `db:"user" json:"user"`
+	SourceID         string                  `db:"source_id" json:"source_id"`
+	ExternalID       string                  `db:"external_id" json:"external_id"`
+	Title            string                  `db:"title" json:"title"`
+	Content          string                  `db:"content" json:"content"`
+	URL              string                  `db:"url" json:"url"`
+	Author           string                  `db:"author" json:"author"`
+	PublishedAt      types.DateTime          `db:"published_at" json:"published_at"`
+	FetchedAt        types.DateTime          `db:"fetched_at" json:"fetched_at"`
+	Status           string                  `db:"status" json:"status"`                         // unread, read, saved, dismissed
+	Rating           int                     `db:"rating" json:"rating"`                         // User rating (1-5)
+	Tags             types.JSONArray[string] `db:"tags" json:"tags"`                             // Tags for the feed item (many-to-many)
+	CategoryIDs      types.JSONArray[string] `db:"category_ids" json:"category_ids"`             // Categories this item belongs to
+	Summary          string                  `db:"summary" json:"summary"`                       // AI-generated summary
+	Metadata         string                  `db:"metadata" json:"metadata"`                     // Additional source-specific data
+	IsProcessed      bool                    `db:"is_processed" json:"is_processed"`             // Whether AI processing is complete
+	FullContent      string                  `db:"full_content" json:"full_content"`             // Cleaned HTML of the linked article
+	LeadImage        string                  `db:"lead_image" json:"lead_image"`                 // Lead image of the linked article
+	ReadingTime      int                     `db:"reading_time" json:"reading_time"`             // Estimated reading time in minutes
+	Language         string                  `db:"language" json:"language"`                     // Language of the linked article
+	CanonicalURL     string                  `db:"canonical_url" json:"canonical_url"`           // URL without tracking parameters and redirects
+	SimHash          string                  `db:"simhash" json:"simhash"`                       // Hex SimHash fingerprint of the title
+	ContentSimHash   string                  `db:"content_simhash" json:"content_simhash"`       // Hex SimHash fingerprint of long content
+	ClusterID        string                  `db:"cluster_id" json:"cluster_id"`                 // Items about the same story share a cluster
+	IsClusterPrimary bool                    `db:"is_cluster_primary" json:"is_cluster_primary"` // The item shown for its cluster
+}
+
+// ArticleContent returns the full article content when it has been extracted,
+// falling back to the content from the feed
+func (m *FeedItem) ArticleContent() string {
+	if m.FullContent != "" {
+		return m.FullContent
+	}
+	return m.Content
 }
 
 // GetMetadataMap converts the Metadata string to a map[string]interface{}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedArchive)(nil)
+
+// Archive status values
+const (
+	ArchiveStatusPending       = "pending"
+	ArchiveStatusArchived      = "archived"
+	ArchiveStatusFailed        = "failed"
+	ArchiveStatusQuotaExceeded = "quota_exceeded"
+)
+
+// FeedArchive is an offline snapshot of the page linked by a saved feed item
+type FeedArchive struct {
+	BaseModel
+
+	User       string                  `db:"user" json:"user"`
+	Item       string                  `db:"item" json:"item"`
+	URL        string                  `db:"url" json:"url"`
+	Title      string                  `db:"title" json:"title"`
+	Status     string                  `db:"status" json:"status"` // pending, archived, failed, quota_exceeded
+	HTML       string                  `db:"html" json:"html"`     // Cleaned article, images point at the stored files
+	Text       string                  `db:"text" json:"text"`
+	Images     types.JSONArray[string] `db:"images" json:"images"` // Stored image file names
+	Size       int                     `db:"size" json:"size"`     // Bytes used by the HTML, text and images
+	Attempts   int                     `db:"attempts" json:"attempts"`
+	Error      string                  `db:"error" json:"error"`
+	ArchivedAt types.DateTime          `db:"archived_at" json:"archived_at"`
+}
+
+func (m *FeedArchive) TableName() string {
+	return "feed_archives"
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedDigestSettings)(nil)
+var _ core.Model = (*FeedDigest)(nil)
+
+// Digest frequencies
+const (
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// Digest delivery channels
+const (
+	DigestChannelEmail        = "email"
+	DigestChannelNotification = "notification"
+)
+
+type FeedDigestSettings struct {
+	BaseModel
+
+	User         string                  `db:"user" json:"user"`
+	IsEnabled    bool                    `db:"is_enabled" json:"is_enabled"`
+	Frequency    string                  `db:"frequency" json:"frequency"`         // daily, weekly
+	TimeOfDay    string                  `db:"time_of_day" json:"time_of_day"`     // HH:MM in the user's timezone
+	Timezone     string                  `db:"timezone" json:"timezone"`           // IANA name, e.g. Europe/Berlin
+	Weekday      int                     `db:"weekday" json:"weekday"`             // 0 (Sunday) - 6, weekly digests only
+	Channels     types.JSONArray[string] `db:"channels" json:"channels"`           // email, notification
+	IncludeSaved bool                    `db:"include_saved" json:"include_saved"` // Also list items saved during the period
+	MaxItems     int                     `db:"max_items" json:"max_items"`
+	LastSentAt   types.DateTime          `db:"last_sent_at" json:"last_sent_at"`
+}
+
+type FeedDigest struct {
+	BaseModel
+
+	User        string                  `db:"user" json:"user"`
+	Title       string                  `db:"title" json:"title"`
+	Frequency   string                  `db:"frequency" json:"frequency"`
+	PeriodStart types.DateTime          `db:"period_start" json:"period_start"`
+	PeriodEnd   types.DateTime          `db:"period_end" json:"period_end"`
+	ItemCount   int                     `db:"item_count" json:"item_count"`
+	ItemIDs     types.JSONArray[string] `db:"item_ids" json:"item_ids"`
+	HTML        string                  `db:"html" json:"html"`
+	Markdown    string                  `db:"markdown" json:"markdown"`
+	Channels    types.JSONArray[string] `db:"channels" json:"channels"` // Channels the digest was delivered to
+}
+
+func (m *FeedDigestSettings) TableName() string {
+	return "feed_digest_settings"
+}
+
+func (m *FeedDigest) TableName() string {
+	return "feed_digests"
+}
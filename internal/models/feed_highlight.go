@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedHighlight)(nil)
+
+// Highlight colors
+const (
+	HighlightColorYellow = "yellow"
+	HighlightColorGreen  = "green"
+	HighlightColorBlue   = "blue"
+	HighlightColorPink   = "pink"
+	HighlightColorPurple = "purple"
+)
+
+// FeedHighlight is a highlighted passage of a feed item, or of its archived
+// article, with an optional note
+type FeedHighlight struct {
+	BaseModel
+
+	User           string         `db:"user" json:"user"`
+	Item           string         `db:"item" json:"item"`
+	Archive        string         `db:"archive" json:"archive"` // Set when highlighted in the archived article
+	Text           string         `db:"text" json:"text"`       // The highlighted passage
+	Note           string         `db:"note" json:"note"`
+	Color          string         `db:"color" json:"color"`               // yellow, green, blue, pink, purple
+	StartOffset    int            `db:"start_offset" json:"start_offset"` // Character range in the plain text of the content
+	EndOffset      int            `db:"end_offset" json:"end_offset"`     // Exclusive
+	Prefix         string         `db:"prefix" json:"prefix"`             // Text before the passage, to find it again when the content changes
+	Suffix         string         `db:"suffix" json:"suffix"`             // Text after the passage
+	ReviewCount    int            `db:"review_count" json:"review_count"` // Times resurfaced by the daily review
+	LastReviewedAt types.DateTime `db:"last_reviewed_at" json:"last_reviewed_at"`
+}
+
+func (m *FeedHighlight) TableName() string {
+	return "feed_highlights"
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedEpisodeDownload)(nil)
+var _ core.Model = (*FeedPlaybackPosition)(nil)
+
+// Episode download status values
+const (
+	EpisodeDownloadPending       = "pending"
+	EpisodeDownloadDownloaded    = "downloaded"
+	EpisodeDownloadFailed        = "failed"
+	EpisodeDownloadTooLarge      = "too_large"
+	EpisodeDownloadQuotaExceeded = "quota_exceeded"
+)
+
+// FeedEpisodeDownload is a podcast episode stored for offline listening
+type FeedEpisodeDownload struct {
+	BaseModel
+
+	User         string         `db:"user" json:"user"`
+	Item         string         `db:"item" json:"item"`
+	URL          string         `db:"url" json:"url"`
+	Audio        string         `db:"audio" json:"audio"` // Stored file name
+	ContentType  string         `db:"content_type" json:"content_type"`
+	Size         int64          `db:"size" json:"size"`
+	Status       string         `db:"status" json:"status"` // pending, downloaded, failed, too_large, quota_exceeded
+	Attempts     int            `db:"attempts" json:"attempts"`
+	Error        string         `db:"error" json:"error"`
+	DownloadedAt types.DateTime `db:"downloaded_at" json:"downloaded_at"`
+}
+
+func (m *FeedEpisodeDownload) TableName() string {
+	return "feed_episode_downloads"
+}
+
+// FeedPlaybackPosition is where a user stopped listening to an episode
+type FeedPlaybackPosition struct {
+	BaseModel
+
+	User      string         `db:"user" json:"user"`
+	Item      string         `db:"item" json:"item"`
+	Position  float64        `db:"position" json:"position"` // Seconds from the start
+	Duration  float64        `db:"duration" json:"duration"` // Seconds, as reported by the player
+	Completed bool           `db:"completed" json:"completed"`
+	PlayedAt  types.DateTime `db:"played_at" json:"played_at"`
+}
+
+func (m *FeedPlaybackPosition) TableName() string {
+	return "feed_playback_positions"
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var _ core.Model = (*FeedPublication)(nil)
+
+// Publication scopes
+const (
+	PublicationScopeAll      = "all"
+	PublicationScopeCategory = "category"
+	PublicationScopeTag      = "tag"
+)
+
+// Publication output formats
+const (
+	PublicationFormatAtom = "atom"
+	PublicationFormatRSS  = "rss"
+	PublicationFormatJSON = "json"
+)
+
+// FeedPublication republishes a selection of a user's feed items as a feed
+// other readers can subscribe to. Anyone holding the token can read it.
+type FeedPublication struct {
+	BaseModel
+
+	User        string `db:"user" json:"user"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	Token       string `db:"token" json:"token"`           // Secret part of the public URL
+	Scope       string `db:"scope" json:"scope"`           // all, category, tag
+	ScopeID     string `db:"scope_id" json:"scope_id"`     // Category or tag ID for those scopes
+	SavedOnly   bool   `db:"saved_only" json:"saved_only"` // Only publish saved items
+	MaxItems    int    `db:"max_items" json:"max_items"`
+	IsActive    bool   `db:"is_active" json:"is_active"`
+}
+
+func (m *FeedPublication) TableName() string {
+	return "feed_publications"
+}
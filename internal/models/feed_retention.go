@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedRetentionPolicy)(nil)
+
+// Retention policy scopes
+const (
+	RetentionScopeAll      = "all"
+	RetentionScopeCategory = "category"
+	RetentionScopeSource   = "source"
+)
+
+// FeedRetentionPolicy deletes old feed items of a source, a category or all
+// sources. Saved items and items with highlights are never deleted.
+type FeedRetentionPolicy struct {
+	BaseModel
+
+	User          string         `db:"user" json:"user"`
+	Scope         string         `db:"scope" json:"scope"`                   // all, category, source
+	ScopeID       string         `db:"scope_id" json:"scope_id"`             // Category or source ID for those scopes
+	ReadDays      int            `db:"read_days" json:"read_days"`           // Delete read items older than this, 0 keeps them
+	UnreadDays    int            `db:"unread_days" json:"unread_days"`       // Delete unread items older than this, 0 keeps them
+	DismissedDays int            `db:"dismissed_days" json:"dismissed_days"` // Delete dismissed items older than this, 0 keeps them
+	KeepRated     bool           `db:"keep_rated" json:"keep_rated"`         // Never delete items with a rating
+	IsActive      bool           `db:"is_active" json:"is_active"`
+	LastRunAt     types.DateTime `db:"last_run_at" json:"last_run_at"`
+	LastDeleted   int            `db:"last_deleted" json:"last_deleted"` // Items deleted by the last run
+}
+
+func (m *FeedRetentionPolicy) TableName() string {
+	return "feed_retention_policies"
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedRule)(nil)
+
+// Rule match modes
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+// Rule condition operators
+const (
+	RuleOpContains    = "contains"
+	RuleOpNotContains = "not_contains"
+	RuleOpEquals      = "equals"
+	RuleOpNotEquals   = "not_equals"
+	RuleOpMatches     = "matches" // regular expression
+	RuleOpIn          = "in"
+	RuleOpGreater     = "gt"
+	RuleOpGreaterEq   = "gte"
+	RuleOpLess        = "lt"
+	RuleOpLessEq      = "lte"
+)
+
+// Rule actions
+const (
+	RuleActionAddTag      = "add_tag"
+	RuleActionAddCategory = "add_category"
+	RuleActionDismiss     = "dismiss"
+	RuleActionSave        = "save"
+	RuleActionNotify      = "notify"
+	RuleActionWorkflow    = "workflow"
+)
+
+// FeedRuleCondition checks a single field of a feed item. Field is one of
+// source, source_type, title, content, text, author, url, tags, score or
+// metadata.<key>.
+type FeedRuleCondition struct {
+	Field         string      `json:"field"`
+	Operator      string      `json:"operator"`
+	Value         interface{} `json:"value"`
+	CaseSensitive bool        `json:"case_sensitive,omitempty"`
+}
+
+// FeedRuleAction is applied to items matching a rule. Value holds the tag
+// name, category ID, notification title or workflow ID depending on the type.
+type FeedRuleAction struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+type FeedRule struct {
+	BaseModel
+
+	User           string                             `db:"user" json:"user"`
+	Name           string                             `db:"name" json:"name"`
+	IsActive       bool                               `db:"is_active" json:"is_active"`
+	SortOrder      int                                `db:"sort_order" json:"sort_order"`             // Rules are evaluated in ascending order
+	MatchMode      string                             `db:"match_mode" json:"match_mode"`             // all, any
+	Conditions     types.JSONArray[FeedRuleCondition] `db:"conditions" json:"conditions"`
+	Actions        types.JSONArray[FeedRuleAction]    `db:"actions" json:"actions"`
+	StopProcessing bool                               `db:"stop_processing" json:"stop_processing"` // Skip later rules once this one matched
+	HitCount       int                                `db:"hit_count" json:"hit_count"`
+	LastHitAt      types.DateTime                     `db:"last_hit_at" json:"last_hit_at"`
+}
+
+func (m *FeedRule) TableName() string {
+	return "feed_rules"
+}
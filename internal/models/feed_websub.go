@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var _ core.Model = (*FeedWebSubSubscription)(nil)
+
+// WebSub subscription states
+const (
+	WebSubStatePending       = "pending"       // Hub discovered, subscription not requested yet
+	WebSubStateSubscribing   = "subscribing"   // Requested, waiting for the hub to verify
+	WebSubStateSubscribed    = "subscribed"    // Verified, the hub pushes updates until the lease expires
+	WebSubStateUnsubscribing = "unsubscribing" // The source no longer wants pushes
+	WebSubStateDenied        = "denied"        // The hub refused the subscription
+	WebSubStateFailed        = "failed"        // Requests kept failing, the source is polled
+)
+
+// FeedWebSubSubscription is a WebSub (PubSubHubbub) subscription of a feed
+// source at the hub its feed advertises
+type FeedWebSubSubscription struct {
+	BaseModel
+
+	User         string         `db:"user" json:"user"`
+	Source       string         `db:"source" json:"source"`
+	Hub          string         `db:"hub" json:"hub"`
+	Topic        string         `db:"topic" json:"topic"` // The feed URL the hub knows the feed by
+	Secret       string         `db:"secret" json:"-"`    // Key of the HMAC signatures on pushed content
+	State        string         `db:"state" json:"state"`
+	LeaseSeconds int            `db:"lease_seconds" json:"lease_seconds"`
+	ExpiresAt    types.DateTime `db:"expires_at" json:"expires_at"`
+	RequestedAt  types.DateTime `db:"requested_at" json:"requested_at"` // When the last request was sent to the hub
+	VerifiedAt   types.DateTime `db:"verified_at" json:"verified_at"`
+	LastPushAt   types.DateTime `db:"last_push_at" json:"last_push_at"`
+	Attempts     int            `db:"attempts" json:"attempts"` // Failed subscription requests in a row
+	LastError    string         `db:"last_error" json:"last_error"`
+}
+
+func (m *FeedWebSubSubscription) TableName() string {
+	return "feed_websub_subscriptions"
+}
@@ -11,6 +11,7 @@ var _ core.Model = (*WorkflowConnection)(nil)
 var _ core.Model = (*WorkflowExecution)(nil)
 var _ core.Model = (*WorkflowResult)(nil)
 var _ core.Model = (*Connector)(nil)
+var _ core.Model = (*WorkflowSyncState)(nil)
 
 // Workflow represents the core workflow definition
 type Workflow struct {
@@ -80,6 +81,18 @@ type Connector struct {
 	Icon         string `db:"icon" json:"icon,omitempty"`
 }
 
+// WorkflowSyncState stores the incremental sync state of a source node between executions
+type WorkflowSyncState struct {
+	BaseModel
+
+	WorkflowID    string         `db:"workflow_id" json:"workflow_id"`
+	NodeID        string         `db:"node_id" json:"node_id"`
+	User          string         `db:"user" json:"user"`
+	State         string         `db:"state" json:"state"`                     // JSON string (last_seen, cursor, history_id, ...)
+	LastRun       types.DateTime `db:"last_run" json:"last_run"`
+	LastExecution string         `db:"last_execution" json:"last_execution"` // ID of the execution that last updated the state
+}
+
 func (m *Workflow) TableName() string {
 	return "workflows"
 }
@@ -102,4 +115,8 @@ func (m *WorkflowResult) TableName() string {
 
 func (m *Connector) TableName() string {
 	return "connectors"
-} 
\ No newline at end of file
+} 
+
+func (m *WorkflowSyncState) TableName() string {
+	return "workflow_sync_states"
+}
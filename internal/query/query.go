@@ -52,6 +52,13 @@ func SaveRecord(model models.Model) error {
 	return nil
 }
 
+func DeleteRecord(model models.Model) error {
+	if err := store.GetDao().Delete(model); err != nil {
+		return err
+	}
+	return nil
+}
+
 func UpsertRecord[T models.Model](model T, filterStruct map[string]interface{}) error {
 	record, err := FindByFilter[T](filterStruct)
 	if err == nil {
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// RegisterAIRoutes registers the AI usage API routes
+func RegisterAIRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, meter *ai.Meter) {
+	aiRouter := apiRouter.Group(path)
+	aiRouter.GET("/usage", func(e *core.RequestEvent) error {
+		return GetAIUsage(e, meter)
+	})
+}
+
+// GetAIUsage reports the AI requests made for the authenticated user in a
+// month: tokens and cost per feature, model and day, and the spend against
+// the monthly budgets.
+//
+// Query parameters: month (YYYY-MM, the current month when empty)
+func GetAIUsage(e *core.RequestEvent, meter *ai.Meter) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	month := time.Now()
+	if value := e.Request.URL.Query().Get("month"); value != "" {
+		if month, err = time.Parse("2006-01", value); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid month, expected YYYY-MM"})
+		}
+	}
+
+	report, err := meter.Report(userId, month)
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch AI usage"})
+	}
+
+	return e.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,208 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/services/assistant"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// AskRequest is a question for the dashboard assistant
+type AskRequest struct {
+	Question       string `json:"question"`
+	ConversationID string `json:"conversation_id"` // Empty starts a new conversation
+	Timezone       string `json:"timezone"`        // IANA name dates are read and given in, UTC when empty
+	Stream         bool   `json:"stream"`          // Send the answer as server-sent events while it is written
+}
+
+func RegisterAssistantRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, assistantService *assistant.Service) {
+	assistantRouter := apiRouter.Group(path)
+	assistantRouter.POST("/ask", func(e *core.RequestEvent) error {
+		return AskAssistant(e, assistantService)
+	})
+	assistantRouter.GET("/conversations", func(e *core.RequestEvent) error {
+		return ListAssistantConversations(e, assistantService)
+	})
+	assistantRouter.GET("/conversations/{id}", func(e *core.RequestEvent) error {
+		return GetAssistantConversation(e, assistantService)
+	})
+	assistantRouter.DELETE("/conversations/{id}", func(e *core.RequestEvent) error {
+		return DeleteAssistantConversation(e, assistantService)
+	})
+}
+
+// AskAssistant answers a question from the records of the authenticated user
+// and cites the records it is based on.
+//
+// With stream set, or an Accept header of text/event-stream, the answer is
+// sent as server-sent events: "conversation" with the conversation and the
+// stored question, "delta" with each piece of the answer, then "answer" with
+// the stored answer and its citations, or "error".
+func AskAssistant(e *core.RequestEvent, assistantService *assistant.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	var req AskRequest
+	if err := e.BindBody(&req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Missing question"})
+	}
+	if utf8.RuneCountInString(req.Question) > assistant.MaxQuestionLength {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": fmt.Sprintf("Question is longer than %d characters", assistant.MaxQuestionLength)})
+	}
+
+	location := time.UTC
+	if req.Timezone != "" {
+		if location, err = time.LoadLocation(req.Timezone); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid timezone"})
+		}
+	}
+
+	opts := assistant.AskOptions{
+		ConversationID: req.ConversationID,
+		Location:       location,
+	}
+
+	stream := req.Stream || strings.Contains(e.Request.Header.Get("Accept"), "text/event-stream")
+	streaming := false
+	send := func(event string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(e.Response, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		return e.Flush()
+	}
+	if stream {
+		// The stream starts once the question is stored, earlier errors are
+		// still plain JSON responses
+		opts.OnQuestion = func(conversation *models.AssistantConversation, question *models.AssistantMessage) error {
+			e.Response.Header().Set("Content-Type", "text/event-stream")
+			e.Response.Header().Set("Cache-Control", "no-cache")
+			e.Response.Header().Set("X-Accel-Buffering", "no")
+			e.Response.WriteHeader(http.StatusOK)
+			streaming = true
+			return send("conversation", map[string]interface{}{
+				"conversation": conversation,
+				"question":     question,
+			})
+		}
+		opts.OnDelta = func(delta string) error {
+			return send("delta", map[string]interface{}{"content": delta})
+		}
+	}
+
+	answer, err := assistantService.Ask(e.Request.Context(), userId, req.Question, opts)
+	if err != nil {
+		if !errors.Is(err, assistant.ErrNotFound) {
+			logger.LogError("Assistant question failed: " + err.Error())
+		}
+		if streaming {
+			send("error", map[string]interface{}{"error": "Failed to answer the question"})
+			return nil
+		}
+		if errors.Is(err, assistant.ErrNotFound) {
+			return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Conversation not found"})
+		}
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to answer the question"})
+	}
+
+	if streaming {
+		send("answer", answer)
+		return nil
+	}
+	return e.JSON(http.StatusOK, answer)
+}
+
+// ListAssistantConversations returns the assistant conversations of the
+// authenticated user, latest first
+func ListAssistantConversations(e *core.RequestEvent, assistantService *assistant.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	params := e.Request.URL.Query()
+	limit := 50
+	if limitStr := params.Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = min(parsed, 100)
+		}
+	}
+	offset := 0
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	conversations, err := assistantService.Conversations(userId, limit, offset)
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch conversations"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"conversations": conversations,
+	})
+}
+
+// GetAssistantConversation returns an assistant conversation with its messages
+func GetAssistantConversation(e *core.RequestEvent, assistantService *assistant.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	conversation, messages, err := assistantService.Conversation(userId, e.Request.PathValue("id"))
+	if errors.Is(err, assistant.ErrNotFound) {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Conversation not found"})
+	}
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch conversation"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"conversation": conversation,
+		"messages":     messages,
+	})
+}
+
+// DeleteAssistantConversation deletes an assistant conversation with its
+// messages
+func DeleteAssistantConversation(e *core.RequestEvent, assistantService *assistant.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	err = assistantService.DeleteConversation(userId, e.Request.PathValue("id"))
+	if errors.Is(err, assistant.ErrNotFound) {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Conversation not found"})
+	}
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete conversation"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Conversation deleted"})
+}
@@ -1,41 +1,49 @@
 package routes
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
-	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase/core"
 )
 
-func streamMP3(path string) (io.Reader, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// serveAudio streams audio with HTTP range support, so players can seek and
+// resume without downloading the whole file first
+func serveAudio(e *core.RequestEvent, name string, contentType string, modTime time.Time, content io.ReadSeeker) error {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "audio/mpeg"
 	}
-	return f, nil
+
+	e.Response.Header().Set("Content-Type", contentType)
+	e.Response.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	http.ServeContent(e.Response, e.Request, name, modTime, content)
+	return nil
 }
 
-func AudioStreamMP3(c echo.Context) error {
-	path := c.QueryParam("path")
+func AudioStreamMP3(e *core.RequestEvent) error {
+	path := e.Request.URL.Query().Get("path")
 	if path == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "path parameter is required"})
+		return e.JSON(http.StatusBadRequest, map[string]string{"error": "path parameter is required"})
 	}
 
-	reader, err := streamMP3(path)
+	f, err := os.Open(path)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("error streaming mp3: %v", err)})
+		return e.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("error streaming mp3: %v", err)})
 	}
+	defer f.Close()
 
-	// Create a buffered reader with a buffer size of 4096 bytes.
-	bufferedReader := bufio.NewReaderSize(reader, 4096)
-
-	c.Response().Header().Set("Content-Type", "audio/mpeg")
-	_, err = io.CopyBuffer(c.Response(), bufferedReader, nil)
+	info, err := f.Stat()
 	if err != nil {
-		return err
+		return e.JSON(http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("error streaming mp3: %v", err)})
 	}
-	return nil
+
+	return serveAudio(e, filepath.Base(path), "audio/mpeg", info.ModTime(), f)
 }
@@ -3,8 +3,11 @@ package routes
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core"
@@ -14,6 +17,8 @@ import (
 	"github.com/shashank-sharma/backend/internal/models"
 	"github.com/shashank-sharma/backend/internal/query"
 	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/services/providers"
 	"github.com/shashank-sharma/backend/internal/util"
 )
 
@@ -66,6 +71,28 @@ type FeedItemResponse struct {
 	CategoryIDs   []string               `json:"category_ids"`
 	CategoryNames []string               `json:"category_names"`
 	Metadata      map[string]interface{} `json:"metadata"`
+	LeadImage     string                 `json:"lead_image"`
+	ReadingTime   int                    `json:"reading_time"`
+	Language      string                 `json:"language"`
+	FullContent   bool                   `json:"has_full_content"`
+	ClusterID     string                 `json:"cluster_id"`
+	AlsoOn        []FeedItemLink         `json:"also_on"`
+}
+
+// FeedItemLink points at another copy of the same story
+type FeedItemLink struct {
+	ID         string `json:"id"`
+	SourceID   string `json:"source_id"`
+	SourceName string `json:"source_name"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+}
+
+type RankedFeedItemResponse struct {
+	FeedItemResponse
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"score_components"`
+	Reasons    []string           `json:"reasons"`
 }
 
 type TagResponse struct {
@@ -81,12 +108,45 @@ func RegisterFeedRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path
 	feedRouter.POST("/sources", func(e *core.RequestEvent) error {
 		return CreateFeedSource(e, feedService)
 	})
+	feedRouter.POST("/sources/test", func(e *core.RequestEvent) error {
+		return TestFeedSource(e, feedService)
+	})
 	feedRouter.POST("/sources/{id}/fetch", func(e *core.RequestEvent) error {
 		return FetchFromSource(e, feedService)
 	})
 	feedRouter.GET("/", func(e *core.RequestEvent) error {
 		return GetFeeds(e, feedService)
 	})
+	feedRouter.GET("/for-you", func(e *core.RequestEvent) error {
+		return GetRankedFeeds(e, feedService)
+	})
+	feedRouter.GET("/rules", func(e *core.RequestEvent) error {
+		return ListFeedRules(e)
+	})
+	feedRouter.POST("/rules", func(e *core.RequestEvent) error {
+		return CreateFeedRule(e)
+	})
+	feedRouter.POST("/rules/reorder", func(e *core.RequestEvent) error {
+		return ReorderFeedRules(e)
+	})
+	feedRouter.POST("/rules/test", func(e *core.RequestEvent) error {
+		return TestFeedRule(e)
+	})
+	feedRouter.PATCH("/rules/{id}", func(e *core.RequestEvent) error {
+		return UpdateFeedRule(e)
+	})
+	feedRouter.DELETE("/rules/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedRule(e)
+	})
+	feedRouter.POST("/rules/{id}/test", func(e *core.RequestEvent) error {
+		return TestFeedRule(e)
+	})
+	feedRouter.POST("/opml/import", func(e *core.RequestEvent) error {
+		return ImportOPML(e, feedService)
+	})
+	feedRouter.GET("/opml/export", func(e *core.RequestEvent) error {
+		return ExportOPML(e)
+	})
 }
 
 // CreateFeedSource creates a new feed source
@@ -113,7 +173,7 @@ func CreateFeedSource(e *core.RequestEvent, feedService services.FeedService) er
 	}
 
 	// Validate provider-specific configuration
-	if err := provider.Validate(req.Config); err != nil {
+	if err := services.ValidateSourceConfig(provider, req.Config, userId); err != nil {
 		return e.JSON(http.StatusBadRequest, map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -158,6 +218,78 @@ func CreateFeedSource(e *core.RequestEvent, feedService services.FeedService) er
 	})
 }
 
+// TestFeedSource validates a source configuration and returns a sample of the
+// items it would produce without saving the source
+func TestFeedSource(e *core.RequestEvent, feedService services.FeedService) error {
+	// Get user ID from token
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &CreateFeedSourceRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	provider, exists := feedService.GetProvider(req.Type)
+	if !exists {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Unsupported feed source type",
+		})
+	}
+
+	if err := services.ValidateSourceConfig(provider, req.Config, userId); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// The sample is read back to the user, so it may only come from public addresses
+	ctx := providers.WithPublicAddressesOnly(services.WithSourceOwner(context.Background(), userId))
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var items []services.RawFeedItem
+	if previewer, ok := provider.(services.FeedSourcePreviewer); ok {
+		items, err = previewer.Preview(ctx, req.Config)
+	} else {
+		items, err = provider.FetchItems(ctx, req.Config, time.Time{})
+	}
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Failed to fetch sample: " + err.Error(),
+		})
+	}
+
+	if len(items) > 5 {
+		items = items[:5]
+	}
+
+	samples := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		sample := map[string]interface{}{
+			"external_id": item.ExternalID,
+			"title":       item.Title,
+			"url":         item.URL,
+			"author":      item.Author,
+			"content":     item.Content,
+			"tags":        item.Tags,
+			"metadata":    item.Metadata,
+		}
+		if !item.PublishedAt.IsZero() {
+			sample["published_at"] = item.PublishedAt.Format(time.RFC3339)
+		}
+		samples = append(samples, sample)
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"count": len(samples),
+		"items": samples,
+	})
+}
+
 // FetchFromSource manually fetches from a source
 func FetchFromSource(e *core.RequestEvent, feedService services.FeedService) error {
 	// Get user ID from token
@@ -188,6 +320,13 @@ func FetchFromSource(e *core.RequestEvent, feedService services.FeedService) err
 
 	// Fetch from source
 	if err := feedService.FetchFromSource(ctx, source); err != nil {
+		var rateLimited *services.RateLimitedError
+		if errors.As(err, &rateLimited) {
+			return e.JSON(http.StatusTooManyRequests, map[string]interface{}{
+				"error":       "Feed source is rate limited",
+				"retry_after": rateLimited.RetryAfter.Format(time.RFC3339),
+			})
+		}
 		return e.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"error": "Failed to fetch from source: " + err.Error(),
 		})
@@ -237,6 +376,11 @@ func GetFeeds(e *core.RequestEvent, feedService services.FeedService) error {
 	if status != "" {
 		filter["status"] = status
 	}
+	// Show one item per story unless asked for every copy. Filtering by source
+	// shows all of its items, whether or not another source had them first.
+	if source == "" && e.Request.URL.Query().Get("collapse") != "false" {
+		filter["is_cluster_primary"] = true
+	}
 
 	// Fetch items
 	items, err := query.FindAllByFilterWithPagination[*models.FeedItem](filter, limit, offset)
@@ -246,6 +390,11 @@ func GetFeeds(e *core.RequestEvent, feedService services.FeedService) error {
 		})
 	}
 
+	return e.JSON(http.StatusOK, buildFeedItemResponses(userId, items))
+}
+
+// buildFeedItemResponses resolves source, category and tag names for feed items
+func buildFeedItemResponses(userId string, items []*models.FeedItem) []FeedItemResponse {
 	sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{
 		"user": userId,
 	})
@@ -280,6 +429,11 @@ func GetFeeds(e *core.RequestEvent, feedService services.FeedService) error {
 		tagMap[tag.Id] = tag
 	}
 
+	clusters, err := feed.FindClusterItems(userId, feed.ClusterIDs(items))
+	if err != nil {
+		logger.LogError(err.Error())
+	}
+
 	response := make([]FeedItemResponse, 0, len(items))
 	for _, item := range items {
 		sourceName := ""
@@ -329,8 +483,143 @@ func GetFeeds(e *core.RequestEvent, feedService services.FeedService) error {
 			CategoryIDs:   item.CategoryIDs,
 			CategoryNames: categoryNames,
 			Metadata:      metadata,
+			LeadImage:     item.LeadImage,
+			ReadingTime:   item.ReadingTime,
+			Language:      item.Language,
+			FullContent:   item.FullContent != "",
+			ClusterID:     item.ClusterID,
+			AlsoOn:        clusterLinks(item, clusters[item.ClusterID], sourceMap),
+		})
+	}
+
+	return response
+}
+
+// clusterLinks lists the other items of an item's cluster
+func clusterLinks(item *models.FeedItem, cluster []*models.FeedItem, sourceMap map[string]string) []FeedItemLink {
+	links := make([]FeedItemLink, 0)
+	for _, other := range cluster {
+		if other.Id == item.Id {
+			continue
+		}
+		links = append(links, FeedItemLink{
+			ID:         other.Id,
+			SourceID:   other.SourceID,
+			SourceName: sourceMap[other.SourceID],
+			Title:      other.Title,
+			URL:        other.URL,
+		})
+	}
+	return links
+}
+
+// GetRankedFeeds returns the feed items of the authenticated user in
+// personalized "For you" order together with why each item ranked where it did
+func GetRankedFeeds(e *core.RequestEvent, feedService services.FeedService) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	params := e.Request.URL.Query()
+	options := map[string]interface{}{}
+	if status := params.Get("status"); status != "" {
+		options["status"] = status
+	}
+	if source := params.Get("source"); source != "" {
+		options["source_id"] = source
+	}
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil {
+		options["limit"] = limit
+	}
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil {
+		options["offset"] = offset
+	}
+	if days, err := strconv.Atoi(params.Get("max_age_days")); err == nil {
+		options["max_age_days"] = days
+	}
+	if hours, err := strconv.ParseFloat(params.Get("half_life_hours"), 64); err == nil {
+		options["half_life_hours"] = hours
+	}
+	if useAI, err := strconv.ParseBool(params.Get("ai")); err == nil {
+		options["ai"] = useAI
+	}
+
+	ranked, err := feedService.RankUserFeeds(e.Request.Context(), userId, options)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to rank feed items: " + err.Error(),
+		})
+	}
+
+	items := make([]*models.FeedItem, 0, len(ranked))
+	for _, entry := range ranked {
+		items = append(items, entry.Item)
+	}
+
+	itemResponses := buildFeedItemResponses(userId, items)
+	response := make([]RankedFeedItemResponse, 0, len(ranked))
+	for i, entry := range ranked {
+		response = append(response, RankedFeedItemResponse{
+			FeedItemResponse: itemResponses[i],
+			Score:            entry.Score,
+			Components:       entry.Components,
+			Reasons:          entry.Reasons,
 		})
 	}
 
 	return e.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// ImportOPML imports feed sources from an uploaded OPML file.
+// The document can be sent as a multipart "file" field or as the raw request body.
+func ImportOPML(e *core.RequestEvent, feedService services.FeedService) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	var body io.Reader = e.Request.Body
+	if strings.HasPrefix(e.Request.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := e.Request.FormFile("file")
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Missing OPML file"})
+		}
+		defer file.Close()
+		body = file
+	}
+
+	// Validating hundreds of feeds can take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	result, err := feed.ImportOPML(ctx, feedService, userId, body)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Failed to import OPML: " + err.Error(),
+		})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
+
+// ExportOPML exports the user's feed sources as an OPML file
+func ExportOPML(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	data, err := feed.ExportOPML(userId)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to export OPML: " + err.Error(),
+		})
+	}
+
+	e.Response.Header().Set("Content-Disposition", "attachment; filename=\"feeds.opml\"")
+	return e.Blob(http.StatusOK, "text/x-opml; charset=utf-8", data)
+}
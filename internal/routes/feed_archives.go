@@ -0,0 +1,256 @@
+package routes
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+type ArchiveFeedItemRequest struct {
+	ItemID string `json:"item_id"`
+}
+
+func RegisterFeedArchiveRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, archiver *feed.Archiver) {
+	archiveRouter := apiRouter.Group(path)
+	archiveRouter.GET("", func(e *core.RequestEvent) error {
+		return ListFeedArchives(e, archiver)
+	})
+	archiveRouter.POST("", func(e *core.RequestEvent) error {
+		return ArchiveFeedItem(e, archiver)
+	})
+	archiveRouter.GET("/export", func(e *core.RequestEvent) error {
+		return ExportFeedArchives(e)
+	})
+	archiveRouter.GET("/{id}", func(e *core.RequestEvent) error {
+		return GetFeedArchive(e)
+	})
+	archiveRouter.POST("/{id}/refresh", func(e *core.RequestEvent) error {
+		return RefreshFeedArchive(e, archiver)
+	})
+	archiveRouter.DELETE("/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedArchive(e)
+	})
+}
+
+// ListFeedArchives returns the archived articles of the authenticated user
+// with their storage usage. The HTML and text are left out, fetch a single
+// archive for them.
+func ListFeedArchives(e *core.RequestEvent, archiver *feed.Archiver) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	limit := 50
+	if limitStr := e.Request.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if offsetStr := e.Request.URL.Query().Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	filter := dbx.HashExp{"user": userId}
+	if status := e.Request.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	var archives []*models.FeedArchive
+	err = query.BaseQuery[*models.FeedArchive]().
+		AndWhere(filter).
+		OrderBy("created DESC").
+		Limit(int64(limit)).
+		Offset(int64(offset)).
+		All(&archives)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch archives"})
+	}
+
+	for _, archive := range archives {
+		archive.HTML = ""
+		archive.Text = ""
+	}
+
+	usage, err := archiver.Usage(userId, "")
+	if err != nil {
+		logger.LogError(err.Error())
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"archives": archives,
+		"usage":    usage,
+		"quota":    archiver.QuotaBytes(),
+	})
+}
+
+// ArchiveFeedItem archives the page of any feed item on demand, downloading
+// it again when it was archived before
+func ArchiveFeedItem(e *core.RequestEvent, archiver *feed.Archiver) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &ArchiveFeedItemRequest{}
+	if err := e.BindBody(req); err != nil || req.ItemID == "" {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	item, err := query.FindByFilter[*models.FeedItem](map[string]interface{}{
+		"id":   req.ItemID,
+		"user": userId,
+	})
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed item not found"})
+	}
+
+	archive, err := feed.QueueArchive(item, true)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Failed to queue archive: " + err.Error()})
+	}
+	archiver.Trigger()
+
+	return e.JSON(http.StatusAccepted, archive)
+}
+
+// RefreshFeedArchive downloads the page of an archive again
+func RefreshFeedArchive(e *core.RequestEvent, archiver *feed.Archiver) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	archive, err := findUserFeedArchive(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Archive not found"})
+	}
+
+	item, err := query.FindById[*models.FeedItem](archive.Item)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed item not found"})
+	}
+
+	archive, err = feed.QueueArchive(item, true)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Failed to queue archive: " + err.Error()})
+	}
+	archiver.Trigger()
+
+	return e.JSON(http.StatusAccepted, archive)
+}
+
+// GetFeedArchive returns an archive as JSON, or with ?format=html as a page
+// that reads offline and ?format=text as plain text
+func GetFeedArchive(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	archive, err := findUserFeedArchive(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Archive not found"})
+	}
+
+	switch e.Request.URL.Query().Get("format") {
+	case "html":
+		content, err := feed.ArchiveImageURLs(archive, func(name string) string {
+			return "/api/files/" + archive.TableName() + "/" + archive.Id + "/" + name
+		})
+		if err != nil {
+			return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to render archive"})
+		}
+		page := "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>" + html.EscapeString(archive.Title) + "</title></head>\n<body>\n" +
+			"<h1>" + html.EscapeString(archive.Title) + "</h1>\n" + content + "\n</body>\n</html>\n"
+		return e.HTML(http.StatusOK, page)
+	case "text":
+		return e.String(http.StatusOK, archive.Text)
+	}
+
+	return e.JSON(http.StatusOK, archive)
+}
+
+// ExportFeedArchives downloads all archived articles as an EPUB book
+// (?format=epub, the default) or a ZIP of HTML files (?format=zip)
+func ExportFeedArchives(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	format := e.Request.URL.Query().Get("format")
+	if format == "" {
+		format = feed.ArchiveExportEPUB
+	}
+
+	contentType := "application/epub+zip"
+	filename := "saved-articles.epub"
+	switch format {
+	case feed.ArchiveExportEPUB:
+	case feed.ArchiveExportZIP:
+		contentType = "application/zip"
+		filename = "saved-articles.zip"
+	default:
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Unsupported export format"})
+	}
+
+	e.Response.Header().Set("Content-Type", contentType)
+	e.Response.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	e.Response.WriteHeader(http.StatusOK)
+
+	// The export is streamed, an error half way can only be logged
+	if err := feed.ExportArchives(userId, format, e.Response); err != nil {
+		logger.LogError("Failed to export archives: " + err.Error())
+	}
+	return nil
+}
+
+// DeleteFeedArchive deletes an archive and its stored images
+func DeleteFeedArchive(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	archive, err := findUserFeedArchive(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Archive not found"})
+	}
+
+	// Deleted through the record so PocketBase removes the files too
+	record, err := e.App.FindRecordById(archive.TableName(), archive.Id)
+	if err == nil {
+		err = e.App.Delete(record)
+	}
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete archive: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Archive deleted"})
+}
+
+func findUserFeedArchive(archiveId string, userId string) (*models.FeedArchive, error) {
+	return query.FindByFilter[*models.FeedArchive](map[string]interface{}{
+		"id":   archiveId,
+		"user": userId,
+	})
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// BulkTagRequest adds and removes tags on the items matching the filter
+type BulkTagRequest struct {
+	feed.BulkFilter
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+func RegisterFeedBulkRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string) {
+	bulkRouter := apiRouter.Group(path)
+	bulkRouter.POST("/mark-read", func(e *core.RequestEvent) error {
+		return BulkMarkRead(e)
+	})
+	bulkRouter.POST("/tag", func(e *core.RequestEvent) error {
+		return BulkTagFeedItems(e)
+	})
+	bulkRouter.POST("/dismiss", func(e *core.RequestEvent) error {
+		return BulkDismissFeedItems(e)
+	})
+}
+
+// BulkMarkRead marks the unread items of a source or category read, up to
+// the time given as before
+func BulkMarkRead(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	filter := feed.BulkFilter{}
+	if err := e.BindBody(&filter); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	result, err := feed.MarkAllRead(userId, filter)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
+
+// BulkTagFeedItems adds and removes tags on the items matching a filter
+func BulkTagFeedItems(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &BulkTagRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	result, err := feed.BulkTag(userId, req.BulkFilter, req.Add, req.Remove)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
+
+// BulkDismissFeedItems dismisses the items matching a filter, leaving saved
+// items alone
+func BulkDismissFeedItems(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	filter := feed.BulkFilter{}
+	if err := e.BindBody(&filter); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	result, err := feed.BulkDismiss(userId, filter)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,262 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// FeedDigestSettingsRequest updates the digest settings. Fields left out keep
+// their current value.
+type FeedDigestSettingsRequest struct {
+	IsEnabled    *bool     `json:"is_enabled"`
+	Frequency    *string   `json:"frequency"`
+	TimeOfDay    *string   `json:"time_of_day"`
+	Timezone     *string   `json:"timezone"`
+	Weekday      *int      `json:"weekday"`
+	Channels     *[]string `json:"channels"`
+	IncludeSaved *bool     `json:"include_saved"`
+	MaxItems     *int      `json:"max_items"`
+}
+
+// FeedDigestSettingsResponse is the digest settings with the next scheduled time
+type FeedDigestSettingsResponse struct {
+	*models.FeedDigestSettings
+	NextDigestAt string `json:"next_digest_at,omitempty"`
+}
+
+// apply copies the set fields of the request onto the settings
+func (r *FeedDigestSettingsRequest) apply(settings *models.FeedDigestSettings) {
+	if r.IsEnabled != nil {
+		settings.IsEnabled = *r.IsEnabled
+	}
+	if r.Frequency != nil {
+		settings.Frequency = *r.Frequency
+	}
+	if r.TimeOfDay != nil {
+		settings.TimeOfDay = *r.TimeOfDay
+	}
+	if r.Timezone != nil {
+		settings.Timezone = *r.Timezone
+	}
+	if r.Weekday != nil {
+		settings.Weekday = *r.Weekday
+	}
+	if r.Channels != nil {
+		settings.Channels = *r.Channels
+	}
+	if r.IncludeSaved != nil {
+		settings.IncludeSaved = *r.IncludeSaved
+	}
+	if r.MaxItems != nil {
+		settings.MaxItems = *r.MaxItems
+	}
+}
+
+func RegisterFeedDigestRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, digestService *feed.DigestService) {
+	digestRouter := apiRouter.Group(path)
+	digestRouter.GET("/settings", func(e *core.RequestEvent) error {
+		return GetFeedDigestSettings(e, digestService)
+	})
+	digestRouter.PUT("/settings", func(e *core.RequestEvent) error {
+		return UpdateFeedDigestSettings(e, digestService)
+	})
+	digestRouter.POST("/generate", func(e *core.RequestEvent) error {
+		return GenerateFeedDigest(e, digestService)
+	})
+	digestRouter.GET("", func(e *core.RequestEvent) error {
+		return ListFeedDigests(e)
+	})
+	digestRouter.GET("/{id}", func(e *core.RequestEvent) error {
+		return GetFeedDigest(e)
+	})
+	digestRouter.DELETE("/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedDigest(e)
+	})
+}
+
+// GetFeedDigestSettings returns the digest settings of the authenticated user,
+// or the defaults when none have been saved
+func GetFeedDigestSettings(e *core.RequestEvent, digestService *feed.DigestService) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	settings, err := digestService.GetSettings(userId)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch digest settings"})
+	}
+
+	return e.JSON(http.StatusOK, buildDigestSettingsResponse(settings))
+}
+
+// UpdateFeedDigestSettings creates or updates the digest settings
+func UpdateFeedDigestSettings(e *core.RequestEvent, digestService *feed.DigestService) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	settings, err := digestService.GetSettings(userId)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch digest settings"})
+	}
+
+	req := &FeedDigestSettingsRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.apply(settings)
+
+	if err := feed.ValidateDigestSettings(settings); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	if err := query.UpsertRecord(settings, map[string]interface{}{"user": userId}); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to save digest settings: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, buildDigestSettingsResponse(settings))
+}
+
+// GenerateFeedDigest builds the digest for the period since the last one. By
+// default it is only previewed, with ?send=true it is archived and delivered
+// like a scheduled digest.
+func GenerateFeedDigest(e *core.RequestEvent, digestService *feed.DigestService) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	settings, err := digestService.GetSettings(userId)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch digest settings"})
+	}
+
+	var digest *models.FeedDigest
+	if e.Request.URL.Query().Get("send") == "true" {
+		digest, err = digestService.Send(e.Request.Context(), settings, time.Now())
+	} else {
+		digest, err = digestService.Build(e.Request.Context(), settings, time.Now())
+	}
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to generate digest: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, digest)
+}
+
+// ListFeedDigests returns the archive of past digests, newest first. The
+// rendered content is left out, fetch a single digest for it.
+func ListFeedDigests(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	limit := 20
+	if limitStr := e.Request.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if offsetStr := e.Request.URL.Query().Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	var digests []*models.FeedDigest
+	err = query.BaseQuery[*models.FeedDigest]().
+		AndWhere(dbx.HashExp{"user": userId}).
+		OrderBy("created DESC").
+		Limit(int64(limit)).
+		Offset(int64(offset)).
+		All(&digests)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch digests"})
+	}
+
+	for _, digest := range digests {
+		digest.HTML = ""
+		digest.Markdown = ""
+	}
+
+	return e.JSON(http.StatusOK, digests)
+}
+
+// GetFeedDigest returns an archived digest as JSON, or with ?format=html or
+// ?format=markdown as the rendered document
+func GetFeedDigest(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	digest, err := findUserFeedDigest(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Digest not found"})
+	}
+
+	switch e.Request.URL.Query().Get("format") {
+	case "html":
+		return e.HTML(http.StatusOK, digest.HTML)
+	case "markdown":
+		e.Response.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		return e.String(http.StatusOK, digest.Markdown)
+	}
+
+	return e.JSON(http.StatusOK, digest)
+}
+
+// DeleteFeedDigest removes a digest from the archive
+func DeleteFeedDigest(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	digest, err := findUserFeedDigest(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Digest not found"})
+	}
+
+	if err := query.DeleteRecord(digest); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete digest: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Digest deleted"})
+}
+
+func buildDigestSettingsResponse(settings *models.FeedDigestSettings) FeedDigestSettingsResponse {
+	response := FeedDigestSettingsResponse{FeedDigestSettings: settings}
+	if settings.IsEnabled {
+		if next, err := feed.NextScheduledTime(settings, time.Now()); err == nil {
+			response.NextDigestAt = next.Format(time.RFC3339)
+		}
+	}
+	return response
+}
+
+func findUserFeedDigest(digestId string, userId string) (*models.FeedDigest, error) {
+	return query.FindByFilter[*models.FeedDigest](map[string]interface{}{
+		"id":   digestId,
+		"user": userId,
+	})
+}
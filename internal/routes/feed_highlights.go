@@ -0,0 +1,343 @@
+package routes
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// FeedHighlightRequest creates or updates a highlight. Fields left out of an
+// update keep their current value. The item and archive can only be set on
+// creation.
+type FeedHighlightRequest struct {
+	ItemID      string  `json:"item_id"`
+	ArchiveID   string  `json:"archive_id"`
+	Text        *string `json:"text"`
+	Note        *string `json:"note"`
+	Color       *string `json:"color"`
+	StartOffset *int    `json:"start_offset"`
+	EndOffset   *int    `json:"end_offset"`
+	Prefix      *string `json:"prefix"`
+	Suffix      *string `json:"suffix"`
+}
+
+// apply copies the set fields of the request onto a highlight
+func (r *FeedHighlightRequest) apply(highlight *models.FeedHighlight) {
+	if r.Text != nil {
+		highlight.Text = *r.Text
+	}
+	if r.Note != nil {
+		highlight.Note = *r.Note
+	}
+	if r.Color != nil {
+		highlight.Color = *r.Color
+	}
+	if r.StartOffset != nil {
+		highlight.StartOffset = *r.StartOffset
+	}
+	if r.EndOffset != nil {
+		highlight.EndOffset = *r.EndOffset
+	}
+	if r.Prefix != nil {
+		highlight.Prefix = *r.Prefix
+	}
+	if r.Suffix != nil {
+		highlight.Suffix = *r.Suffix
+	}
+}
+
+func RegisterFeedHighlightRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string) {
+	highlightRouter := apiRouter.Group(path)
+	highlightRouter.GET("", func(e *core.RequestEvent) error {
+		return ListFeedHighlights(e)
+	})
+	highlightRouter.POST("", func(e *core.RequestEvent) error {
+		return CreateFeedHighlight(e)
+	})
+	highlightRouter.GET("/review", func(e *core.RequestEvent) error {
+		return ReviewFeedHighlights(e)
+	})
+	highlightRouter.GET("/export", func(e *core.RequestEvent) error {
+		return ExportFeedHighlights(e)
+	})
+	highlightRouter.GET("/{id}", func(e *core.RequestEvent) error {
+		return GetFeedHighlight(e)
+	})
+	highlightRouter.PATCH("/{id}", func(e *core.RequestEvent) error {
+		return UpdateFeedHighlight(e)
+	})
+	highlightRouter.DELETE("/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedHighlight(e)
+	})
+}
+
+// ListFeedHighlights returns the highlights of the authenticated user, newest
+// first, optionally for a single item, archive or color
+func ListFeedHighlights(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	limit := 100
+	if limitStr := e.Request.URL.Query().Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if offsetStr := e.Request.URL.Query().Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	filter := dbx.HashExp{"user": userId}
+	if itemId := e.Request.URL.Query().Get("item_id"); itemId != "" {
+		filter["item"] = itemId
+	}
+	if archiveId := e.Request.URL.Query().Get("archive_id"); archiveId != "" {
+		filter["archive"] = archiveId
+	}
+	if color := e.Request.URL.Query().Get("color"); color != "" {
+		filter["color"] = color
+	}
+
+	var highlights []*models.FeedHighlight
+	err = query.BaseQuery[*models.FeedHighlight]().
+		AndWhere(filter).
+		OrderBy("created DESC").
+		Limit(int64(limit)).
+		Offset(int64(offset)).
+		All(&highlights)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch highlights"})
+	}
+
+	return e.JSON(http.StatusOK, highlights)
+}
+
+// CreateFeedHighlight highlights a passage of a feed item, or of its archived
+// article when an archive is given
+func CreateFeedHighlight(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &FeedHighlightRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	highlight := &models.FeedHighlight{User: userId}
+
+	if req.ArchiveID != "" {
+		archive, err := findUserFeedArchive(req.ArchiveID, userId)
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Archive not found"})
+		}
+		if req.ItemID != "" && req.ItemID != archive.Item {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Archive belongs to another item"})
+		}
+		highlight.Archive = archive.Id
+		highlight.Item = archive.Item
+	} else {
+		item, err := query.FindByFilter[*models.FeedItem](map[string]interface{}{
+			"id":   req.ItemID,
+			"user": userId,
+		})
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Feed item not found"})
+		}
+		highlight.Item = item.Id
+	}
+
+	req.apply(highlight)
+	if err := feed.ValidateHighlight(highlight); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	highlight.Id = util.GenerateRandomId()
+	highlight.RefreshCreated()
+	highlight.RefreshUpdated()
+	if err := query.SaveRecord(highlight); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to create highlight: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, highlight)
+}
+
+// GetFeedHighlight returns a single highlight
+func GetFeedHighlight(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	highlight, err := findUserFeedHighlight(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Highlight not found"})
+	}
+
+	return e.JSON(http.StatusOK, highlight)
+}
+
+// UpdateFeedHighlight updates the given fields of a highlight
+func UpdateFeedHighlight(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	highlight, err := findUserFeedHighlight(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Highlight not found"})
+	}
+
+	req := &FeedHighlightRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.apply(highlight)
+
+	if err := feed.ValidateHighlight(highlight); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	highlight.MarkAsNotNew()
+	highlight.RefreshUpdated()
+	if err := query.SaveRecord(highlight); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update highlight: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, highlight)
+}
+
+// DeleteFeedHighlight deletes a highlight
+func DeleteFeedHighlight(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	highlight, err := findUserFeedHighlight(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Highlight not found"})
+	}
+
+	if err := query.DeleteRecord(highlight); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete highlight: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Highlight deleted"})
+}
+
+// ReviewFeedHighlights returns today's past highlights to look back on, with
+// the title and link of their items. ?count sets how many (default 5).
+func ReviewFeedHighlights(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	count := feed.DefaultReviewCount
+	if countStr := e.Request.URL.Query().Get("count"); countStr != "" {
+		if parsed, err := strconv.Atoi(countStr); err == nil && parsed > 0 {
+			count = parsed
+		}
+	}
+
+	highlights, err := feed.ReviewHighlights(userId, count, time.Now())
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to load review"})
+	}
+
+	itemIds := make([]interface{}, 0, len(highlights))
+	for _, highlight := range highlights {
+		itemIds = append(itemIds, highlight.Item)
+	}
+	items := make(map[string]*models.FeedItem)
+	if len(itemIds) > 0 {
+		var found []*models.FeedItem
+		if err := query.BaseQuery[*models.FeedItem]().AndWhere(dbx.HashExp{"id": itemIds}).All(&found); err == nil {
+			for _, item := range found {
+				items[item.Id] = item
+			}
+		}
+	}
+
+	type reviewEntry struct {
+		*models.FeedHighlight
+		ItemTitle       string         `json:"item_title"`
+		ItemURL         string         `json:"item_url"`
+		ItemPublishedAt types.DateTime `json:"item_published_at"`
+	}
+	entries := make([]reviewEntry, 0, len(highlights))
+	for _, highlight := range highlights {
+		entry := reviewEntry{FeedHighlight: highlight}
+		if item, ok := items[highlight.Item]; ok {
+			entry.ItemTitle = item.Title
+			entry.ItemURL = item.URL
+			entry.ItemPublishedAt = item.PublishedAt
+		}
+		entries = append(entries, entry)
+	}
+
+	return e.JSON(http.StatusOK, entries)
+}
+
+// ExportFeedHighlights downloads the highlights as Markdown notes: a ZIP with
+// one note per article, or with ?item_id the note of that item
+func ExportFeedHighlights(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	if itemId := e.Request.URL.Query().Get("item_id"); itemId != "" {
+		name, content, err := feed.ExportArticleHighlights(userId, itemId)
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+		}
+		// Titles aren't ASCII-only, FormatMediaType encodes them when needed
+		e.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		return e.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(content))
+	}
+
+	var buf bytes.Buffer
+	if err := feed.ExportHighlights(userId, &buf); err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to export highlights"})
+	}
+
+	e.Response.Header().Set("Content-Disposition", "attachment; filename=\"highlights.zip\"")
+	return e.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func findUserFeedHighlight(highlightId string, userId string) (*models.FeedHighlight, error) {
+	return query.FindByFilter[*models.FeedHighlight](map[string]interface{}{
+		"id":   highlightId,
+		"user": userId,
+	})
+}
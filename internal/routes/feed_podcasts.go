@@ -0,0 +1,387 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// PlaybackPositionRequest stores where the user stopped in an episode.
+// Completed is worked out from the position when left out.
+type PlaybackPositionRequest struct {
+	Position  float64 `json:"position"`
+	Duration  float64 `json:"duration"`
+	Completed *bool   `json:"completed"`
+}
+
+// PodcastEpisodeResponse is a feed item with its episode metadata, playback
+// position and download
+type PodcastEpisodeResponse struct {
+	*models.FeedItem
+	Episode  *feed.Episode                `json:"episode"`
+	Progress *models.FeedPlaybackPosition `json:"progress"`
+	Download *models.FeedEpisodeDownload  `json:"download"`
+}
+
+func RegisterFeedPodcastRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, downloader *feed.EpisodeDownloader) {
+	podcastRouter := apiRouter.Group(path)
+	podcastRouter.GET("/episodes", func(e *core.RequestEvent) error {
+		return ListPodcastEpisodes(e)
+	})
+	podcastRouter.GET("/episodes/{itemId}", func(e *core.RequestEvent) error {
+		return GetPodcastEpisode(e)
+	})
+	podcastRouter.GET("/episodes/{itemId}/stream", func(e *core.RequestEvent) error {
+		return StreamPodcastEpisode(e)
+	})
+	podcastRouter.PUT("/episodes/{itemId}/progress", func(e *core.RequestEvent) error {
+		return SavePlaybackPosition(e)
+	})
+	podcastRouter.DELETE("/episodes/{itemId}/progress", func(e *core.RequestEvent) error {
+		return ResetPlaybackPosition(e)
+	})
+	podcastRouter.POST("/episodes/{itemId}/download", func(e *core.RequestEvent) error {
+		return DownloadPodcastEpisode(e, downloader)
+	})
+	podcastRouter.DELETE("/episodes/{itemId}/download", func(e *core.RequestEvent) error {
+		return DeletePodcastEpisodeDownload(e)
+	})
+	podcastRouter.GET("/downloads", func(e *core.RequestEvent) error {
+		return ListPodcastEpisodeDownloads(e, downloader)
+	})
+}
+
+// ListPodcastEpisodes returns the podcast episodes of the authenticated user,
+// newest first. ?in_progress=true keeps the episodes started but not finished.
+func ListPodcastEpisodes(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	params := e.Request.URL.Query()
+	limit := 50
+	if limitStr := params.Get("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if offsetStr := params.Get("offset"); offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed > 0 {
+			offset = parsed
+		}
+	}
+
+	itemQuery := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": userId}).
+		AndWhere(dbx.NewExp("COALESCE(json_extract(metadata, '$.audio_url'), '') != ''"))
+	if sourceId := params.Get("source_id"); sourceId != "" {
+		itemQuery.AndWhere(dbx.HashExp{"source_id": sourceId})
+	}
+	if params.Get("in_progress") == "true" {
+		itemQuery.AndWhere(dbx.NewExp(
+			"id IN (SELECT item FROM feed_playback_positions WHERE user = {:user} AND completed = FALSE AND position > 0)",
+			dbx.Params{"user": userId},
+		))
+	}
+
+	var items []*models.FeedItem
+	err = itemQuery.
+		OrderBy("published_at DESC").
+		Limit(int64(limit)).
+		Offset(int64(offset)).
+		All(&items)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch episodes"})
+	}
+
+	episodes, err := podcastEpisodeResponses(userId, items)
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch episodes"})
+	}
+
+	return e.JSON(http.StatusOK, episodes)
+}
+
+// GetPodcastEpisode returns a single episode with its progress and download
+func GetPodcastEpisode(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	item, _, err := findUserPodcastEpisode(e.Request.PathValue("itemId"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Episode not found"})
+	}
+
+	episodes, err := podcastEpisodeResponses(userId, []*models.FeedItem{item})
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch episode"})
+	}
+
+	return e.JSON(http.StatusOK, episodes[0])
+}
+
+// StreamPodcastEpisode plays a downloaded episode from storage with range
+// support, or redirects to the publisher's audio otherwise. Audio elements
+// can't send headers, so the auth token may be given as ?token= instead.
+func StreamPodcastEpisode(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	if token == "" {
+		token = e.Request.URL.Query().Get("token")
+	}
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	item, episode, err := findUserPodcastEpisode(e.Request.PathValue("itemId"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Episode not found"})
+	}
+
+	download, err := query.FindByFilter[*models.FeedEpisodeDownload](map[string]interface{}{
+		"item":   item.Id,
+		"status": models.EpisodeDownloadDownloaded,
+	})
+	if err != nil || download.Audio == "" {
+		return e.Redirect(http.StatusFound, episode.AudioURL)
+	}
+
+	record, err := e.App.FindRecordById(download.TableName(), download.Id)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Episode not found"})
+	}
+
+	fsys, err := e.App.NewFilesystem()
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to open storage"})
+	}
+	defer fsys.Close()
+
+	audio, err := fsys.GetFile(record.BaseFilesPath() + "/" + download.Audio)
+	if err != nil {
+		logger.LogError("Error opening downloaded episode: " + err.Error())
+		return e.Redirect(http.StatusFound, episode.AudioURL)
+	}
+	defer audio.Close()
+
+	return serveAudio(e, download.Audio, download.ContentType, audio.ModTime(), audio)
+}
+
+// SavePlaybackPosition stores where the user stopped listening
+func SavePlaybackPosition(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	item, _, err := findUserPodcastEpisode(e.Request.PathValue("itemId"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Episode not found"})
+	}
+
+	req := &PlaybackPositionRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	playback, err := feed.SavePlaybackPosition(item, req.Position, req.Duration, req.Completed)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, playback)
+}
+
+// ResetPlaybackPosition forgets the playback position of an episode
+func ResetPlaybackPosition(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	playback, err := query.FindByFilter[*models.FeedPlaybackPosition](map[string]interface{}{
+		"user": userId,
+		"item": e.Request.PathValue("itemId"),
+	})
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Playback position not found"})
+	}
+
+	if err := query.DeleteRecord(playback); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to reset playback position: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Playback position reset"})
+}
+
+// DownloadPodcastEpisode queues an episode for offline listening
+func DownloadPodcastEpisode(e *core.RequestEvent, downloader *feed.EpisodeDownloader) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	item, episode, err := findUserPodcastEpisode(e.Request.PathValue("itemId"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Episode not found"})
+	}
+
+	if episode.AudioSize > downloader.MaxBytes() {
+		return e.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": "Episode is larger than the download limit"})
+	}
+
+	download, err := feed.QueueEpisodeDownload(item)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Failed to queue download: " + err.Error()})
+	}
+	downloader.Trigger()
+
+	return e.JSON(http.StatusAccepted, download)
+}
+
+// DeletePodcastEpisodeDownload deletes a downloaded episode and its audio
+func DeletePodcastEpisodeDownload(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	download, err := query.FindByFilter[*models.FeedEpisodeDownload](map[string]interface{}{
+		"user": userId,
+		"item": e.Request.PathValue("itemId"),
+	})
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Download not found"})
+	}
+
+	// Deleted through the record so PocketBase removes the audio too
+	record, err := e.App.FindRecordById(download.TableName(), download.Id)
+	if err == nil {
+		err = e.App.Delete(record)
+	}
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete download: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Download deleted"})
+}
+
+// ListPodcastEpisodeDownloads returns the episode downloads of the
+// authenticated user with their storage usage
+func ListPodcastEpisodeDownloads(e *core.RequestEvent, downloader *feed.EpisodeDownloader) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	filter := dbx.HashExp{"user": userId}
+	if status := e.Request.URL.Query().Get("status"); status != "" {
+		filter["status"] = status
+	}
+
+	var downloads []*models.FeedEpisodeDownload
+	err = query.BaseQuery[*models.FeedEpisodeDownload]().
+		AndWhere(filter).
+		OrderBy("created DESC").
+		All(&downloads)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch downloads"})
+	}
+
+	usage, err := downloader.Usage(userId, "")
+	if err != nil {
+		logger.LogError(err.Error())
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"downloads": downloads,
+		"usage":     usage,
+		"quota":     downloader.QuotaBytes(),
+		"max_size":  downloader.MaxBytes(),
+	})
+}
+
+// podcastEpisodeResponses adds the episode metadata, playback positions and
+// downloads to feed items
+func podcastEpisodeResponses(userId string, items []*models.FeedItem) ([]*PodcastEpisodeResponse, error) {
+	episodes := make([]*PodcastEpisodeResponse, 0, len(items))
+	if len(items) == 0 {
+		return episodes, nil
+	}
+
+	itemIds := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemIds = append(itemIds, item.Id)
+	}
+
+	var positions []*models.FeedPlaybackPosition
+	if err := query.BaseQuery[*models.FeedPlaybackPosition]().
+		AndWhere(dbx.HashExp{"user": userId, "item": itemIds}).
+		All(&positions); err != nil {
+		return nil, err
+	}
+	progress := make(map[string]*models.FeedPlaybackPosition, len(positions))
+	for _, position := range positions {
+		progress[position.Item] = position
+	}
+
+	var downloads []*models.FeedEpisodeDownload
+	if err := query.BaseQuery[*models.FeedEpisodeDownload]().
+		AndWhere(dbx.HashExp{"user": userId, "item": itemIds}).
+		All(&downloads); err != nil {
+		return nil, err
+	}
+	downloaded := make(map[string]*models.FeedEpisodeDownload, len(downloads))
+	for _, download := range downloads {
+		downloaded[download.Item] = download
+	}
+
+	for _, item := range items {
+		episode, _ := feed.ItemEpisode(item)
+		episodes = append(episodes, &PodcastEpisodeResponse{
+			FeedItem: item,
+			Episode:  episode,
+			Progress: progress[item.Id],
+			Download: downloaded[item.Id],
+		})
+	}
+
+	return episodes, nil
+}
+
+func findUserPodcastEpisode(itemId string, userId string) (*models.FeedItem, *feed.Episode, error) {
+	item, err := query.FindByFilter[*models.FeedItem](map[string]interface{}{
+		"id":   itemId,
+		"user": userId,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	episode, ok := feed.ItemEpisode(item)
+	if !ok {
+		return nil, nil, feed.ErrNotAnEpisode
+	}
+	return item, episode, nil
+}
@@ -0,0 +1,322 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// Path of the public feeds, relative to the /api router
+const publishedFeedsPath = "/feeds/published"
+
+// FeedPublicationRequest creates or updates a publication. Fields left out of
+// an update keep their current value.
+type FeedPublicationRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Scope       *string `json:"scope"`
+	ScopeID     *string `json:"scope_id"`
+	SavedOnly   *bool   `json:"saved_only"`
+	MaxItems    *int    `json:"max_items"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// FeedPublicationResponse is a publication with its public feed URLs
+type FeedPublicationResponse struct {
+	*models.FeedPublication
+	URLs map[string]string `json:"urls"`
+}
+
+// apply copies the set fields of the request onto a publication
+func (r *FeedPublicationRequest) apply(pub *models.FeedPublication) {
+	if r.Name != nil {
+		pub.Name = *r.Name
+	}
+	if r.Description != nil {
+		pub.Description = *r.Description
+	}
+	if r.Scope != nil {
+		pub.Scope = *r.Scope
+	}
+	if r.ScopeID != nil {
+		pub.ScopeID = *r.ScopeID
+	}
+	if r.SavedOnly != nil {
+		pub.SavedOnly = *r.SavedOnly
+	}
+	if r.MaxItems != nil {
+		pub.MaxItems = *r.MaxItems
+	}
+	if r.IsActive != nil {
+		pub.IsActive = *r.IsActive
+	}
+}
+
+func RegisterFeedPublicationRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string) {
+	publicationRouter := apiRouter.Group(path)
+	publicationRouter.GET("", func(e *core.RequestEvent) error {
+		return ListFeedPublications(e)
+	})
+	publicationRouter.POST("", func(e *core.RequestEvent) error {
+		return CreateFeedPublication(e)
+	})
+	publicationRouter.PATCH("/{id}", func(e *core.RequestEvent) error {
+		return UpdateFeedPublication(e)
+	})
+	publicationRouter.DELETE("/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedPublication(e)
+	})
+	publicationRouter.POST("/{id}/rotate", func(e *core.RequestEvent) error {
+		return RotateFeedPublicationToken(e)
+	})
+
+	// Public feeds are authenticated by the token in the URL so feed readers
+	// can subscribe to them
+	apiRouter.GET(publishedFeedsPath+"/{token}/{format}", func(e *core.RequestEvent) error {
+		return GetPublishedFeed(e)
+	})
+}
+
+// ListFeedPublications returns the publications of the authenticated user
+func ListFeedPublications(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	var publications []*models.FeedPublication
+	err = query.BaseQuery[*models.FeedPublication]().
+		AndWhere(dbx.HashExp{"user": userId}).
+		OrderBy("name ASC").
+		All(&publications)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch feed publications"})
+	}
+
+	responses := make([]FeedPublicationResponse, 0, len(publications))
+	for _, pub := range publications {
+		responses = append(responses, buildFeedPublicationResponse(e, pub))
+	}
+
+	return e.JSON(http.StatusOK, responses)
+}
+
+// CreateFeedPublication creates a publication with a new secret token
+func CreateFeedPublication(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &FeedPublicationRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	pub := &models.FeedPublication{
+		User:     userId,
+		Scope:    models.PublicationScopeAll,
+		IsActive: true,
+	}
+	req.apply(pub)
+
+	if err := feed.ValidatePublication(pub); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	pub.Id = util.GenerateRandomId()
+	pub.Token = feed.NewPublicationToken()
+	pub.RefreshCreated()
+	pub.RefreshUpdated()
+	if err := query.SaveRecord(pub); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to create feed publication: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, buildFeedPublicationResponse(e, pub))
+}
+
+// UpdateFeedPublication updates the given fields of a publication
+func UpdateFeedPublication(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	pub, err := findUserFeedPublication(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed publication not found"})
+	}
+
+	req := &FeedPublicationRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.apply(pub)
+
+	if err := feed.ValidatePublication(pub); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	pub.MarkAsNotNew()
+	pub.RefreshUpdated()
+	if err := query.SaveRecord(pub); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update feed publication: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, buildFeedPublicationResponse(e, pub))
+}
+
+// DeleteFeedPublication deletes a publication, its URLs stop working
+func DeleteFeedPublication(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	pub, err := findUserFeedPublication(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed publication not found"})
+	}
+
+	if err := query.DeleteRecord(pub); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete feed publication: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Feed publication deleted"})
+}
+
+// RotateFeedPublicationToken replaces the secret token of a publication so
+// previously shared URLs stop working
+func RotateFeedPublicationToken(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	pub, err := findUserFeedPublication(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed publication not found"})
+	}
+
+	pub.Token = feed.NewPublicationToken()
+	pub.MarkAsNotNew()
+	pub.RefreshUpdated()
+	if err := query.SaveRecord(pub); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to rotate token: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, buildFeedPublicationResponse(e, pub))
+}
+
+// GetPublishedFeed serves a publication as Atom, RSS or JSON Feed. Readers
+// polling with If-None-Match or If-Modified-Since get a 304 when nothing
+// changed.
+func GetPublishedFeed(e *core.RequestEvent) error {
+	format := e.Request.PathValue("format")
+	if format != models.PublicationFormatAtom && format != models.PublicationFormatRSS && format != models.PublicationFormatJSON {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Unknown feed format"})
+	}
+
+	pub, err := query.FindByFilter[*models.FeedPublication](map[string]interface{}{
+		"token":     e.Request.PathValue("token"),
+		"is_active": true,
+	})
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed not found"})
+	}
+
+	items, err := feed.LoadPublishedItems(pub)
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to load feed"})
+	}
+
+	published, err := feed.RenderPublication(pub, items, format, publishedFeedURL(e, pub, format))
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to render feed"})
+	}
+
+	header := e.Response.Header()
+	header.Set("ETag", published.ETag)
+	header.Set("Last-Modified", published.LastModified.Format(http.TimeFormat))
+	header.Set("Cache-Control", "private, max-age=300")
+
+	if notModified(e.Request, published) {
+		return e.NoContent(http.StatusNotModified)
+	}
+
+	return e.Blob(http.StatusOK, published.ContentType, published.Body)
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since.
+func notModified(r *http.Request, published *feed.PublishedFeed) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(published.ETag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil {
+			return !published.LastModified.After(t)
+		}
+	}
+
+	return false
+}
+
+func buildFeedPublicationResponse(e *core.RequestEvent, pub *models.FeedPublication) FeedPublicationResponse {
+	return FeedPublicationResponse{
+		FeedPublication: pub,
+		URLs: map[string]string{
+			models.PublicationFormatAtom: publishedFeedURL(e, pub, models.PublicationFormatAtom),
+			models.PublicationFormatRSS:  publishedFeedURL(e, pub, models.PublicationFormatRSS),
+			models.PublicationFormatJSON: publishedFeedURL(e, pub, models.PublicationFormatJSON),
+		},
+	}
+}
+
+// publishedFeedURL returns the public URL of a publication as seen by the
+// client, honouring reverse proxy headers
+func publishedFeedURL(e *core.RequestEvent, pub *models.FeedPublication, format string) string {
+	scheme := "http"
+	if e.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := e.Request.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+
+	host := e.Request.Host
+	if forwarded := e.Request.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	return scheme + "://" + host + "/api" + publishedFeedsPath + "/" + pub.Token + "/" + format
+}
+
+func findUserFeedPublication(pubId string, userId string) (*models.FeedPublication, error) {
+	return query.FindByFilter[*models.FeedPublication](map[string]interface{}{
+		"id":   pubId,
+		"user": userId,
+	})
+}
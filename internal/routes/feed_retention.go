@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// FeedRetentionPolicyRequest creates or updates a retention policy. Fields
+// left out of an update keep their current value.
+type FeedRetentionPolicyRequest struct {
+	Scope         *string `json:"scope"`
+	ScopeID       *string `json:"scope_id"`
+	ReadDays      *int    `json:"read_days"`
+	UnreadDays    *int    `json:"unread_days"`
+	DismissedDays *int    `json:"dismissed_days"`
+	KeepRated     *bool   `json:"keep_rated"`
+	IsActive      *bool   `json:"is_active"`
+}
+
+// apply copies the set fields of the request onto a policy
+func (r *FeedRetentionPolicyRequest) apply(policy *models.FeedRetentionPolicy) {
+	if r.Scope != nil {
+		policy.Scope = *r.Scope
+	}
+	if r.ScopeID != nil {
+		policy.ScopeID = *r.ScopeID
+	}
+	if r.ReadDays != nil {
+		policy.ReadDays = *r.ReadDays
+	}
+	if r.UnreadDays != nil {
+		policy.UnreadDays = *r.UnreadDays
+	}
+	if r.DismissedDays != nil {
+		policy.DismissedDays = *r.DismissedDays
+	}
+	if r.KeepRated != nil {
+		policy.KeepRated = *r.KeepRated
+	}
+	if r.IsActive != nil {
+		policy.IsActive = *r.IsActive
+	}
+}
+
+func RegisterFeedRetentionRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string) {
+	retentionRouter := apiRouter.Group(path)
+	retentionRouter.GET("", func(e *core.RequestEvent) error {
+		return ListFeedRetentionPolicies(e)
+	})
+	retentionRouter.POST("", func(e *core.RequestEvent) error {
+		return CreateFeedRetentionPolicy(e)
+	})
+	retentionRouter.POST("/run", func(e *core.RequestEvent) error {
+		return RunFeedRetention(e)
+	})
+	retentionRouter.PATCH("/{id}", func(e *core.RequestEvent) error {
+		return UpdateFeedRetentionPolicy(e)
+	})
+	retentionRouter.DELETE("/{id}", func(e *core.RequestEvent) error {
+		return DeleteFeedRetentionPolicy(e)
+	})
+}
+
+// ListFeedRetentionPolicies returns the retention policies of the
+// authenticated user
+func ListFeedRetentionPolicies(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	var policies []*models.FeedRetentionPolicy
+	err = query.BaseQuery[*models.FeedRetentionPolicy]().
+		AndWhere(dbx.HashExp{"user": userId}).
+		OrderBy("scope ASC", "created ASC").
+		All(&policies)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch retention policies"})
+	}
+
+	return e.JSON(http.StatusOK, policies)
+}
+
+// CreateFeedRetentionPolicy creates a policy. Rated items are kept unless
+// keep_rated is turned off.
+func CreateFeedRetentionPolicy(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &FeedRetentionPolicyRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	policy := &models.FeedRetentionPolicy{
+		User:      userId,
+		Scope:     models.RetentionScopeAll,
+		KeepRated: true,
+		IsActive:  true,
+	}
+	req.apply(policy)
+
+	if err := feed.ValidateRetentionPolicy(policy); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	if _, err := query.FindByFilter[*models.FeedRetentionPolicy](map[string]interface{}{
+		"user":     userId,
+		"scope":    policy.Scope,
+		"scope_id": policy.ScopeID,
+	}); err == nil {
+		return e.JSON(http.StatusConflict, map[string]interface{}{"error": "A policy for this scope already exists"})
+	}
+
+	policy.Id = util.GenerateRandomId()
+	policy.RefreshCreated()
+	policy.RefreshUpdated()
+	if err := query.SaveRecord(policy); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to create retention policy: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, policy)
+}
+
+// UpdateFeedRetentionPolicy updates the given fields of a policy
+func UpdateFeedRetentionPolicy(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	policy, err := findUserFeedRetentionPolicy(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Retention policy not found"})
+	}
+
+	req := &FeedRetentionPolicyRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.apply(policy)
+
+	if err := feed.ValidateRetentionPolicy(policy); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	if existing, err := query.FindByFilter[*models.FeedRetentionPolicy](map[string]interface{}{
+		"user":     userId,
+		"scope":    policy.Scope,
+		"scope_id": policy.ScopeID,
+	}); err == nil && existing.Id != policy.Id {
+		return e.JSON(http.StatusConflict, map[string]interface{}{"error": "A policy for this scope already exists"})
+	}
+
+	policy.MarkAsNotNew()
+	policy.RefreshUpdated()
+	if err := query.SaveRecord(policy); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update retention policy: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, policy)
+}
+
+// DeleteFeedRetentionPolicy deletes a policy, its items are kept from then on
+func DeleteFeedRetentionPolicy(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	policy, err := findUserFeedRetentionPolicy(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Retention policy not found"})
+	}
+
+	if err := query.DeleteRecord(policy); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete retention policy: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Retention policy deleted"})
+}
+
+// RunFeedRetention applies the retention policies of the user now. With
+// ?dry_run=true it only reports what would be deleted.
+func RunFeedRetention(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	dryRun := e.Request.URL.Query().Get("dry_run") == "true"
+	result, err := feed.ApplyRetention(userId, time.Now(), dryRun)
+	if err != nil {
+		logger.LogError(err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to apply retention policies"})
+	}
+
+	return e.JSON(http.StatusOK, result)
+}
+
+func findUserFeedRetentionPolicy(policyId string, userId string) (*models.FeedRetentionPolicy, error) {
+	return query.FindByFilter[*models.FeedRetentionPolicy](map[string]interface{}{
+		"id":   policyId,
+		"user": userId,
+	})
+}
@@ -0,0 +1,237 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// FeedRuleRequest creates or updates a feed rule. Fields left out of an
+// update keep their current value.
+type FeedRuleRequest struct {
+	Name           *string                     `json:"name"`
+	IsActive       *bool                       `json:"is_active"`
+	SortOrder      *int                        `json:"sort_order"`
+	MatchMode      *string                     `json:"match_mode"`
+	Conditions     *[]models.FeedRuleCondition `json:"conditions"`
+	Actions        *[]models.FeedRuleAction    `json:"actions"`
+	StopProcessing *bool                       `json:"stop_processing"`
+}
+
+type ReorderFeedRulesRequest struct {
+	RuleIDs []string `json:"rule_ids"`
+}
+
+// apply copies the set fields of the request onto a rule
+func (r *FeedRuleRequest) apply(rule *models.FeedRule) {
+	if r.Name != nil {
+		rule.Name = *r.Name
+	}
+	if r.IsActive != nil {
+		rule.IsActive = *r.IsActive
+	}
+	if r.SortOrder != nil {
+		rule.SortOrder = *r.SortOrder
+	}
+	if r.MatchMode != nil {
+		rule.MatchMode = *r.MatchMode
+	}
+	if r.Conditions != nil {
+		rule.Conditions = *r.Conditions
+	}
+	if r.Actions != nil {
+		rule.Actions = *r.Actions
+	}
+	if r.StopProcessing != nil {
+		rule.StopProcessing = *r.StopProcessing
+	}
+}
+
+// ListFeedRules returns the rules of the authenticated user in evaluation order
+func ListFeedRules(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	var rules []*models.FeedRule
+	err = query.BaseQuery[*models.FeedRule]().
+		AndWhere(dbx.HashExp{"user": userId}).
+		OrderBy("sort_order ASC", "created ASC").
+		All(&rules)
+	if err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to fetch feed rules"})
+	}
+
+	return e.JSON(http.StatusOK, rules)
+}
+
+// CreateFeedRule creates a rule, appended after the existing rules unless a
+// sort order is given
+func CreateFeedRule(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &FeedRuleRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	count, err := query.CountRecords[*models.FeedRule](map[string]interface{}{"user": userId})
+	if err != nil {
+		logger.LogError(err.Error())
+	}
+
+	rule := &models.FeedRule{
+		User:      userId,
+		IsActive:  true,
+		SortOrder: int(count),
+		MatchMode: models.RuleMatchAll,
+	}
+	req.apply(rule)
+
+	if err := feed.ValidateRule(rule); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	rule.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(rule); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to create feed rule: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusCreated, rule)
+}
+
+// UpdateFeedRule updates the given fields of a rule
+func UpdateFeedRule(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	rule, err := findUserFeedRule(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed rule not found"})
+	}
+
+	req := &FeedRuleRequest{}
+	if err := e.BindBody(req); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+	req.apply(rule)
+
+	if err := feed.ValidateRule(rule); err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	rule.MarkAsNotNew()
+	rule.RefreshUpdated()
+	if err := query.SaveRecord(rule); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to update feed rule: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, rule)
+}
+
+// DeleteFeedRule deletes a rule
+func DeleteFeedRule(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	rule, err := findUserFeedRule(e.Request.PathValue("id"), userId)
+	if err != nil {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed rule not found"})
+	}
+
+	if err := query.DeleteRecord(rule); err != nil {
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to delete feed rule: " + err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Feed rule deleted"})
+}
+
+// ReorderFeedRules sets the evaluation order to the order of the given IDs
+func ReorderFeedRules(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	req := &ReorderFeedRulesRequest{}
+	if err := e.BindBody(req); err != nil || len(req.RuleIDs) == 0 {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+	}
+
+	for _, ruleId := range req.RuleIDs {
+		if _, err := findUserFeedRule(ruleId, userId); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Feed rule not found: " + ruleId})
+		}
+	}
+
+	for i, ruleId := range req.RuleIDs {
+		if err := query.UpdateRecord[*models.FeedRule](ruleId, map[string]interface{}{
+			"sort_order": i,
+		}); err != nil {
+			return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to reorder feed rules: " + err.Error()})
+		}
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{"message": "Feed rules reordered"})
+}
+
+// TestFeedRule evaluates a rule against the last 100 items of the user and
+// returns the items it would match. A saved rule is tested by ID, an unsaved
+// one can be sent as the request body. No actions are applied.
+func TestFeedRule(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	rule := &models.FeedRule{User: userId}
+	if ruleId := e.Request.PathValue("id"); ruleId != "" {
+		if rule, err = findUserFeedRule(ruleId, userId); err != nil {
+			return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed rule not found"})
+		}
+	} else {
+		req := &FeedRuleRequest{}
+		if err := e.BindBody(req); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid request body"})
+		}
+		req.apply(rule)
+	}
+
+	matches, tested, err := feed.TestRule(rule)
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"tested":  tested,
+		"matched": len(matches),
+		"items":   buildFeedItemResponses(userId, matches),
+	})
+}
+
+func findUserFeedRule(ruleId string, userId string) (*models.FeedRule, error) {
+	return query.FindByFilter[*models.FeedRule](map[string]interface{}{
+		"id":   ruleId,
+		"user": userId,
+	})
+}
@@ -0,0 +1,79 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/feed"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+)
+
+// RegisterFeedWebSubRoutes registers the callbacks WebSub hubs call to verify
+// subscriptions and deliver new content. They are public, pushed content is
+// authenticated by its HMAC signature instead.
+func RegisterFeedWebSubRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, manager *feed.WebSubManager) {
+	webSubRouter := apiRouter.Group(path)
+	webSubRouter.GET("/{sourceId}", func(e *core.RequestEvent) error {
+		return VerifyFeedWebSubIntent(e, manager)
+	})
+	webSubRouter.POST("/{sourceId}", func(e *core.RequestEvent) error {
+		return ReceiveFeedWebSubPush(e, manager)
+	})
+}
+
+// VerifyFeedWebSubIntent confirms a subscription request by echoing the
+// hub's challenge, or takes note of a denied subscription
+func VerifyFeedWebSubIntent(e *core.RequestEvent, manager *feed.WebSubManager) error {
+	params := e.Request.URL.Query()
+	mode := params.Get("hub.mode")
+	challenge := params.Get("hub.challenge")
+	leaseSeconds, _ := strconv.Atoi(params.Get("hub.lease_seconds"))
+
+	if mode != "denied" && challenge == "" {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "hub.challenge is required"})
+	}
+
+	err := manager.VerifyIntent(e.Request.PathValue("sourceId"), mode, params.Get("hub.topic"), leaseSeconds, params.Get("hub.reason"))
+	if errors.Is(err, feed.ErrWebSubNotFound) {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Subscription not found"})
+	}
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error verifying WebSub intent: %v", err))
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	if mode == "denied" {
+		return e.NoContent(http.StatusOK)
+	}
+	return e.String(http.StatusOK, challenge)
+}
+
+// ReceiveFeedWebSubPush ingests feed content delivered by a hub. Content with
+// an invalid signature is acknowledged but ignored, as the spec requires.
+func ReceiveFeedWebSubPush(e *core.RequestEvent, manager *feed.WebSubManager) error {
+	body, err := io.ReadAll(io.LimitReader(e.Request.Body, providers.MaxBodySize+1))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Failed to read body"})
+	}
+	if len(body) > providers.MaxBodySize {
+		return e.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{"error": "Content too large"})
+	}
+
+	_, err = manager.HandlePush(e.Request.Context(), e.Request.PathValue("sourceId"), body, e.Request.Header.Get("X-Hub-Signature"))
+	if errors.Is(err, feed.ErrWebSubNotFound) {
+		// Tells the hub to drop the subscription
+		return e.JSON(http.StatusGone, map[string]interface{}{"error": "Subscription not found"})
+	}
+	if err != nil {
+		// Delivering the same content again won't help
+		logger.LogError(fmt.Sprintf("Error handling WebSub push: %v", err))
+	}
+
+	return e.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,131 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/search"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// RegisterSearchRoutes registers the unified full-text and semantic search
+// API routes
+func RegisterSearchRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, semanticService *semantic.Service) {
+	searchRouter := apiRouter.Group(path)
+	searchRouter.GET("", func(e *core.RequestEvent) error {
+		return Search(e)
+	})
+	searchRouter.GET("/semantic", func(e *core.RequestEvent) error {
+		return SemanticSearch(e, semanticService)
+	})
+}
+
+// Search runs a full-text search over the feed items, mail messages and
+// calendar events of the authenticated user.
+//
+// Query parameters: q, type (feed,mail,calendar,archive), from, to (RFC3339 or
+// YYYY-MM-DD), tag (tag IDs or names), limit and offset
+func Search(e *core.RequestEvent) error {
+	token := e.Request.Header.Get("Authorization")
+	userId, err := util.GetUserId(token)
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	params := e.Request.URL.Query()
+	q := strings.TrimSpace(params.Get("q"))
+	if q == "" {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Missing search query"})
+	}
+
+	opts := search.Options{}
+
+	for _, docType := range splitParam(params.Get("type")) {
+		switch docType {
+		case search.TypeFeed, search.TypeMail, search.TypeCalendar, search.TypeArchive:
+			opts.Types = append(opts.Types, docType)
+		default:
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid type: " + docType})
+		}
+	}
+
+	if from := params.Get("from"); from != "" {
+		if opts.From, err = parseSearchDate(from, false); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid from date"})
+		}
+	}
+	if to := params.Get("to"); to != "" {
+		if opts.To, err = parseSearchDate(to, true); err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Invalid to date"})
+		}
+	}
+
+	// Tags can be given by ID or by name
+	for _, tag := range splitParam(params.Get("tag")) {
+		tags, err := query.FindAllByFilter[*models.Tag](map[string]interface{}{
+			"user": userId,
+			"name": strings.ToLower(tag),
+		})
+		if err == nil && len(tags) > 0 {
+			for _, t := range tags {
+				opts.TagIDs = append(opts.TagIDs, t.Id)
+			}
+		} else {
+			opts.TagIDs = append(opts.TagIDs, tag)
+		}
+	}
+
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil {
+		opts.Limit = limit
+	}
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil {
+		opts.Offset = offset
+	}
+
+	results, total, err := search.Search(e.App, userId, q, opts)
+	if err != nil {
+		logger.LogError("Search failed: " + err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Search failed"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"query":   q,
+		"total":   total,
+		"results": results,
+	})
+}
+
+func splitParam(value string) []string {
+	values := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// parseSearchDate parses RFC3339 or YYYY-MM-DD. A plain date used as the end
+// of a range covers the whole day.
+func parseSearchDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
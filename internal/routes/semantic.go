@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/router"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// RegisterFeedRelatedRoutes registers the related items lookup of feed items
+func RegisterFeedRelatedRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], path string, semanticService *semantic.Service) {
+	itemRouter := apiRouter.Group(path)
+	itemRouter.GET("/{id}/related", func(e *core.RequestEvent) error {
+		return GetRelatedFeedItems(e, semanticService)
+	})
+}
+
+// SemanticSearch finds the feed items, mail messages, notebooks and bookshelf
+// entries of the authenticated user closest in meaning to the query.
+//
+// Query parameters: q, type (feed,mail,notebook,bookshelf), limit and
+// min_score
+func SemanticSearch(e *core.RequestEvent, semanticService *semantic.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	params := e.Request.URL.Query()
+	q := strings.TrimSpace(params.Get("q"))
+	if q == "" {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": "Missing search query"})
+	}
+
+	opts, err := parseSemanticOptions(params.Get("type"), params.Get("limit"), params.Get("min_score"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+
+	results, err := semanticService.Search(e.Request.Context(), userId, q, opts)
+	if err != nil {
+		logger.LogError("Semantic search failed: " + err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Search failed"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"query":   q,
+		"model":   semanticService.Model(),
+		"results": results,
+	})
+}
+
+// GetRelatedFeedItems returns the feed items closest in meaning to an item,
+// leaving out copies of the same story. Other document types can be included
+// with the type parameter.
+func GetRelatedFeedItems(e *core.RequestEvent, semanticService *semantic.Service) error {
+	userId, err := util.GetUserId(e.Request.Header.Get("Authorization"))
+	if err != nil {
+		return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+	}
+
+	item, err := query.FindById[*models.FeedItem](e.Request.PathValue("id"))
+	if err != nil || item.User != userId {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed item not found"})
+	}
+
+	params := e.Request.URL.Query()
+	types := params.Get("type")
+	if types == "" {
+		types = semantic.TypeFeed
+	}
+	opts, err := parseSemanticOptions(types, params.Get("limit"), params.Get("min_score"))
+	if err != nil {
+		return e.JSON(http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+	opts.ExcludeCluster = item.ClusterID
+
+	results, err := semanticService.Related(e.Request.Context(), userId, semantic.TypeFeed, item.Id, opts)
+	if errors.Is(err, semantic.ErrNotFound) {
+		return e.JSON(http.StatusNotFound, map[string]interface{}{"error": "Feed item not found"})
+	}
+	if errors.Is(err, semantic.ErrNotIndexed) {
+		return e.JSON(http.StatusOK, map[string]interface{}{"item_id": item.Id, "results": []*semantic.Result{}})
+	}
+	if err != nil {
+		logger.LogError("Related items lookup failed: " + err.Error())
+		return e.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Failed to find related items"})
+	}
+
+	return e.JSON(http.StatusOK, map[string]interface{}{
+		"item_id": item.Id,
+		"results": results,
+	})
+}
+
+func parseSemanticOptions(types string, limit string, minScore string) (semantic.Options, error) {
+	opts := semantic.Options{}
+
+	var err error
+	if opts.Types, err = semantic.ParseTypes(types); err != nil {
+		return opts, err
+	}
+	if value, err := strconv.Atoi(limit); err == nil {
+		opts.Limit = value
+	}
+	if value, err := strconv.ParseFloat(minScore, 64); err == nil {
+		opts.MinScore = value
+	}
+
+	return opts, nil
+}
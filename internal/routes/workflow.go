@@ -86,6 +86,83 @@ func RegisterWorkflowRoutes(apiRouter *router.RouterGroup[*core.RequestEvent], p
 		return e.JSON(http.StatusAccepted, execution)
 	})
 
+	// Get the incremental sync state of the workflow source nodes
+	workflowRouter.GET("/{id}/sync-state", func(e *core.RequestEvent) error {
+		workflowId := e.Request.PathValue("id")
+		if workflowId == "" {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Missing workflow ID",
+			})
+		}
+
+		token := e.Request.Header.Get("Authorization")
+		userId, err := util.GetUserId(token)
+		if err != nil {
+			return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+		}
+
+		workflow, err := query.FindByFilter[*models.Workflow](map[string]interface{}{
+			"id": workflowId,
+			"user": userId,
+		})
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "Workflow not found or unauthorized",
+			})
+		}
+
+		states, err := engine.GetSyncStates(workflow.Id)
+		if err != nil {
+			return e.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to get sync state: " + err.Error(),
+			})
+		}
+
+		return e.JSON(http.StatusOK, map[string]interface{}{
+			"sync_states": states,
+		})
+	})
+
+	// Reset the sync state so the next run fetches everything again.
+	// Pass ?node_id= to reset a single source node.
+	workflowRouter.DELETE("/{id}/sync-state", func(e *core.RequestEvent) error {
+		workflowId := e.Request.PathValue("id")
+		if workflowId == "" {
+			return e.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Missing workflow ID",
+			})
+		}
+
+		token := e.Request.Header.Get("Authorization")
+		userId, err := util.GetUserId(token)
+		if err != nil {
+			return e.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unauthorized"})
+		}
+
+		workflow, err := query.FindByFilter[*models.Workflow](map[string]interface{}{
+			"id": workflowId,
+			"user": userId,
+		})
+		if err != nil {
+			return e.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "Workflow not found or unauthorized",
+			})
+		}
+
+		nodeId := e.Request.URL.Query().Get("node_id")
+		count, err := engine.ResetSyncState(workflow.Id, nodeId)
+		if err != nil {
+			return e.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to reset sync state: " + err.Error(),
+			})
+		}
+
+		return e.JSON(http.StatusOK, map[string]interface{}{
+			"message": "Sync state reset",
+			"reset":   count,
+		})
+	})
+
 	// Get execution status
 	workflowRouter.GET("/executions/{id}", func(e *core.RequestEvent) error {
 		executionId := e.Request.PathValue("id")
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -16,6 +17,7 @@ import (
 const (
 	claudeAPIBaseURL = "https://api.anthropic.com/v1"
 	defaultTimeout   = 30 * time.Second
+	streamTimeout    = 5 * time.Minute
 )
 
 // ClaudeClient implements the AIClient interface using Anthropic's Claude API
@@ -43,6 +45,7 @@ type claudeRequest struct {
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
 	System      string    `json:"system,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 type claudeMessage struct {
@@ -52,9 +55,15 @@ type claudeMessage struct {
 
 type claudeResponse struct {
 	Content []claudeContent `json:"content"`
+	Usage   claudeUsage     `json:"usage"`
 	Error   *claudeError    `json:"error,omitempty"`
 }
 
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
 type claudeContent struct {
 	Type  string `json:"type"`
 	Text  string `json:"text"`
@@ -104,6 +113,7 @@ func (c *ClaudeClient) makeClaudeRequest(ctx context.Context, claudeReq claudeRe
 	if claudeResp.Error != nil {
 		return "", fmt.Errorf("Claude API error: %s", claudeResp.Error.Message)
 	}
+	reportTokens(ctx, claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens)
 	
 	// Extract text from the response
 	var respText string
@@ -116,6 +126,141 @@ func (c *ClaudeClient) makeClaudeRequest(ctx context.Context, claudeReq claudeRe
 	return strings.TrimSpace(respText), nil
 }
 
+// claudeStreamEvent is a server-sent event of a streamed answer
+type claudeStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Message struct {
+		Usage claudeUsage `json:"usage"`
+	} `json:"message"` // Set on message_start
+	Usage claudeUsage  `json:"usage"` // Set on message_delta, output tokens so far
+	Error *claudeError `json:"error,omitempty"`
+}
+
+// claudeChatRequest converts a chat request to the messages API, which takes
+// the system prompt separately
+func (c *ClaudeClient) claudeChatRequest(req *ChatRequest) claudeRequest {
+	var system []string
+	messages := make([]claudeMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		if message.Role == ChatRoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		messages = append(messages, claudeMessage{Role: message.Role, Content: message.Content})
+	}
+	
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 1024
+	}
+	
+	// There is no JSON mode, callers ask for JSON in the prompt
+	return claudeRequest{
+		Model:       c.model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+		System:      strings.Join(system, "\n\n"),
+	}
+}
+
+// Chat implements the Chatter.Chat method
+func (c *ClaudeClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	response, err := c.makeClaudeRequest(ctx, c.claudeChatRequest(req))
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Claude chat error: %v", err))
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	
+	return &ChatResponse{
+		Content: response,
+		Model:   c.model,
+	}, nil
+}
+
+// ChatStream implements the Chatter.ChatStream method
+func (c *ClaudeClient) ChatStream(ctx context.Context, req *ChatRequest, onDelta ChatStreamFunc) (*ChatResponse, error) {
+	claudeReq := c.claudeChatRequest(req)
+	claudeReq.Stream = true
+	
+	reqBody, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Claude request: %w", err)
+	}
+	
+	endpoint := fmt.Sprintf("%s/messages", claudeAPIBaseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-API-Key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	
+	// Long answers take longer than the timeout of single requests
+	client := &http.Client{Timeout: streamTimeout}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Claude API returned error status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+	
+	var content strings.Builder
+	var usage claudeUsage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		
+		var event claudeStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			continue
+		}
+		if event.Error != nil {
+			return nil, fmt.Errorf("Claude API error: %s", event.Error.Message)
+		}
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		}
+		if event.Type == "message_stop" {
+			break
+		}
+		if event.Type != "content_block_delta" || event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+			continue
+		}
+		
+		content.WriteString(event.Delta.Text)
+		if err := onDelta(event.Delta.Text); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response stream: %w", err)
+	}
+	reportTokens(ctx, usage.InputTokens, usage.OutputTokens)
+	
+	return &ChatResponse{
+		Content: strings.TrimSpace(content.String()),
+		Model:   c.model,
+	}, nil
+}
+
 // Summarize implements the AIClient.Summarize method
 func (c *ClaudeClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
 	if req.Text == "" {
@@ -74,4 +74,66 @@ type AIClient interface {
 	
 	// RecommendContent provides a relevance score for content based on user preferences
 	RecommendContent(ctx context.Context, req *RecommendRequest) (*RecommendResponse, error)
-} 
\ No newline at end of file
+} 
+
+// EmbedRequest contains the texts to turn into vectors
+type EmbedRequest struct {
+	Texts []string `json:"texts"`
+}
+
+// EmbedResponse contains one vector per requested text, in request order
+type EmbedResponse struct {
+	Vectors [][]float32 `json:"vectors"`
+	Model   string      `json:"model"` // The model that produced the vectors
+}
+
+// Embedder is implemented by clients that can embed text into vectors for
+// semantic search. Vectors of different models can't be compared.
+type Embedder interface {
+	// Embed returns a vector for each text
+	Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error)
+
+	// EmbeddingModel names the model vectors are produced with
+	EmbeddingModel() string
+}
+
+// Chat message roles
+const (
+	ChatRoleSystem    = "system"
+	ChatRoleUser      = "user"
+	ChatRoleAssistant = "assistant"
+)
+
+// ChatMessage is a single turn of a conversation
+type ChatMessage struct {
+	Role    string `json:"role"`    // system, user or assistant
+	Content string `json:"content"`
+}
+
+// ChatRequest contains the conversation to continue
+type ChatRequest struct {
+	Messages    []ChatMessage `json:"messages"`    // System messages first, then alternating user and assistant turns ending with a user turn
+	MaxTokens   int           `json:"maxTokens"`   // The maximum length of the answer in tokens
+	Temperature float64       `json:"temperature"`
+	JSON        bool          `json:"json"` // Ask for a JSON object, where the API supports it
+}
+
+// ChatResponse contains the answer to a conversation
+type ChatResponse struct {
+	Content string `json:"content"`
+	Model   string `json:"model"`
+}
+
+// ChatStreamFunc receives an answer piece by piece while it is generated.
+// Returning an error stops the generation.
+type ChatStreamFunc func(delta string) error
+
+// Chatter is implemented by clients that can hold a free-form conversation
+type Chatter interface {
+	// Chat returns the next assistant turn of the conversation
+	Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+
+	// ChatStream returns the next assistant turn of the conversation and
+	// hands it to onDelta as it is generated
+	ChatStream(ctx context.Context, req *ChatRequest, onDelta ChatStreamFunc) (*ChatResponse, error)
+}
@@ -15,7 +15,7 @@ func NewAIClient(cfg config.AIConfig) (AIClient, error) {
 			return nil, fmt.Errorf("OpenAI API key is required")
 		}
 		logger.LogInfo(fmt.Sprintf("Initializing OpenAI client with model: %s", cfg.Model))
-		return NewOpenAIClient(cfg.APIKey, cfg.Model), nil
+		return NewOpenAIClient(cfg.APIKey, cfg.Model, cfg.EmbeddingModel), nil
 	
 	case config.AIServiceClaude:
 		if cfg.AnthropicKey == "" {
@@ -23,6 +23,10 @@ func NewAIClient(cfg config.AIConfig) (AIClient, error) {
 		}
 		logger.LogInfo(fmt.Sprintf("Initializing Claude client with model: %s", cfg.Model))
 		return NewClaudeClient(cfg.AnthropicKey, cfg.Model), nil
+
+	case config.AIServiceLocal:
+		logger.LogInfo(fmt.Sprintf("Initializing local AI client (%s API at %s) with model: %s", cfg.Local.API, cfg.Local.BaseURL, cfg.Model))
+		return NewLocalClient(cfg.Local, cfg.APIKey, cfg.Model, cfg.EmbeddingModel)
 		
 	case config.AIServiceNone:
 		logger.LogInfo("AI services disabled")
@@ -31,4 +35,18 @@ func NewAIClient(cfg config.AIConfig) (AIClient, error) {
 	default:
 		return nil, fmt.Errorf("unsupported AI service: %s", cfg.Service)
 	}
-} 
\ No newline at end of file
+}
+
+// NewEmbedder returns the embedder of the AI client, or the built-in hashing
+// embedder when the client has none, AI is disabled or it is configured
+func NewEmbedder(cfg config.AIConfig, client AIClient) Embedder {
+	if cfg.EmbeddingModel != config.EmbeddingModelHashing {
+		if embedder, ok := client.(Embedder); ok {
+			logger.LogInfo(fmt.Sprintf("Using embedding model: %s", embedder.EmbeddingModel()))
+			return embedder
+		}
+	}
+
+	logger.LogInfo("Using built-in hashing embeddings")
+	return NewHashingEmbedder()
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// Dimensions of the hashing vectors, more means fewer collisions
+	hashingDimensions = 512
+	// Versioned so vectors are rebuilt when the hashing changes
+	hashingModelName = "hashing-512-v1"
+	// Word pairs count less than single words
+	hashingBigramWeight = 0.5
+)
+
+// Words too common to say anything about a text
+var hashingStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "this": true,
+	"that": true, "your": true, "you": true, "are": true, "was": true, "were": true,
+	"has": true, "have": true, "had": true, "but": true, "not": true, "all": true,
+	"its": true, "his": true, "her": true, "they": true, "them": true, "their": true,
+	"our": true, "out": true, "into": true, "about": true, "will": true, "would": true,
+	"can": true, "could": true, "there": true, "which": true, "what": true, "when": true,
+	"who": true, "how": true, "been": true, "also": true, "than": true, "then": true,
+	"some": true, "more": true, "just": true, "only": true, "over": true, "such": true,
+}
+
+// HashingEmbedder embeds text without a model by hashing its words and word
+// pairs into a fixed number of dimensions. It captures shared vocabulary
+// rather than meaning, but works offline and for every AI service.
+type HashingEmbedder struct{}
+
+func NewHashingEmbedder() Embedder {
+	return &HashingEmbedder{}
+}
+
+// EmbeddingModel implements the Embedder.EmbeddingModel method
+func (h *HashingEmbedder) EmbeddingModel() string {
+	return hashingModelName
+}
+
+// Embed implements the Embedder.Embed method
+func (h *HashingEmbedder) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	vectors := make([][]float32, len(req.Texts))
+	for i, text := range req.Texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = hashText(text)
+	}
+
+	return &EmbedResponse{
+		Vectors: vectors,
+		Model:   hashingModelName,
+	}, nil
+}
+
+// hashText builds the L2 normalized vector of a text. Term counts are damped
+// logarithmically so repetition doesn't dominate.
+func hashText(text string) []float32 {
+	counts := make(map[string]float64)
+	previous := ""
+	for _, word := range hashingWords(text) {
+		counts[word]++
+		if previous != "" {
+			counts[previous+" "+word] += hashingBigramWeight
+		}
+		previous = word
+	}
+
+	vector := make([]float64, hashingDimensions)
+	for term, count := range counts {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(term))
+		sum := hasher.Sum64()
+
+		// The sign bit keeps colliding terms from only ever adding up
+		sign := 1.0
+		if sum&(1<<63) != 0 {
+			sign = -1.0
+		}
+		vector[sum%hashingDimensions] += sign * (1 + math.Log(count))
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, hashingDimensions)
+	if norm == 0 {
+		return result
+	}
+	for i, value := range vector {
+		result[i] = float32(value / norm)
+	}
+	return result
+}
+
+// hashingWords splits text into lower case words, dropping stop words and
+// single characters
+func hashingWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || hashingStopWords[field] {
+			continue
+		}
+		words = append(words, field)
+	}
+	return words
+}
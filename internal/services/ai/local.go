@@ -0,0 +1,805 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+)
+
+const (
+	// Rough number of characters per token used to fit prompts into the
+	// context window
+	localCharsPerToken = 4
+	// Tokens kept free for the chat template the server wraps messages in
+	localTemplateTokens = 64
+	// Largest response body read from the model server
+	localMaxResponseSize = 4 << 20
+)
+
+// Reasoning models served locally often think out loud before answering
+var thinkBlockPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// LocalClient implements the AIClient interface against a self-hosted model
+// server, either through the Ollama API or any OpenAI compatible API, so
+// content never leaves the machines we run
+type LocalClient struct {
+	api            string
+	baseURL        string
+	apiKey         string
+	model          string
+	embeddingModel string
+	contextWindow  int
+	httpClient     *http.Client
+}
+
+// NewLocalClient creates a client for the model server in cfg. Servers outside
+// loopback and private networks are refused unless explicitly allowed.
+func NewLocalClient(cfg config.LocalAIConfig, apiKey string, model string, embeddingModel string) (AIClient, error) {
+	if cfg.API != config.LocalAPIOllama && cfg.API != config.LocalAPIOpenAI {
+		return nil, fmt.Errorf("unsupported local AI API: %s", cfg.API)
+	}
+	if !cfg.AllowRemote {
+		if err := checkLocalEndpoint(cfg.BaseURL); err != nil {
+			return nil, err
+		}
+	}
+	if model == "" {
+		model = config.DefaultLocalModel
+	}
+	if embeddingModel == "" {
+		embeddingModel = config.DefaultLocalEmbeddingModel
+	}
+
+	contextWindow := cfg.ContextWindow
+	if contextWindow <= 0 {
+		contextWindow = config.DefaultLocalContextWindow
+	}
+	timeout := time.Duration(cfg.TimeoutSecs) * time.Second
+	if timeout <= 0 {
+		timeout = config.DefaultLocalTimeout * time.Second
+	}
+
+	return &LocalClient{
+		api:            cfg.API,
+		baseURL:        strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:         apiKey,
+		model:          model,
+		embeddingModel: embeddingModel,
+		contextWindow:  contextWindow,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			// A redirect could send the content somewhere else
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+// checkLocalEndpoint makes sure the server URL points at this machine or a
+// private network
+func checkLocalEndpoint(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid local AI URL: %s", rawURL)
+	}
+
+	host := u.Hostname()
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else if ips, err = net.LookupIP(host); err != nil {
+		return fmt.Errorf("failed to resolve local AI host %s: %w", host, err)
+	}
+
+	for _, ip := range ips {
+		if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() {
+			return fmt.Errorf("local AI host %s is not on a local or private network, set %s=true to use it anyway", host, config.EnvAILocalAllowRemote)
+		}
+	}
+	return nil
+}
+
+// localCompletion is a single chat completion request
+type localCompletion struct {
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+	JSON        bool // Ask for a JSON object, where the API supports it
+}
+
+type localMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []localMessage         `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         localMessage `json:"message"`
+	Done            bool         `json:"done"`
+	PromptEvalCount int          `json:"prompt_eval_count"` // Set when done
+	EvalCount       int          `json:"eval_count"`        // Set when done
+	Error           string       `json:"error,omitempty"`
+}
+
+type openAIChatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []localMessage    `json:"messages"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Temperature    float64           `json:"temperature"`
+	Stream         bool              `json:"stream,omitempty"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message localMessage `json:"message"`
+		Delta   localMessage `json:"delta"` // Set instead of message when streaming
+	} `json:"choices"`
+	Usage *localUsage `json:"usage,omitempty"` // Not every server reports it, nor on every chunk
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type localUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// complete runs a chat completion and returns the text of the answer
+func (c *LocalClient) complete(ctx context.Context, completion localCompletion) (string, error) {
+	messages := make([]localMessage, 0, 2)
+	if completion.System != "" {
+		messages = append(messages, localMessage{Role: "system", Content: completion.System})
+	}
+	messages = append(messages, localMessage{Role: "user", Content: c.fitPrompt(completion)})
+
+	text, err := c.chat(ctx, messages, completion.MaxTokens, completion.Temperature, completion.JSON)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(thinkBlockPattern.ReplaceAllString(text, "")), nil
+}
+
+// chatEndpoint returns the chat endpoint of the API and the request for it
+func (c *LocalClient) chatEndpoint(messages []localMessage, maxTokens int, temperature float64, jsonMode bool, stream bool) (string, interface{}) {
+	switch c.api {
+	case config.LocalAPIOllama:
+		request := ollamaChatRequest{
+			Model:    c.model,
+			Messages: messages,
+			Stream:   stream,
+			Options: map[string]interface{}{
+				"temperature": temperature,
+				"num_predict": maxTokens,
+				"num_ctx":     c.contextWindow,
+			},
+		}
+		if jsonMode {
+			request.Format = "json"
+		}
+		return c.baseURL + "/api/chat", request
+	default:
+		request := openAIChatRequest{
+			Model:       c.model,
+			Messages:    messages,
+			MaxTokens:   maxTokens,
+			Temperature: temperature,
+			Stream:      stream,
+		}
+		if jsonMode {
+			request.ResponseFormat = map[string]string{"type": "json_object"}
+		}
+		return c.baseURL + "/chat/completions", request
+	}
+}
+
+// chat sends messages to the chat endpoint and returns the raw answer
+func (c *LocalClient) chat(ctx context.Context, messages []localMessage, maxTokens int, temperature float64, jsonMode bool) (string, error) {
+	endpoint, payload := c.chatEndpoint(messages, maxTokens, temperature, jsonMode, false)
+	respBody, err := c.post(ctx, endpoint, payload)
+	if err != nil {
+		return "", err
+	}
+
+	switch c.api {
+	case config.LocalAPIOllama:
+		var chatResp ollamaChatResponse
+		if err := json.Unmarshal(respBody, &chatResp); err != nil {
+			return "", fmt.Errorf("failed to unmarshal local AI response: %w", err)
+		}
+		if chatResp.Error != "" {
+			return "", fmt.Errorf("local AI error: %s", chatResp.Error)
+		}
+		reportTokens(ctx, chatResp.PromptEvalCount, chatResp.EvalCount)
+		return chatResp.Message.Content, nil
+	default:
+		var chatResp openAIChatResponse
+		if err := json.Unmarshal(respBody, &chatResp); err != nil {
+			return "", fmt.Errorf("failed to unmarshal local AI response: %w", err)
+		}
+		if chatResp.Error != nil {
+			return "", fmt.Errorf("local AI error: %s", chatResp.Error.Message)
+		}
+		if len(chatResp.Choices) == 0 {
+			return "", fmt.Errorf("no response generated")
+		}
+		if chatResp.Usage != nil {
+			reportTokens(ctx, chatResp.Usage.PromptTokens, chatResp.Usage.CompletionTokens)
+		}
+		return chatResp.Choices[0].Message.Content, nil
+	}
+}
+
+// post sends a JSON request to the model server and returns the response body
+func (c *LocalClient) post(ctx context.Context, endpoint string, payload interface{}) ([]byte, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal local AI request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, localMaxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("local AI server returned error status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	return respBody, nil
+}
+
+// EmbeddingModel implements the Embedder.EmbeddingModel method
+func (c *LocalClient) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// Embed implements the Embedder.Embed method
+func (c *LocalClient) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	if len(req.Texts) == 0 {
+		return &EmbedResponse{Vectors: [][]float32{}, Model: c.embeddingModel}, nil
+	}
+
+	// Embedding models have context windows of their own, cut texts the
+	// same way prompts are cut
+	texts := make([]string, len(req.Texts))
+	maxChars := c.contextWindow * localCharsPerToken
+	for i, text := range req.Texts {
+		if utf8.RuneCountInString(text) > maxChars {
+			text = string([]rune(text)[:maxChars])
+		}
+		texts[i] = text
+	}
+
+	var vectors [][]float32
+	switch c.api {
+	case config.LocalAPIOllama:
+		respBody, err := c.post(ctx, c.baseURL+"/api/embed", map[string]interface{}{
+			"model": c.embeddingModel,
+			"input": texts,
+		})
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Local AI embedding error: %v", err))
+			return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+
+		var embedResp struct {
+			Embeddings      [][]float32 `json:"embeddings"`
+			PromptEvalCount int         `json:"prompt_eval_count"`
+			Error           string      `json:"error,omitempty"`
+		}
+		if err := json.Unmarshal(respBody, &embedResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal local AI response: %w", err)
+		}
+		if embedResp.Error != "" {
+			return nil, fmt.Errorf("local AI error: %s", embedResp.Error)
+		}
+		reportTokens(ctx, embedResp.PromptEvalCount, 0)
+		vectors = embedResp.Embeddings
+	default:
+		respBody, err := c.post(ctx, c.baseURL+"/embeddings", map[string]interface{}{
+			"model": c.embeddingModel,
+			"input": texts,
+		})
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Local AI embedding error: %v", err))
+			return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+
+		var embedResp struct {
+			Data []struct {
+				Embedding []float32 `json:"embedding"`
+				Index     int       `json:"index"`
+			} `json:"data"`
+			Usage *localUsage `json:"usage,omitempty"`
+		}
+		if err := json.Unmarshal(respBody, &embedResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal local AI response: %w", err)
+		}
+		if embedResp.Usage != nil {
+			reportTokens(ctx, embedResp.Usage.PromptTokens, 0)
+		}
+		vectors = make([][]float32, len(texts))
+		for _, embedding := range embedResp.Data {
+			if embedding.Index >= 0 && embedding.Index < len(vectors) {
+				vectors[embedding.Index] = embedding.Embedding
+			}
+		}
+	}
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("no embedding generated for text %d", i)
+		}
+	}
+
+	return &EmbedResponse{
+		Vectors: vectors,
+		Model:   c.embeddingModel,
+	}, nil
+}
+
+// fitPrompt truncates the prompt so it fits into the context window together
+// with the system message and the answer. Prompts put their instructions
+// first, so only content is cut.
+func (c *LocalClient) fitPrompt(completion localCompletion) string {
+	budget := c.contextWindow - completion.MaxTokens - localTemplateTokens
+	budget -= utf8.RuneCountInString(completion.System) / localCharsPerToken
+	maxChars := max(budget, 0) * localCharsPerToken
+
+	prompt := completion.Prompt
+	if utf8.RuneCountInString(prompt) <= maxChars {
+		return prompt
+	}
+	return string([]rune(prompt)[:maxChars])
+}
+
+// fitMessages drops the oldest turns of a conversation and then cuts system
+// messages from the end until it fits into the context window together with
+// the answer. The last turn, the one being answered, is always kept.
+func (c *LocalClient) fitMessages(req *ChatRequest) []localMessage {
+	maxChars := max(c.contextWindow-req.MaxTokens-localTemplateTokens, 0) * localCharsPerToken
+
+	var system, turns []localMessage
+	for _, message := range req.Messages {
+		if message.Role == ChatRoleSystem {
+			system = append(system, localMessage{Role: message.Role, Content: message.Content})
+		} else {
+			turns = append(turns, localMessage{Role: message.Role, Content: message.Content})
+		}
+	}
+
+	size := func(messages []localMessage) int {
+		total := 0
+		for _, message := range messages {
+			total += utf8.RuneCountInString(message.Content)
+		}
+		return total
+	}
+
+	for len(turns) > 1 && size(system)+size(turns) > maxChars {
+		turns = turns[1:]
+	}
+	for i := len(system) - 1; i >= 0; i-- {
+		excess := size(system) + size(turns) - maxChars
+		if excess <= 0 {
+			break
+		}
+		runes := []rune(system[i].Content)
+		system[i].Content = string(runes[:max(len(runes)-excess, 0)])
+	}
+
+	return append(system, turns...)
+}
+
+// Chat implements the Chatter.Chat method
+func (c *LocalClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	text, err := c.chat(ctx, c.fitMessages(req), req.MaxTokens, req.Temperature, req.JSON)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI chat error: %v", err))
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	return &ChatResponse{
+		Content: strings.TrimSpace(thinkBlockPattern.ReplaceAllString(text, "")),
+		Model:   c.model,
+	}, nil
+}
+
+// ChatStream implements the Chatter.ChatStream method. Ollama streams one JSON
+// object per line, OpenAI compatible servers send server-sent events.
+func (c *LocalClient) ChatStream(ctx context.Context, req *ChatRequest, onDelta ChatStreamFunc) (*ChatResponse, error) {
+	endpoint, payload := c.chatEndpoint(c.fitMessages(req), req.MaxTokens, req.Temperature, req.JSON, true)
+	body, err := c.openStream(ctx, endpoint, payload)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI chat error: %v", err))
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	defer body.Close()
+
+	var text strings.Builder
+	var usage localUsage
+	sent := ""
+	scanner := bufio.NewScanner(io.LimitReader(body, localMaxResponseSize))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var delta string
+		done := false
+		switch c.api {
+		case config.LocalAPIOllama:
+			var chunk ollamaChatResponse
+			if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal local AI response: %w", err)
+			}
+			if chunk.Error != "" {
+				return nil, fmt.Errorf("local AI error: %s", chunk.Error)
+			}
+			delta, done = chunk.Message.Content, chunk.Done
+			if done {
+				usage = localUsage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			}
+		default:
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				done = true
+				break
+			}
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal local AI response: %w", err)
+			}
+			if chunk.Error != nil {
+				return nil, fmt.Errorf("local AI error: %s", chunk.Error.Message)
+			}
+			if len(chunk.Choices) > 0 {
+				delta = chunk.Choices[0].Delta.Content
+			}
+			if chunk.Usage != nil {
+				usage = *chunk.Usage
+			}
+		}
+
+		if delta != "" {
+			text.WriteString(delta)
+			// Thinking is held back, only the answer is passed on
+			if visible := visibleAnswer(text.String()); len(visible) > len(sent) && strings.HasPrefix(visible, sent) {
+				if err := onDelta(visible[len(sent):]); err != nil {
+					return nil, err
+				}
+				sent = visible
+			}
+		}
+		if done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response stream: %w", err)
+	}
+	reportTokens(ctx, usage.PromptTokens, usage.CompletionTokens)
+
+	return &ChatResponse{
+		Content: strings.TrimSpace(thinkBlockPattern.ReplaceAllString(text.String(), "")),
+		Model:   c.model,
+	}, nil
+}
+
+// visibleAnswer is the part of a partial answer outside think blocks. An open
+// think block, or what may be the start of one, hides everything after it.
+func visibleAnswer(text string) string {
+	text = thinkBlockPattern.ReplaceAllString(text, "")
+	if i := strings.Index(text, "<think>"); i >= 0 {
+		return text[:i]
+	}
+	for i := len("<think>") - 1; i > 0; i-- {
+		if strings.HasSuffix(text, "<think>"[:i]) {
+			return text[:len(text)-i]
+		}
+	}
+	return text
+}
+
+// openStream sends a JSON request to the model server and returns the body
+// of a streamed response
+func (c *LocalClient) openStream(ctx context.Context, endpoint string, payload interface{}) (io.ReadCloser, error) {
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal local AI request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, localMaxResponseSize))
+		return nil, fmt.Errorf("local AI server returned error status: %d, body: %s", resp.StatusCode, string(respBody))
+	}
+
+	return resp.Body, nil
+}
+
+// Summarize implements the AIClient.Summarize method
+func (c *LocalClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
+	if req.Text == "" {
+		return &SummarizeResponse{Summary: ""}, nil
+	}
+
+	// Default max length
+	maxLength := 150
+	if req.MaxLength > 0 {
+		maxLength = req.MaxLength
+	}
+
+	prompt := fmt.Sprintf(
+		"Summarize the following text in a concise, informative way in %d characters or less:\n\n%s",
+		maxLength,
+		req.Text,
+	)
+
+	response, err := c.complete(ctx, localCompletion{
+		Prompt:      prompt,
+		MaxTokens:   int(float64(maxLength) * 0.5), // Estimate tokens from characters
+		Temperature: 0.3,
+	})
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI summarization error: %v", err))
+		return nil, fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	return &SummarizeResponse{
+		Summary: response,
+	}, nil
+}
+
+// SuggestTags implements the AIClient.SuggestTags method
+func (c *LocalClient) SuggestTags(ctx context.Context, req *TagRequest) (*TagResponse, error) {
+	if req.Content == "" && req.Title == "" {
+		return &TagResponse{Tags: []string{}, TagInfos: []*models.Tag{}, TagIDs: []string{}}, nil
+	}
+
+	// Default max tags
+	maxTags := 5
+	if req.MaxTags > 0 {
+		maxTags = req.MaxTags
+	}
+
+	content := req.Content
+	if content == "" {
+		content = req.Title
+	} else if req.Title != "" {
+		content = req.Title + "\n\n" + content
+	}
+
+	prompt := fmt.Sprintf(
+		"Extract up to %d relevant tags from the following content. Return only a JSON array of tag strings, with no explanations:\n\n%s",
+		maxTags,
+		content,
+	)
+
+	response, err := c.complete(ctx, localCompletion{
+		System:      "You are a tagging assistant. Your job is to extract relevant tags from content. Always return tags as a JSON array of strings.",
+		Prompt:      prompt,
+		MaxTokens:   100,
+		Temperature: 0.3,
+	})
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI tagging error: %v", err))
+		return nil, fmt.Errorf("failed to generate tags: %w", err)
+	}
+
+	tagNames := parseTagList(response)
+	if len(tagNames) > maxTags {
+		tagNames = tagNames[:maxTags]
+	}
+
+	tagInfos := make([]*models.Tag, 0, len(tagNames))
+	for _, name := range tagNames {
+		tagInfos = append(tagInfos, &models.Tag{
+			User:        req.UserID,
+			Name:        name,
+			Color:       generateTagColor(name),
+			Description: fmt.Sprintf("AI-generated tag for content related to %s", name),
+			IsAICreated: true,
+		})
+	}
+
+	return &TagResponse{
+		Tags:     tagNames,
+		TagInfos: tagInfos,
+		TagIDs:   []string{}, // Will be populated when the tags are saved to the database
+	}, nil
+}
+
+// parseTagList reads tag names from a model answer. Smaller models don't
+// always stick to a bare JSON array, so comma separated lists are accepted too.
+func parseTagList(response string) []string {
+	content := strings.TrimSpace(response)
+	if start, end := strings.Index(content, "["), strings.LastIndex(content, "]"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var raw []string
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		raw = strings.Split(content, ",")
+	}
+
+	tags := make([]string, 0, len(raw))
+	for _, tag := range raw {
+		tag = strings.Trim(tag, "\"'[]{}.;: \t\n")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// extractJSONObject returns the outermost JSON object in a model answer
+func extractJSONObject(response string) string {
+	content := strings.TrimSpace(response)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		return content[start : end+1]
+	}
+	return content
+}
+
+// ClassifyContent implements the AIClient.ClassifyContent method
+func (c *LocalClient) ClassifyContent(ctx context.Context, req *ClassifyRequest) (*ClassifyResponse, error) {
+	if (req.Content == "" && req.Title == "") || len(req.Labels) == 0 {
+		return &ClassifyResponse{
+			Label:       "",
+			Confidence:  0,
+			OtherLabels: map[string]float64{},
+		}, nil
+	}
+
+	content := req.Content
+	if content == "" {
+		content = req.Title
+	} else if req.Title != "" {
+		content = req.Title + "\n\n" + content
+	}
+
+	prompt := fmt.Sprintf(
+		"Classify the following content into one of these categories: %s.\nReturn a JSON object with keys: 'label' (string), 'confidence' (float 0-1), and 'otherLabels' (map of label to confidence).\n\nContent:\n%s",
+		strings.Join(req.Labels, ", "),
+		content,
+	)
+
+	response, err := c.complete(ctx, localCompletion{
+		System:      "You are a classification assistant. Your job is to classify content into predefined categories. Always return a JSON object with the structure: {\"label\": string, \"confidence\": float, \"otherLabels\": {string: float}}",
+		Prompt:      prompt,
+		MaxTokens:   150,
+		Temperature: 0.2,
+		JSON:        true,
+	})
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI classification error: %v", err))
+		return nil, fmt.Errorf("failed to classify content: %w", err)
+	}
+
+	respContent := extractJSONObject(response)
+
+	var result ClassifyResponse
+	if err := json.Unmarshal([]byte(respContent), &result); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to parse classification JSON: %v, content: %s", err, respContent))
+
+		// Fallback to first label with zero confidence
+		return &ClassifyResponse{
+			Label:       req.Labels[0],
+			Confidence:  0,
+			OtherLabels: map[string]float64{},
+		}, nil
+	}
+	if result.OtherLabels == nil {
+		result.OtherLabels = map[string]float64{}
+	}
+
+	return &result, nil
+}
+
+// RecommendContent implements the AIClient.RecommendContent method
+func (c *LocalClient) RecommendContent(ctx context.Context, req *RecommendRequest) (*RecommendResponse, error) {
+	if req.Item == nil {
+		return nil, fmt.Errorf("item cannot be nil")
+	}
+
+	userMetadataBytes, _ := json.Marshal(req.UserMetadata)
+
+	prompt := fmt.Sprintf(
+		"Evaluate how relevant this content is to the user based on the given metadata.\n\nContent Title: %s\nContent URL: %s\nContent Tags: %s\nContent Summary: %s\n\nUser Metadata: %s\n\nReturn a JSON object with keys: 'score' (float 0-1) and 'explanation' (string with brief reason).",
+		req.Item.Title,
+		req.Item.URL,
+		strings.Join(req.Item.Tags, ", "),
+		req.Item.Summary,
+		string(userMetadataBytes),
+	)
+
+	response, err := c.complete(ctx, localCompletion{
+		System:      "You are a recommendation assistant. Your job is to score content relevance for users. Always return a JSON object with the structure: {\"score\": float, \"explanation\": string}",
+		Prompt:      prompt,
+		MaxTokens:   150,
+		Temperature: 0.3,
+		JSON:        true,
+	})
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Local AI recommendation error: %v", err))
+		return nil, fmt.Errorf("failed to generate recommendation: %w", err)
+	}
+
+	respContent := extractJSONObject(response)
+
+	var result RecommendResponse
+	if err := json.Unmarshal([]byte(respContent), &result); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to parse recommendation JSON: %v, content: %s", err, respContent))
+
+		// Fallback to neutral score
+		return &RecommendResponse{
+			Score:       0.5,
+			Explanation: "Error parsing recommendation response",
+		}, nil
+	}
+	result.Score = min(max(result.Score, 0), 1)
+
+	return &result, nil
+}
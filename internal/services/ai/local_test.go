@@ -0,0 +1,339 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shashank-sharma/backend/internal/config"
+)
+
+// localServer stands in for a model server, it answers the chat and
+// embedding endpoints of the Ollama or the OpenAI compatible API
+type localServer struct {
+	api     string
+	answer  []string // Chunks of the answer, sent one by one when streaming
+	status  int      // Status of every response, 200 when 0
+	path    string   // Path of the last request
+	auth    string   // Authorization header of the last request
+	request map[string]interface{}
+}
+
+func (s *localServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.path, s.auth = r.URL.Path, r.Header.Get("Authorization")
+	s.request = nil
+	json.NewDecoder(r.Body).Decode(&s.request)
+
+	if s.status != 0 && s.status != http.StatusOK {
+		http.Error(w, `{"error":"model not found"}`, s.status)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/chat":
+		if stream, _ := s.request["stream"].(bool); stream {
+			for _, chunk := range s.answer {
+				fmt.Fprintf(w, "%s\n", mustJSON(map[string]interface{}{"message": localMessage{Role: "assistant", Content: chunk}, "done": false}))
+			}
+			fmt.Fprintf(w, "%s\n", mustJSON(map[string]interface{}{"message": localMessage{Role: "assistant"}, "done": true, "prompt_eval_count": 12, "eval_count": 7}))
+			return
+		}
+		w.Write(mustJSON(map[string]interface{}{
+			"message":           localMessage{Role: "assistant", Content: strings.Join(s.answer, "")},
+			"done":              true,
+			"prompt_eval_count": 12,
+			"eval_count":        7,
+		}))
+	case "/chat/completions":
+		if stream, _ := s.request["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range s.answer {
+				fmt.Fprintf(w, "data: %s\n\n", mustJSON(map[string]interface{}{"choices": []interface{}{map[string]interface{}{"delta": localMessage{Content: chunk}}}}))
+			}
+			fmt.Fprintf(w, "data: %s\n\n", mustJSON(map[string]interface{}{"choices": []interface{}{}, "usage": localUsage{PromptTokens: 12, CompletionTokens: 7}}))
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Write(mustJSON(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"message": localMessage{Role: "assistant", Content: strings.Join(s.answer, "")}}},
+			"usage":   localUsage{PromptTokens: 12, CompletionTokens: 7},
+		}))
+	case "/api/embed":
+		inputs, _ := s.request["input"].([]interface{})
+		embeddings := make([][]float32, len(inputs))
+		for i := range inputs {
+			embeddings[i] = []float32{float32(i), 0.5}
+		}
+		w.Write(mustJSON(map[string]interface{}{"embeddings": embeddings, "prompt_eval_count": 4}))
+	case "/embeddings":
+		// Servers may return the embeddings in any order, reversed here
+		inputs, _ := s.request["input"].([]interface{})
+		data := make([]interface{}, 0, len(inputs))
+		for i := len(inputs) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{"embedding": []float32{float32(i), 0.5}, "index": i})
+		}
+		w.Write(mustJSON(map[string]interface{}{"data": data, "usage": localUsage{PromptTokens: 4}}))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func mustJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func newLocalTestClient(t *testing.T, server *localServer) *LocalClient {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	baseURL := ts.URL
+	if server.api == config.LocalAPIOpenAI {
+		baseURL += "/"
+	}
+	client, err := NewLocalClient(config.LocalAIConfig{API: server.api, BaseURL: baseURL, ContextWindow: 2048}, "secret", "llama3.2", "nomic-embed-text")
+	if err != nil {
+		t.Fatalf("NewLocalClient: %v", err)
+	}
+	return client.(*LocalClient)
+}
+
+var localAPIs = []string{config.LocalAPIOllama, config.LocalAPIOpenAI}
+
+func TestLocalSummarize(t *testing.T) {
+	paths := map[string]string{config.LocalAPIOllama: "/api/chat", config.LocalAPIOpenAI: "/chat/completions"}
+	for _, api := range localAPIs {
+		t.Run(api, func(t *testing.T) {
+			server := &localServer{api: api, answer: []string{"<think>Short it is.</think>\n", "A short summary."}}
+			client := newLocalTestClient(t, server)
+
+			ctx, usage := withTokenUsage(context.Background())
+			resp, err := client.Summarize(ctx, &SummarizeRequest{Text: "A long article.", MaxLength: 100})
+			if err != nil {
+				t.Fatalf("Summarize: %v", err)
+			}
+			if resp.Summary != "A short summary." {
+				t.Errorf("got summary %q", resp.Summary)
+			}
+
+			if server.path != paths[api] {
+				t.Errorf("got path %s, want %s", server.path, paths[api])
+			}
+			if server.auth != "Bearer secret" {
+				t.Errorf("got authorization %q", server.auth)
+			}
+			if server.request["model"] != "llama3.2" {
+				t.Errorf("got model %v", server.request["model"])
+			}
+			if input, output, _ := usage.counts(); input != 12 || output != 7 {
+				t.Errorf("got %d input and %d output tokens, want 12 and 7", input, output)
+			}
+		})
+	}
+}
+
+func TestLocalSuggestTags(t *testing.T) {
+	for _, api := range localAPIs {
+		t.Run(api, func(t *testing.T) {
+			server := &localServer{api: api, answer: []string{`Here are the tags: ["golang", "testing", "http"]`}}
+			client := newLocalTestClient(t, server)
+
+			resp, err := client.SuggestTags(context.Background(), &TagRequest{Title: "Testing in Go", Content: "Table driven tests.", MaxTags: 2, UserID: "user1"})
+			if err != nil {
+				t.Fatalf("SuggestTags: %v", err)
+			}
+			if len(resp.Tags) != 2 || resp.Tags[0] != "golang" || resp.Tags[1] != "testing" {
+				t.Errorf("got tags %v", resp.Tags)
+			}
+			if len(resp.TagInfos) != 2 || resp.TagInfos[0].User != "user1" || !resp.TagInfos[0].IsAICreated {
+				t.Errorf("got tag infos %v", resp.TagInfos)
+			}
+
+			messages, _ := server.request["messages"].([]interface{})
+			if len(messages) != 2 || messages[0].(map[string]interface{})["role"] != "system" {
+				t.Errorf("got messages %v", messages)
+			}
+		})
+	}
+}
+
+func TestParseTagList(t *testing.T) {
+	tests := []struct {
+		response string
+		want     []string
+	}{
+		{`["go", "testing"]`, []string{"go", "testing"}},
+		{"Tags:\n```json\n[\"go\", \"testing\"]\n```", []string{"go", "testing"}},
+		{"go, testing, 'http'.", []string{"go", "testing", "http"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		got := parseTagList(tt.response)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("parseTagList(%q): got %v, want %v", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestLocalChatStream(t *testing.T) {
+	for _, api := range localAPIs {
+		t.Run(api, func(t *testing.T) {
+			// The think block is split across chunks, none of it may be passed on
+			server := &localServer{api: api, answer: []string{"<th", "ink>Let me see.</th", "ink>Hello", ", ", "world!"}}
+			client := newLocalTestClient(t, server)
+
+			ctx, usage := withTokenUsage(context.Background())
+			var deltas []string
+			resp, err := client.ChatStream(ctx, &ChatRequest{
+				Messages: []ChatMessage{
+					{Role: ChatRoleSystem, Content: "Be brief."},
+					{Role: ChatRoleUser, Content: "Say hello."},
+				},
+				MaxTokens: 50,
+			}, func(delta string) error {
+				deltas = append(deltas, delta)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ChatStream: %v", err)
+			}
+
+			if got := strings.Join(deltas, ""); got != "Hello, world!" {
+				t.Errorf("got deltas %q", deltas)
+			}
+			if resp.Content != "Hello, world!" || resp.Model != "llama3.2" {
+				t.Errorf("got response %+v", resp)
+			}
+			if stream, _ := server.request["stream"].(bool); !stream {
+				t.Error("request did not ask for a stream")
+			}
+			if input, output, _ := usage.counts(); input != 12 || output != 7 {
+				t.Errorf("got %d input and %d output tokens, want 12 and 7", input, output)
+			}
+		})
+	}
+}
+
+func TestLocalChatStreamCallbackError(t *testing.T) {
+	server := &localServer{api: config.LocalAPIOllama, answer: []string{"Hello", " world"}}
+	client := newLocalTestClient(t, server)
+
+	stop := fmt.Errorf("client went away")
+	_, err := client.ChatStream(context.Background(), &ChatRequest{
+		Messages: []ChatMessage{{Role: ChatRoleUser, Content: "Say hello."}},
+	}, func(delta string) error {
+		return stop
+	})
+	if err != stop {
+		t.Fatalf("got %v, want the error of the callback", err)
+	}
+}
+
+func TestLocalEmbed(t *testing.T) {
+	paths := map[string]string{config.LocalAPIOllama: "/api/embed", config.LocalAPIOpenAI: "/embeddings"}
+	for _, api := range localAPIs {
+		t.Run(api, func(t *testing.T) {
+			server := &localServer{api: api}
+			client := newLocalTestClient(t, server)
+
+			ctx, usage := withTokenUsage(context.Background())
+			resp, err := client.Embed(ctx, &EmbedRequest{Texts: []string{"first", "second", "third"}})
+			if err != nil {
+				t.Fatalf("Embed: %v", err)
+			}
+			if server.path != paths[api] {
+				t.Errorf("got path %s, want %s", server.path, paths[api])
+			}
+			if server.request["model"] != "nomic-embed-text" || resp.Model != "nomic-embed-text" {
+				t.Errorf("got model %v in the request and %s in the response", server.request["model"], resp.Model)
+			}
+			if len(resp.Vectors) != 3 {
+				t.Fatalf("got %d vectors, want 3", len(resp.Vectors))
+			}
+			for i, vector := range resp.Vectors {
+				if len(vector) != 2 || vector[0] != float32(i) {
+					t.Errorf("vector %d: got %v", i, vector)
+				}
+			}
+			if input, _, _ := usage.counts(); input != 4 {
+				t.Errorf("got %d input tokens, want 4", input)
+			}
+		})
+	}
+}
+
+func TestLocalErrors(t *testing.T) {
+	calls := map[string]func(c *LocalClient) error{
+		"summarize": func(c *LocalClient) error {
+			_, err := c.Summarize(context.Background(), &SummarizeRequest{Text: "text"})
+			return err
+		},
+		"tags": func(c *LocalClient) error {
+			_, err := c.SuggestTags(context.Background(), &TagRequest{Content: "text"})
+			return err
+		},
+		"chat stream": func(c *LocalClient) error {
+			_, err := c.ChatStream(context.Background(), &ChatRequest{Messages: []ChatMessage{{Role: ChatRoleUser, Content: "hi"}}}, func(string) error { return nil })
+			return err
+		},
+		"embed": func(c *LocalClient) error {
+			_, err := c.Embed(context.Background(), &EmbedRequest{Texts: []string{"text"}})
+			return err
+		},
+	}
+
+	for _, api := range localAPIs {
+		for name, call := range calls {
+			t.Run(api+"/"+name+"/error status", func(t *testing.T) {
+				client := newLocalTestClient(t, &localServer{api: api, status: http.StatusNotFound})
+				err := call(client)
+				if err == nil || !strings.Contains(err.Error(), "404") {
+					t.Errorf("got %v, want an error with the status", err)
+				}
+			})
+
+			t.Run(api+"/"+name+"/unreachable", func(t *testing.T) {
+				ts := httptest.NewServer(http.NotFoundHandler())
+				ts.Close()
+				client, err := NewLocalClient(config.LocalAIConfig{API: api, BaseURL: ts.URL, TimeoutSecs: 5}, "", "", "")
+				if err != nil {
+					t.Fatalf("NewLocalClient: %v", err)
+				}
+				if err := call(client.(*LocalClient)); err == nil || !strings.Contains(err.Error(), "failed to execute request") {
+					t.Errorf("got %v, want a connection error", err)
+				}
+			})
+		}
+	}
+}
+
+func TestNewLocalClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.LocalAIConfig
+		wantErr bool
+	}{
+		{"loopback", config.LocalAIConfig{API: config.LocalAPIOllama, BaseURL: "http://127.0.0.1:11434"}, false},
+		{"private network", config.LocalAIConfig{API: config.LocalAPIOpenAI, BaseURL: "http://192.168.1.20:8080/v1"}, false},
+		{"public address", config.LocalAIConfig{API: config.LocalAPIOllama, BaseURL: "http://93.184.216.34:11434"}, true},
+		{"public address allowed", config.LocalAIConfig{API: config.LocalAPIOllama, BaseURL: "http://93.184.216.34:11434", AllowRemote: true}, false},
+		{"unsupported API", config.LocalAIConfig{API: "llamacpp", BaseURL: "http://127.0.0.1:8080"}, true},
+		{"invalid URL", config.LocalAIConfig{API: config.LocalAPIOllama, BaseURL: "ftp://127.0.0.1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLocalClient(tt.cfg, "", "", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
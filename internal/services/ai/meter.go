@@ -0,0 +1,302 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/metrics"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+// ErrBudgetExceeded is returned instead of calling the model once a monthly
+// budget is used up, callers fall back to their non-AI paths
+var ErrBudgetExceeded = errors.New("monthly AI budget exceeded")
+
+// Usage records are kept for reports of the past year
+const usageRetentionMonths = 13
+
+// Meter records the tokens, cost and latency of AI requests, enforces the
+// monthly budgets and caches responses of identical requests
+type Meter struct {
+	cfg    config.AIUsageConfig
+	prices map[string]modelPrice // Configured prices, they win over the list prices
+
+	mu           sync.Mutex
+	month        string // YYYY-MM of the totals below
+	globalCost   float64
+	globalLoaded bool
+	userCosts    map[string]float64 // Loaded on the first request of a user
+	exceeded     map[string]bool    // Budgets logged as used up, "" for the global one
+}
+
+// NewMeter creates a meter with the budgets and prices of the configuration
+func NewMeter(cfg config.AIConfig) *Meter {
+	m := &Meter{
+		cfg:       cfg.Usage,
+		prices:    map[string]modelPrice{},
+		userCosts: map[string]float64{},
+		exceeded:  map[string]bool{},
+	}
+
+	if cfg.Model != "" && (cfg.Usage.InputPrice >= 0 || cfg.Usage.OutputPrice >= 0) {
+		price, _ := knownPrice(cfg.Model)
+		if cfg.Usage.InputPrice >= 0 {
+			price.Input = cfg.Usage.InputPrice
+		}
+		if cfg.Usage.OutputPrice >= 0 {
+			price.Output = cfg.Usage.OutputPrice
+		}
+		m.prices[cfg.Model] = price
+	}
+	if cfg.EmbeddingModel != "" && cfg.Usage.EmbeddingPrice >= 0 {
+		m.prices[cfg.EmbeddingModel] = modelPrice{Input: cfg.Usage.EmbeddingPrice}
+	}
+
+	return m
+}
+
+// cost is the price of a request in USD
+func (m *Meter) cost(model string, inputTokens int, outputTokens int) float64 {
+	price, ok := m.prices[model]
+	if !ok {
+		price, _ = knownPrice(model)
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthlyCost adds up the cost of the month of from, of one user or of all
+// users when userID is nil
+func monthlyCost(userID *string, from time.Time) (float64, error) {
+	q := store.GetDao().DB().
+		Select("COALESCE(SUM(cost), 0)").
+		From((&models.AIUsage{}).TableName()).
+		Where(dbx.NewExp("created >= {:from} AND created < {:to}", dbx.Params{
+			"from": from.Format(types.DefaultDateLayout),
+			"to":   from.AddDate(0, 1, 0).Format(types.DefaultDateLayout),
+		}))
+	if userID != nil {
+		q = q.AndWhere(dbx.HashExp{"user": *userID})
+	}
+
+	var total float64
+	if err := q.Row(&total); err != nil {
+		return 0, fmt.Errorf("error loading AI usage: %v", err)
+	}
+	return total, nil
+}
+
+// load makes sure the totals are those of the current month and the global
+// total and the one of the user are loaded. Called with the lock held.
+func (m *Meter) load(userID string, now time.Time) error {
+	from := monthStart(now)
+	if month := from.Format("2006-01"); month != m.month {
+		m.month = month
+		m.globalCost = 0
+		m.globalLoaded = false
+		m.userCosts = map[string]float64{}
+		m.exceeded = map[string]bool{}
+	}
+
+	if !m.globalLoaded {
+		total, err := monthlyCost(nil, from)
+		if err != nil {
+			return err
+		}
+		m.globalCost = total
+		m.globalLoaded = true
+		metrics.UpdateAIMonthlySpend(total)
+	}
+	if _, ok := m.userCosts[userID]; !ok && userID != "" {
+		total, err := monthlyCost(&userID, from)
+		if err != nil {
+			return err
+		}
+		m.userCosts[userID] = total
+	}
+	return nil
+}
+
+// allow checks the budgets before a request is sent for the user
+func (m *Meter) allow(userID string) error {
+	if m.cfg.MonthlyBudget <= 0 && m.cfg.UserMonthlyBudget <= 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(userID, time.Now()); err != nil {
+		// Not knowing the spend is no reason to stop working
+		logger.LogError("Failed to check the AI budget: " + err.Error())
+		return nil
+	}
+
+	if m.cfg.MonthlyBudget > 0 && m.globalCost >= m.cfg.MonthlyBudget {
+		if !m.exceeded[""] {
+			m.exceeded[""] = true
+			logger.LogWarning(fmt.Sprintf("Monthly AI budget of %.2f USD used up, falling back to processing without AI", m.cfg.MonthlyBudget))
+		}
+		return ErrBudgetExceeded
+	}
+	if userID != "" && m.cfg.UserMonthlyBudget > 0 && m.userCosts[userID] >= m.cfg.UserMonthlyBudget {
+		if !m.exceeded[userID] {
+			m.exceeded[userID] = true
+			logger.LogWarning(fmt.Sprintf("Monthly AI budget of user %s used up, falling back to processing without AI", userID))
+		}
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// record stores the usage of a request and adds it to the totals and metrics
+func (m *Meter) record(usage *models.AIUsage, duration time.Duration) {
+	usage.Cost = m.cost(usage.Model, usage.InputTokens, usage.OutputTokens)
+	usage.LatencyMs = int(duration.Milliseconds())
+
+	status := "ok"
+	switch {
+	case usage.Error != "":
+		status = "error"
+	case usage.Cached:
+		status = "cached"
+	}
+	metrics.TrackAIRequest(usage.Feature, usage.Operation, usage.Model, status, usage.InputTokens, usage.OutputTokens, usage.Cost, duration)
+
+	// Saved with the lock held, so totals loaded meanwhile can't count it twice
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage.Id = util.GenerateRandomId()
+	usage.RefreshCreated()
+	usage.RefreshUpdated()
+	if err := query.SaveRecord(usage); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to record AI usage: %v", err))
+		return
+	}
+
+	if usage.Cost <= 0 || usage.Created.Time().Format("2006-01") != m.month {
+		// Totals of another month are loaded afresh with the next request
+		return
+	}
+	if m.globalLoaded {
+		m.globalCost += usage.Cost
+		metrics.UpdateAIMonthlySpend(m.globalCost)
+	}
+	if _, ok := m.userCosts[usage.User]; ok {
+		m.userCosts[usage.User] += usage.Cost
+	}
+}
+
+// rejected counts a request refused for the budget
+func (m *Meter) rejected(caller Caller, operation string, model string) {
+	metrics.TrackAIRequest(caller.Feature, operation, model, "over_budget", 0, 0, 0, 0)
+}
+
+// cacheKey hashes everything the response of a request depends on
+func cacheKey(operation string, model string, parts ...string) string {
+	hash := sha256.New()
+	for _, part := range append([]string{operation, model}, parts...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cached loads the cached response of a request into response
+func (m *Meter) cached(key string, response interface{}) bool {
+	entry, err := query.FindByFilter[*models.AICacheEntry](map[string]interface{}{"hash": key})
+	if err != nil {
+		return false
+	}
+	if time.Since(entry.LastUsed.Time()) > time.Duration(m.cfg.CacheDays)*24*time.Hour {
+		return false
+	}
+	if err := json.Unmarshal(entry.Response, response); err != nil {
+		return false
+	}
+
+	if err := query.UpdateRecord[*models.AICacheEntry](entry.Id, map[string]interface{}{
+		"hits":      entry.Hits + 1,
+		"last_used": types.NowDateTime(),
+	}); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to update AI cache entry: %v", err))
+	}
+	return true
+}
+
+// cache stores the response of a request for identical requests
+func (m *Meter) cache(key string, operation string, model string, response interface{}) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	if existing, err := query.FindByFilter[*models.AICacheEntry](map[string]interface{}{"hash": key}); err == nil {
+		// An expired entry, or one stored by a concurrent request
+		if err := query.UpdateRecord[*models.AICacheEntry](existing.Id, map[string]interface{}{
+			"response":  types.JSONRaw(data),
+			"last_used": types.NowDateTime(),
+		}); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to update AI cache entry: %v", err))
+		}
+		return
+	}
+
+	entry := &models.AICacheEntry{
+		Hash:      key,
+		Operation: operation,
+		Model:     model,
+		Response:  types.JSONRaw(data),
+		LastUsed:  types.NowDateTime(),
+	}
+	entry.Id = util.GenerateRandomId()
+	entry.RefreshCreated()
+	entry.RefreshUpdated()
+	if err := query.SaveRecord(entry); err != nil && !strings.Contains(err.Error(), "UNIQUE") {
+		logger.LogError(fmt.Sprintf("Failed to cache AI response: %v", err))
+	}
+}
+
+// Prune drops cached responses unused for longer than the configured days
+// and usage records too old for reports
+func (m *Meter) Prune(now time.Time) (int64, int64, error) {
+	db := store.GetDao().DB()
+
+	cacheBefore := now.AddDate(0, 0, -m.cfg.CacheDays)
+	res, err := db.Delete((&models.AICacheEntry{}).TableName(), dbx.NewExp("last_used < {:before}", dbx.Params{
+		"before": cacheBefore.UTC().Format(types.DefaultDateLayout),
+	})).Execute()
+	if err != nil {
+		return 0, 0, fmt.Errorf("error pruning AI cache: %v", err)
+	}
+	cacheDeleted, _ := res.RowsAffected()
+
+	usageBefore := monthStart(now).AddDate(0, -usageRetentionMonths, 0)
+	res, err = db.Delete((&models.AIUsage{}).TableName(), dbx.NewExp("created < {:before}", dbx.Params{
+		"before": usageBefore.Format(types.DefaultDateLayout),
+	})).Execute()
+	if err != nil {
+		return cacheDeleted, 0, fmt.Errorf("error pruning AI usage: %v", err)
+	}
+	usageDeleted, _ := res.RowsAffected()
+
+	return cacheDeleted, usageDeleted, nil
+}
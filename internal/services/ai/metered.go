@@ -0,0 +1,271 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/models"
+)
+
+// MeteredClient wraps an AI client to record the usage of every request,
+// refuse requests once a monthly budget is used up and answer identical
+// summarize and tag requests from the cache
+type MeteredClient struct {
+	client AIClient
+	model  string
+	meter  *Meter
+}
+
+// MeteredEmbeddingClient is a MeteredClient of a client that can embed
+type MeteredEmbeddingClient struct {
+	*MeteredClient
+	embedder Embedder
+}
+
+// NewMeteredClient wraps client, which runs model, with the meter. The result
+// embeds only when client does.
+func NewMeteredClient(client AIClient, model string, meter *Meter) AIClient {
+	metered := &MeteredClient{
+		client: client,
+		model:  model,
+		meter:  meter,
+	}
+	if embedder, ok := client.(Embedder); ok {
+		return &MeteredEmbeddingClient{MeteredClient: metered, embedder: embedder}
+	}
+	return metered
+}
+
+// meterCall sends a request within the budgets and records its usage. call
+// returns the request and response texts, which tokens are estimated from
+// when the API reports none.
+func (m *Meter) meterCall(ctx context.Context, operation string, model string, call func(ctx context.Context) (string, string, error)) error {
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		caller.Feature = FeatureUnknown
+	}
+
+	if err := m.allow(caller.UserID); err != nil {
+		m.rejected(caller, operation, model)
+		return err
+	}
+
+	ctx, tokens := withTokenUsage(ctx)
+	start := time.Now()
+	input, output, err := call(ctx)
+	duration := time.Since(start)
+
+	usage := &models.AIUsage{
+		User:      caller.UserID,
+		Feature:   caller.Feature,
+		Operation: operation,
+		Model:     model,
+	}
+	usage.InputTokens, usage.OutputTokens, ok = tokens.counts()
+	if err != nil {
+		usage.Error = err.Error()
+		if len(usage.Error) > 1000 {
+			usage.Error = usage.Error[:1000]
+		}
+	} else if !ok {
+		usage.InputTokens = estimateTokens(input)
+		usage.OutputTokens = estimateTokens(output)
+		usage.Estimated = true
+	}
+	m.record(usage, duration)
+
+	return err
+}
+
+// recordCached records a request answered from the cache
+func (m *Meter) recordCached(ctx context.Context, operation string, model string) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		caller.Feature = FeatureUnknown
+	}
+	m.record(&models.AIUsage{
+		User:      caller.UserID,
+		Feature:   caller.Feature,
+		Operation: operation,
+		Model:     model,
+		Cached:    true,
+	}, 0)
+}
+
+// Summarize implements the AIClient.Summarize method
+func (c *MeteredClient) Summarize(ctx context.Context, req *SummarizeRequest) (*SummarizeResponse, error) {
+	if req.Text == "" {
+		return c.client.Summarize(ctx, req)
+	}
+
+	key := cacheKey(OperationSummarize, c.model, strconv.Itoa(req.MaxLength), req.Text)
+	var cached SummarizeResponse
+	if c.meter.cached(key, &cached) {
+		c.meter.recordCached(ctx, OperationSummarize, c.model)
+		return &cached, nil
+	}
+
+	var resp *SummarizeResponse
+	err := c.meter.meterCall(ctx, OperationSummarize, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = c.client.Summarize(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return req.Text, resp.Summary, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Summary != "" {
+		c.meter.cache(key, OperationSummarize, c.model, resp)
+	}
+	return resp, nil
+}
+
+// SuggestTags implements the AIClient.SuggestTags method. Cached tags are
+// handed out as new tags of the requesting user.
+func (c *MeteredClient) SuggestTags(ctx context.Context, req *TagRequest) (*TagResponse, error) {
+	if req.Content == "" && req.Title == "" {
+		return c.client.SuggestTags(ctx, req)
+	}
+
+	key := cacheKey(OperationTag, c.model, strconv.Itoa(req.MaxTags), req.Title, req.Content)
+	var cached TagResponse
+	if c.meter.cached(key, &cached) {
+		for _, tag := range cached.TagInfos {
+			tag.User = req.UserID
+		}
+		cached.TagIDs = []string{}
+		c.meter.recordCached(ctx, OperationTag, c.model)
+		return &cached, nil
+	}
+
+	var resp *TagResponse
+	err := c.meter.meterCall(ctx, OperationTag, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = c.client.SuggestTags(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return req.Title + "\n\n" + req.Content, strings.Join(resp.Tags, ", "), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Tags) > 0 {
+		c.meter.cache(key, OperationTag, c.model, resp)
+	}
+	return resp, nil
+}
+
+// ClassifyContent implements the AIClient.ClassifyContent method
+func (c *MeteredClient) ClassifyContent(ctx context.Context, req *ClassifyRequest) (*ClassifyResponse, error) {
+	if (req.Content == "" && req.Title == "") || len(req.Labels) == 0 {
+		return c.client.ClassifyContent(ctx, req)
+	}
+
+	var resp *ClassifyResponse
+	err := c.meter.meterCall(ctx, OperationClassify, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = c.client.ClassifyContent(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return req.Title + "\n\n" + req.Content + "\n\n" + strings.Join(req.Labels, ", "), resp.Label, nil
+	})
+	return resp, err
+}
+
+// RecommendContent implements the AIClient.RecommendContent method
+func (c *MeteredClient) RecommendContent(ctx context.Context, req *RecommendRequest) (*RecommendResponse, error) {
+	if req.Item == nil {
+		return c.client.RecommendContent(ctx, req)
+	}
+
+	var resp *RecommendResponse
+	err := c.meter.meterCall(ctx, OperationRecommend, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = c.client.RecommendContent(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("%s\n%s\n%v", req.Item.Title, req.Item.Summary, req.UserMetadata), resp.Explanation, nil
+	})
+	return resp, err
+}
+
+// Chat implements the Chatter.Chat method
+func (c *MeteredClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	chatter, ok := c.client.(Chatter)
+	if !ok {
+		return nil, fmt.Errorf("AI client %T can't chat", c.client)
+	}
+
+	var resp *ChatResponse
+	err := c.meter.meterCall(ctx, OperationChat, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = chatter.Chat(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return chatText(req), resp.Content, nil
+	})
+	return resp, err
+}
+
+// ChatStream implements the Chatter.ChatStream method
+func (c *MeteredClient) ChatStream(ctx context.Context, req *ChatRequest, onDelta ChatStreamFunc) (*ChatResponse, error) {
+	chatter, ok := c.client.(Chatter)
+	if !ok {
+		return nil, fmt.Errorf("AI client %T can't chat", c.client)
+	}
+
+	var resp *ChatResponse
+	err := c.meter.meterCall(ctx, OperationChat, c.model, func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = chatter.ChatStream(ctx, req, onDelta)
+		if err != nil {
+			return "", "", err
+		}
+		return chatText(req), resp.Content, nil
+	})
+	return resp, err
+}
+
+// chatText is the text of a conversation, tokens are estimated from
+func chatText(req *ChatRequest) string {
+	texts := make([]string, len(req.Messages))
+	for i, message := range req.Messages {
+		texts[i] = message.Content
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// EmbeddingModel implements the Embedder.EmbeddingModel method
+func (c *MeteredEmbeddingClient) EmbeddingModel() string {
+	return c.embedder.EmbeddingModel()
+}
+
+// Embed implements the Embedder.Embed method
+func (c *MeteredEmbeddingClient) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	if len(req.Texts) == 0 {
+		return c.embedder.Embed(ctx, req)
+	}
+
+	var resp *EmbedResponse
+	err := c.meter.meterCall(ctx, OperationEmbed, c.embedder.EmbeddingModel(), func(ctx context.Context) (string, string, error) {
+		var err error
+		resp, err = c.embedder.Embed(ctx, req)
+		if err != nil {
+			return "", "", err
+		}
+		return strings.Join(req.Texts, "\n\n"), "", nil
+	})
+	return resp, err
+}
@@ -3,7 +3,9 @@ package ai
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
@@ -13,22 +15,148 @@ import (
 
 // OpenAIClient implements the AIClient interface using OpenAI's API
 type OpenAIClient struct {
-	client *openai.Client
-	model  string
+	client         *openai.Client
+	model          string
+	embeddingModel openai.EmbeddingModel
 }
 
 // NewOpenAIClient creates a new OpenAI client with the provided API key
-func NewOpenAIClient(apiKey string, model string) AIClient {
+func NewOpenAIClient(apiKey string, model string, embeddingModel string) AIClient {
 	client := openai.NewClient(apiKey)
 	
 	if model == "" {
 		model = openai.GPT3Dot5Turbo
 	}
 	
+	// The client library only knows the models it enumerates
+	embedding := openai.AdaEmbeddingV2
+	if embeddingModel != "" {
+		var parsed openai.EmbeddingModel
+		if err := parsed.UnmarshalText([]byte(embeddingModel)); err == nil && parsed != openai.Unknown {
+			embedding = parsed
+		} else {
+			logger.LogError(fmt.Sprintf("Unsupported OpenAI embedding model %s, using %s", embeddingModel, embedding))
+		}
+	}
+	
 	return &OpenAIClient{
-		client: client,
-		model:  model,
+		client:         client,
+		model:          model,
+		embeddingModel: embedding,
+	}
+}
+
+// EmbeddingModel implements the Embedder.EmbeddingModel method
+func (c *OpenAIClient) EmbeddingModel() string {
+	return c.embeddingModel.String()
+}
+
+// Embed implements the Embedder.Embed method
+func (c *OpenAIClient) Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	if len(req.Texts) == 0 {
+		return &EmbedResponse{Vectors: [][]float32{}, Model: c.EmbeddingModel()}, nil
+	}
+	
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: req.Texts,
+		Model: c.embeddingModel,
+	})
+	if err != nil {
+		logger.LogError(fmt.Sprintf("OpenAI embedding error: %v", err))
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	reportTokens(ctx, resp.Usage.PromptTokens, 0)
+	
+	vectors := make([][]float32, len(req.Texts))
+	for _, embedding := range resp.Data {
+		if embedding.Index >= 0 && embedding.Index < len(vectors) {
+			vectors[embedding.Index] = embedding.Embedding
+		}
+	}
+	for i, vector := range vectors {
+		if vector == nil {
+			return nil, fmt.Errorf("no embedding generated for text %d", i)
+		}
+	}
+	
+	return &EmbedResponse{
+		Vectors: vectors,
+		Model:   c.EmbeddingModel(),
+	}, nil
+}
+
+// chatCompletionRequest converts a chat request to the client library's form
+func (c *OpenAIClient) chatCompletionRequest(req *ChatRequest) openai.ChatCompletionRequest {
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{
+			Role:    message.Role,
+			Content: message.Content,
+		}
+	}
+	
+	// The client library has no JSON mode, callers ask for JSON in the prompt
+	return openai.ChatCompletionRequest{
+		Model:       c.model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: float32(req.Temperature),
+	}
+}
+
+// Chat implements the Chatter.Chat method
+func (c *OpenAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	resp, err := c.client.CreateChatCompletion(ctx, c.chatCompletionRequest(req))
+	if err != nil {
+		logger.LogError(fmt.Sprintf("OpenAI chat error: %v", err))
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	reportTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no answer generated")
+	}
+	
+	return &ChatResponse{
+		Content: strings.TrimSpace(resp.Choices[0].Message.Content),
+		Model:   c.model,
+	}, nil
+}
+
+// ChatStream implements the Chatter.ChatStream method
+func (c *OpenAIClient) ChatStream(ctx context.Context, req *ChatRequest, onDelta ChatStreamFunc) (*ChatResponse, error) {
+	stream, err := c.client.CreateChatCompletionStream(ctx, c.chatCompletionRequest(req))
+	if err != nil {
+		logger.LogError(fmt.Sprintf("OpenAI chat error: %v", err))
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	defer stream.Close()
+	
+	var content strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.LogError(fmt.Sprintf("OpenAI chat stream error: %v", err))
+			return nil, fmt.Errorf("failed to generate answer: %w", err)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		
+		delta := resp.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
 	}
+	
+	return &ChatResponse{
+		Content: strings.TrimSpace(content.String()),
+		Model:   c.model,
+	}, nil
 }
 
 // Summarize implements the AIClient.Summarize method
@@ -70,6 +198,7 @@ func (c *OpenAIClient) Summarize(ctx context.Context, req *SummarizeRequest) (*S
 		logger.LogError(fmt.Sprintf("OpenAI summarization error: %v", err))
 		return nil, fmt.Errorf("failed to generate summary: %w", err)
 	}
+	reportTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no summary generated")
@@ -132,6 +261,7 @@ func (c *OpenAIClient) SuggestTags(ctx context.Context, req *TagRequest) (*TagRe
 		logger.LogError(fmt.Sprintf("OpenAI tagging error: %v", err))
 		return nil, fmt.Errorf("failed to generate tags: %w", err)
 	}
+	reportTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no tags generated")
@@ -331,6 +461,7 @@ func (c *OpenAIClient) ClassifyContent(ctx context.Context, req *ClassifyRequest
 		logger.LogError(fmt.Sprintf("OpenAI classification error: %v", err))
 		return nil, fmt.Errorf("failed to classify content: %w", err)
 	}
+	reportTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no classification generated")
@@ -420,6 +551,7 @@ func (c *OpenAIClient) RecommendContent(ctx context.Context, req *RecommendReque
 		logger.LogError(fmt.Sprintf("OpenAI recommendation error: %v", err))
 		return nil, fmt.Errorf("failed to generate recommendation: %w", err)
 	}
+	reportTokens(ctx, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
 	
 	if len(resp.Choices) == 0 {
 		return nil, fmt.Errorf("no recommendation generated")
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/store"
+)
+
+// UsageTotals adds up AI requests
+type UsageTotals struct {
+	Requests     int     `db:"requests" json:"requests"` // Requests sent to the model
+	Cached       int     `db:"cached" json:"cached"`     // Requests answered from the cache
+	Errors       int     `db:"errors" json:"errors"`
+	InputTokens  int     `db:"input_tokens" json:"input_tokens"`
+	OutputTokens int     `db:"output_tokens" json:"output_tokens"`
+	Cost         float64 `db:"cost" json:"cost"` // USD
+}
+
+// UsageGroup is the usage of one feature, model or day
+type UsageGroup struct {
+	Key string `db:"key" json:"key"`
+	UsageTotals
+}
+
+// BudgetStatus is the spend of a month against a budget
+type BudgetStatus struct {
+	Limit     float64 `json:"limit"` // USD, unlimited when 0
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"` // 0 when unlimited
+	Exceeded  bool    `json:"exceeded"`
+}
+
+// UsageReport is the AI usage of a user in one month
+type UsageReport struct {
+	Month    string        `json:"month"` // YYYY-MM, in UTC
+	Totals   UsageTotals   `json:"totals"`
+	Features []*UsageGroup `json:"features"`
+	Models   []*UsageGroup `json:"models"`
+	Days     []*UsageGroup `json:"days"` // YYYY-MM-DD, oldest first
+	Budget   struct {
+		User   BudgetStatus `json:"user"`
+		Global BudgetStatus `json:"global"` // All users together
+	} `json:"budget"`
+}
+
+func newBudgetStatus(limit float64, spent float64) BudgetStatus {
+	status := BudgetStatus{Limit: limit, Spent: spent}
+	if limit > 0 {
+		status.Remaining = max(0, limit-spent)
+		status.Exceeded = spent >= limit
+	}
+	return status
+}
+
+// Report returns the AI usage of the user in the month of the given time
+func (m *Meter) Report(userID string, month time.Time) (*UsageReport, error) {
+	from := monthStart(month)
+	report := &UsageReport{Month: from.Format("2006-01")}
+
+	totals, err := usageGroups(userID, from, "''", "1")
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Totals = totals[0].UsageTotals
+	}
+	if report.Features, err = usageGroups(userID, from, "feature", "cost DESC, requests DESC"); err != nil {
+		return nil, err
+	}
+	if report.Models, err = usageGroups(userID, from, "model", "cost DESC, requests DESC"); err != nil {
+		return nil, err
+	}
+	// Stored dates start with YYYY-MM-DD in UTC
+	if report.Days, err = usageGroups(userID, from, "substr(created, 1, 10)", "1"); err != nil {
+		return nil, err
+	}
+
+	globalCost, err := monthlyCost(nil, from)
+	if err != nil {
+		return nil, err
+	}
+	report.Budget.User = newBudgetStatus(m.cfg.UserMonthlyBudget, report.Totals.Cost)
+	report.Budget.Global = newBudgetStatus(m.cfg.MonthlyBudget, globalCost)
+
+	return report, nil
+}
+
+// usageGroups adds up the usage of the user in the month starting at from,
+// grouped and ordered by the SQL expressions
+func usageGroups(userID string, from time.Time, groupBy string, orderBy string) ([]*UsageGroup, error) {
+	groups := []*UsageGroup{}
+	err := store.GetDao().DB().NewQuery(fmt.Sprintf(`SELECT %s AS key,
+			COALESCE(SUM(cached = 0), 0) AS requests,
+			COALESCE(SUM(cached != 0), 0) AS cached,
+			COALESCE(SUM(error != ''), 0) AS errors,
+			COALESCE(SUM(input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(cost), 0) AS cost
+		FROM %s
+		WHERE user = {:user} AND created >= {:from} AND created < {:to}
+		GROUP BY 1
+		ORDER BY %s`, groupBy, (&models.AIUsage{}).TableName(), orderBy)).
+		Bind(dbx.Params{
+			"user": userID,
+			"from": from.Format(types.DefaultDateLayout),
+			"to":   from.AddDate(0, 1, 0).Format(types.DefaultDateLayout),
+		}).
+		All(&groups)
+	if err != nil {
+		return nil, fmt.Errorf("error loading AI usage: %v", err)
+	}
+	return groups, nil
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Features AI requests are made for, usage is reported per feature
+const (
+	FeatureFeedProcessing = "feed_processing"
+	FeatureFeedDigest     = "feed_digest"
+	FeatureFeedRanking    = "feed_ranking"
+	FeatureSemanticIndex  = "semantic_index"
+	FeatureSearch         = "search"
+	FeatureAssistant      = "assistant"
+	// Requests made without a caller in their context
+	FeatureUnknown = "unknown"
+)
+
+// Operations of the AI clients
+const (
+	OperationSummarize = "summarize"
+	OperationTag       = "tag"
+	OperationClassify  = "classify"
+	OperationRecommend = "recommend"
+	OperationEmbed     = "embed"
+	OperationChat      = "chat"
+)
+
+type callerKey struct{}
+
+// Caller is who an AI request is made for
+type Caller struct {
+	Feature string
+	UserID  string // Empty for work done for all users, like indexing
+}
+
+// WithCaller returns a context whose AI requests are recorded for the feature
+// and counted against the budget of the user
+func WithCaller(ctx context.Context, feature string, userID string) context.Context {
+	return context.WithValue(ctx, callerKey{}, Caller{Feature: feature, UserID: userID})
+}
+
+// CallerFromContext returns the caller set on the context
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+type tokenUsageKey struct{}
+
+// tokenUsage collects the tokens the API reports for the requests a client
+// makes for one call
+type tokenUsage struct {
+	mu       sync.Mutex
+	input    int
+	output   int
+	reported bool
+}
+
+func withTokenUsage(ctx context.Context) (context.Context, *tokenUsage) {
+	usage := &tokenUsage{}
+	return context.WithValue(ctx, tokenUsageKey{}, usage), usage
+}
+
+// reportTokens adds the tokens an API reported for a request to the call the
+// context belongs to. It does nothing outside of metered calls.
+func reportTokens(ctx context.Context, input int, output int) {
+	usage, ok := ctx.Value(tokenUsageKey{}).(*tokenUsage)
+	if !ok || input+output <= 0 {
+		return
+	}
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	usage.input += input
+	usage.output += output
+	usage.reported = true
+}
+
+func (u *tokenUsage) counts() (int, int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.input, u.output, u.reported
+}
+
+// estimateTokens guesses the tokens of texts for APIs that don't report them
+func estimateTokens(texts ...string) int {
+	chars := 0
+	for _, text := range texts {
+		chars += utf8.RuneCountInString(text)
+	}
+	return (chars + localCharsPerToken - 1) / localCharsPerToken
+}
+
+// modelPrice is the price of a model in USD per million tokens
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// List prices of hosted models, matched by the longest prefix of the model
+// name so dated snapshots share the price of their family. Models not listed,
+// like local ones, are free unless a price is configured.
+var modelPrices = map[string]modelPrice{
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+	"gpt-4":                  {Input: 30, Output: 60},
+	"gpt-4-turbo":            {Input: 10, Output: 30},
+	"gpt-4o":                 {Input: 2.50, Output: 10},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.60},
+	"text-embedding-ada-002": {Input: 0.10},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"claude-3-haiku":         {Input: 0.25, Output: 1.25},
+	"claude-3-sonnet":        {Input: 3, Output: 15},
+	"claude-3-opus":          {Input: 15, Output: 75},
+	"claude-3-5-haiku":       {Input: 0.80, Output: 4},
+	"claude-3-5-sonnet":      {Input: 3, Output: 15},
+}
+
+// knownPrice returns the list price of a model
+func knownPrice(model string) (modelPrice, bool) {
+	prefixes := make([]string, 0, len(modelPrices))
+	for prefix := range modelPrices {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	model = strings.ToLower(model)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return modelPrices[prefix], true
+		}
+	}
+	return modelPrice{}, false
+}
@@ -0,0 +1,202 @@
+package assistant
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateRange is the span of time a question is about. To is the last instant
+// of the span.
+type dateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (r dateRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// String describes the range for prompts and lookup results
+func (r dateRange) String() string {
+	switch {
+	case r.IsZero():
+		return "any time"
+	case r.To.IsZero():
+		return "from " + r.From.Format("Mon 2 Jan 2006")
+	case r.From.IsZero():
+		return "until " + r.To.Format("Mon 2 Jan 2006")
+	case sameDay(r.From, r.To):
+		return "on " + r.From.Format("Mon 2 Jan 2006")
+	}
+	return "from " + r.From.Format("Mon 2 Jan 2006") + " to " + r.To.Format("Mon 2 Jan 2006")
+}
+
+var (
+	dayWordPattern      = regexp.MustCompile(`\b(today|yesterday|tomorrow|tonight)\b`)
+	periodPattern       = regexp.MustCompile(`\b(this|last|next|past|previous|coming)\s+(week|month|year)\b`)
+	countPattern        = regexp.MustCompile(`\b(last|past|previous|next|coming)\s+(\d{1,3})\s+(day|week|month)s?\b`)
+	weekdayPattern      = regexp.MustCompile(`\b(last|next)\s+(monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`)
+	monthPattern        = regexp.MustCompile(`\b(?:in|during|of|since)\s+(january|february|march|april|may|june|july|august|september|october|november|december)(?:\s+(\d{4}))?\b|\b(january|february|march|april|may|june|july|august|september|october|november|december)\s+(\d{4})\b`)
+	isoDatePattern      = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	upcomingWordPattern = regexp.MustCompile(`\b(next|upcoming|coming|later|soon|tomorrow|will)\b`)
+)
+
+var monthNames = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March,
+	"april": time.April, "may": time.May, "june": time.June, "july": time.July,
+	"august": time.August, "september": time.September, "october": time.October,
+	"november": time.November, "december": time.December,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sunday": time.Sunday,
+}
+
+// parseDateRange finds the first relative or absolute date expression of a
+// question, like "last month", "past 3 days" or "in March", and returns the
+// range it stands for in the time zone of now together with the matched text
+func parseDateRange(question string, now time.Time) (dateRange, string, bool) {
+	text := strings.ToLower(question)
+	today := startOfDay(now)
+
+	if m := isoDatePattern.FindStringSubmatch(text); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			from := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+			return days(from, 1), m[0], true
+		}
+	}
+
+	if m := countPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		n = max(n, 1)
+		future := m[1] == "next" || m[1] == "coming"
+		var span time.Duration
+		var from, to time.Time
+		switch m[3] {
+		case "day":
+			span = time.Duration(n) * 24 * time.Hour
+		case "week":
+			span = time.Duration(n) * 7 * 24 * time.Hour
+		case "month":
+			if future {
+				return dateRange{From: now, To: now.AddDate(0, n, 0)}, m[0], true
+			}
+			return dateRange{From: now.AddDate(0, -n, 0), To: now}, m[0], true
+		}
+		if future {
+			from, to = now, now.Add(span)
+		} else {
+			from, to = now.Add(-span), now
+		}
+		return dateRange{From: from, To: to}, m[0], true
+	}
+
+	if m := periodPattern.FindStringSubmatch(text); m != nil {
+		offset := 0
+		switch m[1] {
+		case "last", "past", "previous":
+			offset = -1
+		case "next", "coming":
+			offset = 1
+		}
+		switch m[2] {
+		case "week":
+			// Weeks start on Monday
+			monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+			return days(monday.AddDate(0, 0, 7*offset), 7), m[0], true
+		case "month":
+			first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, offset, 0)
+			return dateRange{From: first, To: first.AddDate(0, 1, 0).Add(-time.Nanosecond)}, m[0], true
+		case "year":
+			first := time.Date(today.Year()+offset, time.January, 1, 0, 0, 0, 0, now.Location())
+			return dateRange{From: first, To: first.AddDate(1, 0, 0).Add(-time.Nanosecond)}, m[0], true
+		}
+	}
+
+	if m := weekdayPattern.FindStringSubmatch(text); m != nil {
+		weekday := weekdayNames[m[2]]
+		if m[1] == "last" {
+			diff := (int(today.Weekday()) - int(weekday) + 7) % 7
+			if diff == 0 {
+				diff = 7
+			}
+			return days(today.AddDate(0, 0, -diff), 1), m[0], true
+		}
+		diff := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return days(today.AddDate(0, 0, diff), 1), m[0], true
+	}
+
+	if m := dayWordPattern.FindStringSubmatch(text); m != nil {
+		switch m[1] {
+		case "yesterday":
+			return days(today.AddDate(0, 0, -1), 1), m[0], true
+		case "tomorrow":
+			return days(today.AddDate(0, 0, 1), 1), m[0], true
+		}
+		return days(today, 1), m[0], true
+	}
+
+	if m := monthPattern.FindStringSubmatch(text); m != nil {
+		name, yearText := m[1], m[2]
+		if name == "" {
+			name, yearText = m[3], m[4]
+		}
+		month := monthNames[name]
+
+		var first time.Time
+		if year, err := strconv.Atoi(yearText); err == nil {
+			first = time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		} else {
+			// The occurrence of the month closest to now
+			for _, year := range []int{today.Year(), today.Year() - 1, today.Year() + 1} {
+				candidate := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+				if first.IsZero() || absDuration(candidate.Sub(today)) < absDuration(first.Sub(today)) {
+					first = candidate
+				}
+			}
+		}
+		return dateRange{From: first, To: first.AddDate(0, 1, 0).Add(-time.Nanosecond)}, m[0], true
+	}
+
+	return dateRange{}, "", false
+}
+
+// asksAboutFuture reports whether a question looks ahead rather than back
+func asksAboutFuture(question string) bool {
+	return upcomingWordPattern.MatchString(strings.ToLower(question))
+}
+
+// parseDay reads a YYYY-MM-DD date in the time zone of loc
+func parseDay(value string, loc *time.Location) (time.Time, bool) {
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(value), loc)
+	return day, err == nil
+}
+
+func days(from time.Time, n int) dateRange {
+	return dateRange{From: from, To: from.AddDate(0, 0, n).Add(-time.Nanosecond)}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
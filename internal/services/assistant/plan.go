@@ -0,0 +1,221 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+)
+
+// Lookups run for one question at most, each adds records to the prompt
+const maxToolCalls = 3
+
+// Upcoming events looked at for questions like "when is my next meeting"
+const upcomingDays = 90
+
+// plan is how a question is answered: which records to search for and which
+// lookups to run
+type plan struct {
+	Query    string    // Words to search records for
+	Range    dateRange // Dates records must fall in, any when zero
+	Types    []string  // Record types to search, all when empty
+	Tools    []*ToolCall
+	Location *time.Location
+}
+
+// Words asking for one of the lookups
+var toolWords = map[string][]string{
+	ToolCalendarEvents: {"meeting", "meetings", "meet", "appointment", "appointments", "calendar", "schedule", "scheduled", "event", "events", "call"},
+	ToolSpendingTotal:  {"spend", "spent", "spending", "expense", "expenses", "cost", "costs", "paid", "pay", "bought", "purchases", "money"},
+	ToolTasks:          {"task", "tasks", "todo", "todos", "due", "deadline", "deadlines"},
+}
+
+// Order lookups are added in, so plans are stable
+var toolOrder = []string{ToolCalendarEvents, ToolSpendingTotal, ToolTasks}
+
+const planPrompt = `Turn the question of a user about their own data into a search plan.
+
+Now: %s (time zone %s).
+
+Record types:
+- feed: articles from the feeds the user follows
+- mail: email messages
+- calendar: calendar events
+- task: tasks with due dates
+- track: time tracked in apps on the user's devices
+- daily_log: the user's journal entries
+
+Lookups:
+%s
+
+Return a JSON object with these keys:
+- "query": the words to search records for, without words about dates or record types, empty to not search by words
+- "from", "to": the first and last day the question is about as YYYY-MM-DD, empty when it isn't about a time span
+- "types": the record types to search, empty for all
+- "tools": the lookups the question needs, each {"name": lookup, "query": words, "from": YYYY-MM-DD, "to": YYYY-MM-DD, "limit": number}, empty when it needs none
+
+Earlier questions and answers are given for context, plan only for the last question.`
+
+// makePlan asks the model how to answer a question, or works it out from the
+// words of the question when there is no model or its plan can't be read
+func (s *Service) makePlan(ctx context.Context, question string, history []ai.ChatMessage, now time.Time) *plan {
+	if s.chatter != nil {
+		p, err := s.modelPlan(ctx, question, history, now)
+		if err == nil {
+			return p
+		}
+		logger.LogError("Assistant planning failed, planning from the question words: " + err.Error())
+	}
+	return heuristicPlan(question, now)
+}
+
+type modelPlanResponse struct {
+	Query string      `json:"query"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Types []string    `json:"types"`
+	Tools []*ToolCall `json:"tools"`
+}
+
+func (s *Service) modelPlan(ctx context.Context, question string, history []ai.ChatMessage, now time.Time) (*plan, error) {
+	var tools strings.Builder
+	for _, name := range toolOrder {
+		fmt.Fprintf(&tools, "- %s: %s\n", name, toolDescriptions[name])
+	}
+
+	var prompt strings.Builder
+	for _, message := range history {
+		fmt.Fprintf(&prompt, "%s: %s\n", message.Role, shorten(message.Content))
+	}
+	fmt.Fprintf(&prompt, "user: %s", question)
+
+	resp, err := s.chatter.Chat(ctx, &ai.ChatRequest{
+		Messages: []ai.ChatMessage{
+			{Role: ai.ChatRoleSystem, Content: fmt.Sprintf(planPrompt, now.Format("Monday 2 January 2006 15:04"), now.Location(), strings.TrimRight(tools.String(), "\n"))},
+			{Role: ai.ChatRoleUser, Content: prompt.String()},
+		},
+		MaxTokens:   300,
+		Temperature: 0,
+		JSON:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response modelPlanResponse
+	if err := json.Unmarshal([]byte(extractJSONObject(resp.Content)), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	p := &plan{
+		Query:    strings.Join(keywords(response.Query), " "),
+		Location: now.Location(),
+	}
+	if from, ok := parseDay(response.From, now.Location()); ok {
+		p.Range.From = from
+	}
+	if to, ok := parseDay(response.To, now.Location()); ok {
+		p.Range.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	for _, docType := range response.Types {
+		for _, searched := range searchedTypes {
+			if docType == searched {
+				p.Types = append(p.Types, docType)
+			}
+		}
+	}
+	for _, call := range response.Tools {
+		if call == nil || !isValidTool(call.Name) || len(p.Tools) >= maxToolCalls {
+			continue
+		}
+		p.Tools = append(p.Tools, &ToolCall{
+			Name:  call.Name,
+			Query: call.Query,
+			From:  call.From,
+			To:    call.To,
+			Limit: call.Limit,
+		})
+	}
+
+	if p.Query == "" && p.Range.IsZero() && len(p.Tools) == 0 {
+		return nil, fmt.Errorf("empty plan")
+	}
+	return p, nil
+}
+
+// heuristicPlan works out a plan from the words of a question: a date
+// expression sets the range, words like "meeting" or "spent" pick lookups and
+// words like "read" or "mail" the record types
+func heuristicPlan(question string, now time.Time) *plan {
+	p := &plan{Location: now.Location()}
+
+	text := strings.ToLower(question)
+	r, phrase, ok := parseDateRange(text, now)
+	if ok {
+		p.Range = r
+		text = strings.Replace(text, phrase, " ", 1)
+	}
+
+	words := keywords(text)
+	present := map[string]bool{}
+	for _, word := range words {
+		present[word] = true
+	}
+
+	seenTypes := map[string]bool{}
+	var query []string
+	for _, word := range words {
+		if docType, ok := typeHintWords[word]; ok {
+			if !seenTypes[docType] {
+				seenTypes[docType] = true
+				p.Types = append(p.Types, docType)
+			}
+			continue
+		}
+		if genericToolWords[word] {
+			continue
+		}
+		query = append(query, word)
+	}
+	p.Query = strings.Join(query, " ")
+
+	for _, name := range toolOrder {
+		asked := false
+		for _, word := range toolWords[name] {
+			if present[word] {
+				asked = true
+				break
+			}
+		}
+		if !asked {
+			continue
+		}
+
+		call := &ToolCall{Name: name, Query: p.Query}
+		switch {
+		case ok:
+			call.From = r.From.Format(time.RFC3339)
+			call.To = r.To.Format(time.RFC3339)
+		case name == ToolCalendarEvents && asksAboutFuture(question):
+			call.From = now.Format(time.RFC3339)
+			call.To = now.AddDate(0, 0, upcomingDays).Format(time.RFC3339)
+			call.Limit = 5
+		}
+		p.Tools = append(p.Tools, call)
+	}
+
+	return p
+}
+
+// extractJSONObject returns the outermost JSON object in a model answer
+func extractJSONObject(response string) string {
+	content := strings.TrimSpace(response)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		return content[start : end+1]
+	}
+	return content
+}
@@ -0,0 +1,378 @@
+package assistant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pocketbase/dbx"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/search"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
+)
+
+// Types of the records answers are based on
+const (
+	SourceFeed     = semantic.TypeFeed
+	SourceMail     = semantic.TypeMail
+	SourceCalendar = search.TypeCalendar
+	SourceTask     = "task"
+	SourceTrack    = "track"
+	SourceDailyLog = "daily_log"
+	SourceExpense  = "expense"
+)
+
+// searchedTypes are the record types searched for questions. Expenses are
+// only added up by the spending lookup.
+var searchedTypes = []string{SourceFeed, SourceMail, SourceCalendar, SourceTask, SourceTrack, SourceDailyLog}
+
+const (
+	// Records taken from each retriever before they are merged
+	retrievalListLimit = 10
+	// Records given to the model with a question
+	maxSources = 12
+	// Rank offset of reciprocal rank fusion, damps the weight of top ranks
+	fusionOffset = 60
+	// Snippets given to the model per record
+	snippetLength = 300
+)
+
+// Source is a record an answer can be based on and cite
+type Source struct {
+	Ref      int    `json:"ref"`  // The number the answer cites the record with, [1] for the first
+	Type     string `json:"type"` // feed, mail, calendar, task, track, daily_log, expense
+	RecordID string `json:"record_id"`
+	Title    string `json:"title"`
+	Snippet  string `json:"snippet"`
+	Date     string `json:"date"`
+	URL      string `json:"url,omitempty"`
+}
+
+func (s *Source) key() string {
+	return s.Type + ":" + s.RecordID
+}
+
+// Words that carry no meaning for a search
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true,
+	"of": true, "to": true, "in": true, "on": true, "at": true, "for": true,
+	"with": true, "from": true, "by": true, "about": true, "into": true, "over": true,
+	"is": true, "are": true, "was": true, "were": true, "be": true, "been": true,
+	"am": true, "do": true, "does": true, "did": true, "done": true, "have": true,
+	"has": true, "had": true, "i": true, "me": true, "my": true, "mine": true,
+	"we": true, "our": true, "you": true, "your": true, "it": true, "its": true,
+	"this": true, "that": true, "these": true, "those": true, "there": true,
+	"what": true, "which": true, "who": true, "whom": true, "when": true,
+	"where": true, "why": true, "how": true, "any": true, "all": true, "some": true,
+	"much": true, "many": true, "can": true, "could": true, "would": true,
+	"should": true, "will": true, "shall": true, "may": true, "might": true,
+	"please": true, "tell": true, "show": true, "find": true, "give": true,
+	"list": true, "get": true, "know": true, "anything": true, "something": true,
+	"last": true, "next": true, "past": true, "previous": true, "coming": true,
+	"upcoming": true, "week": true, "weeks": true, "month": true, "months": true,
+	"year": true, "years": true, "day": true, "days": true, "today": true,
+	"yesterday": true, "tomorrow": true, "tonight": true, "ago": true, "since": true,
+	"recently": true, "recent": true, "lately": true, "so": true, "far": true,
+	"if": true, "than": true, "then": true, "up": true, "out": true, "again": true,
+}
+
+// Words that say which kind of record a question is about, mapped to the type
+var typeHintWords = map[string]string{
+	"read": SourceFeed, "reading": SourceFeed, "article": SourceFeed, "articles": SourceFeed,
+	"feed": SourceFeed, "feeds": SourceFeed, "post": SourceFeed, "posts": SourceFeed,
+	"story": SourceFeed, "stories": SourceFeed, "news": SourceFeed,
+	"mail": SourceMail, "mails": SourceMail, "email": SourceMail, "emails": SourceMail,
+	"inbox": SourceMail, "message": SourceMail, "messages": SourceMail, "wrote": SourceMail,
+	"meeting": SourceCalendar, "meetings": SourceCalendar, "calendar": SourceCalendar,
+	"event": SourceCalendar, "events": SourceCalendar, "appointment": SourceCalendar,
+	"task": SourceTask, "tasks": SourceTask, "todo": SourceTask, "todos": SourceTask,
+	"worked": SourceTrack, "working": SourceTrack, "app": SourceTrack, "apps": SourceTrack,
+	"focus": SourceTrack, "screen": SourceTrack, "tracked": SourceTrack,
+	"log": SourceDailyLog, "logs": SourceDailyLog, "journal": SourceDailyLog,
+	"diary": SourceDailyLog, "feel": SourceDailyLog, "felt": SourceDailyLog,
+	"feeling": SourceDailyLog, "mood": SourceDailyLog,
+}
+
+// keywords returns the lower case words of text worth searching for
+func keywords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' && r != '@'
+	})
+
+	seen := map[string]bool{}
+	var result []string
+	for _, word := range words {
+		word = strings.Trim(word, "-_.@")
+		if len([]rune(word)) < 2 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		result = append(result, word)
+	}
+	return result
+}
+
+// retrieve collects the records of the user matching a plan from full-text
+// search, semantic search and the tables neither indexes, and merges them
+// into one ranking
+func (s *Service) retrieve(ctx context.Context, userID string, p *plan) []*Source {
+	types := p.Types
+	if len(types) == 0 {
+		types = searchedTypes
+	}
+	wants := map[string]bool{}
+	for _, docType := range types {
+		wants[docType] = true
+	}
+	// A lookup lists the records of its type that fit the question already,
+	// searching them as well would add the ones it left out on purpose
+	for _, call := range p.Tools {
+		delete(wants, toolTypes[call.Name])
+	}
+
+	var lists [][]*Source
+	add := func(name string, sources []*Source, err error) {
+		if err != nil {
+			// One retriever failing, like the embedder being down, still
+			// leaves the others to answer from
+			logger.LogError(fmt.Sprintf("Assistant %s retrieval failed: %v", name, err))
+			return
+		}
+		if len(sources) > 0 {
+			lists = append(lists, sources)
+		}
+	}
+
+	if p.Query != "" {
+		var indexed []string
+		for _, docType := range []string{SourceFeed, SourceMail, SourceCalendar} {
+			if wants[docType] {
+				indexed = append(indexed, docType)
+			}
+		}
+		if len(indexed) > 0 {
+			sources, err := s.fullTextSources(userID, p, indexed)
+			add("full-text", sources, err)
+		}
+
+		var embedded []string
+		for _, docType := range []string{SourceFeed, SourceMail} {
+			if wants[docType] {
+				embedded = append(embedded, docType)
+			}
+		}
+		if len(embedded) > 0 && s.semantic != nil {
+			sources, err := s.semanticSources(ctx, userID, p, embedded)
+			add("semantic", sources, err)
+		}
+	}
+
+	// Without words or a date there is nothing to narrow these tables down by
+	if p.Query != "" || !p.Range.IsZero() {
+		if wants[SourceTask] {
+			sources, err := s.taskSources(userID, p)
+			add("task", sources, err)
+		}
+		if wants[SourceTrack] {
+			sources, err := s.trackSources(userID, p)
+			add("track", sources, err)
+		}
+		if wants[SourceDailyLog] {
+			sources, err := s.dailyLogSources(userID, p)
+			add("daily log", sources, err)
+		}
+	}
+
+	return fuse(lists, maxSources)
+}
+
+func (s *Service) fullTextSources(userID string, p *plan, types []string) ([]*Source, error) {
+	results, _, err := search.Search(s.app, userID, p.Query, search.Options{
+		Types: types,
+		From:  p.Range.From,
+		To:    p.Range.To,
+		Limit: retrievalListLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, 0, len(results))
+	for _, result := range results {
+		url, _ := result.Metadata["url"].(string)
+		sources = append(sources, &Source{
+			Type:     result.Type,
+			RecordID: result.ID,
+			Title:    search.PlainText(result.Title),
+			Snippet:  shorten(search.PlainText(result.Snippet)),
+			Date:     result.Date,
+			URL:      url,
+		})
+	}
+	return sources, nil
+}
+
+func (s *Service) semanticSources(ctx context.Context, userID string, p *plan, types []string) ([]*Source, error) {
+	results, err := s.semantic.Search(ctx, userID, p.Query, semantic.Options{
+		Types: types,
+		Limit: retrievalListLimit,
+		From:  p.Range.From,
+		To:    p.Range.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, 0, len(results))
+	for _, result := range results {
+		url, _ := result.Meta["url"].(string)
+		sources = append(sources, &Source{
+			Type:     result.Type,
+			RecordID: result.RecordID,
+			Title:    result.Title,
+			Snippet:  shorten(result.Snippet),
+			Date:     result.Date,
+			URL:      url,
+		})
+	}
+	return sources, nil
+}
+
+func (s *Service) taskSources(userID string, p *plan) ([]*Source, error) {
+	q := query.BaseQuery[*models.Task]().
+		AndWhere(dbx.HashExp{"user": userID})
+	q = whereRange(q, "due", p.Range)
+	if condition := keywordCondition(p.Query, []string{"title", "description", "category"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var tasks []*models.Task
+	if err := q.OrderBy("due DESC").Limit(retrievalListLimit).All(&tasks); err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, 0, len(tasks))
+	for _, task := range tasks {
+		sources = append(sources, taskSource(task, p.Location))
+	}
+	return sources, nil
+}
+
+// trackSources returns the longest stretches of tracked activity
+func (s *Service) trackSources(userID string, p *plan) ([]*Source, error) {
+	q := query.BaseQuery[*models.TrackItems]().
+		AndWhere(dbx.HashExp{"user": userID})
+	q = whereRange(q, "begin_date", p.Range)
+	if condition := keywordCondition(p.Query, []string{"title", "app", "task_name"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var items []*models.TrackItems
+	err := q.OrderBy("julianday(end_date) - julianday(begin_date) DESC").
+		Limit(retrievalListLimit).
+		All(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, 0, len(items))
+	for _, item := range items {
+		begin := item.BeginDate.Time().In(p.Location)
+		end := item.EndDate.Time().In(p.Location)
+		details := []string{item.App, begin.Format("Mon 2 Jan 2006 15:04") + "-" + end.Format("15:04")}
+		if item.TaskName != "" {
+			details = append(details, item.TaskName)
+		}
+
+		title := item.Title
+		if title == "" {
+			title = item.App
+		}
+		sources = append(sources, &Source{
+			Type:     SourceTrack,
+			RecordID: item.Id,
+			Title:    title,
+			Snippet:  shorten(strings.Join(details, ", ")),
+			Date:     item.BeginDate.String(),
+		})
+	}
+	return sources, nil
+}
+
+func (s *Service) dailyLogSources(userID string, p *plan) ([]*Source, error) {
+	q := query.BaseQuery[*models.DailyLog]().
+		AndWhere(dbx.HashExp{"user": userID})
+	q = whereRange(q, "date", p.Range)
+	if condition := keywordCondition(p.Query, []string{"summary", "feeling"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var logs []*models.DailyLog
+	if err := q.OrderBy("date DESC").Limit(retrievalListLimit).All(&logs); err != nil {
+		return nil, err
+	}
+
+	sources := make([]*Source, 0, len(logs))
+	for _, log := range logs {
+		details := []string{}
+		if log.Feeling != "" {
+			details = append(details, "feeling "+log.Feeling)
+		}
+		if log.Score != 0 {
+			details = append(details, "score "+formatAmount(log.Score))
+		}
+		if summary := strings.TrimSpace(search.PlainText(log.Summary)); summary != "" {
+			details = append(details, summary)
+		}
+
+		sources = append(sources, &Source{
+			Type:     SourceDailyLog,
+			RecordID: log.Id,
+			Title:    "Daily log of " + log.Date.Time().Format("Mon 2 Jan 2006"),
+			Snippet:  shorten(strings.Join(details, ", ")),
+			Date:     log.Date.String(),
+		})
+	}
+	return sources, nil
+}
+
+// fuse merges rankings by reciprocal rank fusion, so records found by several
+// retrievers rise to the top without having to compare their scores
+func fuse(lists [][]*Source, limit int) []*Source {
+	scores := map[string]float64{}
+	sources := map[string]*Source{}
+	var order []string
+	for _, list := range lists {
+		for rank, source := range list {
+			key := source.key()
+			if _, ok := sources[key]; !ok {
+				sources[key] = source
+				order = append(order, key)
+			}
+			scores[key] += 1 / float64(fusionOffset+rank+1)
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	result := make([]*Source, 0, min(len(order), limit))
+	for _, key := range order[:min(len(order), limit)] {
+		result = append(result, sources[key])
+	}
+	return result
+}
+
+// shorten collapses whitespace and cuts text to the snippet length
+func shorten(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > snippetLength {
+		text = string(runes[:snippetLength]) + "..."
+	}
+	return text
+}
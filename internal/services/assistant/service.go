@@ -0,0 +1,432 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/services/semantic"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	// Longest question accepted
+	MaxQuestionLength = 2000
+	// Earlier messages of a conversation given to the model with a question
+	historyMessages = 6
+	// Conversation titles are the opening question cut to this length
+	titleLength = 80
+	// Answer length in tokens
+	answerMaxTokens = 800
+)
+
+// ErrNotFound is returned for conversations the user doesn't own
+var ErrNotFound = errors.New("conversation not found")
+
+// Citation markers in answers, like [2] or [1, 3]
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+const answerPrompt = `You are the assistant of a personal dashboard. Answer the user's questions about their own data using only the lookups and records below, which come from their feeds, mail, calendar, tasks, tracked time, daily logs and expenses.
+
+Cite the records an answer is based on by their number in square brackets, like [2]. When the records don't answer the question, say so instead of guessing. Keep answers short and give dates and times in the user's time zone.
+
+Now: %s (time zone %s).`
+
+// Service answers questions about the data of a user from their records and
+// keeps the conversations
+type Service struct {
+	app      core.App
+	chatter  ai.Chatter
+	semantic *semantic.Service
+}
+
+// NewService creates the assistant. Without a chatter questions are answered
+// with the matching records only, without the semantic service records are
+// found by their words only.
+func NewService(app core.App, chatter ai.Chatter, semanticService *semantic.Service) *Service {
+	return &Service{
+		app:      app,
+		chatter:  chatter,
+		semantic: semanticService,
+	}
+}
+
+// AskOptions controls how a question is answered
+type AskOptions struct {
+	ConversationID string         // Empty starts a new conversation
+	Location       *time.Location // Time zone of dates in questions and answers, UTC when nil
+	// OnQuestion is called once the question is stored, before it is answered
+	OnQuestion func(conversation *models.AssistantConversation, question *models.AssistantMessage) error
+	// OnDelta receives the answer while it is generated, nil to only get the
+	// whole answer
+	OnDelta ai.ChatStreamFunc
+}
+
+// Answer is an answered question
+type Answer struct {
+	Conversation *models.AssistantConversation `json:"conversation"`
+	Question     *models.AssistantMessage      `json:"question"`
+	Answer       *models.AssistantMessage      `json:"answer"`
+	Citations    []*Source                     `json:"citations"`
+	Tools        []*ToolCall                   `json:"tools"`
+}
+
+// Ask answers a question of the user from their records and stores both in
+// the conversation. Only records of the user are ever looked at.
+func (s *Service) Ask(ctx context.Context, userID string, question string, opts AskOptions) (*Answer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("question is empty")
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	ctx = ai.WithCaller(ctx, ai.FeatureAssistant, userID)
+
+	conversation, err := s.conversation(userID, opts.ConversationID, question)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.history(conversation.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	questionMessage := &models.AssistantMessage{
+		User:         userID,
+		Conversation: conversation.Id,
+		Role:         models.AssistantRoleUser,
+		Content:      question,
+		Citations:    types.JSONRaw("[]"),
+		Tools:        types.JSONRaw("[]"),
+	}
+	if err := saveMessage(questionMessage); err != nil {
+		return nil, err
+	}
+	if opts.OnQuestion != nil {
+		if err := opts.OnQuestion(conversation, questionMessage); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().In(loc)
+	p := s.makePlan(ctx, question, history, now)
+
+	// Lookup results lead, they answer exactly what was asked
+	var sources []*Source
+	for _, call := range p.Tools {
+		found, err := s.runTool(userID, call, now)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Assistant lookup %s failed: %v", call.Name, err))
+			call.Result = "The lookup failed"
+			continue
+		}
+		sources = append(sources, found...)
+	}
+	sources = fuse([][]*Source{sources, s.retrieve(ctx, userID, p)}, maxSources)
+	for i, source := range sources {
+		source.Ref = i + 1
+	}
+
+	answerMessage := &models.AssistantMessage{
+		User:         userID,
+		Conversation: conversation.Id,
+		Role:         models.AssistantRoleAssistant,
+	}
+	citations := s.answer(ctx, answerMessage, question, history, p, sources, now, opts.OnDelta)
+	if citations == nil {
+		citations = []*Source{}
+	}
+	if p.Tools == nil {
+		p.Tools = []*ToolCall{}
+	}
+
+	citationsJSON, _ := json.Marshal(citations)
+	toolsJSON, _ := json.Marshal(p.Tools)
+	answerMessage.Citations = types.JSONRaw(citationsJSON)
+	answerMessage.Tools = types.JSONRaw(toolsJSON)
+	if err := saveMessage(answerMessage); err != nil {
+		return nil, err
+	}
+
+	conversation.LastMessageAt = types.NowDateTime()
+	conversation.RefreshUpdated()
+	if err := query.SaveRecord(conversation); err != nil {
+		logger.LogError(fmt.Sprintf("Error updating assistant conversation %s: %v", conversation.Id, err))
+	}
+
+	return &Answer{
+		Conversation: conversation,
+		Question:     questionMessage,
+		Answer:       answerMessage,
+		Citations:    citations,
+		Tools:        p.Tools,
+	}, nil
+}
+
+// answer writes the answer into message and returns the records it cites.
+// Without a model, or when it fails before writing anything, the answer lists
+// what the lookups and the search found. An answer cut short keeps what was
+// written and records the error.
+func (s *Service) answer(ctx context.Context, message *models.AssistantMessage, question string, history []ai.ChatMessage, p *plan, sources []*Source, now time.Time, onDelta ai.ChatStreamFunc) []*Source {
+	if s.chatter != nil {
+		messages := []ai.ChatMessage{{Role: ai.ChatRoleSystem, Content: answerContext(p, sources, now)}}
+		messages = append(messages, history...)
+		messages = append(messages, ai.ChatMessage{Role: ai.ChatRoleUser, Content: question})
+		req := &ai.ChatRequest{
+			Messages:    messages,
+			MaxTokens:   answerMaxTokens,
+			Temperature: 0.2,
+		}
+
+		var resp *ai.ChatResponse
+		var err error
+		var written strings.Builder
+		if onDelta != nil {
+			resp, err = s.chatter.ChatStream(ctx, req, func(delta string) error {
+				written.WriteString(delta)
+				return onDelta(delta)
+			})
+		} else {
+			resp, err = s.chatter.Chat(ctx, req)
+		}
+
+		switch {
+		case err == nil:
+			message.Content = resp.Content
+			message.Model = resp.Model
+			return cited(resp.Content, sources)
+		case written.Len() > 0:
+			logger.LogError(fmt.Sprintf("Assistant answer interrupted: %v", err))
+			message.Content = strings.TrimSpace(written.String())
+			message.Error = "The answer was interrupted"
+			return cited(message.Content, sources)
+		}
+		logger.LogError(fmt.Sprintf("Assistant answer failed, listing records instead: %v", err))
+	}
+
+	message.Content = fallbackAnswer(p, sources, now.Location())
+	if onDelta != nil {
+		if err := onDelta(message.Content); err != nil {
+			message.Error = "The answer was interrupted"
+		}
+	}
+	return sources
+}
+
+// answerContext is the system prompt of an answer, with the lookup results
+// and the numbered records the answer can cite
+func answerContext(p *plan, sources []*Source, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, answerPrompt, now.Format("Monday 2 January 2006 15:04"), now.Location())
+
+	if len(p.Tools) > 0 {
+		b.WriteString("\n\nLookups:")
+		for _, call := range p.Tools {
+			fmt.Fprintf(&b, "\n- %s: %s", call.Name, call.Result)
+		}
+	}
+
+	if len(sources) == 0 {
+		b.WriteString("\n\nNo records match the question.")
+		return b.String()
+	}
+	b.WriteString("\n\nRecords:")
+	for _, source := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s, %s: %s", source.Ref, source.Type, displayDate(source.Date, now.Location()), source.Title)
+		if source.Snippet != "" {
+			fmt.Fprintf(&b, "\n%s", source.Snippet)
+		}
+	}
+	return b.String()
+}
+
+// fallbackAnswer lists what the lookups and the search found
+func fallbackAnswer(p *plan, sources []*Source, loc *time.Location) string {
+	if len(p.Tools) == 0 && len(sources) == 0 {
+		return "I couldn't find anything in your records about that."
+	}
+
+	var b strings.Builder
+	b.WriteString("I can't write an answer right now, but this is what I found in your records:")
+	for _, call := range p.Tools {
+		fmt.Fprintf(&b, "\n\n%s.", call.Result)
+	}
+	if len(sources) > 0 {
+		b.WriteString("\n")
+		for _, source := range sources {
+			fmt.Fprintf(&b, "\n[%d] %s (%s, %s)", source.Ref, source.Title, source.Type, displayDate(source.Date, loc))
+		}
+	}
+	return b.String()
+}
+
+// cited returns the records an answer cites, in the order it first cites them
+func cited(answer string, sources []*Source) []*Source {
+	byRef := make(map[int]*Source, len(sources))
+	for _, source := range sources {
+		byRef[source.Ref] = source
+	}
+
+	result := []*Source{}
+	seen := map[int]bool{}
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, part := range strings.Split(match[1], ",") {
+			ref, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || seen[ref] || byRef[ref] == nil {
+				continue
+			}
+			seen[ref] = true
+			result = append(result, byRef[ref])
+		}
+	}
+	return result
+}
+
+// displayDate formats a stored record date in the time zone of the user
+func displayDate(value string, loc *time.Location) string {
+	date, err := types.ParseDateTime(value)
+	if err != nil || date.IsZero() {
+		return "undated"
+	}
+	t := date.Time().In(loc)
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("Mon 2 Jan 2006")
+	}
+	return t.Format("Mon 2 Jan 2006 15:04")
+}
+
+// conversation loads a conversation of the user, or starts a new one titled
+// after the question
+func (s *Service) conversation(userID string, conversationID string, question string) (*models.AssistantConversation, error) {
+	if conversationID != "" {
+		conversation, err := query.FindById[*models.AssistantConversation](conversationID)
+		if err != nil || conversation.User != userID {
+			return nil, ErrNotFound
+		}
+		return conversation, nil
+	}
+
+	title := strings.Join(strings.Fields(question), " ")
+	if runes := []rune(title); len(runes) > titleLength {
+		title = string(runes[:titleLength]) + "..."
+	}
+
+	conversation := &models.AssistantConversation{
+		User:          userID,
+		Title:         title,
+		LastMessageAt: types.NowDateTime(),
+	}
+	conversation.Id = util.GenerateRandomId()
+	conversation.RefreshCreated()
+	conversation.RefreshUpdated()
+	if err := query.SaveRecord(conversation); err != nil {
+		return nil, fmt.Errorf("error creating conversation: %v", err)
+	}
+	return conversation, nil
+}
+
+// history returns the latest turns of a conversation as chat messages,
+// alternating between the user and the assistant as chat APIs expect
+func (s *Service) history(conversationID string) ([]ai.ChatMessage, error) {
+	var messages []*models.AssistantMessage
+	err := query.BaseQuery[*models.AssistantMessage]().
+		AndWhere(dbx.HashExp{"conversation": conversationID}).
+		OrderBy("created DESC").
+		Limit(historyMessages).
+		All(&messages)
+	if err != nil {
+		return nil, fmt.Errorf("error loading conversation: %v", err)
+	}
+
+	var history []ai.ChatMessage
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		if strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		turn := ai.ChatMessage{Role: message.Role, Content: message.Content}
+		if len(history) > 0 && history[len(history)-1].Role == turn.Role {
+			// A question left without an answer is replaced by the next one
+			history[len(history)-1] = turn
+			continue
+		}
+		if len(history) == 0 && turn.Role != ai.ChatRoleUser {
+			continue
+		}
+		history = append(history, turn)
+	}
+
+	// The question being asked follows, so history ends with an answer
+	if len(history) > 0 && history[len(history)-1].Role == ai.ChatRoleUser {
+		history = history[:len(history)-1]
+	}
+	return history, nil
+}
+
+// Conversations returns the conversations of the user, latest first
+func (s *Service) Conversations(userID string, limit int, offset int) ([]*models.AssistantConversation, error) {
+	conversations := []*models.AssistantConversation{}
+	err := query.BaseQuery[*models.AssistantConversation]().
+		AndWhere(dbx.HashExp{"user": userID}).
+		OrderBy("last_message_at DESC").
+		Limit(int64(limit)).
+		Offset(int64(offset)).
+		All(&conversations)
+	if err != nil {
+		return nil, fmt.Errorf("error loading conversations: %v", err)
+	}
+	return conversations, nil
+}
+
+// Conversation returns a conversation of the user with its messages, oldest
+// first
+func (s *Service) Conversation(userID string, conversationID string) (*models.AssistantConversation, []*models.AssistantMessage, error) {
+	conversation, err := query.FindById[*models.AssistantConversation](conversationID)
+	if err != nil || conversation.User != userID {
+		return nil, nil, ErrNotFound
+	}
+
+	messages := []*models.AssistantMessage{}
+	err = query.BaseQuery[*models.AssistantMessage]().
+		AndWhere(dbx.HashExp{"conversation": conversation.Id, "user": userID}).
+		OrderBy("created ASC").
+		All(&messages)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading messages: %v", err)
+	}
+	return conversation, messages, nil
+}
+
+// DeleteConversation deletes a conversation of the user with its messages
+func (s *Service) DeleteConversation(userID string, conversationID string) error {
+	record, err := s.app.FindRecordById("assistant_conversations", conversationID)
+	if err != nil || record.GetString("user") != userID {
+		return ErrNotFound
+	}
+	// Deleting the record rather than the model cascades to the messages
+	return s.app.Delete(record)
+}
+
+func saveMessage(message *models.AssistantMessage) error {
+	message.Id = util.GenerateRandomId()
+	message.RefreshCreated()
+	message.RefreshUpdated()
+	if err := query.SaveRecord(message); err != nil {
+		return fmt.Errorf("error saving message: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package assistant
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+)
+
+// Lookups the assistant can run to answer a question
+const (
+	ToolCalendarEvents = "calendar_events"
+	ToolSpendingTotal  = "spending_total"
+	ToolTasks          = "tasks"
+)
+
+const (
+	defaultToolLimit = 10
+	maxToolLimit     = 25
+	// Largest expenses cited with a spending total
+	spendingSourceLimit = 5
+	// Categories listed with a spending total
+	spendingCategoryLimit = 5
+)
+
+// toolDescriptions explains the lookups to the planning model
+var toolDescriptions = map[string]string{
+	ToolCalendarEvents: "calendar events starting in a date range, optionally only those mentioning a person, place or topic; use it for questions about meetings, appointments and schedules",
+	ToolSpendingTotal:  "total money spent in a date range, optionally only for a category or merchant; use it for questions about spending and expenses",
+	ToolTasks:          "tasks due in a date range, optionally only those about a topic; use it for questions about tasks, todos and deadlines",
+}
+
+// toolTypes maps the lookups listing records to the type they list
+var toolTypes = map[string]string{
+	ToolCalendarEvents: SourceCalendar,
+	ToolTasks:          SourceTask,
+}
+
+// Words that say which kind of record is asked about rather than which one.
+// They are left out of lookup filters, no event is titled "meeting".
+var genericToolWords = map[string]bool{
+	"meeting": true, "meetings": true, "meet": true, "event": true, "events": true,
+	"appointment": true, "appointments": true, "calendar": true, "schedule": true,
+	"scheduled": true, "spend": true, "spent": true, "spending": true, "expense": true,
+	"expenses": true, "cost": true, "costs": true, "money": true, "paid": true,
+	"pay": true, "total": true, "task": true, "tasks": true, "todo": true,
+	"todos": true, "due": true, "deadline": true, "deadlines": true,
+}
+
+// ToolCall is a lookup run to answer a question
+type ToolCall struct {
+	Name   string `json:"name"`
+	Query  string `json:"query,omitempty"` // Words records must mention one of, any record when empty
+	From   string `json:"from,omitempty"`  // YYYY-MM-DD or RFC 3339
+	To     string `json:"to,omitempty"`    // YYYY-MM-DD or RFC 3339, inclusive
+	Limit  int    `json:"limit,omitempty"`
+	Result string `json:"result,omitempty"` // What the lookup found, as given to the model
+}
+
+// isValidTool reports whether a lookup of the name exists
+func isValidTool(name string) bool {
+	_, ok := toolDescriptions[name]
+	return ok
+}
+
+// dateRange reads the range of a lookup. Dates without a time cover the
+// whole day.
+func (c *ToolCall) dateRange(loc *time.Location) dateRange {
+	var r dateRange
+	if from, ok := parseToolTime(c.From, loc); ok {
+		r.From = from
+	}
+	if to, ok := parseToolTime(c.To, loc); ok {
+		if _, isDay := parseDay(c.To, loc); isDay {
+			to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		r.To = to
+	}
+	return r
+}
+
+func parseToolTime(value string, loc *time.Location) (time.Time, bool) {
+	if day, ok := parseDay(value, loc); ok {
+		return day, true
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(value)); err == nil {
+		return t.In(loc), true
+	}
+	return time.Time{}, false
+}
+
+// limit is the number of records the lookup returns
+func (c *ToolCall) limit() int {
+	if c.Limit <= 0 {
+		return defaultToolLimit
+	}
+	return min(c.Limit, maxToolLimit)
+}
+
+// runTool runs a lookup over the records of the user, stores what it found in
+// call.Result and returns the records it is based on
+func (s *Service) runTool(userID string, call *ToolCall, now time.Time) ([]*Source, error) {
+	switch call.Name {
+	case ToolCalendarEvents:
+		return s.calendarEvents(userID, call, now)
+	case ToolSpendingTotal:
+		return s.spendingTotal(userID, call, now)
+	case ToolTasks:
+		return s.tasks(userID, call, now)
+	}
+	return nil, fmt.Errorf("unknown tool: %s", call.Name)
+}
+
+// calendarEvents lists the events starting in the range, soonest first
+func (s *Service) calendarEvents(userID string, call *ToolCall, now time.Time) ([]*Source, error) {
+	r := call.dateRange(now.Location())
+	if r.From.IsZero() && r.To.IsZero() {
+		r = dateRange{From: now, To: now.AddDate(0, 0, 30)}
+	}
+
+	q := query.BaseQuery[*models.CalendarEvent]().
+		AndWhere(dbx.HashExp{"user": userID}).
+		AndWhere(dbx.NewExp("status != 'cancelled'"))
+	q = whereRange(q, "start", r)
+	if condition := keywordCondition(call.Query, []string{"summary", "description", "location", "organizer", "organizer_email", "creator", "creator_email"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var events []*models.CalendarEvent
+	if err := q.OrderBy("start ASC").Limit(int64(call.limit())).All(&events); err != nil {
+		return nil, fmt.Errorf("error loading calendar events: %v", err)
+	}
+
+	sources := make([]*Source, 0, len(events))
+	for _, event := range events {
+		start := event.Start.Time().In(now.Location())
+		end := event.End.Time().In(now.Location())
+
+		when := start.Format("Mon 2 Jan 2006 15:04") + "-" + end.Format("15:04")
+		if event.IsDayEvent {
+			when = start.Format("Mon 2 Jan 2006") + ", all day"
+		}
+		details := []string{when}
+		if event.Location != "" {
+			details = append(details, "at "+event.Location)
+		}
+		if event.Organizer != "" || event.OrganizerEmail != "" {
+			details = append(details, "organized by "+strings.TrimSpace(event.Organizer+" "+event.OrganizerEmail))
+		}
+		if description := strings.TrimSpace(event.Description); description != "" {
+			details = append(details, description)
+		}
+
+		sources = append(sources, &Source{
+			Type:     SourceCalendar,
+			RecordID: event.Id,
+			Title:    event.Summary,
+			Snippet:  shorten(strings.Join(details, ", ")),
+			Date:     event.Start.String(),
+		})
+	}
+
+	call.Result = fmt.Sprintf("%d calendar %s %s%s", len(events), plural(len(events), "event", "events"), r, matching(call.Query))
+	return sources, nil
+}
+
+// spendingTotal adds up the expenses in the range per currency and category
+func (s *Service) spendingTotal(userID string, call *ToolCall, now time.Time) ([]*Source, error) {
+	r := call.dateRange(now.Location())
+	if r.From.IsZero() && r.To.IsZero() {
+		// This month so far
+		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		r = dateRange{From: first, To: now}
+	}
+
+	q := query.BaseQuery[*models.Expense]().
+		AndWhere(dbx.HashExp{"user": userID})
+	q = whereRange(q, "spent_at", r)
+	if condition := keywordCondition(call.Query, []string{"category", "merchant", "note"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var expenses []*models.Expense
+	if err := q.OrderBy("amount DESC").All(&expenses); err != nil {
+		return nil, fmt.Errorf("error loading expenses: %v", err)
+	}
+
+	if len(expenses) == 0 {
+		call.Result = fmt.Sprintf("No expenses recorded %s%s", r, matching(call.Query))
+		return nil, nil
+	}
+
+	totals := map[string]float64{}
+	categories := map[string]float64{}
+	for _, expense := range expenses {
+		currency := strings.ToUpper(expense.Currency)
+		totals[currency] += expense.Amount
+		category := expense.Category
+		if category == "" {
+			category = "uncategorized"
+		}
+		categories[category+" "+currency] += expense.Amount
+	}
+
+	amounts := make([]string, 0, len(totals))
+	for currency, total := range totals {
+		amounts = append(amounts, strings.TrimSpace(formatAmount(total)+" "+currency))
+	}
+	sort.Strings(amounts)
+
+	type categoryTotal struct {
+		Name  string
+		Total float64
+	}
+	byCategory := make([]categoryTotal, 0, len(categories))
+	for name, total := range categories {
+		byCategory = append(byCategory, categoryTotal{Name: name, Total: total})
+	}
+	sort.Slice(byCategory, func(i, j int) bool {
+		if byCategory[i].Total != byCategory[j].Total {
+			return byCategory[i].Total > byCategory[j].Total
+		}
+		return byCategory[i].Name < byCategory[j].Name
+	})
+	breakdown := make([]string, 0, spendingCategoryLimit)
+	for _, category := range byCategory[:min(len(byCategory), spendingCategoryLimit)] {
+		name, currency, _ := strings.Cut(category.Name, " ")
+		breakdown = append(breakdown, strings.TrimSpace(name+" "+formatAmount(category.Total)+" "+currency))
+	}
+
+	call.Result = fmt.Sprintf("Spent %s in %d %s %s%s; largest categories: %s",
+		strings.Join(amounts, " and "), len(expenses), plural(len(expenses), "expense", "expenses"),
+		r, matching(call.Query), strings.Join(breakdown, ", "))
+
+	// The largest expenses back the total up
+	sources := make([]*Source, 0, spendingSourceLimit)
+	for _, expense := range expenses[:min(len(expenses), spendingSourceLimit)] {
+		title := expense.Merchant
+		if title == "" {
+			title = expense.Category
+		}
+		details := []string{strings.TrimSpace(formatAmount(expense.Amount) + " " + strings.ToUpper(expense.Currency))}
+		if expense.Category != "" {
+			details = append(details, expense.Category)
+		}
+		if expense.Note != "" {
+			details = append(details, expense.Note)
+		}
+		sources = append(sources, &Source{
+			Type:     SourceExpense,
+			RecordID: expense.Id,
+			Title:    title,
+			Snippet:  shorten(strings.Join(details, ", ")),
+			Date:     expense.SpentAt.String(),
+		})
+	}
+
+	return sources, nil
+}
+
+// tasks lists the tasks due in the range, soonest first
+func (s *Service) tasks(userID string, call *ToolCall, now time.Time) ([]*Source, error) {
+	r := call.dateRange(now.Location())
+	if r.From.IsZero() && r.To.IsZero() {
+		r = dateRange{From: startOfDay(now).AddDate(0, 0, -7), To: now.AddDate(0, 0, 30)}
+	}
+
+	q := query.BaseQuery[*models.Task]().
+		AndWhere(dbx.HashExp{"user": userID})
+	q = whereRange(q, "due", r)
+	if condition := keywordCondition(call.Query, []string{"title", "description", "category"}); condition != nil {
+		q = q.AndWhere(condition)
+	}
+
+	var tasks []*models.Task
+	if err := q.OrderBy("due ASC").Limit(int64(call.limit())).All(&tasks); err != nil {
+		return nil, fmt.Errorf("error loading tasks: %v", err)
+	}
+
+	sources := make([]*Source, 0, len(tasks))
+	for _, task := range tasks {
+		sources = append(sources, taskSource(task, now.Location()))
+	}
+
+	call.Result = fmt.Sprintf("%d %s due %s%s", len(tasks), plural(len(tasks), "task", "tasks"), r, matching(call.Query))
+	return sources, nil
+}
+
+func taskSource(task *models.Task, loc *time.Location) *Source {
+	details := []string{"due " + task.Due.Time().In(loc).Format("Mon 2 Jan 2006 15:04")}
+	if task.Category != "" {
+		details = append(details, task.Category)
+	}
+	if description := strings.TrimSpace(task.Description); description != "" {
+		details = append(details, description)
+	}
+
+	return &Source{
+		Type:     SourceTask,
+		RecordID: task.Id,
+		Title:    task.Title,
+		Snippet:  shorten(strings.Join(details, ", ")),
+		Date:     task.Due.String(),
+	}
+}
+
+// whereRange limits a query to records whose date column falls in the range
+func whereRange(q *dbx.SelectQuery, column string, r dateRange) *dbx.SelectQuery {
+	if !r.From.IsZero() {
+		q = q.AndWhere(dbx.NewExp(column+" >= {:from}", dbx.Params{"from": formatDate(r.From)}))
+	}
+	if !r.To.IsZero() {
+		q = q.AndWhere(dbx.NewExp(column+" <= {:to}", dbx.Params{"to": formatDate(r.To)}))
+	}
+	return q
+}
+
+// keywordCondition matches records mentioning any of the words of text in
+// any of the columns, nil when text has no words to look for
+func keywordCondition(text string, columns []string) dbx.Expression {
+	var conditions []dbx.Expression
+	for _, word := range keywords(text) {
+		if genericToolWords[word] {
+			continue
+		}
+		for _, column := range columns {
+			conditions = append(conditions, dbx.Like(column, word))
+		}
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return dbx.Or(conditions...)
+}
+
+// formatDate formats a time the way record dates are stored, so they compare
+// as strings
+func formatDate(t time.Time) string {
+	date, _ := types.ParseDateTime(t)
+	return date.String()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func matching(text string) string {
+	var words []string
+	for _, word := range keywords(text) {
+		if !genericToolWords[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" mentioning %q", strings.Join(words, " "))
+}
+
+func plural(n int, singular string, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
@@ -0,0 +1,346 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	// Images stored per archived page, further images keep their remote URL
+	maxArchiveImages = 30
+	// Attempts before an archive is marked failed
+	maxArchiveAttempts = 3
+	// Archives processed per run
+	archiveBatchSize    = 10
+	archivePageTimeout  = 2 * time.Minute
+	archiveImageTimeout = 30 * time.Second
+)
+
+// Archiver keeps offline snapshots of the pages linked by saved feed items:
+// the cleaned article HTML with its images stored as files, and a plain text
+// version for search
+type Archiver struct {
+	fetcher    *providers.HTMLContentFetcher
+	quotaBytes int64
+	running    atomic.Bool
+}
+
+func NewArchiver(cfg config.FeedConfig) *Archiver {
+	return &Archiver{
+		fetcher:    providers.NewHTMLContentFetcher(archiveImageTimeout),
+		quotaBytes: int64(cfg.ArchiveQuotaMB) * 1024 * 1024,
+	}
+}
+
+// QuotaBytes returns the archive storage each user may use
+func (a *Archiver) QuotaBytes() int64 {
+	return a.quotaBytes
+}
+
+// RegisterArchiveHooks queues an archive whenever a feed item becomes saved.
+// The page itself is downloaded later by RunPending.
+func RegisterArchiveHooks(app core.App) {
+	app.OnModelAfterUpdateSuccess((&models.FeedItem{}).TableName()).BindFunc(func(e *core.ModelEvent) error {
+		record, ok := e.Model.(*core.Record)
+		if ok && (record.GetString("status") != models.StatusSaved || record.Original().GetString("status") == models.StatusSaved) {
+			return e.Next()
+		}
+
+		item, err := query.FindById[*models.FeedItem](fmt.Sprint(e.Model.PK()))
+		if err != nil || item.Status != models.StatusSaved {
+			return e.Next()
+		}
+
+		// Saving a story marks its other copies saved too, one snapshot is enough
+		if item.ClusterID != "" && !item.IsClusterPrimary {
+			return e.Next()
+		}
+
+		if _, err := QueueArchive(item, false); err != nil {
+			logger.LogError(fmt.Sprintf("Error queueing archive for feed item %s: %v", item.Id, err))
+		}
+		return e.Next()
+	})
+}
+
+// QueueArchive creates a pending archive for an item. An existing archive is
+// left alone unless refresh is set, then it is downloaded again.
+func QueueArchive(item *models.FeedItem, refresh bool) (*models.FeedArchive, error) {
+	if item.URL == "" {
+		return nil, fmt.Errorf("feed item has no URL")
+	}
+
+	archive, err := query.FindByFilter[*models.FeedArchive](map[string]interface{}{"item": item.Id})
+	if err == nil {
+		if !refresh || archive.Status == models.ArchiveStatusPending {
+			return archive, nil
+		}
+		if err := query.UpdateRecord[*models.FeedArchive](archive.Id, map[string]interface{}{
+			"status":   models.ArchiveStatusPending,
+			"url":      item.URL,
+			"attempts": 0,
+			"error":    "",
+		}); err != nil {
+			return nil, err
+		}
+		archive.Status = models.ArchiveStatusPending
+		archive.Attempts = 0
+		archive.Error = ""
+		return archive, nil
+	}
+
+	archive = &models.FeedArchive{
+		User:   item.User,
+		Item:   item.Id,
+		URL:    item.URL,
+		Title:  item.Title,
+		Status: models.ArchiveStatusPending,
+		Images: types.JSONArray[string]{},
+	}
+	archive.Id = util.GenerateRandomId()
+	archive.RefreshCreated()
+	archive.RefreshUpdated()
+	if err := query.SaveRecord(archive); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// Trigger processes the pending archives in the background
+func (a *Archiver) Trigger() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		if err := a.RunPending(ctx); err != nil {
+			logger.LogError(fmt.Sprintf("Error archiving feed items: %v", err))
+		}
+	}()
+}
+
+// RunPending archives the oldest pending pages. Runs that overlap a previous
+// one are skipped.
+func (a *Archiver) RunPending(ctx context.Context) error {
+	if !a.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer a.running.Store(false)
+
+	var archives []*models.FeedArchive
+	err := query.BaseQuery[*models.FeedArchive]().
+		AndWhere(dbx.HashExp{"status": models.ArchiveStatusPending}).
+		OrderBy("created ASC").
+		Limit(archiveBatchSize).
+		All(&archives)
+	if err != nil {
+		return fmt.Errorf("error loading pending archives: %v", err)
+	}
+
+	for _, archive := range archives {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := a.Archive(ctx, archive); err != nil {
+			logger.LogError(fmt.Sprintf("Error archiving %s: %v", archive.URL, err))
+		}
+	}
+
+	return nil
+}
+
+// Archive downloads the page of an archive, stores its images and saves the
+// snapshot. Failed downloads are retried by later runs until the attempts run
+// out. Pages and images only come from public addresses, archive URLs are
+// whatever the user saved.
+func (a *Archiver) Archive(ctx context.Context, archive *models.FeedArchive) error {
+	app := store.GetDao()
+	ctx = providers.WithPublicAddressesOnly(ctx)
+
+	record, err := app.FindRecordById(archive.TableName(), archive.Id)
+	if err != nil {
+		return err
+	}
+	attempts := record.GetInt("attempts") + 1
+	record.Set("attempts", attempts)
+
+	pageCtx, cancel := context.WithTimeout(ctx, archivePageTimeout)
+	defer cancel()
+
+	article, err := a.fetcher.FetchFullArticle(pageCtx, archive.URL)
+	if err != nil {
+		status := models.ArchiveStatusPending
+		if attempts >= maxArchiveAttempts {
+			status = models.ArchiveStatusFailed
+		}
+		record.Set("status", status)
+		record.Set("error", err.Error())
+		if saveErr := app.Save(record); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	htmlContent, images := a.storeImages(pageCtx, article.Content)
+
+	size := int64(len(htmlContent) + len(article.Text))
+	for _, image := range images {
+		size += image.Size
+	}
+
+	usage, err := a.Usage(archive.User, archive.Id)
+	if err != nil {
+		return err
+	}
+
+	// Keep the text when the images don't fit, they still load while online
+	if usage+size > a.quotaBytes && len(images) > 0 {
+		htmlContent = article.Content
+		images = nil
+		size = int64(len(htmlContent) + len(article.Text))
+	}
+	if usage+size > a.quotaBytes {
+		record.Set("status", models.ArchiveStatusQuotaExceeded)
+		record.Set("error", fmt.Sprintf("archive storage quota of %d MB exceeded", a.quotaBytes/1024/1024))
+		return app.Save(record)
+	}
+
+	if article.Title != "" {
+		record.Set("title", article.Title)
+	}
+	record.Set("html", htmlContent)
+	record.Set("text", article.Text)
+	if images != nil {
+		record.Set("images", images)
+	} else {
+		record.Set("images", []string{})
+	}
+	record.Set("size", size)
+	record.Set("status", models.ArchiveStatusArchived)
+	record.Set("error", "")
+	record.Set("archived_at", types.NowDateTime())
+
+	return app.Save(record)
+}
+
+// storeImages downloads the images of the article and points them at the
+// file names they will be stored under. Images that fail to download keep
+// their remote URL.
+func (a *Archiver) storeImages(ctx context.Context, content string) (string, []*filesystem.File) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content, nil
+	}
+
+	stored := make(map[string]string)
+	files := make([]*filesystem.File, 0)
+
+	doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		src := img.AttrOr("src", "")
+		if name, ok := stored[src]; ok {
+			img.SetAttr("src", name)
+			return
+		}
+		if len(files) >= maxArchiveImages || !strings.HasPrefix(src, "http") {
+			return
+		}
+
+		imageCtx, cancel := context.WithTimeout(ctx, archiveImageTimeout)
+		defer cancel()
+
+		data, contentType, err := a.fetcher.FetchResource(imageCtx, src)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error archiving image %s: %v", src, err))
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !strings.HasPrefix(mediaType, "image/") {
+			return
+		}
+		ext := ""
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[len(exts)-1]
+		}
+
+		file, err := filesystem.NewFileFromBytes(data, fmt.Sprintf("image%d%s", len(files)+1, ext))
+		if err != nil {
+			return
+		}
+
+		stored[src] = file.Name
+		files = append(files, file)
+		img.SetAttr("src", file.Name)
+	})
+
+	if len(files) == 0 {
+		return content, nil
+	}
+
+	rendered, err := doc.Find("body").Html()
+	if err != nil {
+		return content, nil
+	}
+	return strings.TrimSpace(rendered), files
+}
+
+// Usage returns the bytes used by the archives of a user, leaving out the
+// given archive so it can be replaced
+func (a *Archiver) Usage(userID string, excludeID string) (int64, error) {
+	var usage struct {
+		Total int64 `db:"total"`
+	}
+	err := store.GetDao().DB().
+		NewQuery("SELECT COALESCE(SUM(size), 0) AS total FROM feed_archives WHERE user = {:user} AND id != {:id}").
+		Bind(dbx.Params{"user": userID, "id": excludeID}).
+		One(&usage)
+	if err != nil {
+		return 0, fmt.Errorf("error computing archive usage: %v", err)
+	}
+	return usage.Total, nil
+}
+
+// ArchiveImageURLs maps the stored image names of an archive to URLs built by
+// fileURL, leaving other sources untouched
+func ArchiveImageURLs(archive *models.FeedArchive, fileURL func(name string) string) (string, error) {
+	if len(archive.Images) == 0 {
+		return archive.HTML, nil
+	}
+
+	stored := make(map[string]bool, len(archive.Images))
+	for _, name := range archive.Images {
+		stored[name] = true
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(archive.HTML))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		if src := img.AttrOr("src", ""); stored[src] {
+			img.SetAttr("src", fileURL(src))
+		}
+	})
+
+	rendered, err := doc.Find("body").Html()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rendered), nil
+}
@@ -0,0 +1,311 @@
+package feed
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/store"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Archive export formats
+const (
+	ArchiveExportEPUB = "epub"
+	ArchiveExportZIP  = "zip"
+)
+
+const archiveExportTitle = "Saved articles"
+
+// exportedImage is an archived image and its path inside the export
+type exportedImage struct {
+	filesPath string
+	path      string
+}
+
+// exportedArticle is an archived article with the paths of its export files
+type exportedArticle struct {
+	archive *models.FeedArchive
+	path    string
+	images  map[string]exportedImage // By stored file name
+}
+
+// ExportArchives writes the archived articles of a user, newest first, as an
+// EPUB book or as a ZIP of HTML files with their images
+func ExportArchives(userID string, format string, w io.Writer) error {
+	if format != ArchiveExportEPUB && format != ArchiveExportZIP {
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	var archives []*models.FeedArchive
+	err := query.BaseQuery[*models.FeedArchive]().
+		AndWhere(dbx.HashExp{"user": userID, "status": models.ArchiveStatusArchived}).
+		OrderBy("archived_at DESC").
+		All(&archives)
+	if err != nil {
+		return fmt.Errorf("error loading archives: %v", err)
+	}
+
+	app := store.GetDao()
+	collection, err := app.FindCollectionByNameOrId((&models.FeedArchive{}).TableName())
+	if err != nil {
+		return err
+	}
+
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	articles := make([]*exportedArticle, 0, len(archives))
+	for i, archive := range archives {
+		article := &exportedArticle{
+			archive: archive,
+			images:  make(map[string]exportedImage, len(archive.Images)),
+		}
+		for _, name := range archive.Images {
+			image := exportedImage{filesPath: collection.Id + "/" + archive.Id + "/" + name}
+			if format == ArchiveExportEPUB {
+				image.path = "images/" + archive.Id + "-" + name
+			} else {
+				image.path = "images/" + archive.Id + "/" + name
+			}
+			article.images[name] = image
+		}
+		if format == ArchiveExportEPUB {
+			article.path = fmt.Sprintf("text/%04d.xhtml", i+1)
+		} else {
+			article.path = fmt.Sprintf("articles/%04d.html", i+1)
+		}
+		articles = append(articles, article)
+	}
+
+	zw := zip.NewWriter(w)
+	if format == ArchiveExportEPUB {
+		err = writeEPUB(zw, fsys, articles)
+	} else {
+		err = writeArchiveZIP(zw, fsys, articles)
+	}
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func writeArchiveZIP(zw *zip.Writer, fsys *filesystem.System, articles []*exportedArticle) error {
+	var index strings.Builder
+	index.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>" + archiveExportTitle + "</title></head>\n<body>\n")
+	index.WriteString("<h1>" + archiveExportTitle + "</h1>\n<ul>\n")
+
+	for _, article := range articles {
+		archive := article.archive
+		content, err := rewriteArchiveHTML(archive.HTML, article.images, "../", false)
+		if err != nil {
+			return err
+		}
+
+		page := "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>" + html.EscapeString(archive.Title) + "</title></head>\n<body>\n" +
+			articleHeader(archive) + content + "\n</body>\n</html>\n"
+		if err := writeZipFile(zw, article.path, []byte(page)); err != nil {
+			return err
+		}
+		if err := writeArchiveImages(zw, fsys, article, ""); err != nil {
+			return err
+		}
+
+		index.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> <small>%s</small></li>\n",
+			article.path, html.EscapeString(archive.Title), archive.ArchivedAt.Time().Format("2006-01-02")))
+	}
+
+	index.WriteString("</ul>\n</body>\n</html>\n")
+	return writeZipFile(zw, "index.html", []byte(index.String()))
+}
+
+func writeEPUB(zw *zip.Writer, fsys *filesystem.System, articles []*exportedArticle) error {
+	// The mimetype has to be the first entry and stored uncompressed
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	container := `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+	if err := writeZipFile(zw, "META-INF/container.xml", []byte(container)); err != nil {
+		return err
+	}
+
+	var manifest, spine, nav strings.Builder
+	for i, article := range articles {
+		archive := article.archive
+		content, err := rewriteArchiveHTML(archive.HTML, article.images, "../", true)
+		if err != nil {
+			return err
+		}
+
+		page := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head><title>` + html.EscapeString(archive.Title) + `</title></head>
+<body>
+` + articleHeader(archive) + content + `
+</body>
+</html>
+`
+		if err := writeZipFile(zw, "OEBPS/"+article.path, []byte(page)); err != nil {
+			return err
+		}
+		if err := writeArchiveImages(zw, fsys, article, "OEBPS/"); err != nil {
+			return err
+		}
+
+		id := fmt.Sprintf("article%d", i+1)
+		manifest.WriteString(fmt.Sprintf("    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", id, article.path))
+		spine.WriteString(fmt.Sprintf("    <itemref idref=\"%s\"/>\n", id))
+		nav.WriteString(fmt.Sprintf("      <li><a href=\"%s\">%s</a></li>\n", article.path, html.EscapeString(archive.Title)))
+
+		for j, name := range archive.Images {
+			image := article.images[name]
+			mediaType := mime.TypeByExtension(path.Ext(image.path))
+			if mediaType == "" {
+				mediaType = "application/octet-stream"
+			}
+			manifest.WriteString(fmt.Sprintf("    <item id=\"%s-image%d\" href=\"%s\" media-type=\"%s\"/>\n", id, j+1, image.path, mediaType))
+		}
+	}
+
+	navPage := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>` + archiveExportTitle + `</title></head>
+<body>
+  <nav epub:type="toc">
+    <h1>` + archiveExportTitle + `</h1>
+    <ol>
+` + nav.String() + `    </ol>
+  </nav>
+</body>
+</html>
+`
+	if err := writeZipFile(zw, "OEBPS/nav.xhtml", []byte(navPage)); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	opf := `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">urn:dashboard:archive-export:` + fmt.Sprint(now.Unix()) + `</dc:identifier>
+    <dc:title>` + archiveExportTitle + `</dc:title>
+    <dc:language>en</dc:language>
+    <meta property="dcterms:modified">` + now.Format("2006-01-02T15:04:05Z") + `</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+` + manifest.String() + `  </manifest>
+  <spine>
+` + spine.String() + `  </spine>
+</package>
+`
+	return writeZipFile(zw, "OEBPS/content.opf", []byte(opf))
+}
+
+// articleHeader renders the title and original link above an article
+func articleHeader(archive *models.FeedArchive) string {
+	header := "<h1>" + html.EscapeString(archive.Title) + "</h1>\n"
+	if archive.URL != "" {
+		header += "<p><a href=\"" + html.EscapeString(archive.URL) + "\">" + html.EscapeString(archive.URL) + "</a></p>\n"
+	}
+	return header
+}
+
+// rewriteArchiveHTML points the stored images of an article at their export
+// paths. Rendering through the HTML parser also balances the markup, which
+// makes it valid XHTML for EPUB.
+func rewriteArchiveHTML(content string, images map[string]exportedImage, prefix string, xhtml bool) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return "", fmt.Errorf("error parsing archived HTML: %v", err)
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			attrs := n.Attr[:0]
+			for _, attr := range n.Attr {
+				if n.DataAtom == atom.Img && attr.Key == "src" {
+					if image, ok := images[attr.Val]; ok {
+						attr.Val = prefix + image.path
+					}
+				}
+				// XHTML only allows plain attribute names
+				if xhtml && strings.ContainsAny(attr.Key, ":\"'<>=") {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			n.Attr = attrs
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	var b strings.Builder
+	for _, node := range nodes {
+		walk(node)
+		if err := html.Render(&b, node); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func writeArchiveImages(zw *zip.Writer, fsys *filesystem.System, article *exportedArticle, prefix string) error {
+	for _, name := range article.archive.Images {
+		image := article.images[name]
+		reader, err := fsys.GetFile(image.filesPath)
+		if err != nil {
+			// A missing file only costs the image, not the export
+			continue
+		}
+
+		w, err := zw.Create(prefix + image.path)
+		if err == nil {
+			_, err = io.Copy(w, reader)
+		}
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,88 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+func TestArchiveRefusesInternalAddress(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><article><h1>Admin</h1><p>Internal dashboard</p></article></body></html>`))
+	}))
+	defer server.Close()
+
+	source := &models.FeedSource{
+		User:     testUserID,
+		Name:     "Intranet",
+		Type:     models.SourceTypeRSS,
+		URL:      server.URL + "/feed.xml",
+		Config:   `{"url":"` + server.URL + `/feed.xml"}`,
+		IsActive: true,
+	}
+	source.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(source); err != nil {
+		t.Fatalf("saving source: %v", err)
+	}
+
+	item := &models.FeedItem{
+		User:       testUserID,
+		SourceID:   source.Id,
+		ExternalID: server.URL + "/admin",
+		Title:      "Internal dashboard",
+		URL:        server.URL + "/admin",
+		Status:     models.StatusSaved,
+	}
+	item.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(item); err != nil {
+		t.Fatalf("saving item: %v", err)
+	}
+
+	archive, err := QueueArchive(item, false)
+	if err != nil {
+		t.Fatalf("QueueArchive: %v", err)
+	}
+
+	archiver := NewArchiver(config.FeedConfig{ArchiveQuotaMB: 10})
+	err = archiver.Archive(context.Background(), archive)
+	if err == nil || !strings.Contains(err.Error(), providers.ErrNonPublicAddress.Error()) {
+		t.Fatalf("got %v, want the internal address to be refused", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("the internal server got %d requests", hits.Load())
+	}
+
+	saved, err := query.FindById[*models.FeedArchive](archive.Id)
+	if err != nil {
+		t.Fatalf("finding archive: %v", err)
+	}
+	if saved.Status != models.ArchiveStatusPending || saved.HTML != "" || saved.Attempts != 1 {
+		t.Errorf("got status %s, %d attempts and %d bytes of HTML", saved.Status, saved.Attempts, len(saved.HTML))
+	}
+}
+
+func TestArchiveRecordsAreServerOwned(t *testing.T) {
+	collection, err := store.GetDao().FindCollectionByNameOrId((&models.FeedArchive{}).TableName())
+	if err != nil {
+		t.Fatalf("finding collection: %v", err)
+	}
+	if collection.CreateRule != nil || collection.UpdateRule != nil {
+		t.Errorf("users may create or update archives, their size counts towards the quota")
+	}
+	if collection.DeleteRule == nil || *collection.DeleteRule != "@request.auth.id = user" {
+		t.Errorf("got delete rule %v, want owners to delete their archives", collection.DeleteRule)
+	}
+}
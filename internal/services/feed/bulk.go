@@ -0,0 +1,233 @@
+package feed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/store"
+)
+
+// Records loaded and saved per step of a bulk operation
+const bulkBatchSize = 200
+
+// BulkFilter selects the feed items of a bulk operation. Set conditions are
+// combined, an empty filter matches nothing.
+type BulkFilter struct {
+	ItemIDs    []string `json:"item_ids"`
+	SourceID   string   `json:"source_id"`
+	CategoryID string   `json:"category_id"` // Items in the category, directly or through their source
+	TagID      string   `json:"tag_id"`
+	Status     string   `json:"status"`
+	Search     string   `json:"search"` // Text the title contains
+	Before     string   `json:"before"` // Items stored at or before this time
+	After      string   `json:"after"`  // Items stored after this time
+}
+
+// BulkResult reports how many items a bulk operation matched and how many of
+// them it changed
+type BulkResult struct {
+	Matched int `json:"matched"`
+	Updated int `json:"updated"`
+}
+
+func (f *BulkFilter) isEmpty() bool {
+	return len(f.ItemIDs) == 0 && f.SourceID == "" && f.CategoryID == "" && f.TagID == "" &&
+		f.Status == "" && f.Search == "" && f.Before == "" && f.After == ""
+}
+
+// MarkAllRead marks the unread items of a source or category as read, up to
+// the given time or now. Passing the time the list was loaded leaves items
+// that arrived since unread.
+func MarkAllRead(userID string, filter BulkFilter) (*BulkResult, error) {
+	if filter.SourceID == "" && filter.CategoryID == "" {
+		return nil, fmt.Errorf("source_id or category_id is required")
+	}
+	if filter.Before == "" {
+		filter.Before = types.NowDateTime().String()
+	}
+	filter.Status = models.StatusUnread
+
+	return bulkUpdate(userID, filter, func(record *core.Record) bool {
+		record.Set("status", models.StatusRead)
+		return true
+	})
+}
+
+// BulkTag adds and removes tags on the matched items. The tags must belong to
+// the user.
+func BulkTag(userID string, filter BulkFilter, add []string, remove []string) (*BulkResult, error) {
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, fmt.Errorf("add or remove is required")
+	}
+	for _, tagID := range append(append([]string{}, add...), remove...) {
+		if _, err := query.FindByFilter[*models.Tag](map[string]interface{}{"id": tagID, "user": userID}); err != nil {
+			return nil, fmt.Errorf("tag not found: %s", tagID)
+		}
+	}
+
+	removed := make(map[string]bool, len(remove))
+	for _, tagID := range remove {
+		removed[tagID] = true
+	}
+
+	return bulkUpdate(userID, filter, func(record *core.Record) bool {
+		current := record.GetStringSlice("tags")
+		tags := make([]string, 0, len(current)+len(add))
+		seen := make(map[string]bool)
+		for _, tagID := range append(current, add...) {
+			if !removed[tagID] && !seen[tagID] {
+				seen[tagID] = true
+				tags = append(tags, tagID)
+			}
+		}
+
+		if sameStrings(current, tags) {
+			return false
+		}
+		record.Set("tags", tags)
+		return true
+	})
+}
+
+// BulkDismiss dismisses the matched items. Saved items are left alone.
+func BulkDismiss(userID string, filter BulkFilter) (*BulkResult, error) {
+	if filter.Status == models.StatusSaved {
+		return nil, fmt.Errorf("saved items can't be dismissed in bulk")
+	}
+
+	return bulkUpdate(userID, filter, func(record *core.Record) bool {
+		status := record.GetString("status")
+		if status == models.StatusSaved || status == models.StatusDismissed {
+			return false
+		}
+		record.Set("status", models.StatusDismissed)
+		return true
+	})
+}
+
+// bulkUpdate applies change to every item the filter matches and saves the
+// changed ones. Items are selected and saved in a single transaction, so items
+// stored or changed meanwhile are either handled as they are then or not at
+// all. Saving records keeps the search index, clusters and archives in sync
+// through their hooks, which run once the transaction is committed.
+func bulkUpdate(userID string, filter BulkFilter, change func(record *core.Record) bool) (*BulkResult, error) {
+	if filter.isEmpty() {
+		return nil, fmt.Errorf("filter matches no items, set at least one condition")
+	}
+
+	result := &BulkResult{}
+	err := store.GetDao().RunInTransaction(func(txApp core.App) error {
+		q, err := bulkQuery(txApp.DB(), userID, filter)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		if err := q.Column(&ids); err != nil {
+			return fmt.Errorf("error loading feed items: %v", err)
+		}
+		result.Matched = len(ids)
+
+		for start := 0; start < len(ids); start += bulkBatchSize {
+			end := min(start+bulkBatchSize, len(ids))
+			records, err := txApp.FindRecordsByIds((&models.FeedItem{}).TableName(), ids[start:end])
+			if err != nil {
+				return fmt.Errorf("error loading feed items: %v", err)
+			}
+			for _, record := range records {
+				if !change(record) {
+					continue
+				}
+				if err := txApp.Save(record); err != nil {
+					return fmt.Errorf("error updating feed items: %v", err)
+				}
+				result.Updated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// bulkQuery selects the IDs of the items matching a filter
+func bulkQuery(db dbx.Builder, userID string, filter BulkFilter) (*dbx.SelectQuery, error) {
+	q := db.
+		Select("id").
+		From((&models.FeedItem{}).TableName()).
+		Where(dbx.HashExp{"user": userID})
+
+	if len(filter.ItemIDs) > 0 {
+		q.AndWhere(dbx.HashExp{"id": toInterfaceSlice(filter.ItemIDs)})
+	}
+	if filter.SourceID != "" {
+		q.AndWhere(dbx.HashExp{"source_id": filter.SourceID})
+	}
+	if filter.CategoryID != "" {
+		q.AndWhere(dbx.NewExp(
+			"(EXISTS (SELECT 1 FROM json_each(feed_items.category_ids) WHERE value = {:category}) OR "+
+				"EXISTS (SELECT 1 FROM feed_sources s, json_each(s.category_ids) WHERE s.id = feed_items.source_id AND value = {:category}))",
+			dbx.Params{"category": filter.CategoryID},
+		))
+	}
+	if filter.TagID != "" {
+		q.AndWhere(dbx.NewExp(
+			"EXISTS (SELECT 1 FROM json_each(feed_items.tags) WHERE value = {:tag})",
+			dbx.Params{"tag": filter.TagID},
+		))
+	}
+	if filter.Status != "" {
+		q.AndWhere(dbx.HashExp{"status": filter.Status})
+	}
+	if filter.Search != "" {
+		q.AndWhere(dbx.Like("title", filter.Search))
+	}
+	if filter.Before != "" {
+		before, err := parseBulkTime(filter.Before)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before: %v", err)
+		}
+		q.AndWhere(dbx.NewExp("created <= {:before}", dbx.Params{"before": before.String()}))
+	}
+	if filter.After != "" {
+		after, err := parseBulkTime(filter.After)
+		if err != nil {
+			return nil, fmt.Errorf("invalid after: %v", err)
+		}
+		q.AndWhere(dbx.NewExp("created > {:after}", dbx.Params{"after": after.String()}))
+	}
+
+	return q.OrderBy("created ASC"), nil
+}
+
+// parseBulkTime accepts RFC 3339 and the PocketBase date format
+func parseBulkTime(value string) (types.DateTime, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return dateTime(t), nil
+	}
+	dt, err := types.ParseDateTime(value)
+	if err != nil || dt.IsZero() {
+		return types.DateTime{}, fmt.Errorf("unrecognized time %q", value)
+	}
+	return dt, nil
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
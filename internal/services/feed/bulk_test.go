@@ -0,0 +1,71 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+func TestMarkAllRead(t *testing.T) {
+	source := &models.FeedSource{
+		User:     testUserID,
+		Name:     "Bulk",
+		Type:     models.SourceTypeRSS,
+		URL:      "https://example.com/bulk.xml",
+		Config:   `{"url":"https://example.com/bulk.xml"}`,
+		IsActive: true,
+	}
+	source.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(source); err != nil {
+		t.Fatalf("saving source: %v", err)
+	}
+
+	// Two items stored before the list was loaded, one after
+	stored := []time.Time{
+		time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC),
+	}
+	ids := make([]string, len(stored))
+	for i, created := range stored {
+		item := &models.FeedItem{
+			User:       testUserID,
+			SourceID:   source.Id,
+			ExternalID: util.GenerateRandomId(),
+			Title:      "Bulk item",
+			Status:     models.StatusUnread,
+		}
+		item.Id = util.GenerateRandomId()
+		item.Created = dateTime(created)
+		if err := query.SaveRecord(item); err != nil {
+			t.Fatalf("saving item: %v", err)
+		}
+		ids[i] = item.Id
+	}
+
+	result, err := MarkAllRead(testUserID, BulkFilter{SourceID: source.Id, Before: "2025-01-06T11:00:00Z"})
+	if err != nil {
+		t.Fatalf("MarkAllRead: %v", err)
+	}
+	if result.Matched != 2 || result.Updated != 2 {
+		t.Errorf("got %d matched and %d updated, want 2 and 2", result.Matched, result.Updated)
+	}
+
+	want := []string{models.StatusRead, models.StatusRead, models.StatusUnread}
+	for i, id := range ids {
+		item, err := query.FindById[*models.FeedItem](id)
+		if err != nil {
+			t.Fatalf("finding item: %v", err)
+		}
+		if item.Status != want[i] {
+			t.Errorf("item %d: got status %s, want %s", i, item.Status, want[i])
+		}
+	}
+
+	if _, err := MarkAllRead(testUserID, BulkFilter{SourceID: source.Id, Before: "yesterday"}); err == nil {
+		t.Error("expected an error for an invalid before time")
+	}
+}
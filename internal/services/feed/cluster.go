@@ -0,0 +1,579 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+)
+
+const (
+	// Near-duplicates are only looked for among items fetched this recently
+	clusterWindow = 72 * time.Hour
+	// Maximum number of recent items compared against a new item
+	clusterCandidateLimit = 1000
+	sameURLLimit          = 50
+
+	// Titles whose SimHash differs in at most this many bits are candidates,
+	// the word overlap then decides
+	titleSimHashDistance = 8
+	minTitleSimilarity   = 0.7
+	minTitleWords        = 4
+
+	// Content of at least this many words is compared on its own, catching the
+	// same article republished under another title
+	contentSimHashDistance = 3
+	minContentWords        = 50
+
+	redirectTimeout = 10 * time.Second
+)
+
+// Query parameters that only track where a click came from
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"igshid": true, "mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true,
+	"ref": true, "ref_src": true, "ref_url": true, "referrer": true,
+	"cmpid": true, "ncid": true, "sr_share": true, "si": true,
+	"spm": true, "trk": true, "feature": true,
+}
+
+var trackingParamPrefixes = []string{"utm_", "pk_", "hmb_", "oly_", "vero_", "__s"}
+
+// Hosts that only redirect to the real article
+var redirectHosts = map[string]bool{
+	"feedproxy.google.com": true,
+	"feeds.feedburner.com": true,
+	"t.co":                 true,
+	"bit.ly":               true,
+	"buff.ly":              true,
+	"ow.ly":                true,
+	"tinyurl.com":          true,
+	"lnkd.in":              true,
+	"dlvr.it":              true,
+	"ift.tt":               true,
+	"trib.al":              true,
+	"goo.gl":               true,
+	"rebrand.ly":           true,
+	"redd.it":              true,
+	"feeds.feedblitz.com":  true,
+	"rss.feedsportal.com":  true,
+}
+
+// Title prefixes that aggregators add to the same story
+var titlePrefixes = []string{"show hn:", "ask hn:", "tell hn:", "launch hn:", "[video]", "[pdf]", "video:", "watch:"}
+
+var redirectClient = providers.NewHTTPClient(redirectTimeout)
+
+// CanonicalizeURL normalizes a URL so the same page linked from different
+// places compares equal: tracking parameters, fragments, default ports,
+// "www." and trailing slashes are removed and the remaining query is sorted
+func CanonicalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	values := u.Query()
+	for key := range values {
+		if isTrackingParam(strings.ToLower(key)) {
+			values.Del(key)
+		}
+	}
+	// Encode sorts the keys
+	u.RawQuery = values.Encode()
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	for _, suffix := range []string{"/amp", "/index.html", "/index.php"} {
+		u.Path = strings.TrimSuffix(u.Path, suffix)
+	}
+
+	return u.String()
+}
+
+func isTrackingParam(key string) bool {
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingParamPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveURL follows redirects for links pointing at known redirectors and
+// URL shorteners. Other links are returned unchanged so ingesting doesn't
+// cost an extra request per item.
+func ResolveURL(ctx context.Context, raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || !redirectHosts[strings.ToLower(strings.TrimPrefix(u.Hostname(), "www."))] {
+		return raw
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, redirectTimeout)
+	defer cancel()
+
+	// Some redirectors don't answer HEAD, fall back to GET
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, raw, nil)
+		if err != nil {
+			return raw
+		}
+
+		resp, err := redirectClient.Do(req)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error resolving redirect for %s: %v", raw, err))
+			return raw
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 400 {
+			return resp.Request.URL.String()
+		}
+	}
+
+	return raw
+}
+
+// normalizedWords lower-cases text and splits it into words, dropping the
+// prefixes aggregators put in front of titles
+func normalizedWords(text string) []string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, prefix := range titlePrefixes {
+		text = strings.TrimSpace(strings.TrimPrefix(text, prefix))
+	}
+
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SimHash computes a 64-bit SimHash over the words and word pairs of a text.
+// Similar texts get fingerprints that differ in few bits.
+func SimHash(words []string) uint64 {
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	for i, word := range words {
+		add(word)
+		if i > 0 {
+			add(words[i-1] + " " + word)
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance returns the number of bits two fingerprints differ in
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func formatSimHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parseSimHash(value string) (uint64, bool) {
+	hash, err := strconv.ParseUint(value, 16, 64)
+	return hash, err == nil
+}
+
+// jaccard returns the overlap of two word sets
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(a))
+	for _, word := range a {
+		set[word] = true
+	}
+
+	union := len(set)
+	intersection := 0
+	seen := make(map[string]bool, len(b))
+	for _, word := range b {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if set[word] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// fingerprint holds what an item is compared by
+type fingerprint struct {
+	titleWords  []string
+	titleHash   uint64
+	contentHash uint64
+	hasContent  bool
+}
+
+// fingerprintItem computes the fingerprints of an item and stores them on it
+func fingerprintItem(item *models.FeedItem) fingerprint {
+	fp := fingerprint{titleWords: normalizedWords(item.Title)}
+	fp.titleHash = SimHash(fp.titleWords)
+	item.SimHash = formatSimHash(fp.titleHash)
+
+	contentWords := normalizedWords(stripTags(item.ArticleContent()))
+	if len(contentWords) >= minContentWords {
+		fp.contentHash = SimHash(contentWords)
+		fp.hasContent = true
+		item.ContentSimHash = formatSimHash(fp.contentHash)
+	}
+
+	return fp
+}
+
+// storedFingerprint reads the fingerprints stored on an item
+func storedFingerprint(item *models.FeedItem) fingerprint {
+	fp := fingerprint{titleWords: normalizedWords(item.Title)}
+
+	if hash, ok := parseSimHash(item.SimHash); ok {
+		fp.titleHash = hash
+	} else {
+		fp.titleHash = SimHash(fp.titleWords)
+	}
+	if hash, ok := parseSimHash(item.ContentSimHash); ok {
+		fp.contentHash = hash
+		fp.hasContent = true
+	}
+
+	return fp
+}
+
+// isNearDuplicate compares an item to a candidate by title and, when both
+// have enough of it, by content
+func (fp fingerprint) isNearDuplicate(candidate fingerprint) bool {
+	if len(fp.titleWords) >= minTitleWords && len(candidate.titleWords) >= minTitleWords &&
+		HammingDistance(fp.titleHash, candidate.titleHash) <= titleSimHashDistance &&
+		jaccard(fp.titleWords, candidate.titleWords) >= minTitleSimilarity {
+		return true
+	}
+
+	if fp.hasContent && candidate.hasContent {
+		return HammingDistance(fp.contentHash, candidate.contentHash) <= contentSimHashDistance
+	}
+
+	return false
+}
+
+// clusterCandidate is a recent item with the fingerprints it's compared by
+type clusterCandidate struct {
+	item *models.FeedItem
+	fp   fingerprint
+}
+
+// clusterCandidates are the recent items of a user new items are clustered
+// with. They are loaded once per batch of new items, which are added as they
+// are stored, so matching doesn't query the recent items for every item.
+type clusterCandidates struct {
+	userID string
+	loaded bool
+	recent []clusterCandidate // Newest first
+	added  []clusterCandidate // Stored during the batch, oldest first
+}
+
+func newClusterCandidates(userID string) *clusterCandidates {
+	return &clusterCandidates{userID: userID}
+}
+
+// load reads the recent items of the user on first use
+func (c *clusterCandidates) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+
+	since := time.Now().Add(-clusterWindow).UTC().Format(types.DefaultDateLayout)
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": c.userID}).
+		AndWhere(dbx.NewExp("fetched_at >= {:since}", dbx.Params{"since": since})).
+		OrderBy("fetched_at DESC").
+		Limit(clusterCandidateLimit).
+		All(&items)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error loading cluster candidates: %v", err))
+		return
+	}
+
+	c.recent = make([]clusterCandidate, len(items))
+	for i, item := range items {
+		c.recent[i] = clusterCandidate{item: item, fp: storedFingerprint(item)}
+	}
+}
+
+// add makes a stored item a candidate for the rest of the batch
+func (c *clusterCandidates) add(item *models.FeedItem, fp fingerprint) {
+	c.added = append(c.added, clusterCandidate{item: item, fp: fp})
+}
+
+// match returns the most recent candidate the item is a near-duplicate of
+func (c *clusterCandidates) match(item *models.FeedItem, fp fingerprint) *models.FeedItem {
+	c.load()
+
+	for i := len(c.added) - 1; i >= 0; i-- {
+		if c.added[i].item.Id != item.Id && fp.isNearDuplicate(c.added[i].fp) {
+			return c.added[i].item
+		}
+	}
+	for _, candidate := range c.recent {
+		if candidate.item.Id != item.Id && fp.isNearDuplicate(candidate.fp) {
+			return candidate.item
+		}
+	}
+
+	return nil
+}
+
+// clusterItem fingerprints a new item and assigns it to the cluster of an
+// existing item about the same story, or starts a new cluster. The item must
+// have its ID set. It returns the matched item, nil when the item is the first
+// of its cluster, and the fingerprint to add the item to the candidates with
+// once it's stored.
+func clusterItem(ctx context.Context, item *models.FeedItem, candidates *clusterCandidates) (*models.FeedItem, fingerprint) {
+	item.CanonicalURL = CanonicalizeURL(ResolveURL(ctx, item.URL))
+
+	fp := fingerprintItem(item)
+	match := findClusterMatch(item, fp, candidates)
+	if match == nil {
+		item.ClusterID = item.Id
+		item.IsClusterPrimary = true
+		return nil, fp
+	}
+
+	item.ClusterID = match.ClusterID
+	if item.ClusterID == "" {
+		item.ClusterID = match.Id
+	}
+	item.IsClusterPrimary = false
+
+	// A story already dealt with elsewhere doesn't need to be read again
+	if match.Status == models.StatusRead || match.Status == models.StatusDismissed {
+		item.Status = match.Status
+	}
+
+	return match, fp
+}
+
+func findClusterMatch(item *models.FeedItem, fp fingerprint, candidates *clusterCandidates) *models.FeedItem {
+	if item.CanonicalURL != "" {
+		sameURL, err := query.FindAllByFilterWithPagination[*models.FeedItem](map[string]interface{}{
+			"user":          item.User,
+			"canonical_url": item.CanonicalURL,
+		}, sameURLLimit, 0)
+		if err == nil {
+			for _, candidate := range sameURL {
+				// Items of one source can share a URL without being the same story,
+				// e.g. scraped pages whose items have no links of their own
+				if candidate.Id != item.Id && (candidate.SourceID != item.SourceID || fp.isNearDuplicate(storedFingerprint(candidate))) {
+					return candidate
+				}
+			}
+		}
+	}
+
+	return candidates.match(item, fp)
+}
+
+// FindClusterItems returns the items of the given clusters by cluster ID,
+// primary items first
+func FindClusterItems(userID string, clusterIDs []string) (map[string][]*models.FeedItem, error) {
+	clusters := make(map[string][]*models.FeedItem)
+	if len(clusterIDs) == 0 {
+		return clusters, nil
+	}
+
+	ids := make([]interface{}, 0, len(clusterIDs))
+	for _, id := range clusterIDs {
+		ids = append(ids, id)
+	}
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": userID, "cluster_id": ids}).
+		OrderBy("is_cluster_primary DESC", "fetched_at ASC").
+		All(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		clusters[item.ClusterID] = append(clusters[item.ClusterID], item)
+	}
+
+	return clusters, nil
+}
+
+// PropagateClusterStatus copies reading progress to the other unread items of
+// the item's cluster. Reading or dismissing one copy of a story does the same
+// for the others, saving one marks the others read.
+func PropagateClusterStatus(item *models.FeedItem) error {
+	if item.ClusterID == "" {
+		return nil
+	}
+
+	status := item.Status
+	switch status {
+	case models.StatusRead, models.StatusDismissed:
+	case models.StatusSaved:
+		status = models.StatusRead
+	default:
+		return nil
+	}
+
+	siblings, err := query.FindAllByFilter[*models.FeedItem](map[string]interface{}{
+		"user":       item.User,
+		"cluster_id": item.ClusterID,
+		"status":     models.StatusUnread,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sibling := range siblings {
+		if sibling.Id == item.Id {
+			continue
+		}
+		if err := query.UpdateRecord[*models.FeedItem](sibling.Id, map[string]interface{}{
+			"status": status,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PromoteClusterPrimary makes the oldest remaining item of a cluster its
+// primary, for when the primary was deleted
+func PromoteClusterPrimary(userID string, clusterID string) error {
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": userID, "cluster_id": clusterID}).
+		OrderBy("is_cluster_primary DESC", "created ASC").
+		Limit(1).
+		All(&items)
+	if err != nil || len(items) == 0 || items[0].IsClusterPrimary {
+		return err
+	}
+
+	return query.UpdateRecord[*models.FeedItem](items[0].Id, map[string]interface{}{
+		"is_cluster_primary": true,
+	})
+}
+
+// RegisterClusterHooks propagates status changes of feed items to the rest of
+// their cluster, both for API updates and updates through the models package,
+// and keeps a primary item in clusters whose primary was deleted
+func RegisterClusterHooks(app core.App) {
+	app.OnModelAfterDeleteSuccess((&models.FeedItem{}).TableName()).BindFunc(func(e *core.ModelEvent) error {
+		var userID, clusterID string
+		var primary bool
+		switch model := e.Model.(type) {
+		case *core.Record:
+			userID, clusterID, primary = model.GetString("user"), model.GetString("cluster_id"), model.GetBool("is_cluster_primary")
+		case *models.FeedItem:
+			userID, clusterID, primary = model.User, model.ClusterID, model.IsClusterPrimary
+		}
+
+		if primary && clusterID != "" {
+			if err := PromoteClusterPrimary(userID, clusterID); err != nil {
+				logger.LogError(fmt.Sprintf("Error promoting primary of cluster %s: %v", clusterID, err))
+			}
+		}
+		return e.Next()
+	})
+
+	app.OnModelAfterUpdateSuccess((&models.FeedItem{}).TableName()).BindFunc(func(e *core.ModelEvent) error {
+		// Records carry their original state, skip updates that didn't change the status
+		if record, ok := e.Model.(*core.Record); ok && record.Original().GetString("status") == record.GetString("status") {
+			return e.Next()
+		}
+
+		itemID := fmt.Sprint(e.Model.PK())
+		item, err := query.FindById[*models.FeedItem](itemID)
+		if err == nil {
+			if err := PropagateClusterStatus(item); err != nil {
+				logger.LogError(fmt.Sprintf("Error propagating status of feed item %s: %v", itemID, err))
+			}
+		}
+		return e.Next()
+	})
+}
+
+// ClusterIDs returns the distinct cluster IDs of items
+func ClusterIDs(items []*models.FeedItem) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, item := range items {
+		if item.ClusterID != "" && !seen[item.ClusterID] {
+			seen[item.ClusterID] = true
+			ids = append(ids, item.ClusterID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,683 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	NotificationTypeFeedDigest = "feed_digest"
+
+	defaultDigestTimeOfDay = "08:00"
+	defaultDigestMaxItems  = 20
+	maxDigestMaxItems      = 100
+	// Number of candidate items a digest is picked from
+	digestCandidateLimit = 500
+	// A digest never reaches further back than this, even when the last one
+	// was sent long ago
+	digestMaxLookback = 14 * 24 * time.Hour
+	// Time allowed for summarizing the items that have no summary yet
+	digestSummaryTimeout = 2 * time.Minute
+	// Number of titles listed in the notification
+	digestNotificationItems = 5
+
+	uncategorizedSection = "Uncategorized"
+)
+
+var digestChannels = map[string]bool{
+	models.DigestChannelEmail:        true,
+	models.DigestChannelNotification: true,
+}
+
+// DigestEntry is a single item of a digest
+type DigestEntry struct {
+	ItemID      string
+	Title       string
+	URL         string
+	SourceName  string
+	Author      string
+	Summary     string
+	Saved       bool
+	PublishedAt time.Time
+}
+
+// DigestSection holds the entries of one category
+type DigestSection struct {
+	Name    string
+	Entries []DigestEntry
+}
+
+// DigestService builds a periodic digest of the best unread and saved items of
+// a user and delivers it by email and/or notification
+type DigestService struct {
+	processor services.FeedProcessor
+	ranker    *FeedRanker
+	running   atomic.Bool
+}
+
+func NewDigestService(processor services.FeedProcessor, ranker *FeedRanker) *DigestService {
+	return &DigestService{
+		processor: processor,
+		ranker:    ranker,
+	}
+}
+
+// DefaultDigestSettings returns the settings used until a user saves their own
+func DefaultDigestSettings(userID string) *models.FeedDigestSettings {
+	return &models.FeedDigestSettings{
+		User:      userID,
+		Frequency: models.DigestFrequencyDaily,
+		TimeOfDay: defaultDigestTimeOfDay,
+		Timezone:  "UTC",
+		Weekday:   int(time.Monday),
+		Channels:  types.JSONArray[string]{models.DigestChannelNotification},
+		MaxItems:  defaultDigestMaxItems,
+	}
+}
+
+// GetSettings returns the digest settings of a user, or the defaults when
+// none have been saved
+func (s *DigestService) GetSettings(userID string) (*models.FeedDigestSettings, error) {
+	settings, err := query.FindByFilter[*models.FeedDigestSettings](map[string]interface{}{
+		"user": userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultDigestSettings(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ValidateDigestSettings checks the schedule and delivery settings
+func ValidateDigestSettings(settings *models.FeedDigestSettings) error {
+	if settings.Frequency != models.DigestFrequencyDaily && settings.Frequency != models.DigestFrequencyWeekly {
+		return fmt.Errorf("frequency must be %s or %s", models.DigestFrequencyDaily, models.DigestFrequencyWeekly)
+	}
+	if _, err := time.Parse("15:04", settings.TimeOfDay); err != nil {
+		return fmt.Errorf("time_of_day must be in HH:MM format")
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone: %s", settings.Timezone)
+	}
+	if settings.Weekday < 0 || settings.Weekday > 6 {
+		return fmt.Errorf("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if settings.MaxItems < 1 || settings.MaxItems > maxDigestMaxItems {
+		return fmt.Errorf("max_items must be between 1 and %d", maxDigestMaxItems)
+	}
+	for _, channel := range settings.Channels {
+		if !digestChannels[channel] {
+			return fmt.Errorf("unknown channel: %s", channel)
+		}
+	}
+	return nil
+}
+
+// LastScheduledTime returns the most recent time at or before now at which a
+// digest was scheduled
+func LastScheduledTime(settings *models.FeedDigestSettings, now time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock, err := time.Parse("15:04", settings.TimeOfDay)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	if settings.Frequency == models.DigestFrequencyWeekly {
+		for int(scheduled.Weekday()) != settings.Weekday {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+	}
+
+	return scheduled, nil
+}
+
+// NextScheduledTime returns when the next digest is due
+func NextScheduledTime(settings *models.FeedDigestSettings, now time.Time) (time.Time, error) {
+	last, err := LastScheduledTime(settings, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if settings.Frequency == models.DigestFrequencyWeekly {
+		return last.AddDate(0, 0, 7), nil
+	}
+	return last.AddDate(0, 0, 1), nil
+}
+
+// IsDue reports whether a scheduled digest time has passed since the last
+// digest was sent, or since the settings were created for a first digest
+func IsDue(settings *models.FeedDigestSettings, now time.Time) bool {
+	if !settings.IsEnabled {
+		return false
+	}
+
+	scheduled, err := LastScheduledTime(settings, now)
+	if err != nil {
+		return false
+	}
+
+	reference := settings.LastSentAt.Time()
+	if reference.IsZero() {
+		reference = settings.Created.Time()
+	}
+	return scheduled.After(reference)
+}
+
+// RunDue sends the digests of all users whose scheduled time has passed.
+// Runs that overlap a previous one are skipped.
+func (s *DigestService) RunDue(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		logger.LogInfo("Feed digest run already in progress, skipping")
+		return nil
+	}
+	defer s.running.Store(false)
+
+	allSettings, err := query.FindAllByFilter[*models.FeedDigestSettings](map[string]interface{}{
+		"is_enabled": true,
+	})
+	if err != nil {
+		return fmt.Errorf("error loading digest settings: %v", err)
+	}
+
+	now := time.Now()
+	for _, settings := range allSettings {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !IsDue(settings, now) {
+			continue
+		}
+
+		digest, err := s.Send(ctx, settings, now)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error sending feed digest for user %s: %v", settings.User, err))
+			continue
+		}
+		logger.LogInfo(fmt.Sprintf("Feed digest for user %s sent with %d items", settings.User, digest.ItemCount))
+	}
+
+	return nil
+}
+
+// Send builds the digest for the period since the last one, archives it and
+// delivers it to the configured channels. Empty digests are neither archived
+// nor delivered, but still start a new period.
+func (s *DigestService) Send(ctx context.Context, settings *models.FeedDigestSettings, now time.Time) (*models.FeedDigest, error) {
+	digest, err := s.Build(ctx, settings, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest.ItemCount > 0 {
+		digest.Id = util.GenerateRandomId()
+		digest.RefreshCreated()
+		digest.RefreshUpdated()
+		if err := query.SaveRecord(digest); err != nil {
+			return nil, fmt.Errorf("error saving digest: %v", err)
+		}
+
+		delivered, err := s.deliver(settings, digest)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error delivering feed digest %s: %v", digest.Id, err))
+		}
+		if len(delivered) > 0 {
+			digest.Channels = delivered
+			if err := query.UpdateRecord[*models.FeedDigest](digest.Id, map[string]interface{}{
+				"channels": delivered,
+			}); err != nil {
+				logger.LogError(fmt.Sprintf("Error updating channels of feed digest %s: %v", digest.Id, err))
+			}
+		}
+	}
+
+	if settings.Id != "" {
+		if err := query.UpdateRecord[*models.FeedDigestSettings](settings.Id, map[string]interface{}{
+			"last_sent_at": now.UTC(),
+		}); err != nil {
+			return digest, fmt.Errorf("error updating digest settings: %v", err)
+		}
+	}
+
+	return digest, nil
+}
+
+// Build collects, ranks and renders the digest of a user without saving or
+// delivering it
+func (s *DigestService) Build(ctx context.Context, settings *models.FeedDigestSettings, now time.Time) (*models.FeedDigest, error) {
+	periodStart := s.periodStart(settings, now)
+
+	items, err := s.loadDigestItems(settings, periodStart)
+	if err != nil {
+		return nil, err
+	}
+
+	maxItems := settings.MaxItems
+	if maxItems <= 0 {
+		maxItems = defaultDigestMaxItems
+	}
+
+	ranked, err := s.ranker.ScoreItems(settings.User, items, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranked) > maxItems {
+		ranked = ranked[:maxItems]
+	}
+
+	selected := make([]*models.FeedItem, 0, len(ranked))
+	itemIDs := make(types.JSONArray[string], 0, len(ranked))
+	for _, entry := range ranked {
+		selected = append(selected, entry.Item)
+		itemIDs = append(itemIDs, entry.Item.Id)
+	}
+
+	s.ensureSummaries(ctx, selected)
+	sections := groupDigestItems(settings.User, selected)
+
+	title := digestTitle(settings, now)
+	htmlContent, err := renderDigestHTML(title, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FeedDigest{
+		User:        settings.User,
+		Title:       title,
+		Frequency:   settings.Frequency,
+		PeriodStart: dateTime(periodStart),
+		PeriodEnd:   dateTime(now),
+		ItemCount:   len(selected),
+		ItemIDs:     itemIDs,
+		HTML:        htmlContent,
+		Markdown:    renderDigestMarkdown(title, sections),
+		Channels:    types.JSONArray[string]{},
+	}, nil
+}
+
+// periodStart returns where the digest period begins: the last digest, or one
+// period back for the first one
+func (s *DigestService) periodStart(settings *models.FeedDigestSettings, now time.Time) time.Time {
+	start := settings.LastSentAt.Time()
+	if start.IsZero() {
+		if settings.Frequency == models.DigestFrequencyWeekly {
+			start = now.AddDate(0, 0, -7)
+		} else {
+			start = now.AddDate(0, 0, -1)
+		}
+	}
+
+	if earliest := now.Add(-digestMaxLookback); start.Before(earliest) {
+		start = earliest
+	}
+	return start
+}
+
+// loadDigestItems returns the unread items fetched during the period and,
+// when enabled, the items saved during it. One item is taken per story.
+func (s *DigestService) loadDigestItems(settings *models.FeedDigestSettings, since time.Time) ([]*models.FeedItem, error) {
+	sinceStr := since.UTC().Format(types.DefaultDateLayout)
+
+	condition := dbx.And(
+		dbx.HashExp{"status": models.StatusUnread},
+		dbx.NewExp("fetched_at >= {:since}", dbx.Params{"since": sinceStr}),
+	)
+	if settings.IncludeSaved {
+		condition = dbx.Or(condition, dbx.And(
+			dbx.HashExp{"status": models.StatusSaved},
+			dbx.NewExp("updated >= {:since}", dbx.Params{"since": sinceStr}),
+		))
+	}
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": settings.User, "is_cluster_primary": true}).
+		AndWhere(condition).
+		OrderBy("fetched_at DESC").
+		Limit(digestCandidateLimit).
+		All(&items)
+	if err != nil {
+		return nil, fmt.Errorf("error loading digest items: %v", err)
+	}
+
+	return items, nil
+}
+
+// ensureSummaries summarizes the selected items the processor hasn't got to
+// yet. Generated summaries are stored so they aren't generated again.
+func (s *DigestService) ensureSummaries(ctx context.Context, items []*models.FeedItem) {
+	if s.processor == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, digestSummaryTimeout)
+	defer cancel()
+
+	for _, item := range items {
+		if item.Summary != "" || ctx.Err() != nil {
+			continue
+		}
+
+		summary, err := s.processor.GenerateSummary(ai.WithCaller(ctx, ai.FeatureFeedDigest, item.User), item)
+		if err != nil || summary == "" {
+			continue
+		}
+		item.Summary = summary
+
+		if err := query.UpdateRecord[*models.FeedItem](item.Id, map[string]interface{}{
+			"summary": summary,
+		}); err != nil {
+			logger.LogError(fmt.Sprintf("Error saving summary for item %s: %v", item.Id, err))
+		}
+	}
+}
+
+// groupDigestItems groups items by their first category, falling back to the
+// categories of their source. Sections follow the category order, items keep
+// their ranked order.
+func groupDigestItems(userID string, items []*models.FeedItem) []DigestSection {
+	categories := make(map[string]*models.FeedCategory)
+	if list, err := query.FindAllByFilter[*models.FeedCategory](map[string]interface{}{"user": userID}); err == nil {
+		for _, category := range list {
+			categories[category.Id] = category
+		}
+	}
+	sources := make(map[string]*models.FeedSource)
+	if list, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{"user": userID}); err == nil {
+		for _, source := range list {
+			sources[source.Id] = source
+		}
+	}
+
+	firstCategory := func(ids []string) *models.FeedCategory {
+		for _, id := range ids {
+			if category, ok := categories[id]; ok {
+				return category
+			}
+		}
+		return nil
+	}
+
+	sectionIndex := make(map[string]int)
+	sectionCategory := make(map[string]*models.FeedCategory)
+	sections := make([]DigestSection, 0)
+
+	for _, item := range items {
+		source := sources[item.SourceID]
+
+		category := firstCategory(item.CategoryIDs)
+		if category == nil && source != nil {
+			category = firstCategory(source.CategoryIDs)
+		}
+
+		name := uncategorizedSection
+		if category != nil {
+			name = category.Name
+		}
+
+		idx, ok := sectionIndex[name]
+		if !ok {
+			idx = len(sections)
+			sectionIndex[name] = idx
+			sectionCategory[name] = category
+			sections = append(sections, DigestSection{Name: name})
+		}
+
+		entry := DigestEntry{
+			ItemID:      item.Id,
+			Title:       item.Title,
+			URL:         item.URL,
+			Author:      item.Author,
+			Summary:     digestSummary(item),
+			Saved:       item.Status == models.StatusSaved,
+			PublishedAt: item.PublishedAt.Time(),
+		}
+		if source != nil {
+			entry.SourceName = source.Name
+		}
+		sections[idx].Entries = append(sections[idx].Entries, entry)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		a, b := sectionCategory[sections[i].Name], sectionCategory[sections[j].Name]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		if a.SortOrder != b.SortOrder {
+			return a.SortOrder < b.SortOrder
+		}
+		return a.Name < b.Name
+	})
+
+	return sections
+}
+
+// digestSummary returns the summary of an item, falling back to the start of
+// its content
+func digestSummary(item *models.FeedItem) string {
+	if summary := strings.TrimSpace(item.Summary); summary != "" {
+		return summary
+	}
+
+	return summarizeText(stripTags(item.Content), 200)
+}
+
+func digestTitle(settings *models.FeedDigestSettings, now time.Time) string {
+	if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+		now = now.In(loc)
+	}
+
+	if settings.Frequency == models.DigestFrequencyWeekly {
+		return "Your weekly feed digest, week of " + now.Format("January 2, 2006")
+	}
+	return "Your daily feed digest for " + now.Format("Monday, January 2, 2006")
+}
+
+var digestHTMLTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; max-width: 640px; margin: 0 auto; padding: 16px; color: #1f2328;">
+<h1 style="font-size: 22px;">{{.Title}}</h1>
+{{range .Sections}}<h2 style="font-size: 18px; border-bottom: 1px solid #d0d7de; padding-bottom: 4px;">{{.Name}}</h2>
+{{range .Entries}}<div style="margin-bottom: 16px;">
+<a href="{{.URL}}" style="font-size: 16px; font-weight: 600; color: #0969da; text-decoration: none;">{{.Title}}</a>{{if .Saved}} <span style="font-size: 12px; color: #9a6700;">saved</span>{{end}}
+<div style="font-size: 12px; color: #656d76;">{{.SourceName}}{{if .Author}} &middot; {{.Author}}{{end}}</div>
+{{if .Summary}}<p style="font-size: 14px; margin: 4px 0 0;">{{.Summary}}</p>{{end}}
+</div>
+{{end}}{{else}}<p>No new items this time.</p>
+{{end}}</body>
+</html>
+`))
+
+func renderDigestHTML(title string, sections []DigestSection) (string, error) {
+	var buf bytes.Buffer
+	err := digestHTMLTemplate.Execute(&buf, map[string]interface{}{
+		"Title":    title,
+		"Sections": sections,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error rendering digest: %v", err)
+	}
+	return buf.String(), nil
+}
+
+var markdownLinkEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+func renderDigestMarkdown(title string, sections []DigestSection) string {
+	var b strings.Builder
+	b.WriteString("# " + title + "\n")
+
+	if len(sections) == 0 {
+		b.WriteString("\nNo new items this time.\n")
+	}
+
+	for _, section := range sections {
+		b.WriteString("\n## " + section.Name + "\n\n")
+		for _, entry := range section.Entries {
+			b.WriteString("- [" + markdownLinkEscaper.Replace(entry.Title) + "](<" + entry.URL + ">)")
+			if entry.Saved {
+				b.WriteString(" *(saved)*")
+			}
+
+			byline := entry.SourceName
+			if entry.Author != "" {
+				if byline != "" {
+					byline += ", "
+				}
+				byline += entry.Author
+			}
+			if byline != "" {
+				b.WriteString(" - " + byline)
+			}
+			b.WriteString("\n")
+
+			if entry.Summary != "" {
+				b.WriteString("  " + entry.Summary + "\n")
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// deliver sends the digest to every configured channel and returns the ones
+// it was delivered to
+func (s *DigestService) deliver(settings *models.FeedDigestSettings, digest *models.FeedDigest) ([]string, error) {
+	delivered := make([]string, 0, len(settings.Channels))
+	var errs []error
+
+	for _, channel := range settings.Channels {
+		var err error
+		switch channel {
+		case models.DigestChannelEmail:
+			err = sendDigestEmail(digest)
+		case models.DigestChannelNotification:
+			err = createDigestNotification(digest)
+		default:
+			err = fmt.Errorf("unknown channel: %s", channel)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", channel, err))
+			continue
+		}
+		delivered = append(delivered, channel)
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// sendDigestEmail mails the digest using the SMTP settings of the app
+func sendDigestEmail(digest *models.FeedDigest) error {
+	app := store.GetDao()
+	if !app.Settings().SMTP.Enabled {
+		return fmt.Errorf("SMTP is not configured")
+	}
+
+	user, err := query.FindById[*models.Users](digest.User)
+	if err != nil {
+		return fmt.Errorf("error loading user: %v", err)
+	}
+	if user.Email == "" {
+		return fmt.Errorf("user has no email address")
+	}
+
+	message := &mailer.Message{
+		From: mail.Address{
+			Name:    app.Settings().Meta.SenderName,
+			Address: app.Settings().Meta.SenderAddress,
+		},
+		To:      []mail.Address{{Name: user.Name, Address: user.Email}},
+		Subject: digest.Title,
+		HTML:    digest.HTML,
+		Text:    digest.Markdown,
+	}
+
+	return app.NewMailClient().Send(message)
+}
+
+func createDigestNotification(digest *models.FeedDigest) error {
+	lines := make([]string, 0, digestNotificationItems+1)
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"id": toInterfaceSlice(digest.ItemIDs)}).
+		All(&items)
+	if err == nil {
+		titles := make(map[string]string, len(items))
+		for _, item := range items {
+			titles[item.Id] = item.Title
+		}
+		for _, id := range digest.ItemIDs {
+			if len(lines) == digestNotificationItems {
+				break
+			}
+			if title := titles[id]; title != "" {
+				lines = append(lines, "- "+title)
+			}
+		}
+	}
+	if remaining := digest.ItemCount - len(lines); remaining > 0 && len(lines) > 0 {
+		lines = append(lines, "and "+strconv.Itoa(remaining)+" more")
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"digest_id":  digest.Id,
+		"item_count": digest.ItemCount,
+	})
+
+	notification := &models.Notification{
+		User:     digest.User,
+		Type:     NotificationTypeFeedDigest,
+		Title:    digest.Title,
+		Content:  strings.Join(lines, "\n"),
+		Priority: "medium",
+		Status:   "unread",
+		Metadata: string(metadata),
+	}
+	notification.Id = util.GenerateRandomId()
+
+	return query.SaveRecord(notification)
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+func dateTime(t time.Time) types.DateTime {
+	dt, _ := types.ParseDateTime(t.UTC())
+	return dt
+}
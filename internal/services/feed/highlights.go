@@ -0,0 +1,353 @@
+package feed
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+)
+
+const (
+	// Folder the Markdown export puts its notes in, one subfolder per source
+	HighlightExportFolder = "Highlights"
+
+	DefaultReviewCount = 5
+	maxReviewCount     = 20
+	maxHighlightLength = 10000
+)
+
+var highlightColors = map[string]bool{
+	models.HighlightColorYellow: true,
+	models.HighlightColorGreen:  true,
+	models.HighlightColorBlue:   true,
+	models.HighlightColorPink:   true,
+	models.HighlightColorPurple: true,
+}
+
+// Characters Obsidian and common filesystems don't allow in note names
+var unsafeFileNameChars = regexp.MustCompile(`[\\/:*?"<>|#^\[\]\x00-\x1f]+`)
+
+// HighlightedArticle is an item with its highlights in reading order
+type HighlightedArticle struct {
+	Item       *models.FeedItem
+	SourceName string
+	Highlights []*models.FeedHighlight
+}
+
+// ValidateHighlight checks the passage, range and color of a highlight,
+// defaulting the color to yellow
+func ValidateHighlight(highlight *models.FeedHighlight) error {
+	if strings.TrimSpace(highlight.Text) == "" {
+		return fmt.Errorf("text is required")
+	}
+	if len(highlight.Text) > maxHighlightLength {
+		return fmt.Errorf("text must be at most %d characters", maxHighlightLength)
+	}
+	if highlight.StartOffset < 0 || highlight.EndOffset <= highlight.StartOffset {
+		return fmt.Errorf("end_offset must be greater than start_offset, and start_offset not negative")
+	}
+	if highlight.Color == "" {
+		highlight.Color = models.HighlightColorYellow
+	}
+	if !highlightColors[highlight.Color] {
+		return fmt.Errorf("unknown color: %s", highlight.Color)
+	}
+	return nil
+}
+
+// ReviewHighlights returns the highlights to resurface today. The first call
+// of a day picks them at random, favouring highlights that haven't been
+// reviewed for the longest time, and later calls that day return the same
+// ones. Days follow the timezone of the user's digest settings.
+func ReviewHighlights(userID string, count int, now time.Time) ([]*models.FeedHighlight, error) {
+	if count <= 0 {
+		count = DefaultReviewCount
+	}
+	if count > maxReviewCount {
+		count = maxReviewCount
+	}
+
+	loc := time.UTC
+	if settings, err := query.FindByFilter[*models.FeedDigestSettings](map[string]interface{}{"user": userID}); err == nil {
+		if l, err := time.LoadLocation(settings.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).UTC()
+
+	var reviewed []*models.FeedHighlight
+	err := query.BaseQuery[*models.FeedHighlight]().
+		AndWhere(dbx.HashExp{"user": userID}).
+		AndWhere(dbx.NewExp("last_reviewed_at >= {:day}", dbx.Params{"day": dateTime(dayStart).String()})).
+		OrderBy("last_reviewed_at ASC", "id ASC").
+		All(&reviewed)
+	if err != nil {
+		return nil, fmt.Errorf("error loading reviewed highlights: %v", err)
+	}
+	if len(reviewed) > 0 {
+		return reviewed, nil
+	}
+
+	// Highlights made today are still fresh in mind
+	var candidates []*models.FeedHighlight
+	err = query.BaseQuery[*models.FeedHighlight]().
+		AndWhere(dbx.HashExp{"user": userID}).
+		AndWhere(dbx.NewExp("created < {:day}", dbx.Params{"day": dateTime(dayStart).String()})).
+		OrderBy("last_reviewed_at ASC", "created ASC").
+		All(&candidates)
+	if err != nil {
+		return nil, fmt.Errorf("error loading highlights: %v", err)
+	}
+
+	// Draw from the least recently reviewed highlights so everything comes
+	// around eventually. The seed keeps a day's pick stable.
+	pool := candidates
+	if len(pool) > count*3 {
+		pool = pool[:count*3]
+	}
+	seed := fnv.New64a()
+	seed.Write([]byte(userID + local.Format("2006-01-02")))
+	rng := rand.New(rand.NewSource(int64(seed.Sum64())))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	if len(pool) > count {
+		pool = pool[:count]
+	}
+
+	reviewedAt := dateTime(now)
+	for _, highlight := range pool {
+		if err := query.UpdateRecord[*models.FeedHighlight](highlight.Id, map[string]interface{}{
+			"review_count":     highlight.ReviewCount + 1,
+			"last_reviewed_at": reviewedAt,
+		}); err != nil {
+			return nil, err
+		}
+		highlight.ReviewCount++
+		highlight.LastReviewedAt = reviewedAt
+	}
+
+	return pool, nil
+}
+
+// LoadHighlightedArticles returns the items of a user that have highlights,
+// most recently highlighted first. An item ID limits it to that item.
+func LoadHighlightedArticles(userID string, itemID string) ([]*HighlightedArticle, error) {
+	filter := dbx.HashExp{"user": userID}
+	if itemID != "" {
+		filter["item"] = itemID
+	}
+
+	var highlights []*models.FeedHighlight
+	err := query.BaseQuery[*models.FeedHighlight]().
+		AndWhere(filter).
+		OrderBy("item ASC", "start_offset ASC", "created ASC").
+		All(&highlights)
+	if err != nil {
+		return nil, fmt.Errorf("error loading highlights: %v", err)
+	}
+	if len(highlights) == 0 {
+		return []*HighlightedArticle{}, nil
+	}
+
+	byItem := make(map[string][]*models.FeedHighlight)
+	itemIDs := make([]interface{}, 0)
+	for _, highlight := range highlights {
+		if _, ok := byItem[highlight.Item]; !ok {
+			itemIDs = append(itemIDs, highlight.Item)
+		}
+		byItem[highlight.Item] = append(byItem[highlight.Item], highlight)
+	}
+
+	var items []*models.FeedItem
+	if err := query.BaseQuery[*models.FeedItem]().AndWhere(dbx.HashExp{"id": itemIDs}).All(&items); err != nil {
+		return nil, fmt.Errorf("error loading feed items: %v", err)
+	}
+
+	sourceNames := make(map[string]string)
+	if sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{"user": userID}); err == nil {
+		for _, source := range sources {
+			sourceNames[source.Id] = source.Name
+		}
+	}
+
+	articles := make([]*HighlightedArticle, 0, len(items))
+	for _, item := range items {
+		articles = append(articles, &HighlightedArticle{
+			Item:       item,
+			SourceName: sourceNames[item.SourceID],
+			Highlights: byItem[item.Id],
+		})
+	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return latestHighlight(articles[i]).After(latestHighlight(articles[j]))
+	})
+
+	return articles, nil
+}
+
+// ExportHighlights writes a ZIP with one Markdown note per highlighted
+// article, laid out as an Obsidian vault folder: Highlights/<source>/<title>.md
+func ExportHighlights(userID string, w io.Writer) error {
+	articles, err := LoadHighlightedArticles(userID, "")
+	if err != nil {
+		return err
+	}
+
+	tagNames := userTagNames(userID)
+	used := make(map[string]bool)
+
+	zw := zip.NewWriter(w)
+	for _, article := range articles {
+		name := uniqueNotePath(HighlightNotePath(article), used)
+		if err := writeZipFile(zw, name, []byte(RenderHighlightsMarkdown(article, tagNames))); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ExportArticleHighlights renders the note of a single item and returns its
+// file name with it
+func ExportArticleHighlights(userID string, itemID string) (string, string, error) {
+	articles, err := LoadHighlightedArticles(userID, itemID)
+	if err != nil {
+		return "", "", err
+	}
+	if len(articles) == 0 {
+		return "", "", fmt.Errorf("item has no highlights")
+	}
+
+	article := articles[0]
+	return path.Base(HighlightNotePath(article)), RenderHighlightsMarkdown(article, userTagNames(userID)), nil
+}
+
+// HighlightNotePath returns where the note of an article goes in the export
+func HighlightNotePath(article *HighlightedArticle) string {
+	folder := safeFileName(article.SourceName)
+	if folder == "" {
+		folder = "Other"
+	}
+	title := safeFileName(article.Item.Title)
+	if title == "" {
+		title = article.Item.Id
+	}
+	return HighlightExportFolder + "/" + folder + "/" + title + ".md"
+}
+
+// RenderHighlightsMarkdown renders the highlights of an article as a Markdown
+// note with YAML front matter. Every highlight gets a block ID so other notes
+// can link to it.
+func RenderHighlightsMarkdown(article *HighlightedArticle, tagNames map[string]string) string {
+	item := article.Item
+	var b strings.Builder
+
+	b.WriteString("---\n")
+	b.WriteString("title: " + yamlString(item.Title) + "\n")
+	if item.Author != "" {
+		b.WriteString("author: " + yamlString(item.Author) + "\n")
+	}
+	if article.SourceName != "" {
+		b.WriteString("source: " + yamlString(article.SourceName) + "\n")
+	}
+	if item.URL != "" {
+		b.WriteString("url: " + yamlString(item.URL) + "\n")
+	}
+	if !item.PublishedAt.IsZero() {
+		b.WriteString("published: " + item.PublishedAt.Time().Format("2006-01-02") + "\n")
+	}
+	b.WriteString("highlighted: " + latestHighlight(article).Format("2006-01-02") + "\n")
+	b.WriteString(fmt.Sprintf("highlights: %d\n", len(article.Highlights)))
+	b.WriteString("tags:\n  - highlights\n")
+	for _, tagID := range item.Tags {
+		if tag := obsidianTag(tagNames[tagID]); tag != "" {
+			b.WriteString("  - " + tag + "\n")
+		}
+	}
+	b.WriteString("---\n\n")
+
+	b.WriteString("# " + strings.TrimSpace(item.Title) + "\n\n")
+	if item.URL != "" {
+		b.WriteString("[Original article](" + item.URL + ")\n\n")
+	}
+
+	for i, highlight := range article.Highlights {
+		if i > 0 {
+			b.WriteString("---\n\n")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(highlight.Text), "\n") {
+			b.WriteString("> " + strings.TrimSpace(line) + "\n")
+		}
+		b.WriteString("\n^hl-" + highlight.Id + "\n\n")
+		if note := strings.TrimSpace(highlight.Note); note != "" {
+			b.WriteString(note + "\n\n")
+		}
+		b.WriteString("%% color: " + highlight.Color + " %%\n\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func latestHighlight(article *HighlightedArticle) time.Time {
+	var latest time.Time
+	for _, highlight := range article.Highlights {
+		if t := highlight.Created.Time(); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+func userTagNames(userID string) map[string]string {
+	names := make(map[string]string)
+	if tags, err := query.FindAllByFilter[*models.Tag](map[string]interface{}{"user": userID}); err == nil {
+		for _, tag := range tags {
+			names[tag.Id] = tag.Name
+		}
+	}
+	return names
+}
+
+// obsidianTag turns a tag name into a valid Obsidian tag: no spaces, and not
+// only digits
+func obsidianTag(name string) string {
+	tag := strings.Join(strings.Fields(unsafeFileNameChars.ReplaceAllString(name, "")), "-")
+	if strings.Trim(tag, "0123456789") == "" {
+		return ""
+	}
+	return tag
+}
+
+func safeFileName(name string) string {
+	name = strings.Join(strings.Fields(unsafeFileNameChars.ReplaceAllString(name, " ")), " ")
+	name = strings.Trim(name, ". ")
+	if runes := []rune(name); len(runes) > 100 {
+		name = strings.TrimSpace(string(runes[:100]))
+	}
+	return name
+}
+
+// uniqueNotePath numbers notes whose path is already taken
+func uniqueNotePath(name string, used map[string]bool) string {
+	base := strings.TrimSuffix(name, ".md")
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s (%d).md", base, i)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// yamlString quotes a front matter value
+func yamlString(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
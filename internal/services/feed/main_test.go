@@ -0,0 +1,65 @@
+package feed
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/store"
+	_ "github.com/shashank-sharma/backend/migrations"
+)
+
+// testUserID owns the records the tests create
+const testUserID = "feedtestuser001"
+
+// TestMain runs the tests against a PocketBase app with a fresh database in
+// a temporary directory
+func TestMain(m *testing.M) {
+	dataDir, err := os.MkdirTemp("", "feed-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: dataDir})
+	if err := app.Bootstrap(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := app.RunAllMigrations(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	store.InitApp(app)
+
+	// Servers standing in for other sites run locally, don't rate limit them
+	providers.SetHostRate("127.0.0.1", 0)
+
+	if err := createTestUser(app); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	app.ResetBootstrapState()
+	os.RemoveAll(dataDir)
+	os.Exit(code)
+}
+
+func createTestUser(app core.App) error {
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		return err
+	}
+
+	user := core.NewRecord(users)
+	user.Id = testUserID
+	user.Set("username", "feedtest")
+	user.SetEmail("feedtest@example.com")
+	user.SetPassword("feedtest-password")
+	return app.Save(user)
+}
@@ -0,0 +1,365 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	MaxOPMLSize            = 5 * 1024 * 1024
+	opmlValidationWorkers  = 8
+	opmlDefaultRefreshRate = 60
+)
+
+// OPML is the root element of an OPML 2.0 document
+type OPML struct {
+	XMLName xml.Name   `xml:"opml"`
+	Version string     `xml:"version,attr"`
+	Head    OPMLHead   `xml:"head"`
+	Body    []*Outline `xml:"body>outline"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Outline is either a feed (has xmlUrl) or a folder containing other outlines
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Outlines []*Outline `xml:"outline"`
+}
+
+// OPMLFeed is a feed found in an OPML document with the folder it was in
+type OPMLFeed struct {
+	Name     string
+	URL      string
+	SiteURL  string
+	Category string
+}
+
+// OPMLImportResult summarizes an OPML import
+type OPMLImportResult struct {
+	Imported          int               `json:"imported"`
+	Skipped           int               `json:"skipped"`
+	Failed            int               `json:"failed"`
+	CategoriesCreated int               `json:"categories_created"`
+	Errors            map[string]string `json:"errors"`
+}
+
+// ParseOPML parses an OPML document and flattens it into a list of feeds
+func ParseOPML(r io.Reader) ([]OPMLFeed, error) {
+	var doc OPML
+	decoder := xml.NewDecoder(io.LimitReader(r, MaxOPMLSize))
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML document: %v", err)
+	}
+
+	feeds := make([]OPMLFeed, 0)
+	var walk func(outlines []*Outline, category string)
+	walk = func(outlines []*Outline, category string) {
+		for _, outline := range outlines {
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+
+			if outline.XMLURL != "" {
+				feeds = append(feeds, OPMLFeed{
+					Name:     name,
+					URL:      strings.TrimSpace(outline.XMLURL),
+					SiteURL:  outline.HTMLURL,
+					Category: category,
+				})
+				continue
+			}
+
+			// Outlines without a feed URL are folders, the closest folder wins
+			folder := category
+			if name != "" {
+				folder = name
+			}
+			walk(outline.Outlines, folder)
+		}
+	}
+	walk(doc.Body, "")
+
+	return feeds, nil
+}
+
+// ImportOPML creates RSS feed sources for all feeds of an OPML document,
+// mapping folders to feed categories and skipping URLs the user already follows
+func ImportOPML(ctx context.Context, feedService services.FeedService, userID string, r io.Reader) (*OPMLImportResult, error) {
+	provider, exists := feedService.GetProvider(models.SourceTypeRSS)
+	if !exists {
+		return nil, fmt.Errorf("no provider registered for source type: %s", models.SourceTypeRSS)
+	}
+
+	feeds, err := ParseOPML(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OPMLImportResult{Errors: make(map[string]string)}
+
+	existingSources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{
+		"user": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed sources: %v", err)
+	}
+
+	knownURLs := make(map[string]bool)
+	for _, source := range existingSources {
+		knownURLs[normalizeFeedURL(sourceFeedURL(source))] = true
+	}
+
+	// Drop duplicates, both against existing sources and within the document
+	pending := make([]OPMLFeed, 0, len(feeds))
+	for _, f := range feeds {
+		key := normalizeFeedURL(f.URL)
+		if knownURLs[key] {
+			result.Skipped++
+			continue
+		}
+		knownURLs[key] = true
+		pending = append(pending, f)
+	}
+
+	// Validating means fetching every feed, so do it concurrently
+	validationErrors := make([]error, len(pending))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, opmlValidationWorkers)
+	for i, f := range pending {
+		wg.Add(1)
+		go func(i int, f OPMLFeed) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if ctx.Err() != nil {
+				validationErrors[i] = ctx.Err()
+				return
+			}
+			validationErrors[i] = provider.Validate(map[string]interface{}{"url": f.URL})
+		}(i, f)
+	}
+	wg.Wait()
+
+	categories, err := loadCategoriesByName(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range pending {
+		if validationErrors[i] != nil {
+			result.Failed++
+			result.Errors[f.URL] = validationErrors[i].Error()
+			continue
+		}
+
+		categoryIDs := types.JSONArray[string]{}
+		if f.Category != "" {
+			category, created, err := findOrCreateCategory(userID, f.Category, categories)
+			if err != nil {
+				logger.LogError(fmt.Sprintf("Error creating feed category %s: %v", f.Category, err))
+			} else {
+				categoryIDs = append(categoryIDs, category.Id)
+				if created {
+					result.CategoriesCreated++
+				}
+			}
+		}
+
+		name := f.Name
+		if name == "" {
+			name = f.URL
+		}
+
+		configJSON, _ := json.Marshal(map[string]interface{}{"url": f.URL})
+		source := &models.FeedSource{
+			User:        userID,
+			Name:        name,
+			Type:        models.SourceTypeRSS,
+			URL:         f.URL,
+			Config:      string(configJSON),
+			CategoryIDs: categoryIDs,
+			RefreshRate: opmlDefaultRefreshRate,
+			IsActive:    true,
+		}
+		source.Id = util.GenerateRandomId()
+
+		if err := query.SaveRecord(source); err != nil {
+			result.Failed++
+			result.Errors[f.URL] = err.Error()
+			continue
+		}
+		result.Imported++
+	}
+
+	logger.LogInfo(fmt.Sprintf("OPML import for user %s: %d imported, %d skipped, %d failed",
+		userID, result.Imported, result.Skipped, result.Failed))
+
+	return result, nil
+}
+
+// ExportOPML renders all RSS sources of a user as an OPML document grouped by category
+func ExportOPML(userID string) ([]byte, error) {
+	sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{
+		"user": userID,
+		"type": models.SourceTypeRSS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed sources: %v", err)
+	}
+
+	categories, err := query.FindAllByFilter[*models.FeedCategory](map[string]interface{}{
+		"user": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed categories: %v", err)
+	}
+
+	folders := make(map[string]*Outline)
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       "Dashboard feed subscriptions",
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	// Keep the folders in the user's category order
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].SortOrder < categories[j].SortOrder
+	})
+	for _, category := range categories {
+		folder := &Outline{Text: category.Name, Title: category.Name}
+		folders[category.Id] = folder
+	}
+
+	for _, source := range sources {
+		feedURL := sourceFeedURL(source)
+		if feedURL == "" {
+			continue
+		}
+
+		outline := &Outline{
+			Text:   source.Name,
+			Title:  source.Name,
+			Type:   "rss",
+			XMLURL: feedURL,
+		}
+
+		placed := false
+		for _, categoryID := range source.CategoryIDs {
+			if folder, ok := folders[categoryID]; ok {
+				folder.Outlines = append(folder.Outlines, outline)
+				placed = true
+			}
+		}
+		if !placed {
+			doc.Body = append(doc.Body, outline)
+		}
+	}
+
+	for _, category := range categories {
+		if folder := folders[category.Id]; len(folder.Outlines) > 0 {
+			doc.Body = append(doc.Body, folder)
+		}
+	}
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error generating OPML: %v", err)
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+// sourceFeedURL returns the feed URL of a source, preferring the provider config
+func sourceFeedURL(source *models.FeedSource) string {
+	if configMap, err := source.GetConfigMap(); err == nil {
+		if url, ok := configMap["url"].(string); ok && url != "" {
+			return url
+		}
+	}
+	return source.URL
+}
+
+// normalizeFeedURL returns the key duplicate feeds share. Only the scheme and
+// host are case-insensitive, the path and query are kept as they are.
+func normalizeFeedURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSuffix(rawURL, "/")
+	}
+
+	// http and https serve the same feed
+	parsed.Scheme = ""
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	parsed.RawPath = strings.TrimSuffix(parsed.RawPath, "/")
+	parsed.Fragment = ""
+	return strings.TrimPrefix(parsed.String(), "//")
+}
+
+func loadCategoriesByName(userID string) (map[string]*models.FeedCategory, error) {
+	categories, err := query.FindAllByFilter[*models.FeedCategory](map[string]interface{}{
+		"user": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed categories: %v", err)
+	}
+
+	byName := make(map[string]*models.FeedCategory, len(categories))
+	for _, category := range categories {
+		byName[strings.ToLower(category.Name)] = category
+	}
+	return byName, nil
+}
+
+func findOrCreateCategory(userID string, name string, categories map[string]*models.FeedCategory) (*models.FeedCategory, bool, error) {
+	key := strings.ToLower(name)
+	if category, ok := categories[key]; ok {
+		return category, false, nil
+	}
+
+	category := &models.FeedCategory{
+		User:      userID,
+		Name:      name,
+		Color:     generateTagColor(name),
+		Type:      models.CategoryFollowing,
+		SortOrder: len(categories),
+	}
+	category.Id = util.GenerateRandomId()
+
+	if err := query.SaveRecord(category); err != nil {
+		return nil, false, err
+	}
+
+	categories[key] = category
+	return category, true, nil
+}
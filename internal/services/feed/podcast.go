@@ -0,0 +1,364 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	// Attempts before a download is marked failed
+	maxEpisodeAttempts = 3
+	// Downloads processed per run
+	episodeBatchSize       = 3
+	episodeDownloadTimeout = 30 * time.Minute
+	// Share of an episode after which it counts as played
+	episodePlayedShare = 0.95
+)
+
+// ErrNotAnEpisode is returned for feed items without an audio enclosure
+var ErrNotAnEpisode = errors.New("feed item is not a podcast episode")
+
+// Episode is the podcast metadata the RSS provider stores on a feed item
+type Episode struct {
+	AudioURL    string `json:"audio_url"`
+	AudioType   string `json:"audio_type"`
+	AudioSize   int64  `json:"audio_size,omitempty"` // Bytes, as announced by the feed
+	Duration    int    `json:"duration,omitempty"`   // Seconds
+	Episode     int    `json:"episode,omitempty"`
+	Season      int    `json:"season,omitempty"`
+	EpisodeType string `json:"episode_type,omitempty"` // full, trailer or bonus
+	Artwork     string `json:"artwork,omitempty"`
+	Explicit    bool   `json:"explicit,omitempty"`
+}
+
+// ItemEpisode returns the episode metadata of a feed item, or false when the
+// item has no audio
+func ItemEpisode(item *models.FeedItem) (*Episode, bool) {
+	if item.Metadata == "" {
+		return nil, false
+	}
+
+	episode := &Episode{}
+	if err := json.Unmarshal([]byte(item.Metadata), episode); err != nil || episode.AudioURL == "" {
+		return nil, false
+	}
+	return episode, true
+}
+
+// EpisodeDownloader stores podcast episodes in PocketBase storage so they can
+// be played offline, within a size limit per episode and a quota per user
+type EpisodeDownloader struct {
+	maxBytes   int64
+	quotaBytes int64
+	running    atomic.Bool
+}
+
+func NewEpisodeDownloader(cfg config.FeedConfig) *EpisodeDownloader {
+	return &EpisodeDownloader{
+		maxBytes:   int64(cfg.EpisodeMaxMB) * 1024 * 1024,
+		quotaBytes: int64(cfg.EpisodeQuotaMB) * 1024 * 1024,
+	}
+}
+
+// MaxBytes returns the size of the largest episode that is downloaded
+func (d *EpisodeDownloader) MaxBytes() int64 {
+	return d.maxBytes
+}
+
+// QuotaBytes returns the episode storage each user may use
+func (d *EpisodeDownloader) QuotaBytes() int64 {
+	return d.quotaBytes
+}
+
+// QueueEpisodeDownload creates a pending download for an episode. Downloads
+// that didn't succeed before are tried again, others are returned as is.
+func QueueEpisodeDownload(item *models.FeedItem) (*models.FeedEpisodeDownload, error) {
+	episode, ok := ItemEpisode(item)
+	if !ok {
+		return nil, ErrNotAnEpisode
+	}
+
+	download, err := query.FindByFilter[*models.FeedEpisodeDownload](map[string]interface{}{"item": item.Id})
+	if err == nil {
+		if download.Status == models.EpisodeDownloadPending || download.Status == models.EpisodeDownloadDownloaded {
+			return download, nil
+		}
+		if err := query.UpdateRecord[*models.FeedEpisodeDownload](download.Id, map[string]interface{}{
+			"status":   models.EpisodeDownloadPending,
+			"url":      episode.AudioURL,
+			"attempts": 0,
+			"error":    "",
+		}); err != nil {
+			return nil, err
+		}
+		download.Status = models.EpisodeDownloadPending
+		download.URL = episode.AudioURL
+		download.Attempts = 0
+		download.Error = ""
+		return download, nil
+	}
+
+	download = &models.FeedEpisodeDownload{
+		User:        item.User,
+		Item:        item.Id,
+		URL:         episode.AudioURL,
+		ContentType: episode.AudioType,
+		Status:      models.EpisodeDownloadPending,
+	}
+	download.Id = util.GenerateRandomId()
+	download.RefreshCreated()
+	download.RefreshUpdated()
+	if err := query.SaveRecord(download); err != nil {
+		return nil, err
+	}
+
+	return download, nil
+}
+
+// Trigger processes the pending downloads in the background
+func (d *EpisodeDownloader) Trigger() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*episodeDownloadTimeout)
+		defer cancel()
+		if err := d.RunPending(ctx); err != nil {
+			logger.LogError(fmt.Sprintf("Error downloading podcast episodes: %v", err))
+		}
+	}()
+}
+
+// RunPending downloads the oldest pending episodes. Runs that overlap a
+// previous one are skipped.
+func (d *EpisodeDownloader) RunPending(ctx context.Context) error {
+	if !d.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer d.running.Store(false)
+
+	var downloads []*models.FeedEpisodeDownload
+	err := query.BaseQuery[*models.FeedEpisodeDownload]().
+		AndWhere(dbx.HashExp{"status": models.EpisodeDownloadPending}).
+		OrderBy("created ASC").
+		Limit(episodeBatchSize).
+		All(&downloads)
+	if err != nil {
+		return fmt.Errorf("error loading pending episode downloads: %v", err)
+	}
+
+	for _, download := range downloads {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := d.Download(ctx, download); err != nil {
+			logger.LogError(fmt.Sprintf("Error downloading episode %s: %v", download.URL, err))
+		}
+	}
+
+	return nil
+}
+
+// Download stores the audio of an episode. Episodes that don't fit the size
+// limit or the user's quota are marked as such, failed downloads are retried
+// by later runs until the attempts run out.
+func (d *EpisodeDownloader) Download(ctx context.Context, download *models.FeedEpisodeDownload) error {
+	app := store.GetDao()
+
+	record, err := app.FindRecordById(download.TableName(), download.Id)
+	if err != nil {
+		return err
+	}
+	attempts := record.GetInt("attempts") + 1
+	record.Set("attempts", attempts)
+
+	usage, err := d.Usage(download.User, download.Id)
+	if err != nil {
+		return err
+	}
+
+	limit := min(d.maxBytes, d.quotaBytes-usage)
+	if limit <= 0 {
+		record.Set("status", models.EpisodeDownloadQuotaExceeded)
+		record.Set("error", fmt.Sprintf("episode storage quota of %d MB exceeded", d.quotaBytes/1024/1024))
+		return app.Save(record)
+	}
+
+	tmp, err := os.CreateTemp("", "episode-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	downloadCtx, cancel := context.WithTimeout(ctx, episodeDownloadTimeout)
+	defer cancel()
+
+	contentType, size, err := providers.DownloadFile(downloadCtx, download.URL, tmp, limit)
+	if errors.Is(err, providers.ErrFileTooLarge) {
+		if limit < d.maxBytes {
+			record.Set("status", models.EpisodeDownloadQuotaExceeded)
+			record.Set("error", fmt.Sprintf("episode storage quota of %d MB exceeded", d.quotaBytes/1024/1024))
+		} else {
+			record.Set("status", models.EpisodeDownloadTooLarge)
+			record.Set("error", fmt.Sprintf("episode is larger than %d MB", d.maxBytes/1024/1024))
+		}
+		return app.Save(record)
+	}
+	if err != nil {
+		status := models.EpisodeDownloadPending
+		if attempts >= maxEpisodeAttempts {
+			status = models.EpisodeDownloadFailed
+		}
+		record.Set("status", status)
+		record.Set("error", err.Error())
+		if saveErr := app.Save(record); saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	contentType = episodeContentType(contentType, download)
+	file, err := filesystem.NewFileFromPath(tmp.Name())
+	if err != nil {
+		return err
+	}
+	// Named after the audio format so players and downloads recognize it,
+	// the suffix keeps a replaced file from clashing with the previous one
+	ext := episodeExtension(contentType, download.URL)
+	file.OriginalName = "episode" + ext
+	file.Name = "episode_" + security.RandomStringWithAlphabet(10, "abcdefghijklmnopqrstuvwxyz0123456789") + ext
+
+	// Replacing the file lets PocketBase remove the previous one
+	record.Set("audio", file)
+	record.Set("content_type", contentType)
+	record.Set("size", size)
+	record.Set("status", models.EpisodeDownloadDownloaded)
+	record.Set("error", "")
+	record.Set("downloaded_at", types.NowDateTime())
+
+	return app.Save(record)
+}
+
+// Usage returns the bytes used by the downloaded episodes of a user, leaving
+// out the given download so it can be replaced
+func (d *EpisodeDownloader) Usage(userID string, excludeID string) (int64, error) {
+	var usage struct {
+		Total int64 `db:"total"`
+	}
+	err := store.GetDao().DB().
+		NewQuery("SELECT COALESCE(SUM(size), 0) AS total FROM feed_episode_downloads WHERE user = {:user} AND id != {:id} AND status = {:status}").
+		Bind(dbx.Params{"user": userID, "id": excludeID, "status": models.EpisodeDownloadDownloaded}).
+		One(&usage)
+	if err != nil {
+		return 0, fmt.Errorf("error computing episode storage usage: %v", err)
+	}
+	return usage.Total, nil
+}
+
+// episodeContentType prefers the audio type the server sent, falling back to
+// the type the feed announced
+func episodeContentType(contentType string, download *models.FeedEpisodeDownload) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "audio/") {
+		return mediaType
+	}
+	if download.ContentType != "" {
+		return download.ContentType
+	}
+	return "audio/mpeg"
+}
+
+// episodeExtension returns the file extension of the audio, taken from the
+// URL when it names one and from the content type otherwise
+func episodeExtension(contentType string, audioURL string) string {
+	if parsed, err := url.Parse(audioURL); err == nil {
+		ext := strings.ToLower(path.Ext(parsed.Path))
+		if mime.TypeByExtension(ext) != "" && len(ext) <= 5 {
+			return ext
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[len(exts)-1]
+	}
+	return ".mp3"
+}
+
+// SavePlaybackPosition stores where a user stopped listening to an episode.
+// Without an explicit completed flag an episode counts as played once the
+// position reaches the end, its item is then marked read.
+func SavePlaybackPosition(item *models.FeedItem, position float64, duration float64, completed *bool) (*models.FeedPlaybackPosition, error) {
+	if position < 0 || duration < 0 {
+		return nil, fmt.Errorf("position and duration can't be negative")
+	}
+
+	playback, err := query.FindByFilter[*models.FeedPlaybackPosition](map[string]interface{}{
+		"user": item.User,
+		"item": item.Id,
+	})
+	if err != nil {
+		playback = &models.FeedPlaybackPosition{
+			User: item.User,
+			Item: item.Id,
+		}
+		playback.Id = util.GenerateRandomId()
+		playback.RefreshCreated()
+	} else {
+		playback.MarkAsNotNew()
+	}
+
+	if duration <= 0 {
+		duration = playback.Duration
+	}
+	if duration <= 0 {
+		if episode, ok := ItemEpisode(item); ok {
+			duration = float64(episode.Duration)
+		}
+	}
+
+	wasCompleted := playback.Completed
+	playback.Position = position
+	playback.Duration = duration
+	if completed != nil {
+		playback.Completed = *completed
+	} else {
+		playback.Completed = duration > 0 && position >= duration*episodePlayedShare
+	}
+	playback.PlayedAt = types.NowDateTime()
+	playback.RefreshUpdated()
+
+	if err := query.SaveRecord(playback); err != nil {
+		return nil, err
+	}
+
+	if playback.Completed && !wasCompleted && item.Status == models.StatusUnread {
+		if err := query.UpdateRecord[*models.FeedItem](item.Id, map[string]interface{}{
+			"status": models.StatusRead,
+		}); err != nil {
+			logger.LogError(fmt.Sprintf("Error marking episode %s read: %v", item.Id, err))
+		}
+	}
+
+	return playback, nil
+}
@@ -0,0 +1,21 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/store"
+)
+
+func TestEpisodeDownloadRecordsAreServerOwned(t *testing.T) {
+	collection, err := store.GetDao().FindCollectionByNameOrId((&models.FeedEpisodeDownload{}).TableName())
+	if err != nil {
+		t.Fatalf("finding collection: %v", err)
+	}
+	if collection.CreateRule != nil || collection.UpdateRule != nil {
+		t.Errorf("users may create or update downloads, their size counts towards the quota")
+	}
+	if collection.DeleteRule == nil || *collection.DeleteRule != "@request.auth.id = user" {
+		t.Errorf("got delete rule %v, want owners to delete their downloads", collection.DeleteRule)
+	}
+}
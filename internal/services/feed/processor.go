@@ -2,24 +2,33 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/shashank-sharma/backend/internal/logger"
 	"github.com/shashank-sharma/backend/internal/models"
 	"github.com/shashank-sharma/backend/internal/query"
 	"github.com/shashank-sharma/backend/internal/services"
 	"github.com/shashank-sharma/backend/internal/services/ai"
+	"github.com/shashank-sharma/backend/internal/services/providers"
 	"github.com/shashank-sharma/backend/internal/util"
 )
 
+// ConfigFetchFullContent is the source config option that enables downloading
+// and extracting the linked article for every item
+const ConfigFetchFullContent = "fetch_full_content"
+
 type FeedProcessorImpl struct {
-	aiClient ai.AIClient
+	aiClient       ai.AIClient
+	contentFetcher *providers.HTMLContentFetcher
 }
 
 func NewFeedProcessor(aiClient ai.AIClient) services.FeedProcessor {
 	return &FeedProcessorImpl{
-		aiClient: aiClient,
+		aiClient:       aiClient,
+		contentFetcher: providers.NewHTMLContentFetcher(30 * time.Second),
 	}
 }
 
@@ -27,8 +36,16 @@ func NewFeedProcessor(aiClient ai.AIClient) services.FeedProcessor {
 func (p *FeedProcessorImpl) ProcessItem(ctx context.Context, item *models.FeedItem) error {
 	logger.LogInfo(fmt.Sprintf("Processing feed item: %s", item.Title))
 
-	// 1. Generate summary if content is available
-	if item.Content != "" {
+	// 1. Extract the full article when the source asks for it, so the summary
+	// and tags below are based on the article rather than the feed teaser
+	if item.FullContent == "" && item.URL != "" && p.shouldFetchFullContent(item) {
+		if err := p.ExtractFullContent(ctx, item); err != nil {
+			logger.LogError(fmt.Sprintf("Error extracting full content for %s: %v", item.URL, err))
+		}
+	}
+
+	// 2. Generate summary if content is available
+	if item.ArticleContent() != "" {
 		summary, err := p.GenerateSummary(ctx, item)
 		if err != nil {
 			logger.LogError(fmt.Sprintf("Error generating summary: %v", err))
@@ -37,7 +54,7 @@ func (p *FeedProcessorImpl) ProcessItem(ctx context.Context, item *models.FeedIt
 		}
 	}
 
-	// 2. Suggest tags if not already present
+	// 3. Suggest tags if not already present
 	if len(item.Tags) == 0 {
 		tags, err := p.SuggestTags(ctx, item)
 		if err != nil {
@@ -49,14 +66,54 @@ func (p *FeedProcessorImpl) ProcessItem(ctx context.Context, item *models.FeedIt
 		}
 	}
 
-	// 3. Update the processed item in the database
+	// 4. Update the processed item in the database
 	return query.UpdateRecord[*models.FeedItem](item.Id, map[string]interface{}{
 		"summary":      item.Summary,
 		"tags":         item.Tags,
+		"full_content": item.FullContent,
+		"lead_image":   item.LeadImage,
+		"reading_time": item.ReadingTime,
+		"language":     item.Language,
 		"is_processed": true,
 	})
 }
 
+// ExtractFullContent downloads the page linked by the item and stores the
+// cleaned article HTML, lead image, reading time and language on the item.
+// The original feed content is left untouched. Item links come from feeds the
+// user added, so only public addresses are fetched.
+func (p *FeedProcessorImpl) ExtractFullContent(ctx context.Context, item *models.FeedItem) error {
+	article, err := p.contentFetcher.FetchArticle(providers.WithPublicAddressesOnly(ctx), item.URL)
+	if err != nil {
+		return err
+	}
+
+	item.FullContent = article.Content
+	item.ReadingTime = article.ReadingTime
+	item.Language = article.Language
+	if item.LeadImage == "" {
+		item.LeadImage = article.LeadImage
+	}
+
+	return nil
+}
+
+// shouldFetchFullContent checks the item's source for the full content option
+func (p *FeedProcessorImpl) shouldFetchFullContent(item *models.FeedItem) bool {
+	source, err := query.FindById[*models.FeedSource](item.SourceID)
+	if err != nil {
+		return false
+	}
+
+	config, err := source.GetConfigMap()
+	if err != nil {
+		return false
+	}
+
+	enabled, _ := config[ConfigFetchFullContent].(bool)
+	return enabled
+}
+
 // GenerateSummary generates a summary for the feed item using AI
 func (p *FeedProcessorImpl) GenerateSummary(ctx context.Context, item *models.FeedItem) (string, error) {
 	// If AI client isn't configured, use a simple fallback approach
@@ -66,19 +123,32 @@ func (p *FeedProcessorImpl) GenerateSummary(ctx context.Context, item *models.Fe
 	}
 
 	// Extract clean text from content
-	content := stripTags(item.Content)
+	content := stripTags(item.ArticleContent())
 	if content == "" {
 		return "", nil
 	}
 
+	maxLength := 150 // Default summary length
+	if item.FullContent != "" {
+		maxLength = 300
+	}
+	if metadata, err := item.GetMetadataMap(); err == nil {
+		// Video transcripts cover much more ground than an article teaser
+		if transcript, _ := metadata["transcript"].(bool); transcript {
+			maxLength = 500
+		}
+	}
+
 	// Use AI client to summarize
-	resp, err := p.aiClient.Summarize(ctx, &ai.SummarizeRequest{
+	resp, err := p.aiClient.Summarize(aiContext(ctx, item), &ai.SummarizeRequest{
 		Text:      content,
-		MaxLength: 150, // Default summary length
+		MaxLength: maxLength,
 	})
 
 	if err != nil {
-		logger.LogError(fmt.Sprintf("AI summarization error: %v", err))
+		if !errors.Is(err, ai.ErrBudgetExceeded) {
+			logger.LogError(fmt.Sprintf("AI summarization error: %v", err))
+		}
 		// If AI fails, fall back to simple approach
 		return p.fallbackGenerateSummary(item)
 	}
@@ -86,9 +156,18 @@ func (p *FeedProcessorImpl) GenerateSummary(ctx context.Context, item *models.Fe
 	return resp.Summary, nil
 }
 
+// aiContext counts the AI requests for an item against the budget of its
+// user, as feed processing unless the caller named its own feature
+func aiContext(ctx context.Context, item *models.FeedItem) context.Context {
+	if _, ok := ai.CallerFromContext(ctx); ok {
+		return ctx
+	}
+	return ai.WithCaller(ctx, ai.FeatureFeedProcessing, item.User)
+}
+
 // fallbackGenerateSummary provides a simple non-AI summary when AI is unavailable
 func (p *FeedProcessorImpl) fallbackGenerateSummary(item *models.FeedItem) (string, error) {
-	content := stripTags(item.Content)
+	content := strings.TrimSpace(stripTags(item.ArticleContent()))
 	if len(content) > 150 {
 		return content[:150] + "...", nil
 	}
@@ -104,17 +183,20 @@ func (p *FeedProcessorImpl) SuggestTags(ctx context.Context, item *models.FeedIt
 		tagNames, err = p.fallbackSuggestTagNames(item)
 	} else {
 		// Extract clean text from content
-		content := stripTags(item.Content)
+		content := stripTags(item.ArticleContent())
 		
 		// Use AI client to suggest tags
-		resp, err := p.aiClient.SuggestTags(ctx, &ai.TagRequest{
+		resp, err := p.aiClient.SuggestTags(aiContext(ctx, item), &ai.TagRequest{
 			Title:   item.Title,
 			Content: content,
 			MaxTags: 5, // Default max tags
+			UserID:  item.User,
 		})
 
 		if err != nil {
-			logger.LogError(fmt.Sprintf("AI tagging error: %v", err))
+			if !errors.Is(err, ai.ErrBudgetExceeded) {
+				logger.LogError(fmt.Sprintf("AI tagging error: %v", err))
+			}
 			tagNames, err = p.fallbackSuggestTagNames(item)
 		} else {
 			tagNames = resp.Tags
@@ -132,46 +214,55 @@ func (p *FeedProcessorImpl) SuggestTags(ctx context.Context, item *models.FeedIt
 			continue
 		}
 		
-		// Normalize tag name (lowercase, trim)
-		tagName = strings.ToLower(strings.TrimSpace(tagName))
-		
-		// Check if tag already exists for this user
-		existingTag, err := query.FindByFilter[*models.Tag](map[string]interface{}{
-			"user": item.User,
-			"name": tagName,
-		})
-		
-		if err == nil && existingTag != nil {
-			// Tag exists, use its ID
-			tagIDs = append(tagIDs, existingTag.Id)
-		} else {
-			// Tag doesn't exist, create a new one
-			// TODO: Use query.Upsert
-			newTag := &models.Tag{
-				User:        item.User,
-				Name:        tagName,
-				Color:       generateTagColor(tagName),
-				Description: "", // Empty description for AI-generated tags
-				IsAICreated: true,
-			}
-			
-			newTag.Id = util.GenerateRandomId()
-			if err := query.SaveRecord(newTag); err != nil {
-				logger.LogError(fmt.Sprintf("Error saving new tag: %v", err))
-				continue
-			}
-			
-			tagIDs = append(tagIDs, newTag.Id)
+		tagID, err := findOrCreateTag(item.User, tagName, true)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error saving new tag: %v", err))
+			continue
 		}
+		
+		tagIDs = append(tagIDs, tagID)
 	}
 	
 	return tagIDs, nil
 }
 
+// findOrCreateTag returns the ID of the user's tag with the given name,
+// creating the tag when it doesn't exist yet
+func findOrCreateTag(userID string, tagName string, aiCreated bool) (string, error) {
+	// Normalize tag name (lowercase, trim)
+	tagName = strings.ToLower(strings.TrimSpace(tagName))
+
+	// Check if tag already exists for this user
+	existingTag, err := query.FindByFilter[*models.Tag](map[string]interface{}{
+		"user": userID,
+		"name": tagName,
+	})
+	if err == nil && existingTag != nil {
+		return existingTag.Id, nil
+	}
+
+	// Tag doesn't exist, create a new one
+	// TODO: Use query.Upsert
+	newTag := &models.Tag{
+		User:        userID,
+		Name:        tagName,
+		Color:       generateTagColor(tagName),
+		Description: "",
+		IsAICreated: aiCreated,
+	}
+
+	newTag.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(newTag); err != nil {
+		return "", err
+	}
+
+	return newTag.Id, nil
+}
+
 // fallbackSuggestTagNames provides simple keyword-based tagging when AI is unavailable
 func (p *FeedProcessorImpl) fallbackSuggestTagNames(item *models.FeedItem) ([]string, error) {
 	// Combine title and content for analysis
-	text := item.Title + " " + stripTags(item.Content)
+	text := item.Title + " " + stripTags(item.ArticleContent())
 	text = strings.ToLower(text)
 
 	// Common tech keywords - basic keyword matching
@@ -0,0 +1,31 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+)
+
+func TestExtractFullContentRefusesInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><article><p>Internal metadata</p></article></body></html>`))
+	}))
+	defer server.Close()
+
+	processor := NewFeedProcessor(nil).(*FeedProcessorImpl)
+	item := &models.FeedItem{URL: server.URL + "/latest/meta-data/"}
+
+	err := processor.ExtractFullContent(context.Background(), item)
+	if err == nil || !strings.Contains(err.Error(), providers.ErrNonPublicAddress.Error()) {
+		t.Fatalf("got %v, want the internal address to be refused", err)
+	}
+	if item.FullContent != "" {
+		t.Errorf("got full content %q", item.FullContent)
+	}
+}
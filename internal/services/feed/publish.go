@@ -0,0 +1,449 @@
+package feed
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+)
+
+const (
+	defaultPublicationMaxItems = 50
+	maxPublicationMaxItems     = 200
+	publicationTokenLength     = 40
+)
+
+// PublishedFeed is a rendered publication with the values needed for HTTP
+// caching
+type PublishedFeed struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// publishedEntry is the format independent form of a published item
+type publishedEntry struct {
+	ID        string
+	Title     string
+	URL       string
+	Author    string
+	Summary   string
+	Content   string
+	Tags      []string
+	Published time.Time
+	Updated   time.Time
+}
+
+// NewPublicationToken returns a new secret token for a publication URL
+func NewPublicationToken() string {
+	return security.RandomString(publicationTokenLength)
+}
+
+// ValidatePublication checks the scope of a publication and that the
+// category or tag it publishes belongs to the user
+func ValidatePublication(pub *models.FeedPublication) error {
+	if strings.TrimSpace(pub.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if pub.MaxItems < 0 || pub.MaxItems > maxPublicationMaxItems {
+		return fmt.Errorf("max_items must be between 0 (default) and %d", maxPublicationMaxItems)
+	}
+
+	switch pub.Scope {
+	case models.PublicationScopeAll:
+		pub.ScopeID = ""
+	case models.PublicationScopeCategory:
+		if _, err := query.FindByFilter[*models.FeedCategory](map[string]interface{}{"id": pub.ScopeID, "user": pub.User}); err != nil {
+			return fmt.Errorf("category not found: %s", pub.ScopeID)
+		}
+	case models.PublicationScopeTag:
+		if _, err := query.FindByFilter[*models.Tag](map[string]interface{}{"id": pub.ScopeID, "user": pub.User}); err != nil {
+			return fmt.Errorf("tag not found: %s", pub.ScopeID)
+		}
+	default:
+		return fmt.Errorf("scope must be %s, %s or %s", models.PublicationScopeAll, models.PublicationScopeCategory, models.PublicationScopeTag)
+	}
+
+	return nil
+}
+
+// LoadPublishedItems returns the newest items of a publication. Dismissed
+// items are never published and only one item is taken per story.
+func LoadPublishedItems(pub *models.FeedPublication) ([]*models.FeedItem, error) {
+	q := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": pub.User, "is_cluster_primary": true})
+
+	if pub.SavedOnly {
+		q.AndWhere(dbx.HashExp{"status": models.StatusSaved})
+	} else {
+		q.AndWhere(dbx.Not(dbx.HashExp{"status": models.StatusDismissed}))
+	}
+
+	switch pub.Scope {
+	case models.PublicationScopeCategory:
+		q.AndWhere(dbx.NewExp(
+			"(EXISTS (SELECT 1 FROM json_each(feed_items.category_ids) WHERE value = {:scope}) OR "+
+				"EXISTS (SELECT 1 FROM feed_sources s, json_each(s.category_ids) WHERE s.id = feed_items.source_id AND value = {:scope}))",
+			dbx.Params{"scope": pub.ScopeID},
+		))
+	case models.PublicationScopeTag:
+		q.AndWhere(dbx.NewExp(
+			"EXISTS (SELECT 1 FROM json_each(feed_items.tags) WHERE value = {:scope})",
+			dbx.Params{"scope": pub.ScopeID},
+		))
+	}
+
+	limit := pub.MaxItems
+	if limit <= 0 {
+		limit = defaultPublicationMaxItems
+	}
+
+	var items []*models.FeedItem
+	err := q.OrderBy("published_at DESC").Limit(int64(limit)).All(&items)
+	if err != nil {
+		return nil, fmt.Errorf("error loading published items: %v", err)
+	}
+
+	return items, nil
+}
+
+// RenderPublication renders the items of a publication in the given format.
+// selfURL is the public URL of the feed itself.
+func RenderPublication(pub *models.FeedPublication, items []*models.FeedItem, format string, selfURL string) (*PublishedFeed, error) {
+	entries := buildPublishedEntries(pub.User, items)
+
+	// The feed changes when an item or the publication itself changes
+	updated := pub.Updated.Time()
+	hash := sha1.New()
+	fmt.Fprintf(hash, "%s|%s|%d|%s", pub.Id, format, pub.Updated.Time().UnixNano(), pub.Name)
+	for _, entry := range entries {
+		if entry.Updated.After(updated) {
+			updated = entry.Updated
+		}
+		fmt.Fprintf(hash, "|%s:%d", entry.ID, entry.Updated.UnixNano())
+	}
+	if updated.IsZero() {
+		updated = pub.Created.Time()
+	}
+	updated = updated.UTC().Truncate(time.Second)
+
+	var body []byte
+	var contentType string
+	var err error
+	switch format {
+	case models.PublicationFormatAtom:
+		body, err = renderAtom(pub, entries, updated, selfURL)
+		contentType = "application/atom+xml; charset=utf-8"
+	case models.PublicationFormatRSS:
+		body, err = renderRSS(pub, entries, updated, selfURL)
+		contentType = "application/rss+xml; charset=utf-8"
+	case models.PublicationFormatJSON:
+		body, err = renderJSONFeed(pub, entries, selfURL)
+		contentType = "application/feed+json; charset=utf-8"
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublishedFeed{
+		Body:         body,
+		ContentType:  contentType,
+		ETag:         `W/"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
+		LastModified: updated,
+	}, nil
+}
+
+func buildPublishedEntries(userID string, items []*models.FeedItem) []publishedEntry {
+	tagNames := make(map[string]string)
+	if tags, err := query.FindAllByFilter[*models.Tag](map[string]interface{}{"user": userID}); err == nil {
+		for _, tag := range tags {
+			tagNames[tag.Id] = tag.Name
+		}
+	}
+
+	entries := make([]publishedEntry, 0, len(items))
+	for _, item := range items {
+		entry := publishedEntry{
+			ID:        item.Id,
+			Title:     item.Title,
+			URL:       item.URL,
+			Author:    item.Author,
+			Summary:   strings.TrimSpace(item.Summary),
+			Content:   item.ArticleContent(),
+			Published: item.PublishedAt.Time(),
+			Updated:   itemUpdatedAt(item),
+		}
+		if entry.Published.IsZero() {
+			entry.Published = entry.Updated
+		}
+		for _, tagID := range item.Tags {
+			if name := tagNames[tagID]; name != "" {
+				entry.Tags = append(entry.Tags, name)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// itemUpdatedAt returns when an item last changed. Items stored without an
+// updated timestamp fall back to when they were fetched or published.
+func itemUpdatedAt(item *models.FeedItem) time.Time {
+	for _, t := range []time.Time{item.Updated.Time(), item.FetchedAt.Time(), item.PublishedAt.Time()} {
+		if !t.IsZero() {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// entryGUID identifies an item in published feeds independently of its URL
+func entryGUID(id string) string {
+	return "urn:dashboard:feed-item:" + id
+}
+
+func publicationDescription(pub *models.FeedPublication) string {
+	if pub.Description != "" {
+		return pub.Description
+	}
+	return pub.Name
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Author   atomAuthor  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+func renderAtom(pub *models.FeedPublication, entries []publishedEntry, updated time.Time, selfURL string) ([]byte, error) {
+	doc := atomFeed{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		ID:       "urn:dashboard:feed-publication:" + pub.Id,
+		Title:    pub.Name,
+		Subtitle: pub.Description,
+		Updated:  updated.Format(time.RFC3339),
+		Author:   atomAuthor{Name: pub.Name},
+		Links:    []atomLink{{Href: selfURL, Rel: "self", Type: "application/atom+xml"}},
+	}
+
+	for _, entry := range entries {
+		atom := atomEntry{
+			ID:        entryGUID(entry.ID),
+			Title:     entry.Title,
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Updated:   entry.Updated.Format(time.RFC3339),
+		}
+		if entry.URL != "" {
+			atom.Links = []atomLink{{Href: entry.URL, Rel: "alternate"}}
+		}
+		if entry.Author != "" {
+			atom.Author = &atomAuthor{Name: entry.Author}
+		}
+		for _, tag := range entry.Tags {
+			atom.Categories = append(atom.Categories, atomCategory{Term: tag})
+		}
+		if entry.Summary != "" {
+			atom.Summary = &atomText{Type: "text", Body: entry.Summary}
+		}
+		if entry.Content != "" {
+			atom.Content = &atomText{Type: "html", Body: entry.Content}
+		}
+		doc.Entries = append(doc.Entries, atom)
+	}
+
+	return marshalXML(doc)
+}
+
+type rssFeed struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	XmlnsAtom    string     `xml:"xmlns:atom,attr"`
+	XmlnsContent string     `xml:"xmlns:content,attr"`
+	XmlnsDC      string     `xml:"xmlns:dc,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content:encoded,omitempty"`
+}
+
+func renderRSS(pub *models.FeedPublication, entries []publishedEntry, updated time.Time, selfURL string) ([]byte, error) {
+	doc := rssFeed{
+		Version:      "2.0",
+		XmlnsAtom:    "http://www.w3.org/2005/Atom",
+		XmlnsContent: "http://purl.org/rss/1.0/modules/content/",
+		XmlnsDC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         pub.Name,
+			Link:          selfURL,
+			Description:   publicationDescription(pub),
+			LastBuildDate: updated.Format(time.RFC1123Z),
+			AtomLink:      atomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+
+	for _, entry := range entries {
+		item := rssItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entryGUID(entry.ID)},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+			Author:      entry.Author,
+			Categories:  entry.Tags,
+			Description: entry.Summary,
+			Content:     entry.Content,
+		}
+		if item.Description == "" {
+			item.Description = summarizeText(stripTags(entry.Content), 300)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return marshalXML(doc)
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	ContentText   string           `json:"content_text,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+func renderJSONFeed(pub *models.FeedPublication, entries []publishedEntry, selfURL string) ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       pub.Name,
+		FeedURL:     selfURL,
+		Description: pub.Description,
+		Items:       make([]jsonFeedItem, 0, len(entries)),
+	}
+
+	for _, entry := range entries {
+		item := jsonFeedItem{
+			ID:            entryGUID(entry.ID),
+			URL:           entry.URL,
+			Title:         entry.Title,
+			ContentHTML:   entry.Content,
+			Summary:       entry.Summary,
+			DatePublished: entry.Published.UTC().Format(time.RFC3339),
+			DateModified:  entry.Updated.Format(time.RFC3339),
+			Tags:          entry.Tags,
+		}
+		// Every item needs content, fall back to the summary
+		if item.ContentHTML == "" {
+			item.ContentText = entry.Summary
+		}
+		if entry.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: entry.Author}}
+		}
+		doc.Items = append(doc.Items, item)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error rendering feed: %v", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// summarizeText shortens plain text to at most n runes
+func summarizeText(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,577 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+)
+
+const (
+	// Number of recent interactions the user profile is learned from
+	profileHistoryLimit = 1000
+	// Number of candidate items ranked per request
+	rankCandidateLimit = 500
+	// Number of top items re-scored by the AI client
+	aiRerankLimit   = 20
+	aiRerankTimeout = 30 * time.Second
+
+	defaultRankHalfLifeHours = 24.0
+	defaultRankMaxAgeDays    = 14
+
+	// Share of the final score taken by preference vs recency
+	preferenceWeight = 0.65
+	recencyWeight    = 0.35
+	// Share of the final score taken by the AI score when it is blended in
+	aiBlendWeight = 0.3
+
+	// Pseudo count added to every feature so a single interaction doesn't
+	// dominate the profile
+	featurePrior = 2.0
+	// Contributions below this are not worth explaining
+	minReasonContribution = 0.1
+	maxReasons            = 3
+)
+
+// Feature groups of the user profile and how much each group counts
+const (
+	featureSource  = "source"
+	featureTag     = "tag"
+	featureAuthor  = "author"
+	featureKeyword = "keyword"
+)
+
+var featureGroupWeights = map[string]float64{
+	featureSource:  1.0,
+	featureTag:     0.8,
+	featureAuthor:  0.6,
+	featureKeyword: 0.5,
+}
+
+// Words that say nothing about the topic of a title
+var rankStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "this": true,
+	"that": true, "your": true, "you": true, "are": true, "how": true, "why": true,
+	"what": true, "when": true, "who": true, "new": true, "not": true, "but": true,
+	"can": true, "all": true, "its": true, "was": true, "will": true, "into": true,
+	"about": true, "have": true, "has": true, "our": true, "out": true, "now": true,
+	"just": true, "more": true, "than": true, "over": true, "show": true, "ask": true,
+}
+
+// FeedRanker orders feed items for a user based on what they read, saved,
+// dismissed and rated. The profile is learned locally so ranking works without
+// an AI client, when one is configured its RecommendContent score can be
+// blended in for the top items.
+type FeedRanker struct {
+	aiClient ai.AIClient
+}
+
+// RankOptions controls a ranking request
+type RankOptions struct {
+	Status        string  // Candidate status, unread by default
+	SourceID      string  // Only rank items of this source
+	MaxAgeDays    int     // Ignore items published before this many days
+	HalfLifeHours float64 // Hours after which the recency score halves
+	UseAI         bool    // Blend in RecommendContent scores
+	Limit         int
+	Offset        int
+}
+
+// featureStat accumulates the signals seen for a single feature
+type featureStat struct {
+	Sum   float64
+	Count float64
+}
+
+// UserProfile holds the learned affinity of a user for sources, tags, authors
+// and title keywords. Weights are in roughly [-3, 3], positive means liked.
+type UserProfile struct {
+	UserID       string
+	Interactions int
+	features     map[string]map[string]*featureStat
+}
+
+// contribution is a single feature's share of an item's preference score
+type contribution struct {
+	Group string
+	Key   string
+	Value float64
+}
+
+func NewFeedRanker(aiClient ai.AIClient) *FeedRanker {
+	return &FeedRanker{
+		aiClient: aiClient,
+	}
+}
+
+// ParseRankOptions reads ranking options from the generic options map used by
+// the feed service
+func ParseRankOptions(options map[string]interface{}) RankOptions {
+	opts := RankOptions{
+		Status:        models.StatusUnread,
+		MaxAgeDays:    defaultRankMaxAgeDays,
+		HalfLifeHours: defaultRankHalfLifeHours,
+		Limit:         50,
+	}
+
+	if status, ok := options["status"].(string); ok && status != "" {
+		opts.Status = status
+	}
+	if sourceID, ok := options["source_id"].(string); ok {
+		opts.SourceID = sourceID
+	}
+	if days, ok := options["max_age_days"].(int); ok && days > 0 {
+		opts.MaxAgeDays = days
+	}
+	if hours, ok := options["half_life_hours"].(float64); ok && hours > 0 {
+		opts.HalfLifeHours = hours
+	}
+	if useAI, ok := options["ai"].(bool); ok {
+		opts.UseAI = useAI
+	}
+	if limit, ok := options["limit"].(int); ok && limit > 0 {
+		opts.Limit = limit
+	}
+	if offset, ok := options["offset"].(int); ok && offset > 0 {
+		opts.Offset = offset
+	}
+
+	return opts
+}
+
+// signalWeight turns the interaction with an item into a single signal:
+// saving is the strongest positive signal, dismissing a negative one and a
+// rating moves it up or down around the neutral 3
+func signalWeight(item *models.FeedItem) float64 {
+	weight := 0.0
+	switch item.Status {
+	case models.StatusSaved:
+		weight = 3
+	case models.StatusRead:
+		weight = 1
+	case models.StatusDismissed:
+		weight = -2
+	}
+
+	if item.Rating > 0 {
+		weight += float64(item.Rating - 3)
+	}
+
+	return weight
+}
+
+// itemFeatures returns the profile features of an item by group
+func itemFeatures(item *models.FeedItem) map[string][]string {
+	features := map[string][]string{
+		featureKeyword: titleKeywords(item.Title),
+	}
+	if item.SourceID != "" {
+		features[featureSource] = []string{item.SourceID}
+	}
+	if len(item.Tags) > 0 {
+		features[featureTag] = item.Tags
+	}
+	if author := strings.ToLower(strings.TrimSpace(item.Author)); author != "" {
+		features[featureAuthor] = []string{author}
+	}
+	return features
+}
+
+// titleKeywords returns the distinct lower-cased words of a title that are
+// long enough to carry meaning
+func titleKeywords(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+
+	seen := make(map[string]bool, len(words))
+	keywords := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 3 || rankStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
+
+// BuildProfile learns the profile of a user from their most recent interactions
+func (r *FeedRanker) BuildProfile(userID string) (*UserProfile, error) {
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": userID}).
+		AndWhere(dbx.Or(
+			dbx.HashExp{"status": []interface{}{models.StatusRead, models.StatusSaved, models.StatusDismissed}},
+			dbx.NewExp("rating > 0"),
+		)).
+		OrderBy("updated DESC").
+		Limit(profileHistoryLimit).
+		All(&items)
+	if err != nil {
+		return nil, fmt.Errorf("error loading interactions: %v", err)
+	}
+
+	profile := &UserProfile{
+		UserID:   userID,
+		features: make(map[string]map[string]*featureStat),
+	}
+
+	for _, item := range items {
+		signal := signalWeight(item)
+		if signal == 0 {
+			continue
+		}
+		profile.Interactions++
+
+		for group, keys := range itemFeatures(item) {
+			if profile.features[group] == nil {
+				profile.features[group] = make(map[string]*featureStat)
+			}
+			for _, key := range keys {
+				stat := profile.features[group][key]
+				if stat == nil {
+					stat = &featureStat{}
+					profile.features[group][key] = stat
+				}
+				stat.Sum += signal
+				stat.Count++
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+// Weight returns the learned affinity for a feature, 0 when it was never seen
+func (p *UserProfile) Weight(group string, key string) float64 {
+	stat, ok := p.features[group][key]
+	if !ok {
+		return 0
+	}
+	return stat.Sum / (stat.Count + featurePrior)
+}
+
+// TopFeatures returns the most liked features of a group
+func (p *UserProfile) TopFeatures(group string, n int) []string {
+	keys := make([]string, 0, len(p.features[group]))
+	for key := range p.features[group] {
+		if p.Weight(group, key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return p.Weight(group, keys[i]) > p.Weight(group, keys[j])
+	})
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// preference scores how well an item matches the profile. The raw affinity is
+// squashed into [0, 1] where 0.5 means no opinion.
+func (p *UserProfile) preference(item *models.FeedItem) (float64, []contribution) {
+	affinity := 0.0
+	contributions := make([]contribution, 0)
+
+	for group, keys := range itemFeatures(item) {
+		if len(keys) == 0 {
+			continue
+		}
+
+		// Multi-valued groups are averaged so items with many tags or long
+		// titles aren't favoured
+		share := featureGroupWeights[group] / float64(len(keys))
+		if group == featureSource || group == featureAuthor {
+			share = featureGroupWeights[group]
+		}
+
+		for _, key := range keys {
+			value := p.Weight(group, key) * share
+			if value == 0 {
+				continue
+			}
+			affinity += value
+			contributions = append(contributions, contribution{Group: group, Key: key, Value: value})
+		}
+	}
+
+	return (math.Tanh(affinity) + 1) / 2, contributions
+}
+
+// recency decays exponentially with the age of the item
+func recency(item *models.FeedItem, now time.Time, halfLifeHours float64) float64 {
+	published := item.PublishedAt.Time()
+	if published.IsZero() {
+		published = item.FetchedAt.Time()
+	}
+	if published.IsZero() || published.After(now) {
+		return 1
+	}
+
+	age := now.Sub(published).Hours()
+	return math.Pow(0.5, age/halfLifeHours)
+}
+
+// Rank loads the candidate items of a user and returns the requested page in
+// personalized order
+func (r *FeedRanker) Rank(ctx context.Context, userID string, opts RankOptions) ([]*services.RankedFeedItem, error) {
+	profile, err := r.BuildProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := r.loadCandidates(userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	names := loadFeatureNames(userID)
+	ranked := scoreItems(profile, candidates, names, opts.HalfLifeHours)
+
+	if opts.UseAI && r.aiClient != nil {
+		r.blendAIScores(ctx, profile, ranked, names)
+		sortRanked(ranked)
+	}
+
+	if opts.Offset >= len(ranked) {
+		return []*services.RankedFeedItem{}, nil
+	}
+	ranked = ranked[opts.Offset:]
+	if opts.Limit > 0 && len(ranked) > opts.Limit {
+		ranked = ranked[:opts.Limit]
+	}
+
+	return ranked, nil
+}
+
+// ScoreItems orders the given items for a user without loading candidates
+// itself, for callers that select the items on their own
+func (r *FeedRanker) ScoreItems(userID string, items []*models.FeedItem, halfLifeHours float64) ([]*services.RankedFeedItem, error) {
+	profile, err := r.BuildProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if halfLifeHours <= 0 {
+		halfLifeHours = defaultRankHalfLifeHours
+	}
+
+	return scoreItems(profile, items, loadFeatureNames(userID), halfLifeHours), nil
+}
+
+// scoreItems scores items against a profile and sorts them best first
+func scoreItems(profile *UserProfile, items []*models.FeedItem, names featureNames, halfLifeHours float64) []*services.RankedFeedItem {
+	now := time.Now()
+
+	ranked := make([]*services.RankedFeedItem, 0, len(items))
+	for _, item := range items {
+		pref, contributions := profile.preference(item)
+		fresh := recency(item, now, halfLifeHours)
+
+		entry := &services.RankedFeedItem{
+			Item:  item,
+			Score: preferenceWeight*pref + recencyWeight*fresh,
+			Components: map[string]float64{
+				"preference": pref,
+				"recency":    fresh,
+			},
+			Reasons: explain(contributions, fresh, names),
+		}
+
+		// Stories gaining points quickly rank higher while they do
+		if rising, ok := momentum(item); ok {
+			entry.Score = (1-momentumBlendWeight)*entry.Score + momentumBlendWeight*rising
+			entry.Components["momentum"] = rising
+			if rising >= 0.5 {
+				entry.Reasons = append(entry.Reasons, "Gaining points quickly")
+			}
+		}
+
+		ranked = append(ranked, entry)
+	}
+
+	sortRanked(ranked)
+	return ranked
+}
+
+func (r *FeedRanker) loadCandidates(userID string, opts RankOptions) ([]*models.FeedItem, error) {
+	filter := dbx.HashExp{"user": userID}
+	if opts.Status != "" {
+		filter["status"] = opts.Status
+	}
+	if opts.SourceID != "" {
+		filter["source_id"] = opts.SourceID
+	} else {
+		// One item per story, the other copies are listed alongside it
+		filter["is_cluster_primary"] = true
+	}
+
+	since := time.Now().AddDate(0, 0, -opts.MaxAgeDays).UTC().Format(types.DefaultDateLayout)
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(filter).
+		AndWhere(dbx.NewExp("published_at >= {:since}", dbx.Params{"since": since})).
+		OrderBy("published_at DESC").
+		Limit(rankCandidateLimit).
+		All(&items)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed items: %v", err)
+	}
+
+	return items, nil
+}
+
+// blendAIScores asks the AI client to score the current top items and mixes
+// the result into their score. Items the AI fails on keep their local score.
+func (r *FeedRanker) blendAIScores(ctx context.Context, profile *UserProfile, ranked []*services.RankedFeedItem, names featureNames) {
+	ctx, cancel := context.WithTimeout(ai.WithCaller(ctx, ai.FeatureFeedRanking, profile.UserID), aiRerankTimeout)
+	defer cancel()
+
+	userMetadata := profile.describe(names)
+
+	top := ranked
+	if len(top) > aiRerankLimit {
+		top = top[:aiRerankLimit]
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 5)
+	for _, entry := range top {
+		wg.Add(1)
+		go func(entry *services.RankedFeedItem) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			resp, err := r.aiClient.RecommendContent(ctx, &ai.RecommendRequest{
+				UserID:       profile.UserID,
+				Item:         entry.Item,
+				UserMetadata: userMetadata,
+			})
+			if errors.Is(err, ai.ErrBudgetExceeded) {
+				return
+			}
+			if err != nil {
+				logger.LogError(fmt.Sprintf("AI recommendation error for item %s: %v", entry.Item.Id, err))
+				return
+			}
+
+			score := math.Max(0, math.Min(1, resp.Score))
+			entry.Score = (1-aiBlendWeight)*entry.Score + aiBlendWeight*score
+			entry.Components["ai"] = score
+			if resp.Explanation != "" {
+				entry.Reasons = append(entry.Reasons, resp.Explanation)
+			}
+		}(entry)
+	}
+	wg.Wait()
+}
+
+// describe summarizes the profile for the AI client
+func (p *UserProfile) describe(names featureNames) map[string]interface{} {
+	sources := make([]string, 0)
+	for _, id := range p.TopFeatures(featureSource, 5) {
+		sources = append(sources, names.label(featureSource, id))
+	}
+	tags := make([]string, 0)
+	for _, id := range p.TopFeatures(featureTag, 10) {
+		tags = append(tags, names.label(featureTag, id))
+	}
+
+	return map[string]interface{}{
+		"favourite_sources": strings.Join(sources, ", "),
+		"favourite_topics":  strings.Join(tags, ", "),
+		"favourite_authors": strings.Join(p.TopFeatures(featureAuthor, 5), ", "),
+		"frequent_keywords": strings.Join(p.TopFeatures(featureKeyword, 15), ", "),
+		"interaction_count": p.Interactions,
+	}
+}
+
+func sortRanked(ranked []*services.RankedFeedItem) {
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+}
+
+// featureNames resolves source and tag IDs to names for explanations
+type featureNames map[string]map[string]string
+
+func loadFeatureNames(userID string) featureNames {
+	names := featureNames{
+		featureSource: make(map[string]string),
+		featureTag:    make(map[string]string),
+	}
+
+	if sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{"user": userID}); err == nil {
+		for _, source := range sources {
+			names[featureSource][source.Id] = source.Name
+		}
+	}
+	if tags, err := query.FindAllByFilter[*models.Tag](map[string]interface{}{"user": userID}); err == nil {
+		for _, tag := range tags {
+			names[featureTag][tag.Id] = tag.Name
+		}
+	}
+
+	return names
+}
+
+func (n featureNames) label(group string, key string) string {
+	if name, ok := n[group][key]; ok && name != "" {
+		return name
+	}
+	return key
+}
+
+// explain turns the strongest positive contributions into readable reasons
+func explain(contributions []contribution, fresh float64, names featureNames) []string {
+	sort.Slice(contributions, func(i, j int) bool {
+		return contributions[i].Value > contributions[j].Value
+	})
+
+	reasons := make([]string, 0, maxReasons+1)
+	for _, c := range contributions {
+		if c.Value < minReasonContribution || len(reasons) >= maxReasons {
+			break
+		}
+
+		label := names.label(c.Group, c.Key)
+		switch c.Group {
+		case featureSource:
+			if label == c.Key {
+				reasons = append(reasons, "From a source you often read")
+			} else {
+				reasons = append(reasons, fmt.Sprintf("You often read %s", label))
+			}
+		case featureTag:
+			reasons = append(reasons, fmt.Sprintf("Tagged %s, a topic you like", label))
+		case featureAuthor:
+			reasons = append(reasons, fmt.Sprintf("By %s, an author you like", label))
+		case featureKeyword:
+			reasons = append(reasons, fmt.Sprintf("Mentions %q", label))
+		}
+	}
+
+	if fresh >= 0.75 {
+		reasons = append(reasons, "Published recently")
+	}
+
+	return reasons
+}
@@ -0,0 +1,301 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/store"
+)
+
+const (
+	maxRetentionDays = 3650
+	// Items examined per query while looking for expired items
+	retentionBatchSize = 500
+)
+
+// RetentionResult reports what a retention run deleted, or would delete on a
+// dry run
+type RetentionResult struct {
+	Deleted  int            `json:"deleted"`
+	ByPolicy map[string]int `json:"by_policy"` // Deleted items per policy ID
+	DryRun   bool           `json:"dry_run"`
+}
+
+// retentionRule is the policy that applies to an item. When several category
+// policies apply they are merged, keeping items as long as the most lenient.
+type retentionRule struct {
+	policyID      string
+	readDays      int
+	unreadDays    int
+	dismissedDays int
+	keepRated     bool
+}
+
+// retentionItem holds the columns retention decides on
+type retentionItem struct {
+	Id          string                  `db:"id"`
+	SourceID    string                  `db:"source_id"`
+	Status      string                  `db:"status"`
+	Rating      int                     `db:"rating"`
+	CategoryIDs types.JSONArray[string] `db:"category_ids"`
+	Created     types.DateTime          `db:"created"`
+}
+
+// ValidateRetentionPolicy checks the scope and ages of a policy. The scoped
+// category or source has to belong to the user.
+func ValidateRetentionPolicy(policy *models.FeedRetentionPolicy) error {
+	switch policy.Scope {
+	case models.RetentionScopeAll:
+		policy.ScopeID = ""
+	case models.RetentionScopeCategory:
+		if _, err := query.FindByFilter[*models.FeedCategory](map[string]interface{}{"id": policy.ScopeID, "user": policy.User}); err != nil {
+			return fmt.Errorf("category not found: %s", policy.ScopeID)
+		}
+	case models.RetentionScopeSource:
+		if _, err := query.FindByFilter[*models.FeedSource](map[string]interface{}{"id": policy.ScopeID, "user": policy.User}); err != nil {
+			return fmt.Errorf("source not found: %s", policy.ScopeID)
+		}
+	default:
+		return fmt.Errorf("scope must be %s, %s or %s", models.RetentionScopeAll, models.RetentionScopeCategory, models.RetentionScopeSource)
+	}
+
+	for name, days := range map[string]int{
+		"read_days":      policy.ReadDays,
+		"unread_days":    policy.UnreadDays,
+		"dismissed_days": policy.DismissedDays,
+	} {
+		if days < 0 || days > maxRetentionDays {
+			return fmt.Errorf("%s must be between 0 and %d", name, maxRetentionDays)
+		}
+	}
+	return nil
+}
+
+// RunRetention applies the active retention policies of every user
+func RunRetention(ctx context.Context) error {
+	var userIDs []string
+	err := store.GetDao().DB().
+		Select("DISTINCT user").
+		From((&models.FeedRetentionPolicy{}).TableName()).
+		Where(dbx.HashExp{"is_active": true}).
+		Column(&userIDs)
+	if err != nil {
+		return fmt.Errorf("error loading retention policies: %v", err)
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := ApplyRetention(userID, time.Now(), false)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error applying retention for user %s: %v", userID, err))
+			continue
+		}
+		if result.Deleted > 0 {
+			logger.LogInfo(fmt.Sprintf("Retention deleted %d feed items of user %s", result.Deleted, userID))
+		}
+	}
+
+	return nil
+}
+
+// ApplyRetention deletes the expired feed items of a user in one transaction.
+// The most specific policy applies to an item: its source, then its
+// categories, then the policy for all sources. Saved items and items with
+// highlights are never deleted. A dry run only counts.
+func ApplyRetention(userID string, now time.Time, dryRun bool) (*RetentionResult, error) {
+	result := &RetentionResult{ByPolicy: make(map[string]int), DryRun: dryRun}
+
+	policies, err := query.FindAllByFilter[*models.FeedRetentionPolicy](map[string]interface{}{
+		"user":      userID,
+		"is_active": true,
+	})
+	if err != nil || len(policies) == 0 {
+		return result, err
+	}
+
+	bySource := make(map[string]*models.FeedRetentionPolicy)
+	byCategory := make(map[string]*models.FeedRetentionPolicy)
+	var forAll *models.FeedRetentionPolicy
+	minDays := 0
+	for _, policy := range policies {
+		switch policy.Scope {
+		case models.RetentionScopeSource:
+			bySource[policy.ScopeID] = policy
+		case models.RetentionScopeCategory:
+			byCategory[policy.ScopeID] = policy
+		case models.RetentionScopeAll:
+			forAll = policy
+		}
+		for _, days := range []int{policy.ReadDays, policy.UnreadDays, policy.DismissedDays} {
+			if days > 0 && (minDays == 0 || days < minDays) {
+				minDays = days
+			}
+		}
+		result.ByPolicy[policy.Id] = 0
+	}
+	if minDays == 0 {
+		return result, nil
+	}
+
+	sourceCategories := make(map[string][]string)
+	if sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{"user": userID}); err == nil {
+		for _, source := range sources {
+			sourceCategories[source.Id] = source.CategoryIDs
+		}
+	}
+
+	ruleFor := func(item *retentionItem) *retentionRule {
+		if policy, ok := bySource[item.SourceID]; ok {
+			return ruleFromPolicy(policy)
+		}
+
+		var merged *retentionRule
+		seen := make(map[string]bool)
+		for _, categoryID := range append(append([]string{}, item.CategoryIDs...), sourceCategories[item.SourceID]...) {
+			policy, ok := byCategory[categoryID]
+			if !ok || seen[categoryID] {
+				continue
+			}
+			seen[categoryID] = true
+			if merged == nil {
+				merged = ruleFromPolicy(policy)
+				continue
+			}
+			merged.readDays = longerRetention(merged.readDays, policy.ReadDays)
+			merged.unreadDays = longerRetention(merged.unreadDays, policy.UnreadDays)
+			merged.dismissedDays = longerRetention(merged.dismissedDays, policy.DismissedDays)
+			merged.keepRated = merged.keepRated || policy.KeepRated
+		}
+		if merged != nil {
+			return merged
+		}
+
+		if forAll != nil {
+			return ruleFromPolicy(forAll)
+		}
+		return nil
+	}
+
+	// Only items past the shortest age of any policy can expire
+	cutoff := dateTime(now.AddDate(0, 0, -minDays))
+	expired := make([]string, 0)
+	lastID := ""
+	for {
+		var batch []*retentionItem
+		err := store.GetDao().DB().
+			Select("id", "source_id", "status", "rating", "category_ids", "created").
+			From((&models.FeedItem{}).TableName()).
+			Where(dbx.HashExp{"user": userID}).
+			AndWhere(dbx.Not(dbx.HashExp{"status": models.StatusSaved})).
+			AndWhere(dbx.NewExp("created < {:cutoff} AND id > {:last}", dbx.Params{"cutoff": cutoff.String(), "last": lastID})).
+			AndWhere(dbx.NewExp("NOT EXISTS (SELECT 1 FROM feed_highlights h WHERE h.item = feed_items.id)")).
+			OrderBy("id ASC").
+			Limit(retentionBatchSize).
+			All(&batch)
+		if err != nil {
+			return nil, fmt.Errorf("error loading feed items: %v", err)
+		}
+
+		for _, item := range batch {
+			rule := ruleFor(item)
+			if rule == nil || !rule.expired(item, now) {
+				continue
+			}
+			expired = append(expired, item.Id)
+			result.ByPolicy[rule.policyID]++
+		}
+
+		if len(batch) < retentionBatchSize {
+			break
+		}
+		lastID = batch[len(batch)-1].Id
+	}
+
+	result.Deleted = len(expired)
+	if dryRun {
+		return result, nil
+	}
+
+	app := store.GetDao()
+	if len(expired) > 0 {
+		err = app.RunInTransaction(func(txApp core.App) error {
+			for start := 0; start < len(expired); start += retentionBatchSize {
+				end := min(start+retentionBatchSize, len(expired))
+				records, err := txApp.FindRecordsByIds((&models.FeedItem{}).TableName(), expired[start:end])
+				if err != nil {
+					return err
+				}
+				for _, record := range records {
+					if err := txApp.Delete(record); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error deleting expired feed items: %v", err)
+		}
+	}
+
+	runAt := dateTime(now)
+	for _, policy := range policies {
+		if err := query.UpdateRecord[*models.FeedRetentionPolicy](policy.Id, map[string]interface{}{
+			"last_run_at":  runAt,
+			"last_deleted": result.ByPolicy[policy.Id],
+		}); err != nil {
+			logger.LogError(fmt.Sprintf("Error updating retention policy %s: %v", policy.Id, err))
+		}
+	}
+
+	return result, nil
+}
+
+func ruleFromPolicy(policy *models.FeedRetentionPolicy) *retentionRule {
+	return &retentionRule{
+		policyID:      policy.Id,
+		readDays:      policy.ReadDays,
+		unreadDays:    policy.UnreadDays,
+		dismissedDays: policy.DismissedDays,
+		keepRated:     policy.KeepRated,
+	}
+}
+
+// longerRetention merges two ages of which 0 means forever
+func longerRetention(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return max(a, b)
+}
+
+// expired reports whether the rule deletes an item at the given time
+func (r *retentionRule) expired(item *retentionItem, now time.Time) bool {
+	if r.keepRated && item.Rating > 0 {
+		return false
+	}
+
+	days := 0
+	switch item.Status {
+	case models.StatusRead:
+		days = r.readDays
+	case models.StatusUnread, "":
+		days = r.unreadDays
+	case models.StatusDismissed:
+		days = r.dismissedDays
+	}
+	if days == 0 {
+		return false
+	}
+
+	return item.Created.Time().Before(now.AddDate(0, 0, -days))
+}
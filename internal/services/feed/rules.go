@@ -0,0 +1,575 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	NotificationTypeFeedRuleMatch = "feed_rule_match"
+
+	// Number of recent items a rule is tested against
+	ruleTestItemLimit = 100
+)
+
+// Fields a rule condition can check, besides metadata.<key>
+var ruleFields = map[string]bool{
+	"source":      true,
+	"source_type": true,
+	"title":       true,
+	"content":     true,
+	"text":        true,
+	"author":      true,
+	"url":         true,
+	"tags":        true,
+	"score":       true,
+}
+
+var ruleOperators = map[string]bool{
+	models.RuleOpContains:    true,
+	models.RuleOpNotContains: true,
+	models.RuleOpEquals:      true,
+	models.RuleOpNotEquals:   true,
+	models.RuleOpMatches:     true,
+	models.RuleOpIn:          true,
+	models.RuleOpGreater:     true,
+	models.RuleOpGreaterEq:   true,
+	models.RuleOpLess:        true,
+	models.RuleOpLessEq:      true,
+}
+
+// WorkflowExecutor starts workflows for the workflow rule action
+type WorkflowExecutor interface {
+	ExecuteWorkflow(ctx context.Context, workflowID string) (*models.WorkflowExecution, error)
+}
+
+// RulesEngine applies the user defined feed rules to new feed items
+type RulesEngine struct {
+	workflows WorkflowExecutor
+}
+
+// compiledRule is a rule with its regular expressions compiled
+type compiledRule struct {
+	rule     *models.FeedRule
+	patterns map[int]*regexp.Regexp
+}
+
+// RuleSet holds the active rules of a user in evaluation order
+type RuleSet struct {
+	rules    []*compiledRule
+	tagNames map[string]string
+}
+
+// ruleItem is what conditions are evaluated against
+type ruleItem struct {
+	item     *models.FeedItem
+	source   *models.FeedSource
+	tagNames map[string]string
+	metadata map[string]interface{}
+	text     string
+}
+
+func NewRulesEngine(workflows WorkflowExecutor) *RulesEngine {
+	return &RulesEngine{
+		workflows: workflows,
+	}
+}
+
+// compileRule checks a rule's conditions and actions and compiles its
+// regular expressions
+func compileRule(rule *models.FeedRule) (*compiledRule, error) {
+	if rule.MatchMode == "" {
+		rule.MatchMode = models.RuleMatchAll
+	}
+	if rule.MatchMode != models.RuleMatchAll && rule.MatchMode != models.RuleMatchAny {
+		return nil, fmt.Errorf("invalid match mode: %s", rule.MatchMode)
+	}
+	if len(rule.Conditions) == 0 {
+		return nil, fmt.Errorf("a rule needs at least one condition")
+	}
+	if len(rule.Actions) == 0 {
+		return nil, fmt.Errorf("a rule needs at least one action")
+	}
+
+	compiled := &compiledRule{
+		rule:     rule,
+		patterns: make(map[int]*regexp.Regexp),
+	}
+
+	for i, condition := range rule.Conditions {
+		if !ruleFields[condition.Field] && !strings.HasPrefix(condition.Field, "metadata.") {
+			return nil, fmt.Errorf("invalid condition field: %s", condition.Field)
+		}
+		if !ruleOperators[condition.Operator] {
+			return nil, fmt.Errorf("invalid condition operator: %s", condition.Operator)
+		}
+
+		switch condition.Operator {
+		case models.RuleOpMatches:
+			pattern := fmt.Sprint(condition.Value)
+			if !condition.CaseSensitive {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", condition.Value, err)
+			}
+			compiled.patterns[i] = re
+		case models.RuleOpGreater, models.RuleOpGreaterEq, models.RuleOpLess, models.RuleOpLessEq:
+			if _, ok := toNumber(condition.Value); !ok {
+				return nil, fmt.Errorf("condition on %s needs a numeric value", condition.Field)
+			}
+		}
+	}
+
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case models.RuleActionAddTag, models.RuleActionAddCategory, models.RuleActionWorkflow:
+			if strings.TrimSpace(action.Value) == "" {
+				return nil, fmt.Errorf("action %s needs a value", action.Type)
+			}
+		case models.RuleActionDismiss, models.RuleActionSave, models.RuleActionNotify:
+		default:
+			return nil, fmt.Errorf("invalid action: %s", action.Type)
+		}
+	}
+
+	return compiled, nil
+}
+
+// ValidateRule checks a rule and that the categories and workflows its
+// actions point at belong to the rule's user
+func ValidateRule(rule *models.FeedRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	if _, err := compileRule(rule); err != nil {
+		return err
+	}
+
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case models.RuleActionAddCategory:
+			category, err := query.FindById[*models.FeedCategory](action.Value)
+			if err != nil || category.User != rule.User {
+				return fmt.Errorf("category not found: %s", action.Value)
+			}
+		case models.RuleActionWorkflow:
+			workflow, err := query.FindById[*models.Workflow](action.Value)
+			if err != nil || workflow.User != rule.User {
+				return fmt.Errorf("workflow not found: %s", action.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadRuleSet loads the active rules of a user. Rules that no longer compile
+// are skipped.
+func (e *RulesEngine) LoadRuleSet(userID string) (*RuleSet, error) {
+	var rules []*models.FeedRule
+	err := query.BaseQuery[*models.FeedRule]().
+		AndWhere(dbx.HashExp{"user": userID, "is_active": true}).
+		OrderBy("sort_order ASC", "created ASC").
+		All(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("error loading feed rules: %v", err)
+	}
+
+	ruleSet := &RuleSet{
+		rules:    make([]*compiledRule, 0, len(rules)),
+		tagNames: loadTagNames(userID),
+	}
+
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Skipping invalid feed rule %s: %v", rule.Id, err))
+			continue
+		}
+		ruleSet.rules = append(ruleSet.rules, compiled)
+	}
+
+	return ruleSet, nil
+}
+
+// Empty reports whether there are no rules to apply
+func (rs *RuleSet) Empty() bool {
+	return rs == nil || len(rs.rules) == 0
+}
+
+// Apply evaluates the rules against a new item that hasn't been saved yet and
+// applies the actions that change the item. The matched rules are returned so
+// their remaining actions can run once the item is saved.
+func (rs *RuleSet) Apply(item *models.FeedItem, source *models.FeedSource) []*models.FeedRule {
+	if rs.Empty() {
+		return nil
+	}
+
+	target := newRuleItem(item, source, rs.tagNames)
+	matched := make([]*models.FeedRule, 0)
+
+	for _, compiled := range rs.rules {
+		if !compiled.matches(target) {
+			continue
+		}
+
+		matched = append(matched, compiled.rule)
+		rs.applyItemActions(item, compiled.rule)
+
+		if compiled.rule.StopProcessing {
+			break
+		}
+	}
+
+	return matched
+}
+
+func (rs *RuleSet) applyItemActions(item *models.FeedItem, rule *models.FeedRule) {
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case models.RuleActionAddTag:
+			tagID, err := findOrCreateTag(item.User, action.Value, false)
+			if err != nil {
+				logger.LogError(fmt.Sprintf("Error creating tag %s for feed rule %s: %v", action.Value, rule.Id, err))
+				continue
+			}
+			rs.tagNames[tagID] = strings.ToLower(strings.TrimSpace(action.Value))
+			item.Tags = appendUnique(item.Tags, tagID)
+		case models.RuleActionAddCategory:
+			item.CategoryIDs = appendUnique(item.CategoryIDs, action.Value)
+		case models.RuleActionDismiss:
+			item.Status = models.StatusDismissed
+		case models.RuleActionSave:
+			item.Status = models.StatusSaved
+		}
+	}
+}
+
+// AfterSave runs the actions of the matched rules that need the saved item,
+// raising notifications and starting workflows, and counts the rule hits
+func (e *RulesEngine) AfterSave(ctx context.Context, item *models.FeedItem, matched []*models.FeedRule) {
+	for _, rule := range matched {
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case models.RuleActionNotify:
+				notifyRuleMatch(item, rule, action)
+			case models.RuleActionWorkflow:
+				if e.workflows == nil {
+					logger.LogError(fmt.Sprintf("Feed rule %s can't start workflows, no workflow engine configured", rule.Id))
+					continue
+				}
+				// Rules may only start workflows of their own user
+				if _, err := query.FindByFilter[*models.Workflow](map[string]interface{}{
+					"id":   action.Value,
+					"user": rule.User,
+				}); err != nil {
+					logger.LogError(fmt.Sprintf("Feed rule %s can't start workflow %s, it's not a workflow of the rule's user", rule.Id, action.Value))
+					continue
+				}
+				// The workflow outlives the fetch, don't tie it to the fetch context.
+				// Connectors read the user from the context, like the workflow routes set it.
+				workflowCtx := context.WithValue(context.Background(), "user", rule.User)
+				if _, err := e.workflows.ExecuteWorkflow(workflowCtx, action.Value); err != nil {
+					logger.LogError(fmt.Sprintf("Error starting workflow %s for feed rule %s: %v", action.Value, rule.Id, err))
+				}
+			}
+		}
+
+		recordRuleHit(rule)
+	}
+}
+
+func notifyRuleMatch(item *models.FeedItem, rule *models.FeedRule, action models.FeedRuleAction) {
+	title := action.Value
+	if title == "" {
+		title = fmt.Sprintf("Feed rule \"%s\" matched", rule.Name)
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"rule_id":   rule.Id,
+		"item_id":   item.Id,
+		"source_id": item.SourceID,
+		"url":       item.URL,
+	})
+
+	notification := &models.Notification{
+		User:     item.User,
+		Type:     NotificationTypeFeedRuleMatch,
+		Title:    title,
+		Content:  item.Title,
+		Priority: "medium",
+		Status:   "unread",
+		Metadata: string(metadata),
+	}
+	notification.Id = util.GenerateRandomId()
+
+	if err := query.SaveRecord(notification); err != nil {
+		logger.LogError(fmt.Sprintf("Error creating notification for feed rule %s: %v", rule.Id, err))
+	}
+}
+
+// recordRuleHit increments the hit count in place so concurrent fetches for
+// the same user don't lose hits
+func recordRuleHit(rule *models.FeedRule) {
+	_, err := store.GetDao().DB().
+		NewQuery("UPDATE feed_rules SET hit_count = hit_count + 1, last_hit_at = {:now} WHERE id = {:id}").
+		Bind(dbx.Params{"id": rule.Id, "now": types.NowDateTime().String()}).
+		Execute()
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error recording hit of feed rule %s: %v", rule.Id, err))
+	}
+}
+
+// TestRule evaluates a rule against the user's most recent items without
+// applying any actions and returns the items it matches
+func TestRule(rule *models.FeedRule) ([]*models.FeedItem, int, error) {
+	compiled, err := compileRule(rule)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var items []*models.FeedItem
+	err = query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"user": rule.User}).
+		OrderBy("fetched_at DESC").
+		Limit(ruleTestItemLimit).
+		All(&items)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error loading feed items: %v", err)
+	}
+
+	sources := make(map[string]*models.FeedSource)
+	if userSources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{"user": rule.User}); err == nil {
+		for _, source := range userSources {
+			sources[source.Id] = source
+		}
+	}
+	tagNames := loadTagNames(rule.User)
+
+	matches := make([]*models.FeedItem, 0)
+	for _, item := range items {
+		if compiled.matches(newRuleItem(item, sources[item.SourceID], tagNames)) {
+			matches = append(matches, item)
+		}
+	}
+
+	return matches, len(items), nil
+}
+
+func newRuleItem(item *models.FeedItem, source *models.FeedSource, tagNames map[string]string) *ruleItem {
+	metadata, _ := item.GetMetadataMap()
+	return &ruleItem{
+		item:     item,
+		source:   source,
+		tagNames: tagNames,
+		metadata: metadata,
+		text:     stripTags(item.ArticleContent()),
+	}
+}
+
+func (c *compiledRule) matches(target *ruleItem) bool {
+	for i, condition := range c.rule.Conditions {
+		matched := c.matchCondition(i, condition, target)
+		if c.rule.MatchMode == models.RuleMatchAny && matched {
+			return true
+		}
+		if c.rule.MatchMode != models.RuleMatchAny && !matched {
+			return false
+		}
+	}
+
+	return c.rule.MatchMode != models.RuleMatchAny
+}
+
+func (c *compiledRule) matchCondition(index int, condition models.FeedRuleCondition, target *ruleItem) bool {
+	switch condition.Operator {
+	case models.RuleOpGreater, models.RuleOpGreaterEq, models.RuleOpLess, models.RuleOpLessEq:
+		actual, ok := target.number(condition.Field)
+		expected, _ := toNumber(condition.Value)
+		if !ok {
+			return false
+		}
+		switch condition.Operator {
+		case models.RuleOpGreater:
+			return actual > expected
+		case models.RuleOpGreaterEq:
+			return actual >= expected
+		case models.RuleOpLess:
+			return actual < expected
+		default:
+			return actual <= expected
+		}
+	case models.RuleOpNotContains:
+		return !c.matchValues(index, models.RuleOpContains, condition, target.values(condition.Field))
+	case models.RuleOpNotEquals:
+		return !c.matchValues(index, models.RuleOpEquals, condition, target.values(condition.Field))
+	default:
+		return c.matchValues(index, condition.Operator, condition, target.values(condition.Field))
+	}
+}
+
+// matchValues reports whether any of the field values satisfies the operator
+func (c *compiledRule) matchValues(index int, operator string, condition models.FeedRuleCondition, values []string) bool {
+	normalize := func(s string) string {
+		if condition.CaseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+
+	expected := toStrings(condition.Value)
+	for _, value := range values {
+		switch operator {
+		case models.RuleOpMatches:
+			if c.patterns[index].MatchString(value) {
+				return true
+			}
+		case models.RuleOpContains:
+			for _, e := range expected {
+				if e != "" && strings.Contains(normalize(value), normalize(e)) {
+					return true
+				}
+			}
+		case models.RuleOpEquals, models.RuleOpIn:
+			for _, e := range expected {
+				if normalize(value) == normalize(e) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// values returns the string values of a field. Sources and tags match both by
+// ID and by name.
+func (t *ruleItem) values(field string) []string {
+	switch field {
+	case "source":
+		values := []string{t.item.SourceID}
+		if t.source != nil {
+			values = append(values, t.source.Name)
+		}
+		return values
+	case "source_type":
+		if t.source != nil {
+			return []string{t.source.Type}
+		}
+		return nil
+	case "title":
+		return []string{t.item.Title}
+	case "content":
+		return []string{t.text}
+	case "text":
+		return []string{t.item.Title + "\n" + t.text}
+	case "author":
+		return []string{t.item.Author}
+	case "url":
+		return []string{t.item.URL}
+	case "tags":
+		values := make([]string, 0, len(t.item.Tags)*2)
+		for _, tag := range t.item.Tags {
+			values = append(values, tag)
+			if name, ok := t.tagNames[tag]; ok {
+				values = append(values, name)
+			}
+		}
+		return values
+	}
+
+	if value, ok := t.metadataValue(field); ok {
+		return toStrings(value)
+	}
+	return nil
+}
+
+// number returns the numeric value of a field, score is the HN and Reddit
+// score stored in the item metadata
+func (t *ruleItem) number(field string) (float64, bool) {
+	if field == "score" {
+		field = "metadata.score"
+	}
+
+	value, ok := t.metadataValue(field)
+	if !ok {
+		return 0, false
+	}
+	return toNumber(value)
+}
+
+func (t *ruleItem) metadataValue(field string) (interface{}, bool) {
+	key, ok := strings.CutPrefix(field, "metadata.")
+	if !ok || t.metadata == nil {
+		return nil, false
+	}
+	value, ok := t.metadata[key]
+	return value, ok && value != nil
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case []string:
+		return v
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+func loadTagNames(userID string) map[string]string {
+	names := make(map[string]string)
+	tags, err := query.FindAllByFilter[*models.Tag](map[string]interface{}{"user": userID})
+	if err != nil {
+		return names
+	}
+	for _, tag := range tags {
+		names[tag.Id] = tag.Name
+	}
+	return names
+}
+
+func appendUnique(values types.JSONArray[string], value string) types.JSONArray[string] {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,208 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	// maxBackoffExponent caps the exponential backoff at refresh_rate * 2^6
+	maxBackoffExponent = 6
+	maxBackoff         = 24 * time.Hour
+
+	NotificationTypeFeedSourceDisabled = "feed_source_disabled"
+)
+
+// FeedScheduler fetches every active source once its refresh rate has elapsed,
+// backing off exponentially on failures and deactivating sources that keep failing
+type FeedScheduler struct {
+	feedService services.FeedService
+	config      config.FeedConfig
+	running     atomic.Bool
+}
+
+func NewFeedScheduler(feedService services.FeedService, cfg config.FeedConfig) *FeedScheduler {
+	return &FeedScheduler{
+		feedService: feedService,
+		config:      cfg,
+	}
+}
+
+// NextFetchTime returns when a source is due to be fetched again
+func (s *FeedScheduler) NextFetchTime(source *models.FeedSource) time.Time {
+	lastFetched := source.LastFetched.Time()
+	if lastFetched.IsZero() {
+		return source.RetryAfter.Time()
+	}
+
+	refreshRate := source.RefreshRate
+	if refreshRate <= 0 {
+		refreshRate = s.config.DefaultRefreshRate
+	}
+	interval := time.Duration(refreshRate) * time.Minute
+
+	if source.ErrorCount > 0 {
+		exponent := source.ErrorCount
+		if exponent > maxBackoffExponent {
+			exponent = maxBackoffExponent
+		}
+		interval = interval * time.Duration(1<<exponent)
+		if interval > maxBackoff {
+			interval = maxBackoff
+		}
+	}
+
+	// Never fetch before the publisher's Retry-After
+	next := lastFetched.Add(interval)
+	if retryAfter := source.RetryAfter.Time(); retryAfter.After(next) {
+		return retryAfter
+	}
+	return next
+}
+
+// DueSources returns all active sources whose next fetch time has passed.
+// Sources pushed over WebSub are only polled as a daily safety net.
+func (s *FeedScheduler) DueSources(now time.Time) ([]*models.FeedSource, error) {
+	sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{
+		"is_active": true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pushed, err := pushedSourceIDs(now)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error loading WebSub subscriptions: %v", err))
+	}
+
+	due := make([]*models.FeedSource, 0, len(sources))
+	for _, source := range sources {
+		next := s.NextFetchTime(source)
+		if pushed[source.Id] {
+			if safetyPoll := source.LastFetched.Time().Add(webSubPollInterval); safetyPoll.After(next) {
+				next = safetyPoll
+			}
+		}
+		if !now.Before(next) {
+			due = append(due, source)
+		}
+	}
+
+	return due, nil
+}
+
+// Run fetches all due sources and processes the newly fetched items,
+// including items pushed over WebSub since the last run.
+// Overlapping runs are skipped so a slow fetch cycle doesn't pile up.
+func (s *FeedScheduler) Run(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
+		logger.LogInfo("Feed scheduler is already running, skipping")
+		return nil
+	}
+	defer s.running.Store(false)
+
+	sources, err := s.DueSources(time.Now())
+	if err != nil {
+		return fmt.Errorf("error finding due sources: %v", err)
+	}
+
+	if len(sources) == 0 {
+		if err := s.feedService.ProcessNewItems(ctx); err != nil {
+			logger.LogError(fmt.Sprintf("Error processing feed items: %v", err))
+		}
+		return nil
+	}
+
+	logger.LogInfo(fmt.Sprintf("Feed scheduler fetching %d due sources", len(sources)))
+
+	concurrency := s.config.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultFeedSchedulerConcurrency
+	}
+
+	var wg sync.WaitGroup
+	var failed atomic.Int32
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, source := range sources {
+		wg.Add(1)
+		go func(src *models.FeedSource) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := s.feedService.FetchFromSource(ctx, src); err != nil {
+				var rateLimited *services.RateLimitedError
+				if errors.As(err, &rateLimited) {
+					logger.LogInfo(fmt.Sprintf("Feed source %s is rate limited until %s", src.Name, rateLimited.RetryAfter.Format(time.RFC3339)))
+					return
+				}
+
+				failed.Add(1)
+				logger.LogError(fmt.Sprintf("Scheduled fetch failed: %v", err))
+				s.handleFailure(src, err)
+			}
+		}(source)
+	}
+
+	wg.Wait()
+
+	if err := s.feedService.ProcessNewItems(ctx); err != nil {
+		logger.LogError(fmt.Sprintf("Error processing feed items: %v", err))
+	}
+
+	logger.LogInfo(fmt.Sprintf("Feed scheduler fetched %d sources (%d failed)", len(sources), failed.Load()))
+	return nil
+}
+
+// handleFailure deactivates a source once it reached the consecutive failure limit
+func (s *FeedScheduler) handleFailure(source *models.FeedSource, fetchErr error) {
+	// FetchFromSource already incremented error_count in the database
+	errorCount := source.ErrorCount + 1
+	if s.config.MaxFailures <= 0 || errorCount < s.config.MaxFailures {
+		return
+	}
+
+	if err := query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{
+		"is_active": false,
+	}); err != nil {
+		logger.LogError(fmt.Sprintf("Error deactivating feed source %s: %v", source.Id, err))
+		return
+	}
+
+	logger.LogInfo(fmt.Sprintf("Deactivated feed source %s after %d consecutive failures", source.Name, errorCount))
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"source_id":   source.Id,
+		"error_count": errorCount,
+		"last_error":  fetchErr.Error(),
+	})
+
+	notification := &models.Notification{
+		User:     source.User,
+		Type:     NotificationTypeFeedSourceDisabled,
+		Title:    fmt.Sprintf("Feed source \"%s\" was disabled", source.Name),
+		Content:  fmt.Sprintf("Fetching failed %d times in a row. Last error: %s", errorCount, fetchErr.Error()),
+		Priority: "medium",
+		Status:   "unread",
+		Metadata: string(metadata),
+	}
+	notification.Id = util.GenerateRandomId()
+
+	if err := query.SaveRecord(notification); err != nil {
+		logger.LogError(fmt.Sprintf("Error creating notification for feed source %s: %v", source.Id, err))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,19 +13,27 @@ import (
 	"github.com/shashank-sharma/backend/internal/models"
 	"github.com/shashank-sharma/backend/internal/query"
 	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/providers"
 	"github.com/shashank-sharma/backend/internal/util"
 )
 
+// Notification type sent when a new item matches the alert keywords of its source
+const NotificationTypeFeedKeywordAlert = "feed_keyword_alert"
+
 type FeedServiceImpl struct {
 	providers   map[string]services.FeedSourceProvider
 	processor   services.FeedProcessor
+	ranker      *FeedRanker
+	rules       *RulesEngine
 	providersMu sync.RWMutex
 }
 
-func NewFeedService(processor services.FeedProcessor) services.FeedService {
+func NewFeedService(processor services.FeedProcessor, ranker *FeedRanker, rules *RulesEngine) services.FeedService {
 	return &FeedServiceImpl{
 		providers: make(map[string]services.FeedSourceProvider),
 		processor: processor,
+		ranker:    ranker,
+		rules:     rules,
 	}
 }
 
@@ -66,9 +75,29 @@ func (s *FeedServiceImpl) FetchFromSource(ctx context.Context, source *models.Fe
 		return fmt.Errorf("error parsing config for source %s: %v", source.Name, err)
 	}
 
-	// Fetch items from the provider
-	rawItems, err := provider.FetchItems(ctx, configMap, source.LastFetched.Time())
+	// Fetch items from the provider, passing the cache validators of the last fetch
+	fetchState := services.NewFetchState(source.ETag, source.LastModified)
+	fetchCtx := services.WithSourceOwner(services.WithFetchState(ctx, fetchState), source.User)
+	fetchCtx = services.WithSourceID(fetchCtx, source.Id)
+	if source.Type == models.SourceTypeCustom && source.User != "" {
+		// Scraped pages end up in items the user can read, keep the URL the
+		// user chose away from internal addresses
+		fetchCtx = providers.WithPublicAddressesOnly(fetchCtx)
+	}
+	rawItems, err := provider.FetchItems(fetchCtx, configMap, source.LastFetched.Time())
 	if err != nil {
+		// Rate limiting is not the source's fault, wait instead of counting a failure
+		if retryAfter := fetchState.RetryAfter(); !retryAfter.IsZero() {
+			retryAt := types.DateTime{}
+			retryAt.Scan(retryAfter)
+			query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{
+				"retry_after":  retryAt,
+				"last_error":   err.Error(),
+				"last_fetched": types.NowDateTime(),
+			})
+			return &services.RateLimitedError{RetryAfter: retryAfter, Err: err}
+		}
+
 		query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{
 			"error_count":  source.ErrorCount + 1,
 			"last_error":   err.Error(),
@@ -77,7 +106,49 @@ func (s *FeedServiceImpl) FetchFromSource(ctx context.Context, source *models.Fe
 		return fmt.Errorf("error fetching from source %s: %v", source.Name, err)
 	}
 
-	// Process and store each item
+	s.IngestItems(ctx, source, rawItems)
+
+	now := types.DateTime{}
+	now.Scan(time.Now())
+
+	etag, lastModified := fetchState.Validators()
+	if fetchState.NotModified() {
+		logger.LogInfo(fmt.Sprintf("Source %s not modified since last fetch", source.Name))
+	}
+
+	if hub, topic, checked := fetchState.WebSub(); checked {
+		if topic == "" {
+			topic, _ = configMap["url"].(string)
+		}
+		recordWebSubHub(source, hub, topic)
+	}
+
+	return query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{
+		"last_fetched":  now,
+		"error_count":   0,
+		"last_error":    "",
+		"etag":          etag,
+		"last_modified": lastModified,
+		"retry_after":   types.DateTime{},
+	})
+}
+
+// IngestItems stores the new items among raw items of a source, running the
+// user's rules and clustering on each, and returns how many were stored
+func (s *FeedServiceImpl) IngestItems(ctx context.Context, source *models.FeedSource, rawItems []services.RawFeedItem) int {
+	// User defined rules run on every new item
+	var ruleSet *RuleSet
+	if s.rules != nil && len(rawItems) > 0 {
+		var err error
+		if ruleSet, err = s.rules.LoadRuleSet(source.User); err != nil {
+			logger.LogError(err.Error())
+		}
+	}
+
+	// Process and store each item, clustering them with the recent items of
+	// the user and the ones stored before them
+	candidates := newClusterCandidates(source.User)
+	stored := 0
 	for _, rawItem := range rawItems {
 		existingItem, err := query.FindByFilter[*models.FeedItem](map[string]interface{}{
 			"source_id":   source.Id,
@@ -127,21 +198,77 @@ func (s *FeedServiceImpl) FetchFromSource(ctx context.Context, source *models.Fe
 		}
 
 		feedItem.Id = util.GenerateRandomId()
+
+		// Group the item with copies of the same story from other sources
+		match, fp := clusterItem(ctx, feedItem, candidates)
+		if match != nil && match.SourceID == source.Id && match.CanonicalURL == feedItem.CanonicalURL {
+			// The source re-published an item it already had under a new ID
+			continue
+		}
+
+		matchedRules := ruleSet.Apply(feedItem, source)
+
 		// TODO: Should use upsert or insert
 		if err := query.SaveRecord(feedItem); err != nil {
 			logger.LogError(fmt.Sprintf("Error saving feed item: %v", err))
 			continue
 		}
+		stored++
+		candidates.add(feedItem, fp)
+
+		if len(matchedRules) > 0 {
+			s.rules.AfterSave(ctx, feedItem, matchedRules)
+		}
+
+		// Providers flag items matching the keywords the user watches
+		if matches := stringSlice(rawItem.Metadata["alert_matches"]); len(matches) > 0 && feedItem.Status != models.StatusDismissed {
+			notifyKeywordAlert(feedItem, source, matches)
+		}
 	}
 
-	now := types.DateTime{}
-	now.Scan(time.Now())
+	return stored
+}
 
-	return query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{
-		"last_fetched": now,
-		"error_count":  0,
-		"last_error":   "",
+// notifyKeywordAlert tells the user a new item mentions terms they watch
+func notifyKeywordAlert(item *models.FeedItem, source *models.FeedSource, matches []string) {
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"item_id":   item.Id,
+		"source_id": source.Id,
+		"url":       item.URL,
+		"keywords":  matches,
 	})
+
+	notification := &models.Notification{
+		User:     item.User,
+		Type:     NotificationTypeFeedKeywordAlert,
+		Title:    fmt.Sprintf("%s mentions %s", source.Name, strings.Join(matches, ", ")),
+		Content:  item.Title,
+		Priority: "medium",
+		Status:   "unread",
+		Metadata: string(metadata),
+	}
+	notification.Id = util.GenerateRandomId()
+
+	if err := query.SaveRecord(notification); err != nil {
+		logger.LogError(fmt.Sprintf("Error creating keyword alert for feed item %s: %v", item.Id, err))
+	}
+}
+
+// stringSlice reads a list of strings from decoded metadata
+func stringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, entry := range v {
+			if s, ok := entry.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
 }
 
 // FetchAllSources fetches items from all active sources
@@ -159,6 +286,11 @@ func (s *FeedServiceImpl) FetchAllSources(ctx context.Context) error {
 	semaphore := make(chan struct{}, 5) // Limit concurrent fetches
 
 	for _, source := range sources {
+		// Skip sources whose publisher asked us to back off
+		if time.Now().Before(source.RetryAfter.Time()) {
+			continue
+		}
+
 		// Skip if refresh rate is set and not enough time has passed
 		if source.RefreshRate > 0 {
 			lastFetchTime := source.LastFetched.Time()
@@ -212,7 +344,6 @@ func (s *FeedServiceImpl) ProcessNewItems(ctx context.Context) error {
 	}
 
 	if len(items) == 0 {
-		logger.LogInfo("No new items to process")
 		return nil
 	}
 
@@ -290,3 +421,13 @@ func (s *FeedServiceImpl) GetUserFeeds(ctx context.Context, userID string, optio
 	// Get the items
 	return query.FindAllByFilter[*models.FeedItem](filter)
 }
+
+// RankUserFeeds returns the feed items of a user in personalized order.
+// Supported options: status, source_id, max_age_days, half_life_hours, ai,
+// limit and offset
+func (s *FeedServiceImpl) RankUserFeeds(ctx context.Context, userID string, options map[string]interface{}) ([]*services.RankedFeedItem, error) {
+	if s.ranker == nil {
+		return nil, fmt.Errorf("feed ranking is not configured")
+	}
+	return s.ranker.Rank(ctx, userID, ParseRankOptions(options))
+}
@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+func TestFetchFromSourceRefusesInternalCustomSource(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<ul><li><a href="/secret">Internal secret</a></li></ul>`))
+	}))
+	defer server.Close()
+
+	config := `{"url":"` + server.URL + `","item_selector":"li","fields":{"title":"a","link":"a@href"}}`
+	source := &models.FeedSource{
+		User:     testUserID,
+		Name:     "Internal page",
+		Type:     models.SourceTypeCustom,
+		URL:      server.URL,
+		Config:   config,
+		IsActive: true,
+	}
+	source.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(source); err != nil {
+		t.Fatalf("saving source: %v", err)
+	}
+
+	feedService := NewFeedService(nil, nil, nil)
+	feedService.RegisterProvider(providers.NewCustomProvider())
+
+	err := feedService.FetchFromSource(context.Background(), source)
+	if err == nil || !strings.Contains(err.Error(), providers.ErrNonPublicAddress.Error()) {
+		t.Fatalf("got %v, want the internal address to be refused", err)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("the internal server got %d requests", hits.Load())
+	}
+
+	items, err := query.FindAllByFilter[*models.FeedItem](map[string]interface{}{"source_id": source.Id})
+	if err != nil {
+		t.Fatalf("finding items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("got %d stored items, want none", len(items))
+	}
+
+	saved, err := query.FindById[*models.FeedSource](source.Id)
+	if err != nil {
+		t.Fatalf("finding source: %v", err)
+	}
+	if saved.ErrorCount != 1 || !strings.Contains(saved.LastError, providers.ErrNonPublicAddress.Error()) {
+		t.Errorf("got error count %d and last error %q", saved.ErrorCount, saved.LastError)
+	}
+
+	// The same page is fine to fetch outside of a user's source
+	rawItems, err := providers.NewCustomProvider().FetchItems(context.Background(), mustConfigMap(t, source), time.Time{})
+	if err != nil || len(rawItems) != 1 {
+		t.Fatalf("got %d items and error %v fetching without the guard", len(rawItems), err)
+	}
+}
+
+func mustConfigMap(t *testing.T, source *models.FeedSource) map[string]interface{} {
+	t.Helper()
+	config, err := source.GetConfigMap()
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	return config
+}
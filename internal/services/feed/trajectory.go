@@ -0,0 +1,241 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	// Items are re-polled while they are this recent
+	statsTrackWindow = 48 * time.Hour
+	// Upper bound of items re-polled per run
+	statsTrackLimit = 1000
+	// Samples kept per item, the oldest are dropped first
+	maxScoreHistory = 48
+
+	// Window the score velocity is measured over
+	momentumWindow = 6 * time.Hour
+	// Points per hour at which momentum reaches ~0.76
+	momentumScale = 30.0
+	// Share of the final score taken by momentum when an item has a trajectory
+	momentumBlendWeight = 0.2
+)
+
+// ScoreSample is the score and comment count of an item at a point in time,
+// stored as the score_history metadata of tracked items
+type ScoreSample struct {
+	At       int64 `json:"at"`
+	Score    int   `json:"score"`
+	Comments int   `json:"comments"`
+}
+
+// ItemStatsTracker re-polls recently fetched items of sources whose provider
+// can report item stats, keeping their score and comment count current and
+// recording how they change over time
+type ItemStatsTracker struct {
+	feedService services.FeedService
+}
+
+func NewItemStatsTracker(feedService services.FeedService) *ItemStatsTracker {
+	return &ItemStatsTracker{feedService: feedService}
+}
+
+// Run updates the stats of all recent items that can be tracked
+func (t *ItemStatsTracker) Run(ctx context.Context) error {
+	sources, err := query.FindAllByFilter[*models.FeedSource](map[string]interface{}{
+		"is_active": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Sources sharing a type share the provider, the same story is polled
+	// once even when several users follow it
+	sourceIDs := make(map[string][]interface{})
+	for _, source := range sources {
+		provider, ok := t.feedService.GetProvider(source.Type)
+		if !ok {
+			continue
+		}
+		if _, ok := provider.(services.FeedItemStatsProvider); ok {
+			sourceIDs[source.Type] = append(sourceIDs[source.Type], source.Id)
+		}
+	}
+
+	updated := 0
+	for sourceType, ids := range sourceIDs {
+		provider, _ := t.feedService.GetProvider(sourceType)
+		count, err := t.track(ctx, provider.(services.FeedItemStatsProvider), ids)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error tracking %s item stats: %v", sourceType, err))
+		}
+		updated += count
+	}
+
+	if updated > 0 {
+		logger.LogInfo(fmt.Sprintf("Updated stats of %d feed items", updated))
+	}
+	return nil
+}
+
+// track polls the recent items of the given sources and returns how many
+// were updated
+func (t *ItemStatsTracker) track(ctx context.Context, provider services.FeedItemStatsProvider, sourceIDs []interface{}) (int, error) {
+	since := types.NowDateTime().Add(-statsTrackWindow).String()
+
+	var items []*models.FeedItem
+	err := query.BaseQuery[*models.FeedItem]().
+		AndWhere(dbx.HashExp{"source_id": sourceIDs}).
+		AndWhere(dbx.NewExp("fetched_at >= {:since}", dbx.Params{"since": since})).
+		OrderBy("fetched_at DESC").
+		Limit(statsTrackLimit).
+		All(&items)
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+
+	externalIDs := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if !seen[item.ExternalID] {
+			seen[item.ExternalID] = true
+			externalIDs = append(externalIDs, item.ExternalID)
+		}
+	}
+
+	stats, err := provider.FetchItemStats(ctx, externalIDs)
+	now := time.Now()
+
+	updated := 0
+	for _, item := range items {
+		current, ok := stats[item.ExternalID]
+		if !ok {
+			continue
+		}
+		if updateErr := recordItemStats(item, current, now); updateErr != nil {
+			logger.LogError(fmt.Sprintf("Error updating stats of feed item %s: %v", item.Id, updateErr))
+			continue
+		}
+		updated++
+	}
+
+	return updated, err
+}
+
+// recordItemStats stores the current stats of an item and appends them to its
+// score history
+func recordItemStats(item *models.FeedItem, stats services.ItemStats, now time.Time) error {
+	metadata, err := item.GetMetadataMap()
+	if err != nil {
+		return err
+	}
+
+	history := scoreHistory(metadata)
+	if len(history) == 0 {
+		// Seed the history with the stats seen when the item was fetched
+		if seed, ok := fetchedSample(item, metadata); ok {
+			history = append(history, seed)
+		}
+	}
+	history = append(history, ScoreSample{At: now.Unix(), Score: stats.Score, Comments: stats.Comments})
+	if len(history) > maxScoreHistory {
+		history = history[len(history)-maxScoreHistory:]
+	}
+
+	metadata["score"] = stats.Score
+	metadata["comment_count"] = stats.Comments
+	metadata["score_history"] = history
+	metadata["score_velocity"] = math.Round(scoreVelocity(history)*10) / 10
+	metadata["stats_updated_at"] = now.UTC().Format(time.RFC3339)
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return query.UpdateRecord[*models.FeedItem](item.Id, map[string]interface{}{
+		"metadata": types.JSONRaw(encoded),
+	})
+}
+
+// scoreHistory reads the score history from decoded item metadata
+func scoreHistory(metadata map[string]interface{}) []ScoreSample {
+	raw, ok := metadata["score_history"]
+	if !ok {
+		return nil
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var history []ScoreSample
+	if err := json.Unmarshal(encoded, &history); err != nil {
+		return nil
+	}
+	return history
+}
+
+// fetchedSample is the sample an item had when it was fetched, if the
+// provider stored its score
+func fetchedSample(item *models.FeedItem, metadata map[string]interface{}) (ScoreSample, bool) {
+	score, ok := metadata["score"].(float64)
+	if !ok || item.FetchedAt.IsZero() {
+		return ScoreSample{}, false
+	}
+	comments, _ := metadata["comment_count"].(float64)
+
+	return ScoreSample{At: item.FetchedAt.Time().Unix(), Score: int(score), Comments: int(comments)}, true
+}
+
+// scoreVelocity is the points per hour an item gained over the last
+// momentum window of its history
+func scoreVelocity(history []ScoreSample) float64 {
+	if len(history) < 2 {
+		return 0
+	}
+
+	latest := history[len(history)-1]
+	base := latest
+	for i := len(history) - 2; i >= 0; i-- {
+		if latest.At-history[i].At > int64(momentumWindow.Seconds()) {
+			break
+		}
+		base = history[i]
+	}
+	if base.At >= latest.At {
+		// Only samples outside the window, measure from the closest one
+		base = history[len(history)-2]
+	}
+
+	hours := float64(latest.At-base.At) / 3600
+	if hours <= 0 {
+		return 0
+	}
+	return math.Max(float64(latest.Score-base.Score), 0) / hours
+}
+
+// momentum maps how fast an item is gaining points to 0-1. Items without a
+// tracked trajectory have none.
+func momentum(item *models.FeedItem) (float64, bool) {
+	metadata, err := item.GetMetadataMap()
+	if err != nil {
+		return 0, false
+	}
+
+	history := scoreHistory(metadata)
+	if len(history) < 2 {
+		return 0, false
+	}
+	return math.Tanh(scoreVelocity(history) / momentumScale), true
+}
@@ -0,0 +1,463 @@
+package feed
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const (
+	WebSubCallbackPath = "/api/feeds/websub/"
+
+	// Lease asked from hubs, they may grant a different one
+	webSubLeaseSeconds = 7 * 24 * 60 * 60
+	// Leases are renewed this long before they expire, or halfway through
+	// shorter leases
+	webSubRenewBefore = 24 * time.Hour
+	// Time to wait for a verification or after a failed request before
+	// asking the hub again
+	webSubRetryInterval = time.Hour
+	// Failed sources are tried again after this long
+	webSubFailedRetry = 24 * time.Hour
+	// Failed requests in a row before a subscription is given up
+	maxWebSubAttempts = 5
+	// Sources pushed over WebSub are still polled this often in case the
+	// hub misses updates
+	webSubPollInterval = 24 * time.Hour
+
+	webSubSecretLength = 40
+	webSubTimeout      = 30 * time.Second
+)
+
+// ErrWebSubNotFound is returned for callbacks of sources without a matching
+// subscription
+var ErrWebSubNotFound = errors.New("websub subscription not found")
+
+// WebSubManager subscribes feed sources to the WebSub hubs their feeds
+// advertise, so new entries are pushed instead of polled. Sources without a
+// hub, or whose hub fails, keep being polled by the FeedScheduler.
+type WebSubManager struct {
+	feedService services.FeedService
+	client      *http.Client
+	baseURL     string
+	running     atomic.Bool
+}
+
+func NewWebSubManager(feedService services.FeedService, cfg config.FeedConfig) *WebSubManager {
+	return &WebSubManager{
+		feedService: feedService,
+		client:      providers.NewHTTPClient(webSubTimeout),
+		baseURL:     cfg.WebSubBaseURL,
+	}
+}
+
+// Enabled reports whether a public callback URL is configured
+func (m *WebSubManager) Enabled() bool {
+	return m.baseURL != ""
+}
+
+// CallbackURL returns the URL hubs deliver the updates of a source to
+func (m *WebSubManager) CallbackURL(sourceID string) string {
+	return m.baseURL + WebSubCallbackPath + sourceID
+}
+
+// recordWebSubHub keeps the subscription of a source in line with the hub its
+// feed advertises. A new or changed hub gets a pending subscription, a hub
+// that went away is unsubscribed from.
+func recordWebSubHub(source *models.FeedSource, hub string, topic string) {
+	sub, err := query.FindByFilter[*models.FeedWebSubSubscription](map[string]interface{}{
+		"source": source.Id,
+	})
+
+	if hub == "" || topic == "" {
+		if err != nil {
+			return
+		}
+		switch sub.State {
+		case models.WebSubStateSubscribed, models.WebSubStateSubscribing:
+			err = query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+				"state":        models.WebSubStateUnsubscribing,
+				"attempts":     0,
+				"requested_at": types.DateTime{},
+			})
+		case models.WebSubStateUnsubscribing:
+		default:
+			err = query.DeleteRecord(sub)
+		}
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error dropping WebSub subscription of source %s: %v", source.Id, err))
+		}
+		return
+	}
+
+	if err == nil {
+		if sub.Hub == hub && sub.Topic == topic {
+			return
+		}
+		// The lease at the previous hub simply runs out
+		if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+			"hub":          hub,
+			"topic":        topic,
+			"state":        models.WebSubStatePending,
+			"attempts":     0,
+			"last_error":   "",
+			"requested_at": types.DateTime{},
+			"expires_at":   types.DateTime{},
+			"verified_at":  types.DateTime{},
+		}); err != nil {
+			logger.LogError(fmt.Sprintf("Error updating WebSub hub of source %s: %v", source.Id, err))
+		}
+		return
+	}
+
+	sub = &models.FeedWebSubSubscription{
+		User:   source.User,
+		Source: source.Id,
+		Hub:    hub,
+		Topic:  topic,
+		Secret: security.RandomString(webSubSecretLength),
+		State:  models.WebSubStatePending,
+	}
+	sub.Id = util.GenerateRandomId()
+	sub.RefreshCreated()
+	sub.RefreshUpdated()
+	if err := query.SaveRecord(sub); err != nil {
+		logger.LogError(fmt.Sprintf("Error saving WebSub subscription of source %s: %v", source.Id, err))
+		return
+	}
+
+	logger.LogInfo(fmt.Sprintf("Feed source %s advertises WebSub hub %s", source.Name, hub))
+}
+
+// pushedSourceIDs returns the sources with a verified lease that hasn't
+// expired yet
+func pushedSourceIDs(now time.Time) (map[string]bool, error) {
+	subs, err := query.FindAllByFilter[*models.FeedWebSubSubscription](map[string]interface{}{
+		"state": models.WebSubStateSubscribed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pushed := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		if sub.ExpiresAt.Time().After(now) {
+			pushed[sub.Source] = true
+		}
+	}
+	return pushed, nil
+}
+
+// Sync sends the subscription requests that are due: new hubs, leases about
+// to expire, retries, and unsubscriptions of sources that no longer want
+// pushes. Runs that overlap a previous one are skipped.
+func (m *WebSubManager) Sync(ctx context.Context) error {
+	if !m.Enabled() {
+		return nil
+	}
+	if !m.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer m.running.Store(false)
+
+	var subs []*models.FeedWebSubSubscription
+	if err := query.BaseQuery[*models.FeedWebSubSubscription]().OrderBy("updated ASC").All(&subs); err != nil {
+		return fmt.Errorf("error loading WebSub subscriptions: %v", err)
+	}
+
+	now := time.Now()
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.syncSubscription(ctx, sub, now)
+	}
+
+	return nil
+}
+
+func (m *WebSubManager) syncSubscription(ctx context.Context, sub *models.FeedWebSubSubscription, now time.Time) {
+	wanted := false
+	if source, err := query.FindById[*models.FeedSource](sub.Source); err == nil && source.IsActive {
+		if provider, ok := m.feedService.GetProvider(source.Type); ok {
+			_, wanted = provider.(services.FeedContentParser)
+		}
+	}
+
+	// Zero when no request was sent yet
+	requestedAt := sub.RequestedAt.Time()
+	sinceRequest := now.Sub(requestedAt)
+
+	if !wanted || sub.State == models.WebSubStateUnsubscribing {
+		switch sub.State {
+		case models.WebSubStateSubscribed, models.WebSubStateSubscribing:
+			m.send(ctx, sub, "unsubscribe", models.WebSubStateUnsubscribing)
+		case models.WebSubStateUnsubscribing:
+			if requestedAt.IsZero() || sinceRequest >= webSubRetryInterval {
+				m.send(ctx, sub, "unsubscribe", models.WebSubStateUnsubscribing)
+			}
+		default:
+			if err := query.DeleteRecord(sub); err != nil {
+				logger.LogError(fmt.Sprintf("Error deleting WebSub subscription %s: %v", sub.Id, err))
+			}
+		}
+		return
+	}
+
+	switch sub.State {
+	case models.WebSubStatePending:
+		m.send(ctx, sub, "subscribe", models.WebSubStateSubscribing)
+	case models.WebSubStateSubscribing:
+		if sinceRequest >= webSubRetryInterval {
+			m.send(ctx, sub, "subscribe", models.WebSubStateSubscribing)
+		}
+	case models.WebSubStateSubscribed:
+		lease := time.Duration(sub.LeaseSeconds) * time.Second
+		window := min(webSubRenewBefore, lease/2)
+		if sub.ExpiresAt.Time().Sub(now) <= window && sinceRequest >= min(webSubRetryInterval, window/2) {
+			m.send(ctx, sub, "subscribe", models.WebSubStateSubscribed)
+		}
+	case models.WebSubStateFailed:
+		if sinceRequest >= webSubFailedRetry {
+			sub.Attempts = 0
+			m.send(ctx, sub, "subscribe", models.WebSubStateSubscribing)
+		}
+	}
+}
+
+// send asks the hub to subscribe or unsubscribe. The state is stored before
+// the request since hubs may verify the intent before answering it.
+func (m *WebSubManager) send(ctx context.Context, sub *models.FeedWebSubSubscription, mode string, state string) {
+	if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+		"state":        state,
+		"attempts":     sub.Attempts,
+		"requested_at": types.NowDateTime(),
+	}); err != nil {
+		logger.LogError(fmt.Sprintf("Error updating WebSub subscription %s: %v", sub.Id, err))
+		return
+	}
+
+	err := m.request(ctx, sub, mode)
+	if err == nil {
+		logger.LogInfo(fmt.Sprintf("Sent WebSub %s request for source %s to %s", mode, sub.Source, sub.Hub))
+		return
+	}
+
+	attempts := sub.Attempts + 1
+	logger.LogError(fmt.Sprintf("WebSub %s request for source %s failed (attempt %d): %v", mode, sub.Source, attempts, err))
+
+	if attempts >= maxWebSubAttempts {
+		if mode == "unsubscribe" {
+			// The lease runs out on its own
+			err = query.DeleteRecord(sub)
+		} else {
+			err = query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+				"state":      models.WebSubStateFailed,
+				"attempts":   attempts,
+				"last_error": err.Error(),
+			})
+		}
+	} else {
+		err = query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+			"attempts":   attempts,
+			"last_error": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error updating WebSub subscription %s: %v", sub.Id, err))
+	}
+}
+
+// request posts a subscription request to the hub
+func (m *WebSubManager) request(ctx context.Context, sub *models.FeedWebSubSubscription, mode string) error {
+	form := url.Values{
+		"hub.callback": {m.CallbackURL(sub.Source)},
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.Topic},
+	}
+	if mode == "subscribe" {
+		form.Set("hub.secret", sub.Secret)
+		form.Set("hub.lease_seconds", strconv.Itoa(webSubLeaseSeconds))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error contacting hub: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub answered with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// VerifyIntent handles the hub's verification of a subscription request.
+// It returns ErrWebSubNotFound when the request doesn't match one we sent,
+// the challenge must then not be echoed.
+func (m *WebSubManager) VerifyIntent(sourceID string, mode string, topic string, leaseSeconds int, reason string) error {
+	sub, err := query.FindByFilter[*models.FeedWebSubSubscription](map[string]interface{}{
+		"source": sourceID,
+	})
+	if err != nil || sub.Topic != topic {
+		return ErrWebSubNotFound
+	}
+
+	switch mode {
+	case "subscribe":
+		if sub.State != models.WebSubStateSubscribing && sub.State != models.WebSubStateSubscribed {
+			return ErrWebSubNotFound
+		}
+		if leaseSeconds <= 0 {
+			leaseSeconds = webSubLeaseSeconds
+		}
+
+		now := time.Now()
+		expiresAt := types.DateTime{}
+		expiresAt.Scan(now.Add(time.Duration(leaseSeconds) * time.Second))
+		verifiedAt := types.DateTime{}
+		verifiedAt.Scan(now)
+
+		if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+			"state":         models.WebSubStateSubscribed,
+			"lease_seconds": leaseSeconds,
+			"expires_at":    expiresAt,
+			"verified_at":   verifiedAt,
+			"attempts":      0,
+			"last_error":    "",
+		}); err != nil {
+			return err
+		}
+		logger.LogInfo(fmt.Sprintf("WebSub subscription of source %s verified for %d seconds", sourceID, leaseSeconds))
+
+	case "unsubscribe":
+		if sub.State != models.WebSubStateUnsubscribing {
+			return ErrWebSubNotFound
+		}
+		if err := query.DeleteRecord(sub); err != nil {
+			return err
+		}
+		logger.LogInfo(fmt.Sprintf("WebSub subscription of source %s removed", sourceID))
+
+	case "denied":
+		if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+			"state":      models.WebSubStateDenied,
+			"last_error": reason,
+		}); err != nil {
+			return err
+		}
+		logger.LogInfo(fmt.Sprintf("WebSub hub denied the subscription of source %s: %s", sourceID, reason))
+
+	default:
+		return fmt.Errorf("unknown hub.mode %q", mode)
+	}
+
+	return nil
+}
+
+// HandlePush ingests the feed content a hub delivered for a source through
+// the same path as fetched items, and returns how many items were new.
+// Content without a valid signature is ignored.
+func (m *WebSubManager) HandlePush(ctx context.Context, sourceID string, body []byte, signature string) (int, error) {
+	sub, err := query.FindByFilter[*models.FeedWebSubSubscription](map[string]interface{}{
+		"source": sourceID,
+	})
+	if err != nil {
+		return 0, ErrWebSubNotFound
+	}
+
+	if !validWebSubSignature(sub.Secret, signature, body) {
+		logger.LogInfo(fmt.Sprintf("Ignoring WebSub content for source %s with an invalid signature", sourceID))
+		return 0, nil
+	}
+
+	source, err := query.FindById[*models.FeedSource](sourceID)
+	if err != nil {
+		return 0, ErrWebSubNotFound
+	}
+
+	provider, ok := m.feedService.GetProvider(source.Type)
+	if !ok {
+		return 0, fmt.Errorf("no provider registered for source type: %s", source.Type)
+	}
+	parser, ok := provider.(services.FeedContentParser)
+	if !ok {
+		return 0, fmt.Errorf("source type %s can't be pushed", source.Type)
+	}
+
+	rawItems, err := parser.ParseContent(body)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing pushed content for source %s: %v", source.Name, err)
+	}
+
+	stored := m.feedService.IngestItems(ctx, source, rawItems)
+
+	if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+		"last_push_at": types.NowDateTime(),
+	}); err != nil {
+		logger.LogError(fmt.Sprintf("Error updating WebSub subscription %s: %v", sub.Id, err))
+	}
+
+	logger.LogInfo(fmt.Sprintf("WebSub push for source %s stored %d new items", source.Name, stored))
+	return stored, nil
+}
+
+// validWebSubSignature checks an X-Hub-Signature header of the form
+// "sha256=<hex HMAC of the body>"
+func validWebSubSignature(secret string, signature string, body []byte) bool {
+	method, digest, ok := strings.Cut(signature, "=")
+	if secret == "" || !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,366 @@
+package feed
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/config"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services/providers"
+	"github.com/shashank-sharma/backend/internal/store"
+	"github.com/shashank-sharma/backend/internal/util"
+)
+
+const webSubTestFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+	<title>Example</title>
+	<link>https://example.com/</link>
+	<item>
+		<title>First pushed entry about WebSub hubs</title>
+		<link>https://example.com/posts/first</link>
+		<guid>https://example.com/posts/first</guid>
+		<pubDate>Mon, 06 Jan 2025 10:00:00 GMT</pubDate>
+	</item>
+	<item>
+		<title>Second pushed entry on lease renewal</title>
+		<link>https://example.com/posts/second</link>
+		<guid>https://example.com/posts/second</guid>
+		<pubDate>Mon, 06 Jan 2025 11:00:00 GMT</pubDate>
+	</item>
+</channel>
+</rss>`
+
+// testHub stands in for a WebSub hub. It verifies the intent of every
+// request it gets through the manager before answering, like hubs calling
+// back the subscriber's callback URL.
+type testHub struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	requests     []url.Values
+	leaseSeconds int    // Lease granted to subscriptions
+	topic        string // Topic verified instead of the requested one when set
+}
+
+func newTestHub(t *testing.T, manager *WebSubManager) *testHub {
+	hub := &testHub{leaseSeconds: 3600}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		hub.mu.Lock()
+		hub.requests = append(hub.requests, r.PostForm)
+		lease, topic := hub.leaseSeconds, hub.topic
+		hub.mu.Unlock()
+
+		if topic == "" {
+			topic = r.PostForm.Get("hub.topic")
+		}
+		sourceID := strings.TrimPrefix(r.PostForm.Get("hub.callback"), manager.CallbackURL(""))
+		if err := manager.VerifyIntent(sourceID, r.PostForm.Get("hub.mode"), topic, lease, ""); err != nil {
+			t.Logf("intent verification failed: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+func (h *testHub) received() []url.Values {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]url.Values(nil), h.requests...)
+}
+
+func newTestWebSubManager() *WebSubManager {
+	feedService := NewFeedService(nil, nil, nil)
+	feedService.RegisterProvider(providers.NewRSSProvider())
+	return NewWebSubManager(feedService, config.FeedConfig{WebSubBaseURL: "https://dashboard.example.com"})
+}
+
+// createWebSubTestSource creates a source to subscribe to, dropping the
+// subscriptions of other tests so Sync only talks to the hub of the test
+func createWebSubTestSource(t *testing.T) *models.FeedSource {
+	t.Helper()
+	if _, err := store.GetDao().DB().Delete((&models.FeedWebSubSubscription{}).TableName(), nil).Execute(); err != nil {
+		t.Fatalf("deleting subscriptions: %v", err)
+	}
+
+	source := &models.FeedSource{
+		User:     testUserID,
+		Name:     "Example",
+		Type:     models.SourceTypeRSS,
+		URL:      "https://example.com/feed.xml",
+		Config:   `{"url":"https://example.com/feed.xml"}`,
+		IsActive: true,
+	}
+	source.Id = util.GenerateRandomId()
+	if err := query.SaveRecord(source); err != nil {
+		t.Fatalf("saving source: %v", err)
+	}
+	return source
+}
+
+func findWebSubSubscription(t *testing.T, sourceID string) *models.FeedWebSubSubscription {
+	t.Helper()
+	sub, err := query.FindByFilter[*models.FeedWebSubSubscription](map[string]interface{}{"source": sourceID})
+	if err != nil {
+		t.Fatalf("loading subscription of source %s: %v", sourceID, err)
+	}
+	return sub
+}
+
+func signWebSubBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebSubSubscribe(t *testing.T) {
+	manager := newTestWebSubManager()
+	hub := newTestHub(t, manager)
+	source := createWebSubTestSource(t)
+
+	recordWebSubHub(source, hub.URL, source.URL)
+	sub := findWebSubSubscription(t, source.Id)
+	if sub.State != models.WebSubStatePending || sub.Secret == "" {
+		t.Fatalf("got state %s, want a pending subscription with a secret", sub.State)
+	}
+
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	requests := hub.received()
+	if len(requests) != 1 {
+		t.Fatalf("hub got %d requests, want 1", len(requests))
+	}
+	form := requests[0]
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != source.URL {
+		t.Errorf("got mode %s and topic %s", form.Get("hub.mode"), form.Get("hub.topic"))
+	}
+	if form.Get("hub.callback") != "https://dashboard.example.com"+WebSubCallbackPath+source.Id {
+		t.Errorf("got callback %s", form.Get("hub.callback"))
+	}
+	if form.Get("hub.secret") != sub.Secret || form.Get("hub.lease_seconds") != strconv.Itoa(webSubLeaseSeconds) {
+		t.Errorf("got secret %q and lease %s", form.Get("hub.secret"), form.Get("hub.lease_seconds"))
+	}
+
+	sub = findWebSubSubscription(t, source.Id)
+	if sub.State != models.WebSubStateSubscribed {
+		t.Fatalf("got state %s after verification, want subscribed", sub.State)
+	}
+	if sub.LeaseSeconds != 3600 {
+		t.Errorf("got lease of %d seconds, want the 3600 the hub granted", sub.LeaseSeconds)
+	}
+	if until := time.Until(sub.ExpiresAt.Time()); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("lease expires in %s, want an hour", until)
+	}
+
+	pushed, err := pushedSourceIDs(time.Now())
+	if err != nil || !pushed[source.Id] {
+		t.Errorf("source not among the pushed sources: %v", err)
+	}
+}
+
+func TestWebSubVerifyIntentRejected(t *testing.T) {
+	manager := newTestWebSubManager()
+	hub := newTestHub(t, manager)
+	hub.topic = "https://attacker.example.com/feed.xml"
+	source := createWebSubTestSource(t)
+
+	recordWebSubHub(source, hub.URL, source.URL)
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	// The hub verified another topic than the one subscribed to
+	sub := findWebSubSubscription(t, source.Id)
+	if sub.State != models.WebSubStateSubscribing {
+		t.Errorf("got state %s, want the subscription still waiting for verification", sub.State)
+	}
+
+	tests := []struct {
+		name     string
+		sourceID string
+		mode     string
+		topic    string
+	}{
+		{"topic mismatch", source.Id, "subscribe", "https://attacker.example.com/feed.xml"},
+		{"unknown source", "unknownsource1", "subscribe", source.URL},
+		{"unsubscribe not requested", source.Id, "unsubscribe", source.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := manager.VerifyIntent(tt.sourceID, tt.mode, tt.topic, 3600, "")
+			if !errors.Is(err, ErrWebSubNotFound) {
+				t.Errorf("got %v, want ErrWebSubNotFound", err)
+			}
+		})
+	}
+
+	if err := manager.VerifyIntent(source.Id, "delete", source.URL, 0, ""); err == nil || errors.Is(err, ErrWebSubNotFound) {
+		t.Errorf("got %v for an unknown mode, want an error", err)
+	}
+	if sub = findWebSubSubscription(t, source.Id); sub.State != models.WebSubStateSubscribing {
+		t.Errorf("rejected verifications changed the state to %s", sub.State)
+	}
+}
+
+func TestWebSubPush(t *testing.T) {
+	manager := newTestWebSubManager()
+	hub := newTestHub(t, manager)
+	source := createWebSubTestSource(t)
+
+	recordWebSubHub(source, hub.URL, source.URL)
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	sub := findWebSubSubscription(t, source.Id)
+	body := []byte(webSubTestFeed)
+
+	stored, err := manager.HandlePush(context.Background(), source.Id, body, "sha256="+strings.Repeat("0", 64))
+	if err != nil || stored != 0 {
+		t.Fatalf("invalid signature: got %d items stored and error %v, want nothing stored", stored, err)
+	}
+	stored, err = manager.HandlePush(context.Background(), source.Id, body, "")
+	if err != nil || stored != 0 {
+		t.Fatalf("missing signature: got %d items stored and error %v, want nothing stored", stored, err)
+	}
+	if count, _ := query.CountRecords[*models.FeedItem](map[string]interface{}{"source_id": source.Id}); count != 0 {
+		t.Fatalf("got %d items after unsigned pushes, want none", count)
+	}
+
+	stored, err = manager.HandlePush(context.Background(), source.Id, body, signWebSubBody(sub.Secret, body))
+	if err != nil {
+		t.Fatalf("HandlePush: %v", err)
+	}
+	if stored != 2 {
+		t.Errorf("got %d items stored, want 2", stored)
+	}
+	if sub = findWebSubSubscription(t, source.Id); sub.LastPushAt.IsZero() {
+		t.Error("last push time not recorded")
+	}
+
+	// Hubs may deliver the same content again
+	if stored, _ = manager.HandlePush(context.Background(), source.Id, body, signWebSubBody(sub.Secret, body)); stored != 0 {
+		t.Errorf("got %d items stored from a repeated push, want 0", stored)
+	}
+
+	if _, err := manager.HandlePush(context.Background(), "unknownsource1", body, ""); !errors.Is(err, ErrWebSubNotFound) {
+		t.Errorf("got %v for an unknown source, want ErrWebSubNotFound", err)
+	}
+}
+
+func TestValidWebSubSignature(t *testing.T) {
+	body := []byte("content")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	digest := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		want      bool
+	}{
+		{"valid", "secret", "sha256=" + digest, true},
+		{"upper case method", "secret", "SHA256=" + digest, true},
+		{"other secret", "other", "sha256=" + digest, false},
+		{"other method", "secret", "sha1=" + digest, false},
+		{"unknown method", "secret", "md5=" + digest, false},
+		{"not hex", "secret", "sha256=zz", false},
+		{"no method", "secret", digest, false},
+		{"no secret", "", "sha256=" + digest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validWebSubSignature(tt.secret, tt.signature, body); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSubLeaseRenewal(t *testing.T) {
+	manager := newTestWebSubManager()
+	hub := newTestHub(t, manager)
+	hub.leaseSeconds = webSubLeaseSeconds
+	source := createWebSubTestSource(t)
+
+	recordWebSubHub(source, hub.URL, source.URL)
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(hub.received()) != 1 {
+		t.Fatalf("hub got %d requests, want 1", len(hub.received()))
+	}
+
+	// A fresh lease isn't renewed
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(hub.received()) != 1 {
+		t.Fatalf("hub got %d requests for a fresh lease, want 1", len(hub.received()))
+	}
+
+	// One about to expire, requested long ago, is
+	sub := findWebSubSubscription(t, source.Id)
+	expiresAt := types.DateTime{}
+	expiresAt.Scan(time.Now().Add(12 * time.Hour))
+	requestedAt := types.DateTime{}
+	requestedAt.Scan(time.Now().Add(-6 * 24 * time.Hour))
+	if err := query.UpdateRecord[*models.FeedWebSubSubscription](sub.Id, map[string]interface{}{
+		"expires_at":   expiresAt,
+		"requested_at": requestedAt,
+	}); err != nil {
+		t.Fatalf("updating subscription: %v", err)
+	}
+
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	requests := hub.received()
+	if len(requests) != 2 || requests[1].Get("hub.mode") != "subscribe" {
+		t.Fatalf("hub got %d requests, want a renewal", len(requests))
+	}
+
+	sub = findWebSubSubscription(t, source.Id)
+	if sub.State != models.WebSubStateSubscribed {
+		t.Errorf("got state %s after renewal, want subscribed", sub.State)
+	}
+	if until := time.Until(sub.ExpiresAt.Time()); until < 6*24*time.Hour {
+		t.Errorf("renewed lease expires in %s, want a week", until)
+	}
+
+	// A source that is no longer active is unsubscribed
+	if err := query.UpdateRecord[*models.FeedSource](source.Id, map[string]interface{}{"is_active": false}); err != nil {
+		t.Fatalf("updating source: %v", err)
+	}
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	requests = hub.received()
+	if len(requests) != 3 || requests[2].Get("hub.mode") != "unsubscribe" {
+		t.Fatalf("hub got %d requests, want an unsubscription", len(requests))
+	}
+	if _, err := query.FindByFilter[*models.FeedWebSubSubscription](map[string]interface{}{"source": source.Id}); err == nil {
+		t.Error("subscription kept after the hub verified the unsubscription")
+	}
+}
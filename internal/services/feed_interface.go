@@ -20,6 +20,15 @@ type RawFeedItem struct {
 	Metadata    map[string]interface{}
 }
 
+// RankedFeedItem is a feed item with its personalized score and the reasons
+// it was ranked where it is
+type RankedFeedItem struct {
+	Item       *models.FeedItem
+	Score      float64            // Final score (0-1), higher ranks first
+	Components map[string]float64 // Score parts: preference, recency, momentum and ai when used
+	Reasons    []string           // Human readable explanations
+}
+
 // FeedSourceProvider defines the interface for all feed source implementations
 type FeedSourceProvider interface {
 	// GetProviderType returns the type of feed source (rss, hackernews, etc.)
@@ -33,6 +42,42 @@ type FeedSourceProvider interface {
 	Validate(config map[string]interface{}) error
 }
 
+// FeedSourceOwnerValidator is implemented by providers whose configuration
+// references records of the user owning the source, such as access tokens
+type FeedSourceOwnerValidator interface {
+	// ValidateForUser validates the configuration of a source of the user
+	ValidateForUser(config map[string]interface{}, userID string) error
+}
+
+// FeedSourcePreviewer is implemented by providers that can return a sample of
+// parsed items for a configuration before the source is saved
+type FeedSourcePreviewer interface {
+	// Preview fetches and parses a small sample of items
+	Preview(ctx context.Context, config map[string]interface{}) ([]RawFeedItem, error)
+}
+
+// FeedContentParser is implemented by providers whose sources can be pushed
+// over WebSub. It parses the feed content delivered by the hub.
+type FeedContentParser interface {
+	// ParseContent parses a feed document into raw items
+	ParseContent(body []byte) ([]RawFeedItem, error)
+}
+
+// ItemStats is the engagement of an item at the source at a point in time
+type ItemStats struct {
+	Score    int
+	Comments int
+}
+
+// FeedItemStatsProvider is implemented by providers whose items keep
+// collecting score and comments after they were fetched, so their trajectory
+// can be tracked
+type FeedItemStatsProvider interface {
+	// FetchItemStats returns the current stats of items by external ID.
+	// Items that no longer exist are left out.
+	FetchItemStats(ctx context.Context, externalIDs []string) (map[string]ItemStats, error)
+}
+
 // FeedProcessor handles processing of raw feed items
 type FeedProcessor interface {
 	// ProcessItem processes a raw feed item and returns a processed feed item
@@ -56,6 +101,10 @@ type FeedService interface {
 	// FetchFromSource fetches items from a specific source
 	FetchFromSource(ctx context.Context, source *models.FeedSource) error
 
+	// IngestItems stores the new items among raw items of a source and
+	// returns how many were stored
+	IngestItems(ctx context.Context, source *models.FeedSource, rawItems []RawFeedItem) int
+
 	// FetchAllSources fetches items from all active sources
 	FetchAllSources(ctx context.Context) error
 
@@ -64,4 +113,7 @@ type FeedService interface {
 
 	// GetUserFeeds gets feed items for a user with filtering options
 	GetUserFeeds(ctx context.Context, userID string, options map[string]interface{}) ([]*models.FeedItem, error)
+
+	// RankUserFeeds returns the feed items of a user in personalized "For you" order
+	RankUserFeeds(ctx context.Context, userID string, options map[string]interface{}) ([]*RankedFeedItem, error)
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type fetchStateKey struct{}
+
+// FetchState carries the HTTP cache validators of a feed source to its provider
+// and collects the updated validators, rate limit hints and advertised WebSub
+// hub from the responses
+type FetchState struct {
+	mu            sync.Mutex
+	etag          string
+	lastModified  string
+	notModified   bool
+	retryAfter    time.Time
+	hub           string
+	topic         string
+	webSubChecked bool
+}
+
+// NewFetchState creates a fetch state from the stored validators of a source
+func NewFetchState(etag string, lastModified string) *FetchState {
+	return &FetchState{
+		etag:         etag,
+		lastModified: lastModified,
+	}
+}
+
+// WithFetchState returns a context carrying the given fetch state
+func WithFetchState(ctx context.Context, state *FetchState) context.Context {
+	return context.WithValue(ctx, fetchStateKey{}, state)
+}
+
+// FetchStateFromContext returns the fetch state of the context, or nil
+func FetchStateFromContext(ctx context.Context) *FetchState {
+	state, _ := ctx.Value(fetchStateKey{}).(*FetchState)
+	return state
+}
+
+// Validators returns the ETag and Last-Modified values to send
+func (s *FetchState) Validators() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.etag, s.lastModified
+}
+
+// SetValidators stores the ETag and Last-Modified values of a fresh response
+func (s *FetchState) SetValidators(etag string, lastModified string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag = etag
+	s.lastModified = lastModified
+}
+
+// SetNotModified records that the server answered 304 Not Modified
+func (s *FetchState) SetNotModified() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notModified = true
+}
+
+// NotModified reports whether the source was unchanged since the last fetch
+func (s *FetchState) NotModified() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// SetRetryAfter records when the server allows the next request. The latest
+// time wins when several responses carry a hint.
+func (s *FetchState) SetRetryAfter(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.After(s.retryAfter) {
+		s.retryAfter = t
+	}
+}
+
+// RetryAfter returns when the source may be fetched again, or the zero time
+func (s *FetchState) RetryAfter() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.retryAfter
+}
+
+// SetWebSub records the WebSub hub and topic (self URL) a fetched feed
+// advertises, empty when it advertises none. Values already found, e.g. in
+// the Link headers, are kept.
+func (s *FetchState) SetWebSub(hub string, topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hub == "" {
+		s.hub = hub
+	}
+	if s.topic == "" {
+		s.topic = topic
+	}
+	s.webSubChecked = true
+}
+
+// WebSub returns the advertised hub and topic. checked is false when no
+// content was fetched to look for them, e.g. on 304 Not Modified.
+func (s *FetchState) WebSub() (hub string, topic string, checked bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hub, s.topic, s.webSubChecked
+}
+
+// RateLimitedError is returned when a source was rate limited by its server
+type RateLimitedError struct {
+	RetryAfter time.Time
+	Err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited until %s: %v", e.RetryAfter.Format(time.RFC3339), e.Err)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
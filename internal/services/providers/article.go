@@ -0,0 +1,415 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// WordsPerMinute is the reading speed used for reading time estimates
+	WordsPerMinute = 230
+
+	minArticleTextLength = 250
+)
+
+var (
+	positiveClassPattern = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeClassPattern = regexp.MustCompile(`(?i)comment|meta|footer|footnote|foot|sidebar|sponsor|share|social|related|promo|advert|ad-|newsletter|subscribe|popup|modal|cookie|nav|menu|breadcrumb|widget`)
+	languagePattern      = regexp.MustCompile(`^[a-zA-Z]{2,3}`)
+)
+
+// Elements that never contain article content
+var unlikelyElements = "script, style, noscript, iframe, form, nav, header, footer, aside, button, input, select, textarea, svg, canvas, template"
+
+// Attributes kept on elements of the cleaned article HTML
+var allowedAttributes = map[string]bool{
+	"href":    true,
+	"src":     true,
+	"alt":     true,
+	"title":   true,
+	"colspan": true,
+	"rowspan": true,
+}
+
+// Article is the readable content extracted from a web page
+type Article struct {
+	Title       string
+	Content     string // Cleaned HTML of the main content
+	Text        string // Plain text of the main content
+	LeadImage   string
+	Language    string
+	ReadingTime int // Minutes
+	WordCount   int
+}
+
+// FetchArticle downloads a page and extracts the readable article from it
+func (f *HTMLContentFetcher) FetchArticle(ctx context.Context, pageURL string) (*Article, error) {
+	if pageURL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+
+	body, err := f.fetchBody(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExtractArticle(body, pageURL)
+}
+
+// FetchFullArticle is FetchArticle without the content length limit, for
+// keeping a complete copy of the page
+func (f *HTMLContentFetcher) FetchFullArticle(ctx context.Context, pageURL string) (*Article, error) {
+	if pageURL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+
+	body, err := f.fetchBody(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractArticle(body, pageURL, 0)
+}
+
+// ExtractArticle finds the main content of an HTML page by scoring block
+// elements on the amount of paragraph text they contain, in the spirit of
+// Readability. Boilerplate is removed, URLs are made absolute and only a small
+// set of presentational attributes is kept.
+func ExtractArticle(body []byte, pageURL string) (*Article, error) {
+	return extractArticle(body, pageURL, MaxContentLength)
+}
+
+// extractArticle extracts the article, truncating its HTML to maxLength bytes
+// unless maxLength is 0
+func extractArticle(body []byte, pageURL string, maxLength int) (*Article, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %v", err)
+	}
+
+	base, _ := url.Parse(pageURL)
+
+	article := &Article{
+		Title:     extractArticleTitle(doc),
+		Language:  extractLanguage(doc),
+		LeadImage: metaContent(doc, "og:image", "twitter:image"),
+	}
+
+	doc.Find(unlikelyElements).Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if isHidden(s) {
+			s.Remove()
+		}
+	})
+
+	content := findArticleNode(doc)
+	if content == nil {
+		return nil, fmt.Errorf("no content extracted")
+	}
+
+	cleanArticleNode(content, base)
+
+	if article.LeadImage == "" {
+		if src, ok := content.Find("img[src]").First().Attr("src"); ok {
+			article.LeadImage = src
+		}
+	}
+	if article.LeadImage != "" && base != nil {
+		article.LeadImage = absoluteURL(base, article.LeadImage)
+	}
+
+	contentHTML, err := content.Html()
+	if err != nil {
+		return nil, fmt.Errorf("error rendering content: %v", err)
+	}
+	article.Content = strings.TrimSpace(contentHTML)
+	if maxLength > 0 && len(article.Content) > maxLength {
+		article.Content = truncateHTML(article.Content, maxLength)
+	}
+
+	article.Text = strings.Join(strings.Fields(blockText(content)), " ")
+	article.WordCount = len(strings.Fields(article.Text))
+	article.ReadingTime = int(math.Ceil(float64(article.WordCount) / WordsPerMinute))
+
+	if article.Text == "" {
+		return nil, fmt.Errorf("no content extracted")
+	}
+
+	return article, nil
+}
+
+// findArticleNode returns the element with the highest content score. Each
+// paragraph scores its parent fully and its grandparent by half.
+func findArticleNode(doc *goquery.Document) *goquery.Selection {
+	var best *goquery.Selection
+	bestScore := 0.0
+
+	scores := make(map[*html.Node]float64)
+	candidates := make(map[*html.Node]*goquery.Selection)
+
+	doc.Find("p, pre, td, blockquote, li").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1.0
+		score += float64(strings.Count(text, ",") + strings.Count(text, "，"))
+		score += math.Min(float64(len(text))/100, 3)
+
+		parent := p.Parent()
+		grandparent := parent.Parent()
+		for i, ancestor := range []*goquery.Selection{parent, grandparent} {
+			if ancestor.Length() == 0 {
+				continue
+			}
+			node := ancestor.Get(0)
+			if _, ok := candidates[node]; !ok {
+				candidates[node] = ancestor
+				scores[node] = classWeight(ancestor)
+			}
+			if i == 0 {
+				scores[node] += score
+			} else {
+				scores[node] += score / 2
+			}
+		}
+	})
+
+	for node, candidate := range candidates {
+		// Penalize link-heavy blocks such as navigation lists
+		score := scores[node] * (1 - linkDensity(candidate))
+		if score > bestScore {
+			bestScore = score
+			best = candidate
+		}
+	}
+
+	// Prefer an explicit <article> that wraps the best block, so headings and
+	// figures next to the densest paragraphs are kept
+	if article := doc.Find("article").First(); article.Length() > 0 && len(strings.TrimSpace(article.Text())) >= minArticleTextLength {
+		if best == nil || containsNode(article, best) {
+			return article
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	if body := doc.Find("body"); body.Length() > 0 {
+		return body
+	}
+	return nil
+}
+
+// containsNode reports whether outer contains or is the same node as inner
+func containsNode(outer *goquery.Selection, inner *goquery.Selection) bool {
+	innerNode := inner.Get(0)
+	if outer.Get(0) == innerNode {
+		return true
+	}
+	return outer.Find("*").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Get(0) == innerNode
+	}).Length() > 0
+}
+
+// classWeight scores an element by its class and id names
+func classWeight(s *goquery.Selection) float64 {
+	weight := 0.0
+	for _, attr := range []string{"class", "id"} {
+		value, ok := s.Attr(attr)
+		if !ok || value == "" {
+			continue
+		}
+		if negativeClassPattern.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClassPattern.MatchString(value) {
+			weight += 25
+		}
+	}
+
+	switch goquery.NodeName(s) {
+	case "article", "main":
+		weight += 10
+	case "div":
+		weight += 5
+	case "ol", "ul", "dl", "form":
+		weight -= 3
+	}
+
+	return weight
+}
+
+// linkDensity is the share of an element's text that sits inside links
+func linkDensity(s *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(s.Text()))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(a.Text()))
+	})
+
+	return float64(linkLength) / float64(textLength)
+}
+
+func isHidden(s *goquery.Selection) bool {
+	if _, ok := s.Attr("hidden"); ok {
+		return true
+	}
+	if hidden, _ := s.Attr("aria-hidden"); hidden == "true" {
+		return true
+	}
+	style, _ := s.Attr("style")
+	style = strings.ReplaceAll(strings.ToLower(style), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// cleanArticleNode strips boilerplate blocks and unsafe attributes from the
+// extracted content and resolves relative links against the page URL
+func cleanArticleNode(content *goquery.Selection, base *url.URL) {
+	content.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if classWeight(s) < 0 && linkDensity(s) > 0.3 {
+			s.Remove()
+		}
+	})
+
+	content.Find("*").AddSelection(content).Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+
+		// Lazy-loaded images keep the real source in a data attribute
+		if node.Data == "img" {
+			for _, attr := range []string{"data-src", "data-original", "data-lazy-src"} {
+				if value, ok := s.Attr(attr); ok && value != "" {
+					s.SetAttr("src", value)
+					break
+				}
+			}
+		}
+
+		attrs := node.Attr[:0]
+		for _, attr := range node.Attr {
+			if !allowedAttributes[attr.Key] {
+				continue
+			}
+			if base != nil && (attr.Key == "href" || attr.Key == "src") {
+				if strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:") {
+					continue
+				}
+				attr.Val = absoluteURL(base, attr.Val)
+			}
+			attrs = append(attrs, attr)
+		}
+		node.Attr = attrs
+	})
+
+	// Drop elements left empty by the cleanup
+	content.Find("div, span, p, section").Each(func(_ int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) == "" && s.Find("img, video, picture").Length() == 0 {
+			s.Remove()
+		}
+	})
+}
+
+func extractArticleTitle(doc *goquery.Document) string {
+	if title := metaContent(doc, "og:title", "twitter:title"); title != "" {
+		return title
+	}
+	if h1 := strings.TrimSpace(doc.Find("h1").First().Text()); h1 != "" {
+		return h1
+	}
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+// extractLanguage reads the page language from the html lang attribute or
+// meta tags and returns the primary language subtag, e.g. "en"
+func extractLanguage(doc *goquery.Document) string {
+	candidates := []string{doc.Find("html").AttrOr("lang", "")}
+	doc.Find("meta[http-equiv]").Each(func(_ int, s *goquery.Selection) {
+		if strings.EqualFold(s.AttrOr("http-equiv", ""), "content-language") {
+			candidates = append(candidates, s.AttrOr("content", ""))
+		}
+	})
+	candidates = append(candidates, metaContent(doc, "og:locale", "language"))
+
+	for _, candidate := range candidates {
+		if match := languagePattern.FindString(strings.TrimSpace(candidate)); match != "" {
+			return strings.ToLower(match)
+		}
+	}
+	return ""
+}
+
+// metaContent returns the content of the first matching meta property or name
+func metaContent(doc *goquery.Document, names ...string) string {
+	for _, name := range names {
+		for _, attr := range []string{"property", "name"} {
+			selector := fmt.Sprintf(`meta[%s="%s"]`, attr, name)
+			if content := strings.TrimSpace(doc.Find(selector).AttrOr("content", "")); content != "" {
+				return content
+			}
+		}
+	}
+	return ""
+}
+
+// blockText returns the text of a selection like Text, but keeps the words of
+// adjacent block elements apart
+func blockText(s *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.P, atom.Div, atom.Br, atom.Li, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+				atom.Blockquote, atom.Pre, atom.Tr, atom.Td, atom.Th, atom.Figcaption, atom.Section, atom.Article:
+				b.WriteByte('\n')
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range s.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
+
+func absoluteURL(base *url.URL, ref string) string {
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(refURL).String()
+}
+
+// truncateHTML cuts HTML to roughly maxLength bytes and re-renders it so the
+// result is well formed
+func truncateHTML(content string, maxLength int) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content[:maxLength]))
+	if err != nil {
+		return content[:maxLength]
+	}
+	truncated, err := doc.Find("body").Html()
+	if err != nil {
+		return content[:maxLength]
+	}
+	return truncated
+}
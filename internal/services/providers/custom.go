@@ -0,0 +1,369 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	CustomFormatHTML = "html"
+	CustomFormatJSON = "json"
+
+	customPreviewLimit = 5
+)
+
+// Date layouts tried in order when no date_format is configured
+var customDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"02/01/2006",
+}
+
+// CustomProvider implements the FeedSourceProvider interface for sites without
+// a feed by scraping items out of HTML (CSS selectors) or JSON (JSONPath) pages
+type CustomProvider struct {
+	client *http.Client
+}
+
+// customConfig is the parsed provider configuration
+type customConfig struct {
+	URL          string
+	Format       string
+	ItemSelector string
+	Fields       map[string]string
+	DateFormat   string
+	Limit        int
+}
+
+func NewCustomProvider() services.FeedSourceProvider {
+	return &CustomProvider{
+		client: NewHTTPClient(30 * time.Second),
+	}
+}
+
+// GetProviderType returns the type of feed source
+func (p *CustomProvider) GetProviderType() string {
+	return models.SourceTypeCustom
+}
+
+// FetchItems fetches the configured page and extracts items from it
+func (p *CustomProvider) FetchItems(ctx context.Context, config map[string]interface{}, lastFetched time.Time) ([]services.RawFeedItem, error) {
+	cfg, err := parseCustomConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := p.scrape(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pages often only show the day an item was published, so there's no cutoff
+	// by the last fetch: items already stored are skipped by external ID
+	for i := range items {
+		if items[i].PublishedAt.IsZero() {
+			items[i].PublishedAt = time.Now()
+		}
+	}
+
+	logger.LogInfo(fmt.Sprintf("Fetched %d items from custom source %s", len(items), cfg.URL))
+	return items, nil
+}
+
+// Preview scrapes the configured page and returns the first few parsed items
+// so selectors can be tested before the source is saved
+func (p *CustomProvider) Preview(ctx context.Context, config map[string]interface{}) ([]services.RawFeedItem, error) {
+	cfg, err := parseCustomConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Limit == 0 || cfg.Limit > customPreviewLimit {
+		cfg.Limit = customPreviewLimit
+	}
+
+	return p.scrape(ctx, cfg)
+}
+
+func (p *CustomProvider) scrape(ctx context.Context, cfg *customConfig) ([]services.RawFeedItem, error) {
+	body, notModified, err := conditionalGet(ctx, p.client, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if notModified {
+		return nil, nil
+	}
+
+	var items []services.RawFeedItem
+	switch cfg.Format {
+	case CustomFormatJSON:
+		items, err = scrapeJSON(body, cfg)
+	default:
+		items, err = scrapeHTML(body, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Limit > 0 && len(items) > cfg.Limit {
+		items = items[:cfg.Limit]
+	}
+
+	return items, nil
+}
+
+// scrapeHTML extracts items using CSS selectors. Field selectors are relative to
+// the item and may end in "@attr" to read an attribute instead of the text,
+// e.g. "a.title@href". An empty selector before "@" reads the item itself.
+func scrapeHTML(body []byte, cfg *customConfig) ([]services.RawFeedItem, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing HTML: %v", err)
+	}
+
+	items := make([]services.RawFeedItem, 0)
+	doc.Find(cfg.ItemSelector).Each(func(_ int, selection *goquery.Selection) {
+		values := make(map[string]string, len(cfg.Fields))
+		for field, selector := range cfg.Fields {
+			values[field] = selectHTML(selection, selector, field == "content")
+		}
+
+		if item, ok := buildCustomItem(values, cfg); ok {
+			items = append(items, item)
+		}
+	})
+
+	return items, nil
+}
+
+func selectHTML(selection *goquery.Selection, selector string, asHTML bool) string {
+	selector, attr, hasAttr := strings.Cut(selector, "@")
+
+	target := selection
+	if strings.TrimSpace(selector) != "" {
+		target = selection.Find(selector).First()
+	}
+
+	if hasAttr {
+		value, _ := target.Attr(attr)
+		return strings.TrimSpace(value)
+	}
+
+	if asHTML {
+		content, _ := target.Html()
+		return strings.TrimSpace(content)
+	}
+
+	return strings.Join(strings.Fields(target.Text()), " ")
+}
+
+// scrapeJSON extracts items using JSONPath expressions. The item selector points
+// at the list of items and field paths are evaluated relative to each item.
+func scrapeJSON(body []byte, cfg *customConfig) ([]services.RawFeedItem, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("error decoding JSON: %v", err)
+	}
+
+	matches, err := EvaluateJSONPath(data, cfg.ItemSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// "$.items" matches the array itself, "$.items[*]" its elements
+	if len(matches) == 1 {
+		if list, ok := matches[0].([]interface{}); ok {
+			matches = list
+		}
+	}
+
+	items := make([]services.RawFeedItem, 0, len(matches))
+	for _, match := range matches {
+		values := make(map[string]string, len(cfg.Fields))
+		for field, path := range cfg.Fields {
+			values[field] = FirstJSONPathString(match, path)
+		}
+
+		if item, ok := buildCustomItem(values, cfg); ok {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// buildCustomItem maps the extracted field values to a raw feed item
+func buildCustomItem(values map[string]string, cfg *customConfig) (services.RawFeedItem, bool) {
+	title := values["title"]
+	if title == "" {
+		return services.RawFeedItem{}, false
+	}
+
+	link := resolveURL(cfg.URL, values["link"])
+
+	// Prefer an explicit ID, then the link, then a hash of the title
+	externalID := values["id"]
+	if externalID == "" && link != cfg.URL {
+		externalID = link
+	}
+	if externalID == "" {
+		hash := sha1.Sum([]byte(cfg.URL + "|" + title))
+		externalID = hex.EncodeToString(hash[:])
+	}
+
+	item := services.RawFeedItem{
+		ExternalID: externalID,
+		Title:      title,
+		Content:    values["content"],
+		URL:        link,
+		Author:     values["author"],
+		Tags:       []string{"custom"},
+		Metadata: map[string]interface{}{
+			"source_url": cfg.URL,
+		},
+	}
+
+	if rawDate := values["date"]; rawDate != "" {
+		if publishedAt, ok := parseCustomDate(rawDate, cfg.DateFormat); ok {
+			item.PublishedAt = publishedAt
+		} else {
+			item.Metadata["raw_date"] = rawDate
+		}
+	}
+
+	return item, true
+}
+
+func parseCustomDate(value string, layout string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	if layout != "" {
+		t, err := time.Parse(layout, value)
+		return t, err == nil
+	}
+
+	for _, layout := range customDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func resolveURL(base string, ref string) string {
+	if ref == "" {
+		return base
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return baseURL.ResolveReference(refURL).String()
+}
+
+func parseCustomConfig(config map[string]interface{}) (*customConfig, error) {
+	cfg := &customConfig{
+		Fields: make(map[string]string),
+	}
+
+	cfg.URL, _ = config["url"].(string)
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	if parsed, err := url.Parse(cfg.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid URL: %s", cfg.URL)
+	}
+
+	cfg.Format, _ = config["format"].(string)
+	if cfg.Format == "" {
+		cfg.Format = CustomFormatHTML
+	}
+	if cfg.Format != CustomFormatHTML && cfg.Format != CustomFormatJSON {
+		return nil, fmt.Errorf("invalid format: %s", cfg.Format)
+	}
+
+	cfg.ItemSelector, _ = config["item_selector"].(string)
+	if cfg.ItemSelector == "" {
+		return nil, fmt.Errorf("item_selector is required")
+	}
+
+	if fields, ok := config["fields"].(map[string]interface{}); ok {
+		for field, selector := range fields {
+			if value, ok := selector.(string); ok && value != "" {
+				cfg.Fields[field] = value
+			}
+		}
+	}
+	if cfg.Fields["title"] == "" {
+		return nil, fmt.Errorf("a title selector is required")
+	}
+
+	cfg.DateFormat, _ = config["date_format"].(string)
+
+	if limit, ok := config["limit"].(float64); ok {
+		cfg.Limit = int(limit)
+	}
+
+	return cfg, nil
+}
+
+// Validate validates the source configuration
+func (p *CustomProvider) Validate(config map[string]interface{}) error {
+	cfg, err := parseCustomConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format == CustomFormatJSON {
+		for _, path := range append([]string{cfg.ItemSelector}, mapValues(cfg.Fields)...) {
+			if _, err := parseJSONPath(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if limit, ok := config["limit"].(float64); ok {
+		if limit < 1 || limit > 100 {
+			return fmt.Errorf("limit must be between 1 and 100")
+		}
+	}
+
+	return nil
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
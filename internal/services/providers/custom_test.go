@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCustomFetchItemsDateOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<ul>
+			<li><a href="/posts/morning">Morning post</a><time>2025-01-06</time></li>
+			<li><a href="/posts/evening">Evening post</a><time>2025-01-06</time></li>
+			<li><a href="/posts/undated">Undated post</a></li>
+		</ul>`))
+	}))
+	defer server.Close()
+
+	p := &CustomProvider{client: NewHTTPClient(5 * time.Second)}
+	config := map[string]interface{}{
+		"url":           server.URL,
+		"item_selector": "li",
+		"fields": map[string]interface{}{
+			"title": "a",
+			"link":  "a@href",
+			"date":  "time",
+		},
+	}
+
+	// Fetched last at noon, the evening post still parses to midnight of the
+	// same day and must not be dropped
+	items, err := p.FetchItems(context.Background(), config, time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("FetchItems: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	if items[1].ExternalID != server.URL+"/posts/evening" {
+		t.Errorf("got external ID %s", items[1].ExternalID)
+	}
+	if !items[1].PublishedAt.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got published at %s", items[1].PublishedAt)
+	}
+	if items[2].PublishedAt.IsZero() {
+		t.Error("undated item has no published time")
+	}
+}
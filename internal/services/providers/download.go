@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrFileTooLarge is returned by DownloadFile when a file exceeds the size
+// limit it was given
+var ErrFileTooLarge = errors.New("file exceeds the size limit")
+
+// downloadClient fetches large media such as podcast episodes through the
+// shared polite transport. Requests have no timeout of their own, callers
+// bound downloads with their context.
+var downloadClient = &http.Client{
+	Transport:     sharedTransport,
+	CheckRedirect: checkPublicRedirect,
+}
+
+// DownloadFile streams a URL into w, failing with ErrFileTooLarge once more
+// than maxBytes were received. It returns the content type and the number of
+// bytes written. URLs come from feeds, so only public addresses are fetched,
+// as the file is stored where the user can read it back.
+func DownloadFile(ctx context.Context, url string, w io.Writer, maxBytes int64) (string, int64, error) {
+	ctx = withBodyLimit(WithPublicAddressesOnly(ctx), maxBytes+1)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("error fetching URL: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return "", 0, ErrFileTooLarge
+	}
+
+	n, err := io.Copy(w, io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", n, fmt.Errorf("error reading response body: %v", err)
+	}
+	if n > maxBytes {
+		return "", n, ErrFileTooLarge
+	}
+
+	return resp.Header.Get("Content-Type"), n, nil
+}
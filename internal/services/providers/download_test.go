@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDownloadFileRefusesInternalAddress(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	_, _, err := DownloadFile(context.Background(), server.URL+"/episode.mp3", &buf, 1024)
+	if err == nil || !strings.Contains(err.Error(), ErrNonPublicAddress.Error()) {
+		t.Fatalf("got %v, want the internal address to be refused", err)
+	}
+	if hits != 0 || buf.Len() != 0 {
+		t.Errorf("the internal server got %d requests and %d bytes were written", hits, buf.Len())
+	}
+}
+
+func TestCheckPublicRedirect(t *testing.T) {
+	publicCtx := WithPublicAddressesOnly(context.Background())
+
+	tests := []struct {
+		target  string
+		ctx     context.Context
+		allowed bool
+	}{
+		{"https://cdn.example.com/episode.mp3", publicCtx, true},
+		{"http://93.184.216.34/episode.mp3", publicCtx, true},
+		{"http://169.254.169.254/latest/meta-data/", publicCtx, false},
+		{"http://127.0.0.1:8090/api/", publicCtx, false},
+		{"http://[::1]/", publicCtx, false},
+		{"http://localhost:8090/", publicCtx, false},
+		{"file:///etc/passwd", publicCtx, false},
+		{"https://cdn.example.com/episode.mp3", context.Background(), false},
+	}
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.target)
+		req := (&http.Request{URL: target}).WithContext(tt.ctx)
+		err := checkPublicRedirect(req, []*http.Request{{}})
+		if tt.allowed && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.target, err)
+		}
+		if !tt.allowed && err == nil {
+			t.Errorf("%s: redirect allowed", tt.target)
+		}
+	}
+
+	if err := checkPublicRedirect((&http.Request{URL: &url.URL{Scheme: "https", Host: "example.com"}}).WithContext(publicCtx), make([]*http.Request, 10)); err == nil {
+		t.Error("expected an error after 10 redirects")
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), MaxBodySize+1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newPoliteTransport(http.DefaultTransport, http.DefaultTransport, 0)}
+	read := func(ctx context.Context) (int, error) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		return buf.Len(), err
+	}
+
+	if _, err := read(context.Background()); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("got %v, want ErrBodyTooLarge", err)
+	}
+	if n, err := read(withBodyLimit(context.Background(), int64(len(body)))); err != nil || n != len(body) {
+		t.Errorf("got %d bytes and error %v with a larger limit", n, err)
+	}
+}
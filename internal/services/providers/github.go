@@ -0,0 +1,583 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	GithubAPIURL = "https://api.github.com"
+
+	GithubModeReleases = "releases"
+	GithubModeIssues   = "issues"
+	GithubModeCommits  = "commits"
+	GithubModeActivity = "activity"
+	GithubModeStarred  = "starred"
+)
+
+var (
+	githubRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	githubUserPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+// GithubProvider implements the FeedSourceProvider interface for GitHub repositories and users
+type GithubProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+type githubUser struct {
+	Login   string `json:"login"`
+	HTMLURL string `json:"html_url"`
+}
+
+type githubLabel struct {
+	Name string `json:"name"`
+}
+
+type githubRelease struct {
+	ID          int64      `json:"id"`
+	TagName     string     `json:"tag_name"`
+	Name        string     `json:"name"`
+	Body        string     `json:"body"`
+	HTMLURL     string     `json:"html_url"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	PublishedAt time.Time  `json:"published_at"`
+	Author      githubUser `json:"author"`
+}
+
+type githubIssue struct {
+	ID          int64         `json:"id"`
+	Number      int           `json:"number"`
+	Title       string        `json:"title"`
+	Body        string        `json:"body"`
+	HTMLURL     string        `json:"html_url"`
+	State       string        `json:"state"`
+	Comments    int           `json:"comments"`
+	CreatedAt   time.Time     `json:"created_at"`
+	User        githubUser    `json:"user"`
+	Labels      []githubLabel `json:"labels"`
+	PullRequest *struct{}     `json:"pull_request"`
+}
+
+type githubCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+	Author *githubUser `json:"author"`
+}
+
+type githubEvent struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	CreatedAt time.Time  `json:"created_at"`
+	Actor     githubUser `json:"actor"`
+	Repo      struct {
+		Name string `json:"name"`
+	} `json:"repo"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type githubStar struct {
+	StarredAt time.Time `json:"starred_at"`
+	Repo      struct {
+		ID          int64      `json:"id"`
+		FullName    string     `json:"full_name"`
+		Description string     `json:"description"`
+		HTMLURL     string     `json:"html_url"`
+		Language    string     `json:"language"`
+		Stars       int        `json:"stargazers_count"`
+		Topics      []string   `json:"topics"`
+		Owner       githubUser `json:"owner"`
+	} `json:"repo"`
+}
+
+func NewGithubProvider() services.FeedSourceProvider {
+	return &GithubProvider{
+		client:  NewHTTPClient(30 * time.Second),
+		baseURL: GithubAPIURL,
+	}
+}
+
+// GetProviderType returns the type of feed source
+func (p *GithubProvider) GetProviderType() string {
+	return models.SourceTypeGithub
+}
+
+// FetchItems fetches releases, issues, commits or user activity from GitHub
+func (p *GithubProvider) FetchItems(ctx context.Context, config map[string]interface{}, lastFetched time.Time) ([]services.RawFeedItem, error) {
+	mode, _ := config["mode"].(string)
+	if mode == "" {
+		mode = GithubModeReleases
+	}
+
+	limit := 30
+	if val, ok := config["limit"].(float64); ok {
+		limit = int(val)
+	}
+
+	token, err := p.loadToken(config, services.SourceOwnerFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []services.RawFeedItem
+	switch mode {
+	case GithubModeReleases:
+		items, err = p.fetchReleases(ctx, config, token, limit)
+	case GithubModeIssues:
+		items, err = p.fetchIssues(ctx, config, token, limit)
+	case GithubModeCommits:
+		items, err = p.fetchCommits(ctx, config, token, limit, lastFetched)
+	case GithubModeActivity:
+		items, err = p.fetchActivity(ctx, config, token, limit)
+	case GithubModeStarred:
+		items, err = p.fetchStarred(ctx, config, token, limit)
+	default:
+		return nil, fmt.Errorf("invalid mode: %s", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop items we've already seen in a previous fetch
+	filtered := make([]services.RawFeedItem, 0, len(items))
+	for _, item := range items {
+		if !lastFetched.IsZero() && !item.PublishedAt.After(lastFetched) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Fetched %d items from GitHub (%s)", len(filtered), mode))
+	return filtered, nil
+}
+
+func (p *GithubProvider) fetchReleases(ctx context.Context, config map[string]interface{}, token string, limit int) ([]services.RawFeedItem, error) {
+	repo, _ := config["repo"].(string)
+	includePrereleases, _ := config["include_prereleases"].(bool)
+
+	var releases []githubRelease
+	endpoint := fmt.Sprintf("/repos/%s/releases?per_page=%d", repo, limit)
+	if err := p.get(ctx, endpoint, token, "", &releases); err != nil {
+		return nil, err
+	}
+
+	items := make([]services.RawFeedItem, 0, len(releases))
+	for _, release := range releases {
+		if release.Draft || (release.Prerelease && !includePrereleases) {
+			continue
+		}
+
+		title := release.Name
+		if title == "" {
+			title = release.TagName
+		}
+
+		items = append(items, services.RawFeedItem{
+			ExternalID:  fmt.Sprintf("release-%d", release.ID),
+			Title:       fmt.Sprintf("%s %s", repo, title),
+			Content:     release.Body,
+			URL:         release.HTMLURL,
+			Author:      release.Author.Login,
+			PublishedAt: release.PublishedAt,
+			Tags:        []string{"github", "release"},
+			Metadata: map[string]interface{}{
+				"repo":       repo,
+				"tag":        release.TagName,
+				"author":     release.Author.Login,
+				"prerelease": release.Prerelease,
+				"type":       GithubModeReleases,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func (p *GithubProvider) fetchIssues(ctx context.Context, config map[string]interface{}, token string, limit int) ([]services.RawFeedItem, error) {
+	repo, _ := config["repo"].(string)
+	issueType, _ := config["issue_type"].(string)
+	if issueType == "" {
+		issueType = "all"
+	}
+
+	params := url.Values{}
+	params.Set("state", "open")
+	params.Set("sort", "created")
+	params.Set("direction", "desc")
+	params.Set("per_page", fmt.Sprintf("%d", limit))
+	if labels := stringList(config["labels"]); len(labels) > 0 {
+		params.Set("labels", strings.Join(labels, ","))
+	}
+
+	var issues []githubIssue
+	if err := p.get(ctx, fmt.Sprintf("/repos/%s/issues?%s", repo, params.Encode()), token, "", &issues); err != nil {
+		return nil, err
+	}
+
+	items := make([]services.RawFeedItem, 0, len(issues))
+	for _, issue := range issues {
+		isPR := issue.PullRequest != nil
+		if (issueType == "issues" && isPR) || (issueType == "pulls" && !isPR) {
+			continue
+		}
+
+		kind := "issue"
+		if isPR {
+			kind = "pull_request"
+		}
+
+		labels := make([]string, 0, len(issue.Labels))
+		for _, label := range issue.Labels {
+			labels = append(labels, label.Name)
+		}
+
+		items = append(items, services.RawFeedItem{
+			ExternalID:  fmt.Sprintf("issue-%d", issue.ID),
+			Title:       fmt.Sprintf("%s#%d: %s", repo, issue.Number, issue.Title),
+			Content:     issue.Body,
+			URL:         issue.HTMLURL,
+			Author:      issue.User.Login,
+			PublishedAt: issue.CreatedAt,
+			Tags:        append([]string{"github", kind}, labels...),
+			Metadata: map[string]interface{}{
+				"repo":          repo,
+				"number":        issue.Number,
+				"author":        issue.User.Login,
+				"labels":        labels,
+				"state":         issue.State,
+				"comment_count": issue.Comments,
+				"type":          kind,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func (p *GithubProvider) fetchCommits(ctx context.Context, config map[string]interface{}, token string, limit int, lastFetched time.Time) ([]services.RawFeedItem, error) {
+	repo, _ := config["repo"].(string)
+	branch, _ := config["branch"].(string)
+
+	params := url.Values{}
+	params.Set("per_page", fmt.Sprintf("%d", limit))
+	if branch != "" {
+		params.Set("sha", branch)
+	}
+	if !lastFetched.IsZero() {
+		params.Set("since", lastFetched.UTC().Format(time.RFC3339))
+	}
+
+	var commits []githubCommit
+	if err := p.get(ctx, fmt.Sprintf("/repos/%s/commits?%s", repo, params.Encode()), token, "", &commits); err != nil {
+		return nil, err
+	}
+
+	items := make([]services.RawFeedItem, 0, len(commits))
+	for _, commit := range commits {
+		author := commit.Commit.Author.Name
+		if commit.Author != nil && commit.Author.Login != "" {
+			author = commit.Author.Login
+		}
+
+		// The first line of the message is the commit subject
+		title, _, _ := strings.Cut(commit.Commit.Message, "\n")
+
+		items = append(items, services.RawFeedItem{
+			ExternalID:  "commit-" + commit.SHA,
+			Title:       fmt.Sprintf("%s: %s", repo, title),
+			Content:     commit.Commit.Message,
+			URL:         commit.HTMLURL,
+			Author:      author,
+			PublishedAt: commit.Commit.Author.Date,
+			Tags:        []string{"github", "commit"},
+			Metadata: map[string]interface{}{
+				"repo":   repo,
+				"sha":    commit.SHA,
+				"branch": branch,
+				"author": author,
+				"type":   GithubModeCommits,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func (p *GithubProvider) fetchActivity(ctx context.Context, config map[string]interface{}, token string, limit int) ([]services.RawFeedItem, error) {
+	user, _ := config["user"].(string)
+
+	var events []githubEvent
+	endpoint := fmt.Sprintf("/users/%s/events/public?per_page=%d", user, limit)
+	if err := p.get(ctx, endpoint, token, "", &events); err != nil {
+		return nil, err
+	}
+
+	items := make([]services.RawFeedItem, 0, len(events))
+	for _, event := range events {
+		title, eventURL := describeGithubEvent(event)
+		if title == "" {
+			continue
+		}
+
+		items = append(items, services.RawFeedItem{
+			ExternalID:  "event-" + event.ID,
+			Title:       title,
+			URL:         eventURL,
+			Author:      event.Actor.Login,
+			PublishedAt: event.CreatedAt,
+			Tags:        []string{"github", "activity"},
+			Metadata: map[string]interface{}{
+				"repo":       event.Repo.Name,
+				"author":     event.Actor.Login,
+				"event_type": event.Type,
+				"type":       GithubModeActivity,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+func (p *GithubProvider) fetchStarred(ctx context.Context, config map[string]interface{}, token string, limit int) ([]services.RawFeedItem, error) {
+	user, _ := config["user"].(string)
+
+	// The star media type adds starred_at to each repository
+	var stars []githubStar
+	endpoint := fmt.Sprintf("/users/%s/starred?per_page=%d&sort=created&direction=desc", user, limit)
+	if err := p.get(ctx, endpoint, token, "application/vnd.github.star+json", &stars); err != nil {
+		return nil, err
+	}
+
+	items := make([]services.RawFeedItem, 0, len(stars))
+	for _, star := range stars {
+		items = append(items, services.RawFeedItem{
+			ExternalID:  fmt.Sprintf("star-%s-%d", user, star.Repo.ID),
+			Title:       fmt.Sprintf("%s starred %s", user, star.Repo.FullName),
+			Content:     star.Repo.Description,
+			URL:         star.Repo.HTMLURL,
+			Author:      star.Repo.Owner.Login,
+			PublishedAt: star.StarredAt,
+			Tags:        append([]string{"github", "starred"}, star.Repo.Topics...),
+			Metadata: map[string]interface{}{
+				"repo":     star.Repo.FullName,
+				"author":   star.Repo.Owner.Login,
+				"language": star.Repo.Language,
+				"stars":    star.Repo.Stars,
+				"labels":   star.Repo.Topics,
+				"type":     GithubModeStarred,
+			},
+		})
+	}
+
+	return items, nil
+}
+
+// describeGithubEvent builds a human readable title and link for a public event
+func describeGithubEvent(event githubEvent) (string, string) {
+	repoURL := "https://github.com/" + event.Repo.Name
+
+	var payload struct {
+		Action  string `json:"action"`
+		RefType string `json:"ref_type"`
+		Ref     string `json:"ref"`
+		Size    int    `json:"size"`
+		Release struct {
+			TagName string `json:"tag_name"`
+			HTMLURL string `json:"html_url"`
+		} `json:"release"`
+		Issue struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+		} `json:"issue"`
+		PullRequest struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+		} `json:"pull_request"`
+	}
+	json.Unmarshal(event.Payload, &payload)
+
+	actor := event.Actor.Login
+	switch event.Type {
+	case "WatchEvent":
+		return fmt.Sprintf("%s starred %s", actor, event.Repo.Name), repoURL
+	case "ForkEvent":
+		return fmt.Sprintf("%s forked %s", actor, event.Repo.Name), repoURL
+	case "CreateEvent":
+		if payload.RefType == "repository" {
+			return fmt.Sprintf("%s created repository %s", actor, event.Repo.Name), repoURL
+		}
+		return fmt.Sprintf("%s created %s %s in %s", actor, payload.RefType, payload.Ref, event.Repo.Name), repoURL
+	case "PushEvent":
+		return fmt.Sprintf("%s pushed %d commits to %s", actor, payload.Size, event.Repo.Name), repoURL
+	case "ReleaseEvent":
+		return fmt.Sprintf("%s released %s of %s", actor, payload.Release.TagName, event.Repo.Name), payload.Release.HTMLURL
+	case "IssuesEvent":
+		return fmt.Sprintf("%s %s issue %s#%d: %s", actor, payload.Action, event.Repo.Name, payload.Issue.Number, payload.Issue.Title), payload.Issue.HTMLURL
+	case "PullRequestEvent":
+		return fmt.Sprintf("%s %s pull request %s#%d: %s", actor, payload.Action, event.Repo.Name, payload.PullRequest.Number, payload.PullRequest.Title), payload.PullRequest.HTMLURL
+	case "PublicEvent":
+		return fmt.Sprintf("%s open sourced %s", actor, event.Repo.Name), repoURL
+	}
+
+	return "", ""
+}
+
+// get performs an authenticated GET request against the GitHub API and decodes the JSON response
+func (p *GithubProvider) get(ctx context.Context, endpoint string, token string, accept string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching from GitHub: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			// X-RateLimit-Reset is the unix time the quota refills
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				if state := services.FetchStateFromContext(ctx); state != nil {
+					state.SetRetryAfter(time.Unix(reset, 0))
+				}
+			}
+			return fmt.Errorf("GitHub rate limit exceeded, configure a token to raise it")
+		}
+		return fmt.Errorf("unexpected status code from GitHub: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding GitHub response: %v", err)
+	}
+
+	return nil
+}
+
+// loadToken returns the GitHub access token of the user referenced by
+// token_id, if any
+func (p *GithubProvider) loadToken(config map[string]interface{}, userID string) (string, error) {
+	tokenID, _ := config["token_id"].(string)
+	if tokenID == "" {
+		return "", nil
+	}
+	if userID == "" {
+		return "", fmt.Errorf("token_id can only be used by sources of a user")
+	}
+
+	token, err := query.FindByFilter[*models.Token](map[string]interface{}{
+		"id":       tokenID,
+		"user":     userID,
+		"provider": models.SourceTypeGithub,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find GitHub token with ID %s", tokenID)
+	}
+
+	return token.AccessToken, nil
+}
+
+// Validate validates the source configuration
+func (p *GithubProvider) Validate(config map[string]interface{}) error {
+	return p.ValidateForUser(config, "")
+}
+
+// ValidateForUser validates the configuration of a source of the user, whose
+// GitHub token token_id has to reference
+func (p *GithubProvider) ValidateForUser(config map[string]interface{}, userID string) error {
+	mode, _ := config["mode"].(string)
+	if mode == "" {
+		mode = GithubModeReleases
+	}
+
+	switch mode {
+	case GithubModeReleases, GithubModeIssues, GithubModeCommits:
+		repo, _ := config["repo"].(string)
+		if !githubRepoPattern.MatchString(repo) {
+			return fmt.Errorf("repo must be in the form owner/name")
+		}
+	case GithubModeActivity, GithubModeStarred:
+		user, _ := config["user"].(string)
+		if !githubUserPattern.MatchString(user) {
+			return fmt.Errorf("a valid GitHub user is required")
+		}
+	default:
+		return fmt.Errorf("invalid mode: %s", mode)
+	}
+
+	if issueType, ok := config["issue_type"].(string); ok && issueType != "" {
+		switch issueType {
+		case "all", "issues", "pulls":
+			// Valid issue types
+		default:
+			return fmt.Errorf("invalid issue type: %s", issueType)
+		}
+	}
+
+	if limit, ok := config["limit"].(float64); ok {
+		if limit < 1 || limit > 100 {
+			return fmt.Errorf("limit must be between 1 and 100")
+		}
+	}
+
+	if _, err := p.loadToken(config, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// stringList converts a JSON array or comma separated string config value to a string slice
+func stringList(value interface{}) []string {
+	var result []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				result = append(result, strings.TrimSpace(s))
+			}
+		}
+	case []string:
+		result = v
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if strings.TrimSpace(s) != "" {
+				result = append(result, strings.TrimSpace(s))
+			}
+		}
+	}
+	return result
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shashank-sharma/backend/internal/logger"
@@ -18,14 +21,32 @@ const (
 	HNTopStoriesURL  = "https://hacker-news.firebaseio.com/v0/topstories.json"
 	HNNewStoriesURL  = "https://hacker-news.firebaseio.com/v0/newstories.json"
 	HNBestStoriesURL = "https://hacker-news.firebaseio.com/v0/beststories.json"
+	HNAskStoriesURL  = "https://hacker-news.firebaseio.com/v0/askstories.json"
+	HNShowStoriesURL = "https://hacker-news.firebaseio.com/v0/showstories.json"
+	HNJobStoriesURL  = "https://hacker-news.firebaseio.com/v0/jobstories.json"
 	MaxConcurrentFetches = 5
+
+	// Bounds of the comment threads stored with a story
+	maxHNCommentLimit = 50
+	maxHNCommentDepth = 3
+	// Replies kept per comment below the top level
+	hnRepliesPerComment = 3
 )
 
+// hnListURLs maps the supported feed types to their story lists
+var hnListURLs = map[string]string{
+	"top":  HNTopStoriesURL,
+	"new":  HNNewStoriesURL,
+	"best": HNBestStoriesURL,
+	"ask":  HNAskStoriesURL,
+	"show": HNShowStoriesURL,
+	"job":  HNJobStoriesURL,
+}
+
 // HackerNewsProvider implements the FeedSourceProvider interface for Hacker News
 type HackerNewsProvider struct {
 	client         *http.Client
 	contentFetcher *HTMLContentFetcher
-	fetchContent   bool
 }
 
 type HNItem struct {
@@ -39,17 +60,28 @@ type HNItem struct {
 	Score       int    `json:"score"`
 	Kids        []int  `json:"kids"`
 	Descendants int    `json:"descendants"`
+	Deleted     bool   `json:"deleted"`
+	Dead        bool   `json:"dead"`
+}
+
+// HNComment is a comment stored in the metadata of a story
+type HNComment struct {
+	ID      int         `json:"id"`
+	Author  string      `json:"author"`
+	Text    string      `json:"text"`
+	Time    int64       `json:"time"`
+	Replies []HNComment `json:"replies,omitempty"`
 }
 
 func NewHackerNewsProvider() services.FeedSourceProvider {
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	
+	// The Firebase API is built for bulk item requests
+	SetHostRate("hacker-news.firebaseio.com", 0)
+
+	httpClient := NewHTTPClient(30 * time.Second)
+
 	return &HackerNewsProvider{
 		client:         httpClient,
 		contentFetcher: NewHTMLContentFetcher(60 * time.Second), // Longer timeout for content fetching
-		fetchContent:   true, // Enable content fetching by default
 	}
 }
 
@@ -60,7 +92,7 @@ func (p *HackerNewsProvider) GetProviderType() string {
 
 // FetchItems fetches items from Hacker News
 func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]interface{}, lastFetched time.Time) ([]services.RawFeedItem, error) {
-	// Get feed type (top, new, best)
+	// Get feed type (top, new, best, ask, show, job)
 	feedType, ok := config["feed_type"].(string)
 	if !ok || feedType == "" {
 		feedType = "top" // Default to top stories
@@ -71,20 +103,27 @@ func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]i
 		limit = int(limitVal)
 	}
 	
-	if fetchContent, ok := config["fetch_content"].(bool); ok {
-		p.fetchContent = fetchContent
+	// Enable content fetching by default
+	fetchContent := true
+	if val, ok := config["fetch_content"].(bool); ok {
+		fetchContent = val
 	}
 
+	commentLimit := 0
+	if val, ok := config["comment_limit"].(float64); ok {
+		commentLimit = min(max(int(val), 0), maxHNCommentLimit)
+	}
+	commentDepth := 1
+	if val, ok := config["comment_depth"].(float64); ok {
+		commentDepth = min(max(int(val), 1), maxHNCommentDepth)
+	}
+
+	alert := newKeywordMatcher(stringList(config["alert_keywords"]))
+	alertOnly, _ := config["alert_only"].(bool)
+
 	// Get the story IDs URL based on feed type
-	var storiesURL string
-	switch feedType {
-	case "top":
-		storiesURL = HNTopStoriesURL
-	case "new":
-		storiesURL = HNNewStoriesURL
-	case "best":
-		storiesURL = HNBestStoriesURL
-	default:
+	storiesURL, ok := hnListURLs[feedType]
+	if !ok {
 		return nil, fmt.Errorf("invalid feed type: %s", feedType)
 	}
 
@@ -161,8 +200,13 @@ func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]i
 				return
 			}
 
-			// Only include stories, not comments
-			if item.Type != "story" || item.Title == "" {
+			// Only include stories and job posts, not comments or polls
+			if (item.Type != "story" && item.Type != "job") || item.Title == "" || item.Deleted || item.Dead {
+				return
+			}
+
+			matches := alert.Match(item.Title + "\n" + item.Text)
+			if alertOnly && len(matches) == 0 {
 				return
 			}
 			
@@ -171,7 +215,7 @@ func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]i
 			
 			// If no content but there's a URL and content fetching is enabled, try to fetch content
 			// TODO: Support content fetch for PDF/other file formats
-			if content == "" && item.URL != "" && p.fetchContent {
+			if content == "" && item.URL != "" && fetchContent {
 				fetchedContent, err := p.contentFetcher.FetchContent(ctx, item.URL)
 				if err != nil {
 					logger.LogError(fmt.Sprintf("Error fetching content for %s: %v", item.URL, err))
@@ -181,6 +225,20 @@ func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]i
 				}
 			}
 
+			metadata := map[string]interface{}{
+				"score":         item.Score,
+				"comment_count": item.Descendants,
+				"type":          item.Type,
+				"hn_url":        fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.ID),
+				"content_fetched": content != "" && content != item.Text,
+			}
+			if commentLimit > 0 && len(item.Kids) > 0 {
+				metadata["comments"] = p.fetchComments(ctx, item.Kids, commentLimit, commentDepth)
+			}
+			if len(matches) > 0 {
+				metadata["alert_matches"] = matches
+			}
+
 			// Create feed item
 			feedItem := services.RawFeedItem{
 				ExternalID:  strconv.Itoa(item.ID),
@@ -190,13 +248,7 @@ func (p *HackerNewsProvider) FetchItems(ctx context.Context, config map[string]i
 				Author:      item.By,
 				PublishedAt: itemTime,
 				Tags:        []string{"hackernews", feedType},
-				Metadata: map[string]interface{}{
-					"score":         item.Score,
-					"comment_count": item.Descendants,
-					"type":          item.Type,
-					"hn_url":        fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.ID),
-					"content_fetched": content != "" && content != item.Text,
-				},
+				Metadata:    metadata,
 			}
 
 			select {
@@ -249,13 +301,88 @@ func (p *HackerNewsProvider) fetchItem(ctx context.Context, id int) (*HNItem, er
 	return &item, nil
 }
 
+// fetchComments fetches the first limit live comments among ids, in the order
+// Hacker News ranks them, with their first replies down to depth levels
+func (p *HackerNewsProvider) fetchComments(ctx context.Context, ids []int, limit int, depth int) []HNComment {
+	comments := make([]HNComment, 0, limit)
+	for _, id := range ids {
+		if len(comments) >= limit || ctx.Err() != nil {
+			break
+		}
+
+		item, err := p.fetchItem(ctx, id)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error fetching Hacker News comment %d: %v", id, err))
+			continue
+		}
+		if item.Type != "comment" || item.Deleted || item.Dead {
+			continue
+		}
+
+		comment := HNComment{
+			ID:     item.ID,
+			Author: item.By,
+			Text:   item.Text,
+			Time:   item.Time,
+		}
+		if depth > 1 && len(item.Kids) > 0 {
+			comment.Replies = p.fetchComments(ctx, item.Kids, hnRepliesPerComment, depth-1)
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments
+}
+
+// FetchItemStats fetches the current score and comment count of items so
+// their trajectory can be tracked after they were stored
+func (p *HackerNewsProvider) FetchItemStats(ctx context.Context, externalIDs []string) (map[string]services.ItemStats, error) {
+	stats := make(map[string]services.ItemStats, len(externalIDs))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, MaxConcurrentFetches)
+
+	for _, externalID := range externalIDs {
+		id, err := strconv.Atoi(externalID)
+		if err != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(externalID string, id int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			item, err := p.fetchItem(ctx, id)
+			if err != nil {
+				logger.LogError(fmt.Sprintf("Error fetching Hacker News item %d: %v", id, err))
+				return
+			}
+			if item.ID == 0 || item.Deleted || item.Dead {
+				return
+			}
+
+			mu.Lock()
+			stats[externalID] = services.ItemStats{Score: item.Score, Comments: item.Descendants}
+			mu.Unlock()
+		}(externalID, id)
+	}
+	wg.Wait()
+
+	return stats, ctx.Err()
+}
+
 // Validate validates the source configuration
 func (p *HackerNewsProvider) Validate(config map[string]interface{}) error {
 	if feedType, ok := config["feed_type"].(string); ok {
-		switch feedType {
-		case "top", "new", "best":
-			// Valid feed types
-		default:
+		if _, ok := hnListURLs[feedType]; !ok {
 			return fmt.Errorf("invalid feed type: %s", feedType)
 		}
 	}
@@ -270,5 +397,58 @@ func (p *HackerNewsProvider) Validate(config map[string]interface{}) error {
 		_ = fetchContent
 	}
 
+	if limit, ok := config["comment_limit"].(float64); ok {
+		if limit < 0 || limit > maxHNCommentLimit {
+			return fmt.Errorf("comment_limit must be between 0 and %d", maxHNCommentLimit)
+		}
+	}
+	if depth, ok := config["comment_depth"].(float64); ok {
+		if depth < 1 || depth > maxHNCommentDepth {
+			return fmt.Errorf("comment_depth must be between 1 and %d", maxHNCommentDepth)
+		}
+	}
+
+	if keywords, ok := config["alert_keywords"]; ok {
+		switch keywords.(type) {
+		case []interface{}, string:
+		default:
+			return fmt.Errorf("alert_keywords must be a list or a comma separated string")
+		}
+	}
+	if alertOnly, _ := config["alert_only"].(bool); alertOnly && len(stringList(config["alert_keywords"])) == 0 {
+		return fmt.Errorf("alert_only requires alert_keywords")
+	}
+
 	return nil
 }
+
+// keywordMatcher finds watched terms in text. Terms match whole words or
+// phrases, case insensitively.
+type keywordMatcher struct {
+	terms    []string
+	patterns []*regexp.Regexp
+}
+
+func newKeywordMatcher(terms []string) *keywordMatcher {
+	matcher := &keywordMatcher{}
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		matcher.terms = append(matcher.terms, term)
+		matcher.patterns = append(matcher.patterns, regexp.MustCompile(`(?i)(^|\W)`+regexp.QuoteMeta(term)+`($|\W)`))
+	}
+	return matcher
+}
+
+// Match returns the terms found in text
+func (m *keywordMatcher) Match(text string) []string {
+	var matches []string
+	for i, pattern := range m.patterns {
+		if pattern.MatchString(text) {
+			matches = append(matches, m.terms[i])
+		}
+	}
+	return matches
+}
@@ -25,9 +25,7 @@ type HTMLContentFetcher struct {
 
 func NewHTMLContentFetcher(timeout time.Duration) *HTMLContentFetcher {
 	return &HTMLContentFetcher{
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client: NewHTTPClient(timeout),
 	}
 }
 
@@ -37,42 +35,75 @@ func (f *HTMLContentFetcher) FetchContent(ctx context.Context, url string) (stri
 		return "", nil
 	}
 
-	// Create a request with the given context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	body, err := f.fetchBody(ctx, url)
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+		return "", err
+	}
+
+	// Extract content
+	content, err := ExtractMainContent(body)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error extracting content from %s: %v", url, err))
+		// Return partial content if possible
+		if len(content) > 0 {
+			return content, nil
+		}
+		return "", err
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Anonymous/1.0)")
+	return content, nil
+}
+
+// FetchResource downloads a file such as an image and returns it with its
+// content type. The body is limited to MaxBodySize.
+func (f *HTMLContentFetcher) FetchResource(ctx context.Context, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating request: %v", err)
+	}
 
 	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error fetching URL: %v", err)
+		return nil, "", fmt.Errorf("error fetching URL: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	limitedReader := io.LimitReader(resp.Body, MaxBodySize)
-	body, err := io.ReadAll(limitedReader)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
+		return nil, "", fmt.Errorf("error reading response body: %v", err)
 	}
 
-	// Extract content
-	content, err := ExtractMainContent(body)
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// fetchBody downloads a page, limiting the body to MaxBodySize
+func (f *HTMLContentFetcher) fetchBody(ctx context.Context, url string) ([]byte, error) {
+	// Create a request with the given context
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		logger.LogError(fmt.Sprintf("Error extracting content from %s: %v", url, err))
-		// Return partial content if possible
-		if len(content) > 0 {
-			return content, nil
-		}
-		return "", err
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
-	return content, nil
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching URL: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	return body, nil
 }
 
 // ExtractMainContent extracts the main content from HTML
@@ -0,0 +1,513 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	// UserAgent identifies the feed reader to the sites it fetches from
+	UserAgent = "dashboard-feed-reader/1.0"
+
+	// DefaultRetryAfter is used when a 429 response has no Retry-After header
+	DefaultRetryAfter = 5 * time.Minute
+	maxRetryAfter     = 24 * time.Hour
+
+	defaultHostRequestsPerMin = 60
+	hostBurst                 = 5
+)
+
+// ErrBodyTooLarge is returned when a response body exceeds MaxBodySize
+var ErrBodyTooLarge = fmt.Errorf("response body exceeds %d bytes", MaxBodySize)
+
+// ErrNonPublicAddress is returned for requests of a public only context to
+// loopback, private or link-local addresses
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// sharedTransport is used by every provider so connections, per-host rate
+// limits and Retry-After back-offs are shared across sources
+var sharedTransport = newPoliteTransport(newBaseTransport(nil), newBaseTransport(publicAddressControl), defaultHostRequestsPerMin)
+
+// newBaseTransport creates the transport requests are sent with. Requests of
+// public only contexts get their own transport, which checks every address it
+// dials with control and doesn't use a proxy, whose dials it couldn't check.
+func newBaseTransport(control func(network string, address string, c syscall.RawConn) error) *http.Transport {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   control,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if control != nil {
+		transport.Proxy = nil
+	}
+	return transport
+}
+
+type publicOnlyKey struct{}
+
+// WithPublicAddressesOnly returns a context whose requests may only connect to
+// public addresses. Used when fetching URLs a user entered and reading the
+// response back to them, e.g. when testing a source, so they can't reach
+// services of the server's network.
+func WithPublicAddressesOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, publicOnlyKey{}, true)
+}
+
+func publicAddressesOnly(ctx context.Context) bool {
+	publicOnly, _ := ctx.Value(publicOnlyKey{}).(bool)
+	return publicOnly
+}
+
+// publicAddressControl refuses to connect to loopback, private, link-local and
+// unspecified addresses. It runs on the resolved address of every dial, so
+// neither DNS names nor redirects get around it.
+func publicAddressControl(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// checkPublicRedirect is a CheckRedirect for clients of public only contexts.
+// Redirects are dialed through publicAddressControl like the first request,
+// this refuses what can be told from the URL alone before anything is sent.
+func checkPublicRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	host := req.URL.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	if !publicAddressesOnly(req.Context()) {
+		return fmt.Errorf("redirect to %s lost the public address restriction", host)
+	}
+	return nil
+}
+
+type bodyLimitKey struct{}
+
+// withBodyLimit returns a context whose responses may be read up to limit
+// bytes instead of MaxBodySize, for downloads that check the size themselves
+func withBodyLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, bodyLimitKey{}, limit)
+}
+
+// NewHTTPClient returns a client using the shared polite transport. The
+// timeout covers each request from when its host's rate limit lets it go, so
+// time spent queueing behind other requests to the host doesn't count.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &timeoutTransport{politeTransport: sharedTransport, timeout: timeout},
+	}
+}
+
+// SetHostRequestsPerMinute changes the default per-host request rate
+func SetHostRequestsPerMinute(requestsPerMin int) {
+	sharedTransport.setDefaultRate(requestsPerMin)
+}
+
+// SetHostRate overrides the request rate for a host and its subdomains. A rate
+// of zero disables rate limiting for the host, e.g. for APIs built for bulk access.
+func SetHostRate(host string, requestsPerMin int) {
+	sharedTransport.setHostRate(host, requestsPerMin)
+}
+
+// SetHostRates overrides the request rates of several hosts
+func SetHostRates(requestsPerMin map[string]int) {
+	for host, rate := range requestsPerMin {
+		SetHostRate(host, rate)
+	}
+}
+
+// timeoutTransport sends requests through the polite transport with a
+// timeout that starts once the rate limit wait is over
+type timeoutTransport struct {
+	*politeTransport
+	timeout time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.roundTrip(req, t.timeout)
+}
+
+// politeTransport sets a user agent, rate limits requests per host with a
+// token bucket, caps response bodies and stops calling hosts that answered
+// 429 or 503 until their Retry-After has passed
+type politeTransport struct {
+	base       http.RoundTripper
+	publicBase http.RoundTripper // Used for requests of public only contexts
+
+	mu          sync.Mutex
+	defaultRate float64 // tokens per second
+	hostRates   map[string]float64
+	hosts       map[string]*hostState
+}
+
+type hostState struct {
+	tokens       float64
+	lastRefill   time.Time
+	blockedUntil time.Time
+}
+
+func newPoliteTransport(base http.RoundTripper, publicBase http.RoundTripper, requestsPerMin int) *politeTransport {
+	return &politeTransport{
+		base:        base,
+		publicBase:  publicBase,
+		defaultRate: float64(requestsPerMin) / 60,
+		hostRates:   make(map[string]float64),
+		hosts:       make(map[string]*hostState),
+	}
+}
+
+func (t *politeTransport) setDefaultRate(requestsPerMin int) {
+	if requestsPerMin <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.defaultRate = float64(requestsPerMin) / 60
+}
+
+func (t *politeTransport) setHostRate(host string, requestsPerMin int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hostRates[strings.ToLower(host)] = float64(requestsPerMin) / 60
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.roundTrip(req, 0)
+}
+
+// roundTrip waits for the rate limit of the host and sends the request. A
+// timeout above zero limits the request, including reading the body, from
+// then on.
+func (t *politeTransport) roundTrip(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	state := services.FetchStateFromContext(req.Context())
+
+	wait, blockedUntil := t.reserve(host)
+	if !blockedUntil.IsZero() {
+		if state != nil {
+			state.SetRetryAfter(blockedUntil)
+		}
+		return nil, fmt.Errorf("host %s is rate limited until %s", host, blockedUntil.Format(time.RFC3339))
+	}
+
+	if wait > 0 {
+		// No point queueing for a turn that comes after the caller gave up
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			t.refund(host)
+			return nil, fmt.Errorf("host %s is rate limited for another %s: %w", host, wait.Round(time.Second), context.DeadlineExceeded)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			t.refund(host)
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		req = req.WithContext(ctx)
+	}
+
+	// A RoundTripper must not modify the caller's request
+	userAgent := req.Header.Get("User-Agent")
+	if userAgent == "" || strings.HasPrefix(userAgent, "Go-http-client") || strings.HasPrefix(userAgent, "Gofeed") {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", UserAgent)
+	}
+
+	base := t.base
+	if publicAddressesOnly(req.Context()) {
+		base = t.publicBase
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || (resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "") {
+		retryAfter := time.Now().Add(parseRetryAfter(resp.Header.Get("Retry-After")))
+		t.block(host, retryAfter)
+		if state != nil {
+			state.SetRetryAfter(retryAfter)
+		}
+	}
+
+	limit := int64(MaxBodySize)
+	if bodyLimit, ok := req.Context().Value(bodyLimitKey{}).(int64); ok {
+		limit = bodyLimit
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, cancel: cancel}
+	return resp, nil
+}
+
+// reserve takes a token for the host and returns how long to wait for it, or
+// the time the host is blocked until
+func (t *politeTransport) reserve(host string) (time.Duration, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	state, ok := t.hosts[host]
+	if !ok {
+		state = &hostState{tokens: hostBurst, lastRefill: now}
+		t.hosts[host] = state
+	}
+
+	if now.Before(state.blockedUntil) {
+		return 0, state.blockedUntil
+	}
+
+	rate := t.hostRate(host)
+	if rate <= 0 {
+		return 0, time.Time{}
+	}
+
+	state.tokens += now.Sub(state.lastRefill).Seconds() * rate
+	if state.tokens > hostBurst {
+		state.tokens = hostBurst
+	}
+	state.lastRefill = now
+
+	// Tokens may go negative, which queues later callers behind this one
+	state.tokens--
+	if state.tokens >= 0 {
+		return 0, time.Time{}
+	}
+	return time.Duration(-state.tokens / rate * float64(time.Second)), time.Time{}
+}
+
+// hostRate returns the rate of the host, the override of the host or of its
+// closest parent domain winning over the default. Called with the lock held.
+func (t *politeTransport) hostRate(host string) float64 {
+	for domain := host; domain != ""; {
+		if rate, ok := t.hostRates[domain]; ok {
+			return rate
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return t.defaultRate
+}
+
+// refund gives back the token of a request that gave up waiting for it, so
+// later requests aren't queued behind it
+func (t *politeTransport) refund(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.hosts[host]; ok {
+		state.tokens = min(state.tokens+1, hostBurst)
+	}
+}
+
+func (t *politeTransport) block(host string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.hosts[host]
+	if !ok {
+		state = &hostState{tokens: hostBurst, lastRefill: time.Now()}
+		t.hosts[host] = state
+	}
+	if until.After(state.blockedUntil) {
+		state.blockedUntil = until
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultRetryAfter
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		delay = time.Until(t)
+	} else {
+		return DefaultRetryAfter
+	}
+
+	if delay <= 0 {
+		return time.Second
+	}
+	if delay > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return delay
+}
+
+// limitedBody fails reads once more than its limit of bytes were read, instead
+// of silently truncating the body like io.LimitReader
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	cancel    context.CancelFunc // Ends the timeout of the request, if any
+}
+
+func (b *limitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.cancel != nil {
+		b.cancel()
+	}
+	return err
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Allow a clean EOF exactly at the limit
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// conditionalGet fetches a URL using the cache validators of the fetch state in
+// the context. It returns notModified when the server answered 304 and stores
+// the validators of a fresh response back on the fetch state.
+func conditionalGet(ctx context.Context, client *http.Client, url string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating request: %v", err)
+	}
+
+	state := services.FetchStateFromContext(ctx)
+	if state != nil {
+		etag, lastModified := state.Validators()
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching URL: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		if state != nil {
+			state.SetNotModified()
+		}
+		return nil, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	if state != nil {
+		state.SetValidators(resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+		if hub, self := webSubLinkHeaders(resp.Header.Values("Link")); hub != "" {
+			state.SetWebSub(hub, self)
+		}
+	}
+
+	return body, false, nil
+}
+
+// webSubLinkHeaders returns the hub and self URLs of Link headers such as
+// `<https://hub.example.com/>; rel="hub"`
+func webSubLinkHeaders(headers []string) (hub string, self string) {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.Trim(target, "<>")
+
+			for _, param := range parts[1:] {
+				key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					switch strings.ToLower(rel) {
+					case "hub":
+						if hub == "" {
+							hub = target
+						}
+					case "self":
+						if self == "" {
+							self = target
+						}
+					}
+				}
+			}
+		}
+	}
+	return hub, self
+}
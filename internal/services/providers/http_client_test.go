@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicAddressControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.5:80", false},
+		{"172.16.3.4:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fc00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+	}
+	for _, tt := range tests {
+		err := publicAddressControl("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("%s: got %v, want ErrNonPublicAddress", tt.address, err)
+		}
+	}
+}
+
+func TestPublicAddressesOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(5 * time.Second)
+
+	req, _ := http.NewRequestWithContext(context.Background(), "GET", server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request without restriction: %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ = http.NewRequestWithContext(WithPublicAddressesOnly(context.Background()), "GET", server.URL, nil)
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the loopback address to be refused")
+	} else if !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("got %v, want ErrNonPublicAddress", err)
+	}
+}
+
+func TestRateLimitWaitExcludedFromTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// Ten requests a second, so the last of 8 requests waits 300ms for its
+	// turn, longer than the timeout of every request
+	transport := newPoliteTransport(http.DefaultTransport, http.DefaultTransport, 600)
+	client := &http.Client{Transport: &timeoutTransport{politeTransport: transport, timeout: 150 * time.Millisecond}}
+
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("request failed: %v", err)
+		}
+	}
+}
+
+func TestRateLimitRefund(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport := newPoliteTransport(http.DefaultTransport, http.DefaultTransport, 6)
+	client := &http.Client{Transport: transport}
+	for i := 0; i < hostBurst; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	tokens := func() float64 {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		return transport.hosts["127.0.0.1"].tokens
+	}
+
+	// A deadline before the turn of the request fails right away
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	start := time.Now()
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a deadline error", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Errorf("request waited %s for a turn past its deadline", time.Since(start))
+	}
+	if got := tokens(); got < -0.1 {
+		t.Errorf("token of the failed request not refunded, %f tokens left", got)
+	}
+
+	// So does cancelling while waiting
+	ctx, cancel = context.WithCancel(context.Background())
+	req, _ = http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := client.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want a cancellation error", err)
+	}
+	if got := tokens(); got < -0.1 {
+		t.Errorf("token of the cancelled request not refunded, %f tokens left", got)
+	}
+}
+
+func TestHostRateOverrides(t *testing.T) {
+	transport := newPoliteTransport(http.DefaultTransport, http.DefaultTransport, 60)
+	transport.setHostRate("youtube.com", 120)
+	transport.setHostRate("api.example.com", 0)
+
+	tests := []struct {
+		host string
+		want float64
+	}{
+		{"youtube.com", 2},
+		{"www.youtube.com", 2},
+		{"notyoutube.com", 1},
+		{"api.example.com", 0},
+		{"example.com", 1},
+	}
+	for _, tt := range tests {
+		if got := transport.hostRate(tt.host); got != tt.want {
+			t.Errorf("%s: got %f requests per second, want %f", tt.host, got, tt.want)
+		}
+	}
+}
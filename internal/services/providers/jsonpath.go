@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSegment is a single step of a parsed JSONPath expression
+type jsonPathSegment struct {
+	key      string
+	index    int
+	wildcard bool
+	isIndex  bool
+}
+
+// EvaluateJSONPath evaluates a JSONPath expression against decoded JSON data.
+// It supports the subset needed for scraping: dotted and bracketed member
+// access ($.a.b, $['a']), array indexes ($.a[0]) and wildcards ($.a[*], $.a.*).
+// The leading "$" is optional, so relative paths like "title" work as well.
+func EvaluateJSONPath(data interface{}, path string) ([]interface{}, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []interface{}{data}
+	for _, segment := range segments {
+		next := make([]interface{}, 0, len(current))
+		for _, value := range current {
+			next = append(next, segment.apply(value)...)
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// FirstJSONPathString returns the first match of path formatted as a string
+func FirstJSONPathString(data interface{}, path string) string {
+	values, err := EvaluateJSONPath(data, path)
+	if err != nil || len(values) == 0 || values[0] == nil {
+		return ""
+	}
+
+	switch v := values[0].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func (s jsonPathSegment) apply(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			result := make([]interface{}, 0, len(v))
+			for _, child := range v {
+				result = append(result, child)
+			}
+			return result
+		}
+		if child, ok := v[s.key]; ok && !s.isIndex {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return v
+		}
+		if s.isIndex {
+			index := s.index
+			if index < 0 {
+				index += len(v)
+			}
+			if index >= 0 && index < len(v) {
+				return []interface{}{v[index]}
+			}
+		}
+	}
+	return nil
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	segments := make([]jsonPathSegment, 0)
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			name := path[start:i]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty member name", path)
+			}
+			if name == "*" {
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			} else {
+				segments = append(segments, jsonPathSegment{key: name})
+			}
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed bracket", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "*":
+				segments = append(segments, jsonPathSegment{wildcard: true})
+			case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, "\""):
+				segments = append(segments, jsonPathSegment{key: strings.Trim(inner, "'\"")})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: bad index %q", path, inner)
+				}
+				segments = append(segments, jsonPathSegment{index: index, isIndex: true})
+			}
+		default:
+			// Relative path without a leading dot
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			segments = append(segments, jsonPathSegment{key: path[start:i]})
+		}
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// podcastMetadata adds the audio enclosure and iTunes episode fields of a
+// podcast item to its metadata. Items without an audio enclosure are left
+// untouched.
+func podcastMetadata(feed *gofeed.Feed, item *gofeed.Item, metadata map[string]interface{}) {
+	enclosure, contentType := audioEnclosure(item.Enclosures)
+	if enclosure == nil {
+		return
+	}
+
+	metadata["audio_url"] = enclosure.URL
+	metadata["audio_type"] = contentType
+	if size, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && size > 0 {
+		metadata["audio_size"] = size
+	}
+
+	if ext := item.ITunesExt; ext != nil {
+		if seconds, ok := parseITunesDuration(ext.Duration); ok {
+			metadata["duration"] = seconds
+		}
+		if episode, err := strconv.Atoi(strings.TrimSpace(ext.Episode)); err == nil {
+			metadata["episode"] = episode
+		}
+		if season, err := strconv.Atoi(strings.TrimSpace(ext.Season)); err == nil {
+			metadata["season"] = season
+		}
+		if ext.EpisodeType != "" {
+			metadata["episode_type"] = strings.ToLower(ext.EpisodeType)
+		}
+		switch strings.ToLower(ext.Explicit) {
+		case "yes", "true", "explicit":
+			metadata["explicit"] = true
+		}
+	}
+
+	if artwork := episodeArtwork(feed, item); artwork != "" {
+		metadata["artwork"] = artwork
+	}
+}
+
+// audioEnclosure returns the first audio enclosure and its content type,
+// guessed from the file extension when the feed doesn't give one
+func audioEnclosure(enclosures []*gofeed.Enclosure) (*gofeed.Enclosure, string) {
+	for _, enclosure := range enclosures {
+		if enclosure == nil || enclosure.URL == "" {
+			continue
+		}
+
+		contentType := strings.ToLower(strings.TrimSpace(enclosure.Type))
+		if contentType == "" {
+			if parsed, err := url.Parse(enclosure.URL); err == nil {
+				contentType = mime.TypeByExtension(strings.ToLower(path.Ext(parsed.Path)))
+			}
+		}
+		if strings.HasPrefix(contentType, "audio/") {
+			return enclosure, contentType
+		}
+	}
+	return nil, ""
+}
+
+// episodeArtwork prefers the episode's own image over the show's
+func episodeArtwork(feed *gofeed.Feed, item *gofeed.Item) string {
+	if item.ITunesExt != nil && item.ITunesExt.Image != "" {
+		return item.ITunesExt.Image
+	}
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	if feed.ITunesExt != nil && feed.ITunesExt.Image != "" {
+		return feed.ITunesExt.Image
+	}
+	if feed.Image != nil {
+		return feed.Image.URL
+	}
+	return ""
+}
+
+// parseITunesDuration parses an itunes:duration given in seconds, MM:SS or
+// HH:MM:SS into seconds
+func parseITunesDuration(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return int(total), true
+}
@@ -0,0 +1,285 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	RedditBaseURL = "https://www.reddit.com"
+)
+
+var redditNamePattern = regexp.MustCompile(`^[A-Za-z0-9_+-]+$`)
+
+// RedditProvider implements the FeedSourceProvider interface for Reddit listings
+type RedditProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+type redditListing struct {
+	Data struct {
+		After    string `json:"after"`
+		Children []struct {
+			Kind string     `json:"kind"`
+			Data redditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Title        string  `json:"title"`
+	Author       string  `json:"author"`
+	Subreddit    string  `json:"subreddit"`
+	SelfText     string  `json:"selftext"`
+	SelfTextHTML string  `json:"selftext_html"`
+	URL          string  `json:"url"`
+	Permalink    string  `json:"permalink"`
+	Domain       string  `json:"domain"`
+	Thumbnail    string  `json:"thumbnail"`
+	Flair        string  `json:"link_flair_text"`
+	CreatedUTC   float64 `json:"created_utc"`
+	Score        int     `json:"score"`
+	UpvoteRatio  float64 `json:"upvote_ratio"`
+	NumComments  int     `json:"num_comments"`
+	Over18       bool    `json:"over_18"`
+	IsSelf       bool    `json:"is_self"`
+	Stickied     bool    `json:"stickied"`
+}
+
+func NewRedditProvider() services.FeedSourceProvider {
+	return &RedditProvider{
+		client:  NewHTTPClient(30 * time.Second),
+		baseURL: RedditBaseURL,
+	}
+}
+
+// GetProviderType returns the type of feed source
+func (p *RedditProvider) GetProviderType() string {
+	return models.SourceTypeReddit
+}
+
+// FetchItems fetches posts from a subreddit, multireddit or user listing
+func (p *RedditProvider) FetchItems(ctx context.Context, config map[string]interface{}, lastFetched time.Time) ([]services.RawFeedItem, error) {
+	listingURL, err := p.listingURL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	minScore := 0
+	if val, ok := config["min_score"].(float64); ok {
+		minScore = int(val)
+	}
+	includeNSFW, _ := config["include_nsfw"].(bool)
+
+	logger.LogInfo(fmt.Sprintf("Fetching Reddit listing: %s", listingURL))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", listingURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Reddit listing: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code from Reddit: %d", resp.StatusCode)
+	}
+
+	var listing redditListing
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("error decoding Reddit listing: %v", err)
+	}
+
+	// Posts of hot listings often pass min_score long after they were created,
+	// so there's no cutoff by time: items already stored are skipped by external ID
+	items := parseRedditListing(listing, minScore, includeNSFW)
+
+	logger.LogInfo(fmt.Sprintf("Fetched %d items from Reddit", len(items)))
+	return items, nil
+}
+
+// parseRedditListing converts a Reddit listing into raw feed items, applying the
+// score and NSFW filters
+func parseRedditListing(listing redditListing, minScore int, includeNSFW bool) []services.RawFeedItem {
+	items := make([]services.RawFeedItem, 0, len(listing.Data.Children))
+
+	for _, child := range listing.Data.Children {
+		// t3 is a link/post, user listings can also contain comments (t1)
+		if child.Kind != "t3" {
+			continue
+		}
+		post := child.Data
+
+		if post.Stickied || post.Score < minScore {
+			continue
+		}
+		if post.Over18 && !includeNSFW {
+			continue
+		}
+
+		createdAt := time.Unix(int64(post.CreatedUTC), 0)
+
+		commentsURL := RedditBaseURL + post.Permalink
+		itemURL := post.URL
+		if post.IsSelf || itemURL == "" {
+			itemURL = commentsURL
+		}
+
+		tags := []string{"reddit", strings.ToLower(post.Subreddit)}
+		if post.Flair != "" {
+			tags = append(tags, strings.ToLower(post.Flair))
+		}
+
+		metadata := map[string]interface{}{
+			"subreddit":     post.Subreddit,
+			"score":         post.Score,
+			"upvote_ratio":  post.UpvoteRatio,
+			"comment_count": post.NumComments,
+			"comments_url":  commentsURL,
+			"domain":        post.Domain,
+			"is_self":       post.IsSelf,
+			"nsfw":          post.Over18,
+		}
+		if strings.HasPrefix(post.Thumbnail, "http") {
+			metadata["thumbnail"] = post.Thumbnail
+		}
+		if post.Flair != "" {
+			metadata["flair"] = post.Flair
+		}
+
+		items = append(items, services.RawFeedItem{
+			ExternalID:  post.Name,
+			Title:       html.UnescapeString(post.Title),
+			Content:     redditContent(post),
+			URL:         itemURL,
+			Author:      post.Author,
+			PublishedAt: createdAt,
+			Tags:        tags,
+			Metadata:    metadata,
+		})
+	}
+
+	return items
+}
+
+// redditContent returns the self-text of a post, or a link to the submitted URL
+func redditContent(post redditPost) string {
+	if post.IsSelf {
+		// selftext_html is entity-escaped HTML
+		if post.SelfTextHTML != "" {
+			return html.UnescapeString(post.SelfTextHTML)
+		}
+		return post.SelfText
+	}
+
+	return fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(post.URL), html.EscapeString(post.Domain))
+}
+
+// listingURL builds the JSON listing URL for the configured subreddit, multireddit or user
+func (p *RedditProvider) listingURL(config map[string]interface{}) (string, error) {
+	sort, _ := config["sort"].(string)
+	if sort == "" {
+		sort = "hot"
+	}
+
+	limit := 25
+	if val, ok := config["limit"].(float64); ok {
+		limit = int(val)
+	}
+
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	if sort == "top" {
+		timeWindow, _ := config["time"].(string)
+		if timeWindow == "" {
+			timeWindow = "day"
+		}
+		params.Set("t", timeWindow)
+	}
+
+	var path string
+	if subreddit, ok := config["subreddit"].(string); ok && subreddit != "" {
+		path = fmt.Sprintf("/r/%s/%s.json", strings.TrimPrefix(subreddit, "r/"), sort)
+	} else if multireddit, ok := config["multireddit"].(string); ok && multireddit != "" {
+		// Multireddits are configured as "username/multiname"
+		parts := strings.SplitN(multireddit, "/", 2)
+		if len(parts) != 2 {
+			return "", fmt.Errorf("multireddit must be in the form username/name")
+		}
+		path = fmt.Sprintf("/user/%s/m/%s/%s.json", parts[0], parts[1], sort)
+	} else if user, ok := config["user"].(string); ok && user != "" {
+		path = fmt.Sprintf("/user/%s/submitted.json", strings.TrimPrefix(user, "u/"))
+		params.Set("sort", sort)
+	} else {
+		return "", fmt.Errorf("one of subreddit, multireddit or user is required")
+	}
+
+	return p.baseURL + path + "?" + params.Encode(), nil
+}
+
+// Validate validates the source configuration
+func (p *RedditProvider) Validate(config map[string]interface{}) error {
+	targets := 0
+	for _, key := range []string{"subreddit", "multireddit", "user"} {
+		value, ok := config[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		targets++
+
+		for _, part := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(value, "r/"), "u/"), "/") {
+			if !redditNamePattern.MatchString(part) {
+				return fmt.Errorf("invalid %s: %s", key, value)
+			}
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("exactly one of subreddit, multireddit or user is required")
+	}
+
+	if sort, ok := config["sort"].(string); ok && sort != "" {
+		switch sort {
+		case "hot", "new", "top", "rising":
+			// Valid sort orders
+		default:
+			return fmt.Errorf("invalid sort: %s", sort)
+		}
+	}
+
+	if timeWindow, ok := config["time"].(string); ok && timeWindow != "" {
+		switch timeWindow {
+		case "hour", "day", "week", "month", "year", "all":
+			// Valid time windows
+		default:
+			return fmt.Errorf("invalid time window: %s", timeWindow)
+		}
+	}
+
+	if limit, ok := config["limit"].(float64); ok {
+		if limit < 1 || limit > 100 {
+			return fmt.Errorf("limit must be between 1 and 100")
+		}
+	}
+
+	if _, err := p.listingURL(config); err != nil {
+		return err
+	}
+
+	return nil
+}
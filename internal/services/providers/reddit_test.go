@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadRedditFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func TestParseRedditListing(t *testing.T) {
+	var listing redditListing
+	if err := json.Unmarshal(loadRedditFixture(t, "reddit_hot.json"), &listing); err != nil {
+		t.Fatalf("decoding fixture: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		minScore    int
+		includeNSFW bool
+		want        []string
+	}{
+		{"defaults", 0, false, []string{"t3_1c0a111", "t3_1c0b222", "t3_1c0c333"}},
+		{"min score", 10, false, []string{"t3_1c0a111", "t3_1c0b222"}},
+		{"nsfw", 10, true, []string{"t3_1c0a111", "t3_1c0b222", "t3_1c0d444"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := parseRedditListing(listing, tt.minScore, tt.includeNSFW)
+			if len(items) != len(tt.want) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.want))
+			}
+			for i, item := range items {
+				if item.ExternalID != tt.want[i] {
+					t.Errorf("item %d: got external ID %s, want %s", i, item.ExternalID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	items := parseRedditListing(listing, 10, false)
+
+	link := items[0]
+	if link.Title != "Go 1.22 range over func & iterators explained" {
+		t.Errorf("link title: got %q", link.Title)
+	}
+	if link.URL != "https://go.dev/blog/range-functions" {
+		t.Errorf("link URL: got %q", link.URL)
+	}
+	if link.Content != `<p><a href="https://go.dev/blog/range-functions">go.dev</a></p>` {
+		t.Errorf("link content: got %q", link.Content)
+	}
+	if !link.PublishedAt.Equal(time.Unix(1712300000, 0)) {
+		t.Errorf("link published at: got %s", link.PublishedAt)
+	}
+	if link.Metadata["comments_url"] != "https://www.reddit.com/r/golang/comments/1c0a111/go_122_range_over_func_iterators_explained/" {
+		t.Errorf("link comments URL: got %v", link.Metadata["comments_url"])
+	}
+	if link.Metadata["thumbnail"] != "https://b.thumbs.redditmedia.com/abc.jpg" || link.Metadata["flair"] != "Discussion" {
+		t.Errorf("link metadata: got %v", link.Metadata)
+	}
+	if len(link.Tags) != 3 || link.Tags[1] != "golang" || link.Tags[2] != "discussion" {
+		t.Errorf("link tags: got %v", link.Tags)
+	}
+
+	self := items[1]
+	if self.URL != "https://www.reddit.com/r/golang/comments/1c0b222/how_do_you_structure_large_services/" {
+		t.Errorf("self post URL: got %q", self.URL)
+	}
+	if self.Content != `<div class="md"><p>We have <strong>40</strong> packages.</p></div>` {
+		t.Errorf("self post content: got %q", self.Content)
+	}
+	if _, ok := self.Metadata["thumbnail"]; ok {
+		t.Errorf("self post thumbnail: got %v", self.Metadata["thumbnail"])
+	}
+}
+
+func TestRedditFetchItems(t *testing.T) {
+	fixture := loadRedditFixture(t, "reddit_hot.json")
+
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	p := &RedditProvider{client: NewHTTPClient(5 * time.Second), baseURL: server.URL}
+	config := map[string]interface{}{
+		"subreddit": "r/golang",
+		"sort":      "top",
+		"time":      "week",
+		"limit":     float64(50),
+		"min_score": float64(10),
+	}
+
+	// Posts created before the last fetch are still returned, they may only
+	// have passed min_score since
+	items, err := p.FetchItems(context.Background(), config, time.Unix(1712400000, 0))
+	if err != nil {
+		t.Fatalf("FetchItems: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	if gotPath != "/r/golang/top.json" {
+		t.Errorf("got path %s", gotPath)
+	}
+	if gotQuery != "limit=50&t=week" {
+		t.Errorf("got query %s", gotQuery)
+	}
+}
+
+func TestRedditFetchItemsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	p := &RedditProvider{client: NewHTTPClient(5 * time.Second), baseURL: server.URL}
+	if _, err := p.FetchItems(context.Background(), map[string]interface{}{"user": "spez"}, time.Time{}); err == nil {
+		t.Fatal("expected an error for a 403 response")
+	}
+}
+
+func TestRedditListingURL(t *testing.T) {
+	p := &RedditProvider{baseURL: RedditBaseURL}
+
+	tests := []struct {
+		config map[string]interface{}
+		want   string
+	}{
+		{map[string]interface{}{"subreddit": "golang"}, RedditBaseURL + "/r/golang/hot.json?limit=25"},
+		{map[string]interface{}{"multireddit": "someone/tech", "sort": "new"}, RedditBaseURL + "/user/someone/m/tech/new.json?limit=25"},
+		{map[string]interface{}{"user": "u/spez", "sort": "top"}, RedditBaseURL + "/user/spez/submitted.json?limit=25&sort=top&t=day"},
+	}
+	for _, tt := range tests {
+		got, err := p.listingURL(tt.config)
+		if err != nil {
+			t.Errorf("listingURL(%v): %v", tt.config, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("listingURL(%v): got %s, want %s", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestRedditValidate(t *testing.T) {
+	p := &RedditProvider{baseURL: RedditBaseURL}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"subreddit", map[string]interface{}{"subreddit": "golang", "sort": "top", "time": "week"}, false},
+		{"multireddit", map[string]interface{}{"multireddit": "someone/tech"}, false},
+		{"no target", map[string]interface{}{}, true},
+		{"two targets", map[string]interface{}{"subreddit": "golang", "user": "spez"}, true},
+		{"invalid name", map[string]interface{}{"subreddit": "go lang"}, true},
+		{"invalid multireddit", map[string]interface{}{"multireddit": "tech"}, true},
+		{"invalid sort", map[string]interface{}{"subreddit": "golang", "sort": "best"}, true},
+		{"invalid time", map[string]interface{}{"subreddit": "golang", "time": "decade"}, true},
+		{"limit too low", map[string]interface{}{"subreddit": "golang", "limit": float64(0)}, true},
+		{"limit too high", map[string]interface{}{"subreddit": "golang", "limit": float64(101)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package providers
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
@@ -18,11 +22,15 @@ type RSSProvider struct {
 }
 
 func NewRSSProvider() services.FeedSourceProvider {
+	client := NewHTTPClient(30 * time.Second)
+
+	parser := gofeed.NewParser()
+	parser.Client = client
+	parser.UserAgent = UserAgent
+
 	return &RSSProvider{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		parser: gofeed.NewParser(),
+		client: client,
+		parser: parser,
 	}
 }
 
@@ -38,8 +46,40 @@ func (p *RSSProvider) FetchItems(ctx context.Context, config map[string]interfac
 
 	logger.LogInfo(fmt.Sprintf("Fetching RSS feed: %s", url))
 
+	// Conditional GET using the validators stored on the source
+	body, notModified, err := conditionalGet(ctx, p.client, url)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching RSS feed: %v", err)
+	}
+	if notModified {
+		logger.LogInfo(fmt.Sprintf("RSS feed not modified: %s", url))
+		return nil, nil
+	}
+
+	// Feeds advertising a hub can be pushed over WebSub instead of polled
+	if state := services.FetchStateFromContext(ctx); state != nil {
+		state.SetWebSub(webSubLinks(body))
+	}
+
+	items, err := p.parseItems(body, lastFetched)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogInfo(fmt.Sprintf("Fetched %d items from RSS feed", len(items)))
+	return items, nil
+}
+
+// ParseContent parses feed content pushed by a WebSub hub
+func (p *RSSProvider) ParseContent(body []byte) ([]services.RawFeedItem, error) {
+	return p.parseItems(body, time.Time{})
+}
+
+// parseItems parses a feed document, skipping items published before
+// lastFetched unless it is zero
+func (p *RSSProvider) parseItems(body []byte, lastFetched time.Time) ([]services.RawFeedItem, error) {
 	// Parse the feed
-	feed, err := p.parser.ParseURLWithContext(url, ctx)
+	feed, err := p.parser.Parse(bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("error parsing RSS feed: %v", err)
 	}
@@ -48,7 +88,7 @@ func (p *RSSProvider) FetchItems(ctx context.Context, config map[string]interfac
 	var items []services.RawFeedItem
 	for _, item := range feed.Items {
 		// Skip items older than the last fetch time
-		if !lastFetched.IsZero() && !item.PublishedParsed.IsZero() && !item.PublishedParsed.After(lastFetched) {
+		if !lastFetched.IsZero() && item.PublishedParsed != nil && !item.PublishedParsed.After(lastFetched) {
 			continue
 		}
 
@@ -63,6 +103,8 @@ func (p *RSSProvider) FetchItems(ctx context.Context, config map[string]interfac
 
 		if item.Author != nil {
 			feedItem.Author = item.Author.Name
+		} else if item.ITunesExt != nil {
+			feedItem.Author = item.ITunesExt.Author
 		}
 
 		if item.PublishedParsed != nil {
@@ -84,14 +126,59 @@ func (p *RSSProvider) FetchItems(ctx context.Context, config map[string]interfac
 		if item.Content != "" {
 			feedItem.Metadata["full_content"] = item.Content
 		}
+		podcastMetadata(feed, item, feedItem.Metadata)
 
 		items = append(items, feedItem)
 	}
 
-	logger.LogInfo(fmt.Sprintf("Fetched %d items from RSS feed", len(items)))
 	return items, nil
 }
 
+// webSubLinks returns the hub and self links of a feed document: atom:link
+// elements in RSS and link elements in Atom. Only the feed header is read.
+func webSubLinks(body []byte) (hub string, self string) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return hub, self
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(start.Name.Local) {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = strings.ToLower(attr.Value)
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+			if href == "" {
+				continue
+			}
+			if rel == "hub" && hub == "" {
+				hub = href
+			}
+			if rel == "self" && self == "" {
+				self = href
+			}
+		}
+	}
+}
+
 func (p *RSSProvider) Validate(config map[string]interface{}) error {
 	url, ok := config["url"].(string)
 	if !ok || url == "" {
@@ -0,0 +1,421 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/pocketbase/dbx"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/models"
+	"github.com/shashank-sharma/backend/internal/query"
+	"github.com/shashank-sharma/backend/internal/services"
+)
+
+const (
+	YoutubeBaseURL         = "https://www.youtube.com"
+	YoutubeChannelFeedURL  = YoutubeBaseURL + "/feeds/videos.xml?channel_id=%s"
+	YoutubePlaylistFeedURL = YoutubeBaseURL + "/feeds/videos.xml?playlist_id=%s"
+	YoutubeWatchURL        = YoutubeBaseURL + "/watch?v=%s"
+
+	// Keep transcripts within what the summarization models accept
+	MaxTranscriptLength = 30 * 1024
+)
+
+var (
+	youtubeChannelIDPattern  = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+	youtubePlaylistIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{10,}$`)
+	youtubeHandlePattern     = regexp.MustCompile(`^@?[A-Za-z0-9._-]{3,30}$`)
+	youtubePageChannelID     = regexp.MustCompile(`"(?:channelId|externalId)":"(UC[A-Za-z0-9_-]{22})"`)
+)
+
+// YoutubeProvider implements the FeedSourceProvider interface for YouTube channels and playlists
+type YoutubeProvider struct {
+	client *http.Client
+	parser *gofeed.Parser
+}
+
+// youtubePlayerResponse is the subset of ytInitialPlayerResponse we need
+type youtubePlayerResponse struct {
+	VideoDetails struct {
+		LengthSeconds string `json:"lengthSeconds"`
+		ViewCount     string `json:"viewCount"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Renderer struct {
+			CaptionTracks []struct {
+				BaseURL      string `json:"baseUrl"`
+				LanguageCode string `json:"languageCode"`
+				Kind         string `json:"kind"`
+			} `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+type youtubeTranscript struct {
+	Texts []string `xml:"text"`
+}
+
+func NewYoutubeProvider() services.FeedSourceProvider {
+	return &YoutubeProvider{
+		client: NewHTTPClient(30 * time.Second),
+		parser: gofeed.NewParser(),
+	}
+}
+
+// GetProviderType returns the type of feed source
+func (p *YoutubeProvider) GetProviderType() string {
+	return models.SourceTypeYoutube
+}
+
+// FetchItems fetches the latest videos of a channel or playlist
+func (p *YoutubeProvider) FetchItems(ctx context.Context, config map[string]interface{}, lastFetched time.Time) ([]services.RawFeedItem, error) {
+	feedURL, err := p.resolveFeedURL(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchTranscript := true
+	if val, ok := config["fetch_transcript"].(bool); ok {
+		fetchTranscript = val
+	}
+	language, _ := config["transcript_language"].(string)
+	if language == "" {
+		language = "en"
+	}
+
+	logger.LogInfo(fmt.Sprintf("Fetching YouTube feed: %s", feedURL))
+
+	body, notModified, err := conditionalGet(ctx, p.client, feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching YouTube feed: %v", err)
+	}
+	if notModified {
+		logger.LogInfo(fmt.Sprintf("YouTube feed not modified: %s", feedURL))
+		return nil, nil
+	}
+
+	feed, err := p.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing YouTube feed: %v", err)
+	}
+
+	items := make([]services.RawFeedItem, 0, len(feed.Items))
+	for _, entry := range feed.Items {
+		if !lastFetched.IsZero() && entry.PublishedParsed != nil && !entry.PublishedParsed.After(lastFetched) {
+			continue
+		}
+
+		videoID := youtubeExtension(entry, "yt", "videoId")
+		if videoID == "" {
+			continue
+		}
+
+		description := ""
+		metadata := map[string]interface{}{
+			"video_id":      videoID,
+			"channel_title": feed.Title,
+		}
+		if groups, ok := entry.Extensions["media"]["group"]; ok && len(groups) > 0 {
+			group := groups[0]
+			if descriptions := group.Children["description"]; len(descriptions) > 0 {
+				description = descriptions[0].Value
+			}
+			if thumbnails := group.Children["thumbnail"]; len(thumbnails) > 0 {
+				metadata["thumbnail"] = thumbnails[0].Attrs["url"]
+			}
+		}
+		if channelID := youtubeExtension(entry, "yt", "channelId"); channelID != "" {
+			metadata["channel_id"] = channelID
+		}
+		metadata["description"] = description
+
+		item := services.RawFeedItem{
+			ExternalID: videoID,
+			Title:      entry.Title,
+			Content:    description,
+			URL:        fmt.Sprintf(YoutubeWatchURL, videoID),
+			Tags:       []string{"youtube", "video"},
+			Metadata:   metadata,
+		}
+		if entry.Author != nil {
+			item.Author = entry.Author.Name
+		}
+		if entry.PublishedParsed != nil {
+			item.PublishedAt = *entry.PublishedParsed
+		} else {
+			item.PublishedAt = time.Now()
+		}
+
+		items = append(items, item)
+	}
+
+	// Video details require a page load per video, do them concurrently for
+	// the videos that aren't stored yet
+	stored := p.storedVideoIDs(ctx, items)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, MaxConcurrentFetches)
+	for i := range items {
+		if stored[items[i].ExternalID] {
+			continue
+		}
+		wg.Add(1)
+		go func(item *services.RawFeedItem) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			p.enrichVideo(ctx, item, fetchTranscript, language)
+		}(&items[i])
+	}
+	wg.Wait()
+
+	logger.LogInfo(fmt.Sprintf("Fetched %d items from YouTube", len(items)))
+	return items, nil
+}
+
+// enrichVideo adds duration and view count to the item and, when captions are
+// available, replaces the content with the transcript so summaries cover the whole video
+func (p *YoutubeProvider) enrichVideo(ctx context.Context, item *services.RawFeedItem, fetchTranscript bool, language string) {
+	videoID := item.ExternalID
+
+	player, err := p.fetchPlayerResponse(ctx, videoID)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error fetching YouTube video details for %s: %v", videoID, err))
+		return
+	}
+
+	if seconds, err := strconv.Atoi(player.VideoDetails.LengthSeconds); err == nil {
+		item.Metadata["duration"] = seconds
+	}
+	if views, err := strconv.Atoi(player.VideoDetails.ViewCount); err == nil {
+		item.Metadata["view_count"] = views
+	}
+
+	tracks := player.Captions.Renderer.CaptionTracks
+	item.Metadata["has_captions"] = len(tracks) > 0
+	if !fetchTranscript || len(tracks) == 0 {
+		return
+	}
+
+	// Prefer manual captions in the requested language, then auto-generated ones
+	trackURL := ""
+	for _, track := range tracks {
+		if !strings.HasPrefix(track.LanguageCode, language) {
+			continue
+		}
+		if trackURL == "" || track.Kind != "asr" {
+			trackURL = track.BaseURL
+		}
+	}
+	if trackURL == "" {
+		trackURL = tracks[0].BaseURL
+	}
+
+	transcript, err := p.fetchTranscript(ctx, trackURL)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error fetching YouTube transcript for %s: %v", videoID, err))
+		return
+	}
+	if transcript == "" {
+		return
+	}
+
+	if len(transcript) > MaxTranscriptLength {
+		// Cut at a rune boundary so the text stays valid UTF-8
+		cut := MaxTranscriptLength
+		for cut > 0 && !utf8.RuneStart(transcript[cut]) {
+			cut--
+		}
+		transcript = transcript[:cut] + "..."
+	}
+	item.Content = transcript
+	item.Metadata["transcript"] = true
+}
+
+// storedVideoIDs returns the videos among items the source being fetched has
+// already stored
+func (p *YoutubeProvider) storedVideoIDs(ctx context.Context, items []services.RawFeedItem) map[string]bool {
+	stored := map[string]bool{}
+	sourceID := services.SourceIDFromContext(ctx)
+	if sourceID == "" || len(items) == 0 {
+		return stored
+	}
+
+	videoIDs := make([]interface{}, len(items))
+	for i, item := range items {
+		videoIDs[i] = item.ExternalID
+	}
+
+	var existing []string
+	err := query.BaseQuery[*models.FeedItem]().
+		Select("external_id").
+		Where(dbx.HashExp{"source_id": sourceID}).
+		AndWhere(dbx.In("external_id", videoIDs...)).
+		Column(&existing)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error loading stored YouTube videos: %v", err))
+		return stored
+	}
+
+	for _, videoID := range existing {
+		stored[videoID] = true
+	}
+	return stored
+}
+
+// fetchPlayerResponse loads the watch page and extracts ytInitialPlayerResponse
+func (p *YoutubeProvider) fetchPlayerResponse(ctx context.Context, videoID string) (*youtubePlayerResponse, error) {
+	body, err := p.fetchPage(ctx, fmt.Sprintf(YoutubeWatchURL, videoID))
+	if err != nil {
+		return nil, err
+	}
+
+	marker := []byte("ytInitialPlayerResponse = ")
+	start := bytes.Index(body, marker)
+	if start < 0 {
+		return nil, fmt.Errorf("player response not found")
+	}
+
+	// The decoder stops after the first JSON value, ignoring the rest of the script
+	var player youtubePlayerResponse
+	decoder := json.NewDecoder(bytes.NewReader(body[start+len(marker):]))
+	if err := decoder.Decode(&player); err != nil {
+		return nil, fmt.Errorf("error decoding player response: %v", err)
+	}
+
+	return &player, nil
+}
+
+// fetchTranscript downloads a caption track and flattens it to plain text
+func (p *YoutubeProvider) fetchTranscript(ctx context.Context, trackURL string) (string, error) {
+	body, err := p.fetchPage(ctx, trackURL)
+	if err != nil {
+		return "", err
+	}
+
+	var transcript youtubeTranscript
+	if err := xml.Unmarshal(body, &transcript); err != nil {
+		return "", fmt.Errorf("error parsing transcript: %v", err)
+	}
+
+	lines := make([]string, 0, len(transcript.Texts))
+	for _, text := range transcript.Texts {
+		// Caption text is double escaped
+		line := strings.TrimSpace(html.UnescapeString(text))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, " "), nil
+}
+
+// resolveFeedURL turns the configured channel, handle or playlist into an Atom feed URL
+func (p *YoutubeProvider) resolveFeedURL(ctx context.Context, config map[string]interface{}) (string, error) {
+	if playlist, ok := config["playlist"].(string); ok && playlist != "" {
+		return fmt.Sprintf(YoutubePlaylistFeedURL, playlist), nil
+	}
+
+	channel, _ := config["channel"].(string)
+	channel = strings.TrimSpace(channel)
+	if channel == "" {
+		return "", fmt.Errorf("channel or playlist is required")
+	}
+
+	if youtubeChannelIDPattern.MatchString(channel) {
+		return fmt.Sprintf(YoutubeChannelFeedURL, channel), nil
+	}
+
+	// Handles have to be resolved to a channel ID through the channel page
+	channelID, err := p.resolveHandle(ctx, channel)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(YoutubeChannelFeedURL, channelID), nil
+}
+
+func (p *YoutubeProvider) resolveHandle(ctx context.Context, handle string) (string, error) {
+	if !strings.HasPrefix(handle, "@") {
+		handle = "@" + handle
+	}
+
+	body, err := p.fetchPage(ctx, YoutubeBaseURL+"/"+handle)
+	if err != nil {
+		return "", fmt.Errorf("error resolving YouTube handle %s: %v", handle, err)
+	}
+
+	match := youtubePageChannelID.FindSubmatch(body)
+	if match == nil {
+		return "", fmt.Errorf("could not find channel ID for %s", handle)
+	}
+
+	return string(match[1]), nil
+}
+
+func (p *YoutubeProvider) fetchPage(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Anonymous/1.0)")
+	// Skip the EU consent interstitial
+	req.Header.Set("Cookie", "CONSENT=YES+1")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// Validate validates the source configuration
+func (p *YoutubeProvider) Validate(config map[string]interface{}) error {
+	playlist, _ := config["playlist"].(string)
+	channel, _ := config["channel"].(string)
+
+	switch {
+	case playlist != "" && channel != "":
+		return fmt.Errorf("only one of channel or playlist can be set")
+	case playlist != "":
+		if !youtubePlaylistIDPattern.MatchString(playlist) {
+			return fmt.Errorf("invalid playlist ID: %s", playlist)
+		}
+	case channel != "":
+		if !youtubeChannelIDPattern.MatchString(channel) && !youtubeHandlePattern.MatchString(channel) {
+			return fmt.Errorf("invalid channel: %s", channel)
+		}
+	default:
+		return fmt.Errorf("channel or playlist is required")
+	}
+
+	return nil
+}
+
+func youtubeExtension(item *gofeed.Item, namespace string, name string) string {
+	if extensions, ok := item.Extensions[namespace][name]; ok && len(extensions) > 0 {
+		return extensions[0].Value
+	}
+	return ""
+}
@@ -0,0 +1,345 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"golang.org/x/net/html"
+)
+
+// Searchable document types
+const (
+	TypeFeed     = "feed"
+	TypeMail     = "mail"
+	TypeCalendar = "calendar"
+	TypeArchive  = "archive"
+)
+
+// Indexed text is capped so a single huge mail can't bloat the index
+const maxIndexedContent = 200 * 1024
+
+const reindexBatchSize = 500
+
+// collectionTypes maps the indexed tables to their document type
+var collectionTypes = map[string]string{
+	"feed_items":      TypeFeed,
+	"mail_messages":   TypeMail,
+	"calendar_events": TypeCalendar,
+	"feed_archives":   TypeArchive,
+}
+
+// Document is a single entry of the search index
+type Document struct {
+	Type     string
+	RecordID string
+	User     string
+	Title    string
+	Content  string
+	Extra    string
+	Tags     []string
+	Date     types.DateTime
+	Meta     map[string]interface{}
+}
+
+// DocumentFromRecord builds the search document for a feed item, mail message,
+// calendar event or archived article record
+func DocumentFromRecord(record *core.Record) (*Document, bool) {
+	docType, ok := collectionTypes[record.TableName()]
+	if !ok {
+		return nil, false
+	}
+
+	doc := &Document{
+		Type:     docType,
+		RecordID: record.Id,
+		User:     record.GetString("user"),
+	}
+
+	switch docType {
+	case TypeFeed:
+		content := record.GetString("full_content")
+		if content == "" {
+			content = record.GetString("content")
+		}
+		doc.Title = record.GetString("title")
+		doc.Content = content
+		doc.Extra = record.GetString("summary")
+		doc.Tags = record.GetStringSlice("tags")
+		doc.Date = record.GetDateTime("published_at")
+		doc.Meta = map[string]interface{}{
+			"url":       record.GetString("url"),
+			"source_id": record.GetString("source_id"),
+			"author":    record.GetString("author"),
+			"status":    record.GetString("status"),
+		}
+	case TypeMail:
+		doc.Title = record.GetString("subject")
+		doc.Content = record.GetString("body")
+		if doc.Content == "" {
+			doc.Content = record.GetString("snippet")
+		}
+		doc.Extra = record.GetString("from")
+		doc.Date = record.GetDateTime("internal_date")
+		doc.Meta = map[string]interface{}{
+			"from":       record.GetString("from"),
+			"thread_id":  record.GetString("thread_id"),
+			"message_id": record.GetString("message_id"),
+			"is_unread":  record.GetBool("is_unread"),
+		}
+	case TypeCalendar:
+		doc.Title = record.GetString("summary")
+		doc.Content = record.GetString("description")
+		doc.Extra = record.GetString("location")
+		doc.Date = record.GetDateTime("start")
+		doc.Meta = map[string]interface{}{
+			"start":    record.GetDateTime("start").String(),
+			"end":      record.GetDateTime("end").String(),
+			"location": record.GetString("location"),
+			"calendar": record.GetString("calendar"),
+		}
+	case TypeArchive:
+		doc.Title = record.GetString("title")
+		doc.Content = record.GetString("text")
+		doc.Extra = record.GetString("url")
+		doc.Date = record.GetDateTime("archived_at")
+		doc.Meta = map[string]interface{}{
+			"url":     record.GetString("url"),
+			"item_id": record.GetString("item"),
+			"status":  record.GetString("status"),
+		}
+	}
+
+	return doc, true
+}
+
+// IndexRecord loads a record and adds or replaces it in the search index
+func IndexRecord(app core.App, table string, recordID string) error {
+	record, err := app.FindRecordById(table, recordID)
+	if err != nil {
+		return err
+	}
+
+	doc, ok := DocumentFromRecord(record)
+	if !ok {
+		return fmt.Errorf("collection %s is not searchable", table)
+	}
+
+	return Index(app, doc)
+}
+
+// Index adds or replaces a document in the search index
+func Index(app core.App, doc *Document) error {
+	meta, err := json.Marshal(doc.Meta)
+	if err != nil {
+		return err
+	}
+
+	tags := ""
+	if len(doc.Tags) > 0 {
+		// Padded so tags can be matched with instr(tags, ' id ')
+		tags = " " + strings.Join(doc.Tags, " ") + " "
+	}
+
+	return app.RunInTransaction(func(txApp core.App) error {
+		db := txApp.DB()
+
+		var rowID int64
+		err := db.NewQuery("SELECT id FROM search_documents WHERE type = {:type} AND record_id = {:record_id}").
+			Bind(dbx.Params{"type": doc.Type, "record_id": doc.RecordID}).
+			Row(&rowID)
+
+		params := dbx.Params{
+			"record_id": doc.RecordID,
+			"type":      doc.Type,
+			"user":      doc.User,
+			"tags":      tags,
+			"date":      doc.Date.String(),
+			"meta":      string(meta),
+		}
+
+		if err == nil {
+			params["id"] = rowID
+			if _, err := db.NewQuery("UPDATE search_documents SET user = {:user}, tags = {:tags}, date = {:date}, meta = {:meta} WHERE id = {:id}").
+				Bind(params).Execute(); err != nil {
+				return err
+			}
+			if _, err := db.NewQuery("DELETE FROM search_index WHERE rowid = {:id}").
+				Bind(dbx.Params{"id": rowID}).Execute(); err != nil {
+				return err
+			}
+		} else {
+			result, err := db.NewQuery("INSERT INTO search_documents (record_id, type, user, tags, date, meta) VALUES ({:record_id}, {:type}, {:user}, {:tags}, {:date}, {:meta})").
+				Bind(params).Execute()
+			if err != nil {
+				return err
+			}
+			if rowID, err = result.LastInsertId(); err != nil {
+				return err
+			}
+		}
+
+		_, err = db.NewQuery("INSERT INTO search_index (rowid, title, content, extra) VALUES ({:id}, {:title}, {:content}, {:extra})").
+			Bind(dbx.Params{
+				"id":      rowID,
+				"title":   doc.Title,
+				"content": PlainText(doc.Content),
+				"extra":   PlainText(doc.Extra),
+			}).Execute()
+		return err
+	})
+}
+
+// Remove deletes a record from the search index
+func Remove(app core.App, docType string, recordID string) error {
+	return app.RunInTransaction(func(txApp core.App) error {
+		db := txApp.DB()
+
+		var rowID int64
+		err := db.NewQuery("SELECT id FROM search_documents WHERE type = {:type} AND record_id = {:record_id}").
+			Bind(dbx.Params{"type": docType, "record_id": recordID}).
+			Row(&rowID)
+		if err != nil {
+			// Not indexed
+			return nil
+		}
+
+		if _, err := db.NewQuery("DELETE FROM search_index WHERE rowid = {:id}").
+			Bind(dbx.Params{"id": rowID}).Execute(); err != nil {
+			return err
+		}
+		_, err = db.NewQuery("DELETE FROM search_documents WHERE id = {:id}").
+			Bind(dbx.Params{"id": rowID}).Execute()
+		return err
+	})
+}
+
+// Reindex rebuilds the index for every searchable collection
+func Reindex(app core.App) (int, error) {
+	total := 0
+	for table := range collectionTypes {
+		for offset := 0; ; offset += reindexBatchSize {
+			records, err := app.FindRecordsByFilter(table, "", "id", reindexBatchSize, offset)
+			if err != nil {
+				return total, fmt.Errorf("error loading %s: %v", table, err)
+			}
+
+			for _, record := range records {
+				doc, _ := DocumentFromRecord(record)
+				if err := Index(app, doc); err != nil {
+					logger.LogError(fmt.Sprintf("Error indexing %s %s: %v", table, record.Id, err))
+					continue
+				}
+				total++
+			}
+
+			if len(records) < reindexBatchSize {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// EnsureIndexed builds the index when it is empty, e.g. right after the
+// search migration ran on an existing database
+func EnsureIndexed(app core.App) {
+	var count int
+	if err := app.DB().NewQuery("SELECT COUNT(*) FROM search_documents").Row(&count); err != nil {
+		logger.LogError(fmt.Sprintf("Error checking search index: %v", err))
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	total, err := Reindex(app)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error building search index: %v", err))
+		return
+	}
+	logger.LogInfo(fmt.Sprintf("Built search index with %d documents", total))
+}
+
+// RegisterHooks keeps the search index in sync with the indexed collections.
+// Model hooks are used so saves through both records and the models package
+// are picked up. The after-success hooks run once the save is committed, so
+// the root app is used rather than a possibly finished transaction.
+func RegisterHooks(app core.App) {
+	tables := make([]string, 0, len(collectionTypes))
+	for table := range collectionTypes {
+		tables = append(tables, table)
+	}
+
+	index := func(e *core.ModelEvent) error {
+		table := e.Model.TableName()
+		recordID := fmt.Sprint(e.Model.PK())
+		if err := IndexRecord(app, table, recordID); err != nil {
+			logger.LogError(fmt.Sprintf("Error indexing %s %s: %v", table, recordID, err))
+		}
+		return e.Next()
+	}
+
+	app.OnModelAfterCreateSuccess(tables...).BindFunc(index)
+	app.OnModelAfterUpdateSuccess(tables...).BindFunc(index)
+	app.OnModelAfterDeleteSuccess(tables...).BindFunc(func(e *core.ModelEvent) error {
+		table := e.Model.TableName()
+		recordID := fmt.Sprint(e.Model.PK())
+		if err := Remove(app, collectionTypes[table], recordID); err != nil {
+			logger.LogError(fmt.Sprintf("Error removing %s %s from search index: %v", table, recordID, err))
+		}
+		return e.Next()
+	})
+}
+
+// PlainText strips HTML tags so markup doesn't end up in the index or snippets
+func PlainText(content string) string {
+	if !strings.Contains(content, "<") {
+		return truncate(content)
+	}
+
+	var builder strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return truncate(strings.Join(strings.Fields(builder.String()), " "))
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+			builder.WriteByte(' ')
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if (string(name) == "script" || string(name) == "style") && skip > 0 {
+				skip--
+			}
+			builder.WriteByte(' ')
+		case html.TextToken:
+			if skip == 0 {
+				builder.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// truncate cuts content to maxIndexedContent bytes at a rune boundary, so the
+// index and its snippets stay valid UTF-8
+func truncate(content string) string {
+	if len(content) <= maxIndexedContent {
+		return content
+	}
+	cut := maxIndexedContent
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut]
+}
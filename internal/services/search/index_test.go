@@ -0,0 +1,32 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int // Length of the result in bytes
+	}{
+		{"short", "hello", 5},
+		{"ascii", strings.Repeat("a", maxIndexedContent+10), maxIndexedContent},
+		// Two byte runes starting on an odd offset end one byte after the limit
+		{"split rune", "a" + strings.Repeat("é", maxIndexedContent/2), maxIndexedContent - 1},
+		{"four byte runes", strings.Repeat("😀", maxIndexedContent/4+1), maxIndexedContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.content)
+			if len(got) != tt.want {
+				t.Errorf("got %d bytes, want %d", len(got), tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Error("result is not valid UTF-8")
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+// Column weights for bm25, in search_index column order: title, content, extra
+const rankExpression = "bm25(search_index, 10.0, 1.0, 4.0)"
+
+// Options filters a search
+type Options struct {
+	Types  []string
+	From   time.Time
+	To     time.Time
+	TagIDs []string
+	Limit  int
+	Offset int
+}
+
+// Result is a single search hit
+type Result struct {
+	Type     string                 `json:"type"`
+	ID       string                 `json:"id"`
+	Title    string                 `json:"title"`
+	Snippet  string                 `json:"snippet"`
+	Date     string                 `json:"date"`
+	Rank     float64                `json:"rank"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type resultRow struct {
+	RecordID string  `db:"record_id"`
+	Type     string  `db:"type"`
+	Date     string  `db:"date"`
+	Meta     string  `db:"meta"`
+	Title    string  `db:"title"`
+	Snippet  string  `db:"snippet"`
+	Rank     float64 `db:"rank"`
+}
+
+// Search runs a full-text query over the documents of a user and returns the
+// matching page ordered by relevance together with the total number of hits
+func Search(app core.App, userID string, q string, opts Options) ([]Result, int, error) {
+	match := BuildMatchQuery(q)
+	if match == "" {
+		return []Result{}, 0, nil
+	}
+
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultLimit
+	}
+	if opts.Limit > MaxLimit {
+		opts.Limit = MaxLimit
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	where := []string{"search_index MATCH {:match}", "d.user = {:user}"}
+	params := dbx.Params{"match": match, "user": userID}
+
+	if len(opts.Types) > 0 {
+		placeholders := make([]string, 0, len(opts.Types))
+		for i, docType := range opts.Types {
+			key := fmt.Sprintf("type%d", i)
+			placeholders = append(placeholders, "{:"+key+"}")
+			params[key] = docType
+		}
+		where = append(where, "d.type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !opts.From.IsZero() {
+		where = append(where, "d.date >= {:from}")
+		params["from"] = formatDate(opts.From)
+	}
+	if !opts.To.IsZero() {
+		where = append(where, "d.date <= {:to}")
+		params["to"] = formatDate(opts.To)
+	}
+	if len(opts.TagIDs) > 0 {
+		// Any of the given tag IDs matches, several IDs come from one tag name
+		conditions := make([]string, 0, len(opts.TagIDs))
+		for i, tagID := range opts.TagIDs {
+			key := fmt.Sprintf("tag%d", i)
+			conditions = append(conditions, "instr(d.tags, {:"+key+"}) > 0")
+			params[key] = " " + tagID + " "
+		}
+		where = append(where, "("+strings.Join(conditions, " OR ")+")")
+	}
+
+	from := " FROM search_index JOIN search_documents d ON d.id = search_index.rowid WHERE " + strings.Join(where, " AND ")
+
+	var total int
+	if err := app.DB().NewQuery("SELECT COUNT(*)" + from).Bind(params).Row(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting search results: %v", err)
+	}
+
+	params["limit"] = opts.Limit
+	params["offset"] = opts.Offset
+
+	rows := []resultRow{}
+	err := app.DB().NewQuery(fmt.Sprintf(
+		"SELECT d.record_id, d.type, d.date, d.meta, "+
+			"highlight(search_index, 0, '%s', '%s') AS title, "+
+			"snippet(search_index, -1, '%s', '%s', '…', 32) AS snippet, "+
+			"%s AS rank"+from+" ORDER BY rank LIMIT {:limit} OFFSET {:offset}",
+		highlightStart, highlightEnd, highlightStart, highlightEnd, rankExpression,
+	)).Bind(params).All(&rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching: %v", err)
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, row := range rows {
+		metadata := map[string]interface{}{}
+		if row.Meta != "" {
+			json.Unmarshal([]byte(row.Meta), &metadata)
+		}
+
+		results = append(results, Result{
+			Type:     row.Type,
+			ID:       row.RecordID,
+			Title:    row.Title,
+			Snippet:  row.Snippet,
+			Date:     row.Date,
+			Rank:     -row.Rank, // bm25 is lower for better matches
+			Metadata: metadata,
+		})
+	}
+
+	return results, total, nil
+}
+
+// BuildMatchQuery turns user input into a safe FTS5 query. Words are quoted so
+// FTS5 syntax characters can't cause errors, "quoted phrases" are kept, a
+// trailing * does prefix matching, -word excludes a word and OR is passed through.
+func BuildMatchQuery(q string) string {
+	terms := make([]string, 0)
+	excluded := make([]string, 0)
+
+	for _, token := range tokenize(q) {
+		if token == "OR" {
+			if len(terms) > 0 && terms[len(terms)-1] != "OR" {
+				terms = append(terms, "OR")
+			}
+			continue
+		}
+
+		negate := strings.HasPrefix(token, "-") && len(token) > 1
+		if negate {
+			token = token[1:]
+		}
+
+		prefix := strings.HasSuffix(token, "*")
+		token = strings.TrimRight(token, "*")
+
+		// Quotes are the only character that needs escaping inside an FTS5 string
+		token = strings.TrimSpace(strings.ReplaceAll(token, `"`, ""))
+		if !containsWordCharacter(token) {
+			continue
+		}
+
+		term := `"` + token + `"`
+		if prefix {
+			term += "*"
+		}
+
+		if negate {
+			excluded = append(excluded, term)
+		} else {
+			terms = append(terms, term)
+		}
+	}
+
+	// A dangling OR is a syntax error
+	for len(terms) > 0 && terms[len(terms)-1] == "OR" {
+		terms = terms[:len(terms)-1]
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+
+	match := strings.Join(terms, " ")
+	for _, term := range excluded {
+		match += " NOT " + term
+	}
+	return match
+}
+
+// tokenize splits a query on whitespace while keeping "quoted phrases" together
+func tokenize(q string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			if inQuotes {
+				flush()
+			}
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func containsWordCharacter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDate formats a time the way dates are stored in search_documents
+func formatDate(t time.Time) string {
+	dt := types.DateTime{}
+	dt.Scan(t)
+	return dt.String()
+}
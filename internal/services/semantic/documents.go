@@ -0,0 +1,196 @@
+package semantic
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/shashank-sharma/backend/internal/services/search"
+)
+
+// Embedded document types
+const (
+	TypeFeed      = "feed"
+	TypeMail      = "mail"
+	TypeNotebook  = "notebook"
+	TypeBookshelf = "bookshelf"
+)
+
+// Text embedded per record. Embedding models only read a few thousand tokens
+// and the opening of a text says most about it.
+const maxEmbeddedText = 8000
+
+// Snippets returned with results
+const snippetLength = 240
+
+// collectionTypes maps the embedded tables to their document type
+var collectionTypes = map[string]string{
+	"feed_items":    TypeFeed,
+	"mail_messages": TypeMail,
+	"notebooks":     TypeNotebook,
+	"bookshelf":     TypeBookshelf,
+}
+
+// typeCollections maps the document types back to their table
+var typeCollections = map[string]string{
+	TypeFeed:      "feed_items",
+	TypeMail:      "mail_messages",
+	TypeNotebook:  "notebooks",
+	TypeBookshelf: "bookshelf",
+}
+
+// Keys of notebook cells that hold no content
+var notebookSkipKeys = map[string]bool{
+	"id": true, "type": true, "cell_type": true, "language": true,
+	"execution_count": true, "metadata": true, "outputs": true,
+}
+
+// Document is the text of a record as it is embedded
+type Document struct {
+	Type     string
+	RecordID string
+	User     string
+	Title    string
+	Text     string // Title and content, the text the vector is built from
+	Date     string
+	Meta     map[string]interface{}
+}
+
+// IsValidType reports whether records of the document type are embedded
+func IsValidType(docType string) bool {
+	_, ok := typeCollections[docType]
+	return ok
+}
+
+// DocumentFromRecord builds the document of a feed item, mail message,
+// notebook or bookshelf record
+func DocumentFromRecord(record *core.Record) (*Document, bool) {
+	docType, ok := collectionTypes[record.TableName()]
+	if !ok {
+		return nil, false
+	}
+
+	doc := &Document{
+		Type:     docType,
+		RecordID: record.Id,
+		User:     record.GetString("user"),
+	}
+
+	var parts []string
+	switch docType {
+	case TypeFeed:
+		content := record.GetString("full_content")
+		if content == "" {
+			content = record.GetString("content")
+		}
+		doc.Title = record.GetString("title")
+		doc.Date = record.GetDateTime("published_at").String()
+		parts = []string{doc.Title, record.GetString("summary"), content}
+		doc.Meta = map[string]interface{}{
+			"url":        record.GetString("url"),
+			"source_id":  record.GetString("source_id"),
+			"author":     record.GetString("author"),
+			"status":     record.GetString("status"),
+			"cluster_id": record.GetString("cluster_id"),
+		}
+	case TypeMail:
+		doc.Title = record.GetString("subject")
+		doc.Date = record.GetDateTime("internal_date").String()
+		body := record.GetString("body")
+		if body == "" {
+			body = record.GetString("snippet")
+		}
+		parts = []string{doc.Title, record.GetString("from"), body}
+		doc.Meta = map[string]interface{}{
+			"from":      record.GetString("from"),
+			"thread_id": record.GetString("thread_id"),
+			"is_unread": record.GetBool("is_unread"),
+		}
+	case TypeNotebook:
+		doc.Title = record.GetString("name")
+		doc.Date = record.GetDateTime("updated").String()
+		var cells interface{}
+		if err := json.Unmarshal([]byte(record.GetString("cells")), &cells); err == nil {
+			parts = append([]string{doc.Title}, notebookText(cells)...)
+		} else {
+			parts = []string{doc.Title}
+		}
+		doc.Meta = map[string]interface{}{
+			"version": record.GetString("version"),
+		}
+	case TypeBookshelf:
+		doc.Title = record.GetString("title")
+		doc.Date = record.GetDateTime("updated").String()
+		parts = []string{doc.Title, record.GetString("category"), record.GetString("review")}
+		doc.Meta = map[string]interface{}{
+			"link":     record.GetString("link"),
+			"category": record.GetString("category"),
+			"status":   record.GetString("status"),
+			"rating":   record.GetFloat("rating"),
+		}
+	}
+
+	doc.Text = joinText(parts)
+	return doc, true
+}
+
+// notebookText collects the text of notebook cells, whatever shape they are
+// stored in
+func notebookText(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		var texts []string
+		for _, entry := range v {
+			texts = append(texts, notebookText(entry)...)
+		}
+		return texts
+	case map[string]interface{}:
+		// Sorted so the text, and with it the content hash, is stable
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if !notebookSkipKeys[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		var texts []string
+		for _, key := range keys {
+			texts = append(texts, notebookText(v[key])...)
+		}
+		return texts
+	}
+	return nil
+}
+
+// joinText joins the non-empty parts as plain text, capped to what is embedded
+func joinText(parts []string) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if text := strings.TrimSpace(search.PlainText(part)); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	text := strings.Join(texts, "\n\n")
+	if runes := []rune(text); len(runes) > maxEmbeddedText {
+		text = string(runes[:maxEmbeddedText])
+	}
+	return text
+}
+
+// snippet is the start of the document text after the title
+func (d *Document) snippet() string {
+	text := strings.TrimSpace(strings.TrimPrefix(d.Text, d.Title))
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > snippetLength {
+		text = string(runes[:snippetLength]) + "..."
+	}
+	return text
+}
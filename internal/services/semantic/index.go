@@ -0,0 +1,282 @@
+package semantic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/shashank-sharma/backend/internal/logger"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+)
+
+const (
+	// Records sent to the embedder in one request
+	indexBatchSize = 32
+	// Upper bound of records embedded per table in one run, so a large
+	// backlog is worked off over several runs
+	indexRunLimit = 2000
+)
+
+// Indexed tables in the order they are worked through, most read first
+var indexOrder = []string{"feed_items", "mail_messages", "notebooks", "bookshelf"}
+
+// Service keeps the vectors of feed items, mail messages, notebooks and
+// bookshelf entries up to date and searches them by meaning
+type Service struct {
+	app      core.App
+	embedder ai.Embedder
+}
+
+func NewService(app core.App, embedder ai.Embedder) *Service {
+	return &Service{
+		app:      app,
+		embedder: embedder,
+	}
+}
+
+// Model names the model the vectors of this service are built with
+func (s *Service) Model() string {
+	return s.embedder.EmbeddingModel()
+}
+
+// storedEmbedding is the index state of a record
+type storedEmbedding struct {
+	Model       string `db:"model"`
+	ContentHash string `db:"content_hash"`
+}
+
+// Run embeds the records that are new, changed since they were embedded or
+// were embedded with another model, and returns how many it embedded
+func (s *Service) Run(ctx context.Context) (int, error) {
+	// Records of all users are embedded together, only the global budget applies
+	ctx = ai.WithCaller(ctx, ai.FeatureSemanticIndex, "")
+
+	total := 0
+	for _, table := range indexOrder {
+		count, err := s.indexTable(ctx, table)
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("error indexing %s: %w", table, err)
+		}
+	}
+	return total, nil
+}
+
+// indexTable embeds the stale records of one table, newest first
+func (s *Service) indexTable(ctx context.Context, table string) (int, error) {
+	docType := collectionTypes[table]
+	model := s.Model()
+
+	embedded := 0
+	for seen := 0; seen < indexRunLimit; {
+		if err := ctx.Err(); err != nil {
+			return embedded, err
+		}
+
+		var ids []string
+		err := s.app.DB().NewQuery(fmt.Sprintf(`SELECT t.id FROM %s t
+			LEFT JOIN embeddings e ON e.type = {:type} AND e.record_id = t.id
+			WHERE e.id IS NULL OR e.model != {:model} OR e.source_updated < t.updated
+			ORDER BY t.updated DESC
+			LIMIT {:limit}`, table)).
+			Bind(dbx.Params{"type": docType, "model": model, "limit": indexBatchSize}).
+			Column(&ids)
+		if err != nil || len(ids) == 0 {
+			return embedded, err
+		}
+		seen += len(ids)
+
+		records, err := s.app.FindRecordsByIds(table, ids)
+		if err != nil {
+			return embedded, err
+		}
+
+		count, err := s.indexRecords(ctx, records)
+		embedded += count
+		if err != nil {
+			return embedded, err
+		}
+		if len(ids) < indexBatchSize {
+			return embedded, nil
+		}
+	}
+
+	return embedded, nil
+}
+
+// indexRecords embeds records whose text changed and marks the others as
+// current. Errors of the embedder are returned, there is no point trying
+// further batches when it is down.
+func (s *Service) indexRecords(ctx context.Context, records []*core.Record) (int, error) {
+	model := s.Model()
+
+	var pending []*Document
+	var pendingRecords []*core.Record
+	for _, record := range records {
+		doc, ok := DocumentFromRecord(record)
+		if !ok {
+			continue
+		}
+		if doc.Text == "" {
+			// Nothing to embed, an empty vector marks it as indexed and is
+			// never matched
+			if err := s.store(doc, record, nil); err != nil {
+				logger.LogError(fmt.Sprintf("Error storing embedding of %s %s: %v", doc.Type, doc.RecordID, err))
+			}
+			continue
+		}
+		hash := contentHash(doc.Text)
+
+		var stored storedEmbedding
+		err := s.app.DB().NewQuery("SELECT model, content_hash FROM embeddings WHERE type = {:type} AND record_id = {:record_id}").
+			Bind(dbx.Params{"type": doc.Type, "record_id": doc.RecordID}).
+			One(&stored)
+		if err == nil && stored.Model == model && stored.ContentHash == hash {
+			// Something other than the text changed, e.g. the read status
+			if err := s.touch(doc, record); err != nil {
+				logger.LogError(fmt.Sprintf("Error updating embedding of %s %s: %v", doc.Type, doc.RecordID, err))
+			}
+			continue
+		}
+
+		pending = append(pending, doc)
+		pendingRecords = append(pendingRecords, record)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	texts := make([]string, len(pending))
+	for i, doc := range pending {
+		texts[i] = doc.Text
+	}
+	resp, err := s.embedder.Embed(ctx, &ai.EmbedRequest{Texts: texts})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Vectors) != len(pending) {
+		return 0, fmt.Errorf("expected %d embeddings, got %d", len(pending), len(resp.Vectors))
+	}
+
+	for i, doc := range pending {
+		if err := s.store(doc, pendingRecords[i], resp.Vectors[i]); err != nil {
+			logger.LogError(fmt.Sprintf("Error storing embedding of %s %s: %v", doc.Type, doc.RecordID, err))
+		}
+	}
+
+	return len(pending), nil
+}
+
+// store adds or replaces the vector of a record
+func (s *Service) store(doc *Document, record *core.Record, vector []float32) error {
+	vector = normalize(vector)
+
+	_, err := s.app.DB().NewQuery(`INSERT INTO embeddings (record_id, type, user, model, dimensions, vector, content_hash, source_updated, date)
+		VALUES ({:record_id}, {:type}, {:user}, {:model}, {:dimensions}, {:vector}, {:content_hash}, {:source_updated}, {:date})
+		ON CONFLICT (type, record_id) DO UPDATE SET
+			user = excluded.user,
+			model = excluded.model,
+			dimensions = excluded.dimensions,
+			vector = excluded.vector,
+			content_hash = excluded.content_hash,
+			source_updated = excluded.source_updated,
+			date = excluded.date`).
+		Bind(dbx.Params{
+			"record_id":      doc.RecordID,
+			"type":           doc.Type,
+			"user":           doc.User,
+			"model":          s.Model(),
+			"dimensions":     len(vector),
+			"vector":         encodeVector(vector),
+			"content_hash":   contentHash(doc.Text),
+			"source_updated": record.GetDateTime("updated").String(),
+			"date":           doc.Date,
+		}).Execute()
+	return err
+}
+
+// touch marks the vector of a record as current without embedding it again
+func (s *Service) touch(doc *Document, record *core.Record) error {
+	_, err := s.app.DB().NewQuery("UPDATE embeddings SET user = {:user}, source_updated = {:source_updated}, date = {:date} WHERE type = {:type} AND record_id = {:record_id}").
+		Bind(dbx.Params{
+			"type":           doc.Type,
+			"record_id":      doc.RecordID,
+			"user":           doc.User,
+			"source_updated": record.GetDateTime("updated").String(),
+			"date":           doc.Date,
+		}).Execute()
+	return err
+}
+
+// Remove deletes the vector of a record
+func Remove(app core.App, docType string, recordID string) error {
+	_, err := app.DB().NewQuery("DELETE FROM embeddings WHERE type = {:type} AND record_id = {:record_id}").
+		Bind(dbx.Params{"type": docType, "record_id": recordID}).
+		Execute()
+	return err
+}
+
+// RegisterHooks drops the vectors of deleted records. New and changed records
+// are picked up by the next indexer run, embedding can take a while and
+// shouldn't hold up saves.
+func RegisterHooks(app core.App) {
+	tables := make([]string, 0, len(collectionTypes))
+	for table := range collectionTypes {
+		tables = append(tables, table)
+	}
+
+	app.OnModelAfterDeleteSuccess(tables...).BindFunc(func(e *core.ModelEvent) error {
+		table := e.Model.TableName()
+		recordID := fmt.Sprint(e.Model.PK())
+		if err := Remove(app, collectionTypes[table], recordID); err != nil {
+			logger.LogError(fmt.Sprintf("Error removing embedding of %s %s: %v", table, recordID, err))
+		}
+		return e.Next()
+	})
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize scales a vector to unit length so cosine similarity is a dot product
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+
+	norm = math.Sqrt(norm)
+	result := make([]float32, len(vector))
+	for i, value := range vector {
+		result[i] = float32(float64(value) / norm)
+	}
+	return result
+}
+
+// encodeVector stores a vector as little endian float32 values
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector
+}
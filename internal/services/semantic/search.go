@@ -0,0 +1,238 @@
+package semantic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/shashank-sharma/backend/internal/services/ai"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// ErrNotIndexed is returned for related lookups of records without text
+var ErrNotIndexed = errors.New("record has no text to compare")
+
+// ErrNotFound is returned for related lookups of records the user doesn't own
+var ErrNotFound = errors.New("record not found")
+
+// Options narrows a semantic search
+type Options struct {
+	Types    []string // Document types to search, all when empty
+	Limit    int
+	MinScore float64   // Results less similar than this are dropped
+	From     time.Time // Records dated before this are dropped, when set
+	To       time.Time // Records dated after this are dropped, when set
+	// Feed items of this cluster, copies of the same story, are dropped
+	ExcludeCluster string
+}
+
+// Result is a record matching a semantic search
+type Result struct {
+	Type     string                 `json:"type"`
+	RecordID string                 `json:"record_id"`
+	Score    float64                `json:"score"` // Cosine similarity, 1 is identical
+	Title    string                 `json:"title"`
+	Snippet  string                 `json:"snippet"`
+	Date     string                 `json:"date"`
+	Meta     map[string]interface{} `json:"meta"`
+}
+
+type scoredRecord struct {
+	Type     string
+	RecordID string
+	Score    float64
+}
+
+// Search embeds the query and returns the records of the user closest to it
+// in meaning
+func (s *Service) Search(ctx context.Context, userID string, query string, opts Options) ([]*Result, error) {
+	// Searches of other features, like the assistant, are counted as theirs
+	if _, ok := ai.CallerFromContext(ctx); !ok {
+		ctx = ai.WithCaller(ctx, ai.FeatureSearch, userID)
+	}
+
+	resp, err := s.embedder.Embed(ctx, &ai.EmbedRequest{Texts: []string{query}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(resp.Vectors) != 1 {
+		return nil, fmt.Errorf("no embedding generated for query")
+	}
+
+	return s.nearest(userID, normalize(resp.Vectors[0]), opts, nil)
+}
+
+// Related returns the records of the user closest in meaning to one of their
+// records
+func (s *Service) Related(ctx context.Context, userID string, docType string, recordID string, opts Options) ([]*Result, error) {
+	record, err := s.app.FindRecordById(typeCollections[docType], recordID)
+	if err != nil || record.GetString("user") != userID {
+		return nil, ErrNotFound
+	}
+
+	var row struct {
+		Model  string `db:"model"`
+		Vector []byte `db:"vector"`
+	}
+	err = s.app.DB().NewQuery("SELECT model, vector FROM embeddings WHERE type = {:type} AND record_id = {:record_id}").
+		Bind(dbx.Params{"type": docType, "record_id": recordID}).
+		One(&row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Records the indexer hasn't got to yet are embedded right away
+	if err != nil || row.Model != s.Model() {
+		if _, err := s.indexRecords(ctx, []*core.Record{record}); err != nil {
+			return nil, err
+		}
+		err = s.app.DB().NewQuery("SELECT model, vector FROM embeddings WHERE type = {:type} AND record_id = {:record_id}").
+			Bind(dbx.Params{"type": docType, "record_id": recordID}).
+			One(&row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(row.Vector) == 0 {
+		return nil, ErrNotIndexed
+	}
+
+	exclude := map[string]bool{docType + ":" + recordID: true}
+	return s.nearest(userID, decodeVector(row.Vector), opts, exclude)
+}
+
+// nearest scores every vector of the user against the query vector. A linear
+// scan is plenty for the records of a single user and needs no index upkeep.
+func (s *Service) nearest(userID string, query []float32, opts Options, exclude map[string]bool) ([]*Result, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	q := s.app.DB().Select("record_id", "type", "vector").
+		From("embeddings").
+		Where(dbx.HashExp{"user": userID, "model": s.Model(), "dimensions": len(query)})
+	if len(opts.Types) > 0 {
+		types := make([]interface{}, len(opts.Types))
+		for i, docType := range opts.Types {
+			types[i] = docType
+		}
+		q = q.AndWhere(dbx.In("type", types...))
+	}
+	if !opts.From.IsZero() {
+		q = q.AndWhere(dbx.NewExp("date >= {:from}", dbx.Params{"from": formatDate(opts.From)}))
+	}
+	if !opts.To.IsZero() {
+		q = q.AndWhere(dbx.NewExp("date <= {:to}", dbx.Params{"to": formatDate(opts.To)}))
+	}
+
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scored []scoredRecord
+	for rows.Next() {
+		var recordID, docType string
+		var vector []byte
+		if err := rows.Scan(&recordID, &docType, &vector); err != nil {
+			return nil, err
+		}
+		if exclude[docType+":"+recordID] {
+			continue
+		}
+
+		score := dot(query, vector)
+		if score <= 0 || score < opts.MinScore {
+			continue
+		}
+		scored = append(scored, scoredRecord{Type: docType, RecordID: recordID, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+
+	// Matches deleted since they were indexed are skipped
+	results := make([]*Result, 0, limit)
+	for _, match := range scored {
+		if len(results) >= limit {
+			break
+		}
+
+		record, err := s.app.FindRecordById(typeCollections[match.Type], match.RecordID)
+		if err != nil || record.GetString("user") != userID {
+			continue
+		}
+		doc, _ := DocumentFromRecord(record)
+		if opts.ExcludeCluster != "" && doc.Meta["cluster_id"] == opts.ExcludeCluster {
+			continue
+		}
+
+		results = append(results, &Result{
+			Type:     match.Type,
+			RecordID: match.RecordID,
+			Score:    match.Score,
+			Title:    doc.Title,
+			Snippet:  doc.snippet(),
+			Date:     doc.Date,
+			Meta:     doc.Meta,
+		})
+	}
+
+	return results, nil
+}
+
+// dot multiplies a unit query vector with an encoded unit vector, which is
+// their cosine similarity
+func dot(query []float32, encoded []byte) float64 {
+	vector := decodeVector(encoded)
+	if len(vector) != len(query) {
+		return 0
+	}
+
+	var sum float64
+	for i, value := range vector {
+		sum += float64(value) * float64(query[i])
+	}
+	return sum
+}
+
+// formatDate formats a time the way record dates are stored, so they compare
+// as strings
+func formatDate(t time.Time) string {
+	date, _ := types.ParseDateTime(t)
+	return date.String()
+}
+
+// ParseTypes reads a comma separated list of document types
+func ParseTypes(value string) ([]string, error) {
+	var types []string
+	for _, docType := range strings.Split(value, ",") {
+		docType = strings.TrimSpace(docType)
+		if docType == "" {
+			continue
+		}
+		if !IsValidType(docType) {
+			return nil, fmt.Errorf("invalid type: %s", docType)
+		}
+		types = append(types, docType)
+	}
+	return types, nil
+}
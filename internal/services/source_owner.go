@@ -0,0 +1,42 @@
+package services
+
+import "context"
+
+type sourceOwnerKey struct{}
+
+// WithSourceOwner returns a context fetching for a source of the given user.
+// Providers use it to only load records of that user, like access tokens.
+func WithSourceOwner(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, sourceOwnerKey{}, userID)
+}
+
+// SourceOwnerFromContext returns the user owning the source being fetched, or
+// an empty string
+func SourceOwnerFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(sourceOwnerKey{}).(string)
+	return userID
+}
+
+type sourceIDKey struct{}
+
+// WithSourceID returns a context fetching for the stored source with the id.
+// Providers use it to skip work for items that are already stored.
+func WithSourceID(ctx context.Context, sourceID string) context.Context {
+	return context.WithValue(ctx, sourceIDKey{}, sourceID)
+}
+
+// SourceIDFromContext returns the id of the source being fetched, or an empty
+// string when it isn't stored yet
+func SourceIDFromContext(ctx context.Context) string {
+	sourceID, _ := ctx.Value(sourceIDKey{}).(string)
+	return sourceID
+}
+
+// ValidateSourceConfig validates the configuration of a source of the user,
+// checking the records it references when the provider supports it
+func ValidateSourceConfig(provider FeedSourceProvider, config map[string]interface{}, userID string) error {
+	if validator, ok := provider.(FeedSourceOwnerValidator); ok {
+		return validator.ValidateForUser(config, userID)
+	}
+	return provider.Validate(config)
+}
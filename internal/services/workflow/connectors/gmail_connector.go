@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/shashank-sharma/backend/internal/logger"
 	"github.com/shashank-sharma/backend/internal/services/workflow/types"
 	"github.com/shashank-sharma/backend/internal/store"
 	"golang.org/x/oauth2"
@@ -14,6 +17,13 @@ import (
 	"google.golang.org/api/option"
 )
 
+const (
+	// Largest page of message ids Gmail returns
+	gmailListPageSize = 500
+	// Most pages of new message ids listed in one incremental run
+	gmailMaxListPages = 20
+)
+
 // GmailConnector is a connector for reading emails from Gmail
 // TODO: Not required, used for testing
 type GmailConnector struct {
@@ -51,6 +61,13 @@ func NewGmailSourceConnector() types.Connector {
 			"default":     true,
 			"required":    false,
 		},
+		"incremental": map[string]interface{}{
+			"type":        "boolean",
+			"title":       "Only New Emails",
+			"description": "Only fetch emails received since the last successful run",
+			"default":     false,
+			"required":    false,
+		},
 	}
 
 	connector := &GmailConnector{
@@ -150,10 +167,21 @@ func (c *GmailConnector) Execute(ctx context.Context, input map[string]interface
 	// Get parameters from config
 	query, _ := config["query"].(string)
 	maxResults := 10
-	if maxVal, ok := config["max_results"].(float64); ok {
+	if maxVal, ok := config["max_results"].(float64); ok && maxVal > 0 {
 		maxResults = int(maxVal)
 	}
 	includeBody, _ := config["include_content"].(bool)
+	incremental, _ := config["incremental"].(bool)
+
+	// Restrict the query to messages newer than the last run
+	var lastSeen time.Time
+	syncState := types.SyncStateFromContext(ctx)
+	if incremental && syncState != nil {
+		lastSeen = syncState.GetTime(types.SyncStateLastSeen)
+		if !lastSeen.IsZero() {
+			query = strings.TrimSpace(fmt.Sprintf("%s after:%d", query, lastSeen.Unix()))
+		}
+	}
 
 	// Get label IDs if specified
 	var labelIDs []string
@@ -165,8 +193,13 @@ func (c *GmailConnector) Execute(ctx context.Context, input map[string]interface
 		}
 	}
 
-	// Create the list request
-	listReq := c.client.Users.Messages.List("me").MaxResults(int64(maxResults))
+	// Create the list request. With a watermark only ids are listed up front,
+	// so use the largest page Gmail allows
+	pageSize := maxResults
+	if !lastSeen.IsZero() {
+		pageSize = gmailListPageSize
+	}
+	listReq := c.client.Users.Messages.List("me").MaxResults(int64(pageSize))
 	
 	// Add query if specified
 	if query != "" {
@@ -184,16 +217,62 @@ func (c *GmailConnector) Execute(ctx context.Context, input map[string]interface
 		return nil, fmt.Errorf("failed to list messages: %w", err)
 	}
 
+	messages := resp.Messages
+	hasMore := resp.NextPageToken != ""
+	nextPageToken := resp.NextPageToken
+	if !lastSeen.IsZero() {
+		// Gmail lists newest first, so the pages are read up to a cap and the
+		// oldest max_results messages are processed. The watermark then moves
+		// to the last one processed and the next run continues from there.
+		for pages := 1; resp.NextPageToken != "" && pages < gmailMaxListPages; pages++ {
+			resp, err = listReq.PageToken(resp.NextPageToken).Do()
+			if err != nil {
+				return nil, fmt.Errorf("failed to list messages: %w", err)
+			}
+			messages = append(messages, resp.Messages...)
+		}
+		hasMore = resp.NextPageToken != ""
+		if hasMore {
+			logger.LogWarning(fmt.Sprintf("Gmail sync stopped listing after %d new messages, older messages since %s are skipped", len(messages), lastSeen.Format(time.RFC3339)))
+		}
+
+		oldestFirst := make([]*gmail.Message, len(messages))
+		for i, message := range messages {
+			oldestFirst[len(messages)-1-i] = message
+		}
+		messages = oldestFirst
+		nextPageToken = ""
+	}
+
 	// Process the messages
 	emails := make([]map[string]interface{}, 0)
+	newestSeen := lastSeen
+	var newestHistoryID uint64
 	
-	for _, message := range resp.Messages {
+	for _, message := range messages {
+		if !lastSeen.IsZero() && len(emails) >= maxResults {
+			hasMore = true
+			break
+		}
+
 		// Get the full message
 		msg, err := c.client.Users.Messages.Get("me", message.Id).Do()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get message %s: %w", message.Id, err)
 		}
 
+		// after: only has second precision, skip messages we already returned last time
+		received := time.UnixMilli(msg.InternalDate)
+		if !lastSeen.IsZero() && !received.After(lastSeen) {
+			continue
+		}
+		if received.After(newestSeen) {
+			newestSeen = received
+		}
+		if msg.HistoryId > newestHistoryID {
+			newestHistoryID = msg.HistoryId
+		}
+
 		// Extract headers
 		headers := make(map[string]string)
 		for _, header := range msg.Payload.Headers {
@@ -224,12 +303,18 @@ func (c *GmailConnector) Execute(ctx context.Context, input map[string]interface
 		emails = append(emails, email)
 	}
 
+	// Advance the watermark for the next run
+	if incremental && syncState != nil && newestSeen.After(lastSeen) {
+		syncState.SetTime(types.SyncStateLastSeen, newestSeen)
+		syncState.Set(types.SyncStateHistoryID, strconv.FormatUint(newestHistoryID, 10))
+	}
+
 	// Return the results
 	return map[string]interface{}{
 		"data":         emails,
 		"result_count": len(emails),
-		"has_more":     resp.NextPageToken != "",
-		"next_page_token": resp.NextPageToken,
+		"has_more":     hasMore,
+		"next_page_token": nextPageToken,
 	}, nil
 }
 
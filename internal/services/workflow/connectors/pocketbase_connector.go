@@ -58,6 +58,18 @@ func NewPocketBaseSourceConnector() types.Connector {
 			"description": "If true, will not apply user-based filtering (use with caution)",
 			"default":     false,
 		},
+		"incremental": map[string]interface{}{
+			"type":        "boolean",
+			"title":       "Only New Records",
+			"description": "Only fetch records whose watermark field changed since the last successful run",
+			"default":     false,
+		},
+		"watermark_field": map[string]interface{}{
+			"type":        "string",
+			"title":       "Watermark Field",
+			"description": "Field used to detect new records in incremental mode",
+			"default":     "updated",
+		},
 	}
 
 	connector := &PocketBaseConnector{
@@ -158,6 +170,27 @@ func (c *PocketBaseConnector) Execute(ctx context.Context, input map[string]inte
 		}
 	}
 
+	// In incremental mode only records past the stored (watermark, id) cursor are
+	// fetched, oldest first, so that max_records never skips records between two
+	// runs, not even ones sharing the watermark of the last record returned
+	incremental, _ := c.Config["incremental"].(bool)
+	syncState := types.SyncStateFromContext(ctx)
+	watermarkField := "updated"
+	if val, ok := c.Config["watermark_field"].(string); ok && val != "" {
+		watermarkField = val
+	}
+	if incremental && !isValidIdentifier(watermarkField) {
+		return nil, fmt.Errorf("invalid watermark field: %s", watermarkField)
+	}
+
+	watermark, watermarkID := "", ""
+	if incremental && syncState != nil {
+		watermark = syncState.GetString(types.SyncStateCursor)
+		watermarkID = syncState.GetString(types.SyncStateCursorID)
+		sort = fmt.Sprintf("%s ASC, id ASC", watermarkField)
+	}
+	newestWatermark, newestWatermarkID := watermark, watermarkID
+
 	// Initialize variables for pagination
 	offset := 0
 	totalRecords := 0
@@ -190,6 +223,13 @@ func (c *PocketBaseConnector) Execute(ctx context.Context, input map[string]inte
 			query.AndWhere(dbx.NewExp(filter))
 		}
 
+		if watermark != "" {
+			query.AndWhere(dbx.NewExp(
+				watermarkField+" > {:watermark} OR ("+watermarkField+" = {:watermark} AND id > {:watermarkId})",
+				dbx.Params{"watermark": watermark, "watermarkId": watermarkID},
+			))
+		}
+
 		// Apply sorting
 		query.OrderBy(sort)
 
@@ -221,6 +261,12 @@ func (c *PocketBaseConnector) Execute(ctx context.Context, input map[string]inte
 				}
 			}
 
+			// Records come in cursor order, so the last one is the newest
+			if value, ok := record[watermarkField].(string); ok && incremental {
+				newestWatermark = value
+				newestWatermarkID, _ = record["id"].(string)
+			}
+
 			// Clean up system fields if present
 			delete(record, "collectionId")
 			delete(record, "collectionName")
@@ -243,6 +289,11 @@ func (c *PocketBaseConnector) Execute(ctx context.Context, input map[string]inte
 		}
 	}
 
+	if incremental && syncState != nil && (newestWatermark != watermark || newestWatermarkID != watermarkID) {
+		syncState.Set(types.SyncStateCursor, newestWatermark)
+		syncState.Set(types.SyncStateCursorID, newestWatermarkID)
+	}
+
 	// Return the results
 	return map[string]interface{}{
 		"records": allRecords,
@@ -254,6 +305,18 @@ func (c *PocketBaseConnector) Execute(ctx context.Context, input map[string]inte
 			"batch_size": batchSize,
 			"max_records": maxRecords,
 			"user":    userID,
+			"watermark": newestWatermark,
 		},
 	}, nil
+}
+
+// isValidIdentifier reports whether name is safe to use as a column name
+func isValidIdentifier(name string) bool {
+	for i, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return name != ""
 } 
\ No newline at end of file
@@ -47,8 +47,10 @@ type WorkflowData struct {
 
 // Graph represents the workflow execution graph
 type Graph struct {
-	Nodes map[string]*Node // Map of node ID to node
-	Edges []*Edge          // List of connections between nodes
+	WorkflowID string                      // ID of the workflow the graph was built from
+	Nodes      map[string]*Node            // Map of node ID to node
+	Edges      []*Edge                     // List of connections between nodes
+	SyncStates map[string]*types.SyncState // Sync state of each executed source node
 }
 
 // WorkflowEngine handles the execution of workflows
@@ -222,6 +224,8 @@ func (e *WorkflowEngine) runWorkflow(ctx context.Context, workflowID string, exe
 		return
 	}
 
+	graph.WorkflowID = workflowID
+
 	// Log graph built
 	logs = append(logs, map[string]interface{}{
 		"timestamp": time.Now().Format(time.RFC3339),
@@ -242,6 +246,16 @@ func (e *WorkflowEngine) runWorkflow(ctx context.Context, workflowID string, exe
 		return
 	}
 
+	// Persist source sync states only once the whole workflow succeeded,
+	// so a failing destination doesn't advance the watermark past unsent records
+	if err := e.saveSyncStates(ctx, graph, executionID); err != nil {
+		logs = append(logs, map[string]interface{}{
+			"timestamp": time.Now().Format(time.RFC3339),
+			"level":     "warn",
+			"message":   fmt.Sprintf("Failed to save sync state: %v", err),
+		})
+	}
+
 	// Update execution as completed
 	logs = append(logs, map[string]interface{}{
 		"timestamp": time.Now().Format(time.RFC3339),
@@ -324,8 +338,9 @@ func (e *WorkflowEngine) loadWorkflowConnections(workflowID string) ([]*core.Rec
 // buildGraph builds the execution graph from nodes and connections
 func (e *WorkflowEngine) buildGraph(nodes []*core.Record, connections []*core.Record) (*Graph, error) {
 	graph := &Graph{
-		Nodes: make(map[string]*Node),
-		Edges: make([]*Edge, 0, len(connections)),
+		Nodes:      make(map[string]*Node),
+		Edges:      make([]*Edge, 0, len(connections)),
+		SyncStates: make(map[string]*types.SyncState),
 	}
 
 	// Create nodes
@@ -449,7 +464,16 @@ func (e *WorkflowEngine) executeGraph(ctx context.Context, graph *Graph, logs []
 			"name", sourceNode.Name, 
 			"connector", sourceNode.NodeType)
 
-		result, err := e.executeNode(ctx, sourceNode, nil)
+		syncState, err := e.loadSyncState(graph.WorkflowID, sourceNode.ID)
+		if err != nil {
+			logger.LogError("Failed to load sync state, running without it",
+				"id", sourceNode.ID,
+				"error", err.Error())
+			syncState = types.NewSyncState(nil)
+		}
+		graph.SyncStates[sourceNode.ID] = syncState
+
+		result, err := e.executeNode(types.WithSyncState(ctx, syncState), sourceNode, nil)
 		if err != nil {
 			logger.LogError("Failed to execute source node", 
 				"id", sourceNode.ID, 
@@ -762,6 +786,115 @@ func (e *WorkflowEngine) updateExecutionLogs(executionID string, logs []map[stri
 	}
 }
 
+// loadSyncState loads the persisted sync state of a source node
+func (e *WorkflowEngine) loadSyncState(workflowID string, nodeID string) (*types.SyncState, error) {
+	record, err := query.FindByFilter[*wfModels.WorkflowSyncState](map[string]interface{}{
+		"workflow_id": workflowID,
+		"node_id":     nodeID,
+	})
+	if err != nil {
+		// No state yet, this is the first run of the node
+		return types.NewSyncState(nil), nil
+	}
+
+	values := make(map[string]interface{})
+	if record.State != "" {
+		if err := json.Unmarshal([]byte(record.State), &values); err != nil {
+			return nil, fmt.Errorf("invalid sync state for node %s: %w", nodeID, err)
+		}
+	}
+
+	return types.NewSyncState(values), nil
+}
+
+// saveSyncStates persists the sync state of every source node that changed it
+func (e *WorkflowEngine) saveSyncStates(ctx context.Context, graph *Graph, executionID string) error {
+	userID, _ := ctx.Value("user").(string)
+
+	for nodeID, syncState := range graph.SyncStates {
+		if !syncState.Changed() {
+			continue
+		}
+
+		stateJSON, err := json.Marshal(syncState.Values())
+		if err != nil {
+			return fmt.Errorf("failed to marshal sync state for node %s: %w", nodeID, err)
+		}
+
+		record := &wfModels.WorkflowSyncState{
+			WorkflowID:    graph.WorkflowID,
+			NodeID:        nodeID,
+			User:          userID,
+			State:         string(stateJSON),
+			LastRun:       pbTypes.NowDateTime(),
+			LastExecution: executionID,
+		}
+
+		if err := query.UpsertRecord[*wfModels.WorkflowSyncState](record, map[string]interface{}{
+			"workflow_id": graph.WorkflowID,
+			"node_id":     nodeID,
+		}); err != nil {
+			return fmt.Errorf("failed to save sync state for node %s: %w", nodeID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSyncStates returns the persisted sync state of all source nodes of a workflow
+func (e *WorkflowEngine) GetSyncStates(workflowID string) ([]map[string]interface{}, error) {
+	records, err := query.FindAllByFilter[*wfModels.WorkflowSyncState](map[string]interface{}{
+		"workflow_id": workflowID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync states: %w", err)
+	}
+
+	states := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		values := map[string]interface{}{}
+		if record.State != "" {
+			if err := json.Unmarshal([]byte(record.State), &values); err != nil {
+				logger.LogError("Failed to parse sync state", "id", record.Id, "error", err.Error())
+			}
+		}
+
+		states = append(states, map[string]interface{}{
+			"id":             record.Id,
+			"node_id":        record.NodeID,
+			"state":          values,
+			"last_run":       record.LastRun,
+			"last_execution": record.LastExecution,
+		})
+	}
+
+	return states, nil
+}
+
+// ResetSyncState deletes the persisted sync state of a workflow so the next run
+// starts from scratch. If nodeID is empty the state of every node is reset.
+func (e *WorkflowEngine) ResetSyncState(workflowID string, nodeID string) (int, error) {
+	filter := map[string]interface{}{
+		"workflow_id": workflowID,
+	}
+	if nodeID != "" {
+		filter["node_id"] = nodeID
+	}
+
+	records, err := query.FindAllByFilter[*wfModels.WorkflowSyncState](filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sync states: %w", err)
+	}
+
+	for _, record := range records {
+		if err := query.DeleteRecord(record); err != nil {
+			return 0, fmt.Errorf("failed to delete sync state %s: %w", record.Id, err)
+		}
+	}
+
+	return len(records), nil
+}
+
 // RegisterConnectors registers all available connectors with the registry
 // This is exported for use by the application to get available connectors
 func RegisterConnectors(registry types.ConnectorRegistry) {
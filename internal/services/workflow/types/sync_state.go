@@ -0,0 +1,103 @@
+package types
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Well-known sync state keys shared by source connectors
+const (
+	SyncStateLastSeen  = "last_seen"  // RFC3339 timestamp of the newest record seen
+	SyncStateCursor    = "cursor"     // Opaque pagination cursor
+	SyncStateCursorID  = "cursor_id"  // Id of the record at the cursor, to page through records sharing it
+	SyncStateHistoryID = "history_id" // Provider specific history marker (e.g. Gmail history ID)
+)
+
+type syncStateKey struct{}
+
+// SyncState holds the state a source node persists between workflow executions.
+// Connectors read it to fetch only new records and update it once they are done.
+type SyncState struct {
+	mu      sync.RWMutex
+	values  map[string]interface{}
+	changed bool
+}
+
+// NewSyncState creates a sync state initialized with the given values
+func NewSyncState(values map[string]interface{}) *SyncState {
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return &SyncState{values: values}
+}
+
+// Get returns the raw value stored under key
+func (s *SyncState) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// GetString returns the value stored under key as a string
+func (s *SyncState) GetString(key string) string {
+	value, _ := s.Get(key)
+	str, _ := value.(string)
+	return str
+}
+
+// GetTime returns the value stored under key parsed as an RFC3339 timestamp
+func (s *SyncState) GetTime(key string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s.GetString(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Set stores a value under key and marks the state as changed
+func (s *SyncState) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	s.changed = true
+}
+
+// SetTime stores t under key as an RFC3339 timestamp
+func (s *SyncState) SetTime(key string, t time.Time) {
+	s.Set(key, t.UTC().Format(time.RFC3339Nano))
+}
+
+// Changed reports whether the state was modified since it was loaded
+func (s *SyncState) Changed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.changed
+}
+
+// Values returns a copy of all stored values
+func (s *SyncState) Values() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]interface{}, len(s.values))
+	for key, value := range s.values {
+		result[key] = value
+	}
+	return result
+}
+
+// WithSyncState returns a copy of ctx carrying the given sync state
+func WithSyncState(ctx context.Context, state *SyncState) context.Context {
+	return context.WithValue(ctx, syncStateKey{}, state)
+}
+
+// SyncStateFromContext returns the sync state attached to ctx, or nil if there is none
+func SyncStateFromContext(ctx context.Context) *SyncState {
+	state, _ := ctx.Value(syncStateKey{}).(*SyncState)
+	return state
+}
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3928066657")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(17, []byte(`{
+			"convertURLs": false,
+			"hidden": false,
+			"id": "editor2917951941",
+			"maxSize": 0,
+			"name": "full_content",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "editor"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(18, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text2749024720",
+			"max": 0,
+			"min": 0,
+			"name": "lead_image",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(19, []byte(`{
+			"hidden": false,
+			"id": "number1489170449",
+			"max": null,
+			"min": null,
+			"name": "reading_time",
+			"onlyInt": false,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(20, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3571151285",
+			"max": 0,
+			"min": 0,
+			"name": "language",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3928066657")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("editor2917951941")
+
+		// remove field
+		collection.Fields.RemoveById("text2749024720")
+
+		// remove field
+		collection.Fields.RemoveById("number1489170449")
+
+		// remove field
+		collection.Fields.RemoveById("text3571151285")
+
+		return app.Save(collection)
+	})
+}
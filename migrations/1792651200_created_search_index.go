@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// search_documents holds the filterable metadata, its id is the rowid of
+		// the matching row in the search_index FTS5 table
+		statements := []string{
+			`CREATE TABLE IF NOT EXISTS search_documents (
+				id        INTEGER PRIMARY KEY,
+				record_id TEXT NOT NULL,
+				type      TEXT NOT NULL,
+				user      TEXT NOT NULL,
+				tags      TEXT NOT NULL DEFAULT '',
+				date      TEXT NOT NULL DEFAULT '',
+				meta      TEXT NOT NULL DEFAULT '{}'
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_search_documents_record ON search_documents (type, record_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_search_documents_user ON search_documents (user, type, date)`,
+			`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+				title,
+				content,
+				extra,
+				tokenize = 'porter unicode61 remove_diacritics 2'
+			)`,
+		}
+
+		for _, statement := range statements {
+			if _, err := app.DB().NewQuery(statement).Execute(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		statements := []string{
+			`DROP TABLE IF EXISTS search_index`,
+			`DROP TABLE IF EXISTS search_documents`,
+		}
+
+		for _, statement := range statements {
+			if _, err := app.DB().NewQuery(statement).Execute(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3928066657")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(21, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3416014995",
+			"max": 0,
+			"min": 0,
+			"name": "canonical_url",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(22, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text4266214658",
+			"max": 0,
+			"min": 0,
+			"name": "simhash",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(23, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text557948081",
+			"max": 0,
+			"min": 0,
+			"name": "content_simhash",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(24, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text3278517032",
+			"max": 0,
+			"min": 0,
+			"name": "cluster_id",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(25, []byte(`{
+			"hidden": false,
+			"id": "bool2841991632",
+			"name": "is_cluster_primary",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+
+		collection.AddIndex("idx_feed_items_canonical_url", false, "user, canonical_url", "")
+		collection.AddIndex("idx_feed_items_cluster", false, "user, cluster_id", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Existing items start out as clusters of their own
+		_, err = app.DB().NewQuery("UPDATE feed_items SET cluster_id = id, is_cluster_primary = TRUE WHERE cluster_id = ''").Execute()
+		return err
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3928066657")
+		if err != nil {
+			return err
+		}
+
+		collection.RemoveIndex("idx_feed_items_canonical_url")
+		collection.RemoveIndex("idx_feed_items_cluster")
+
+		// remove field
+		collection.Fields.RemoveById("text3416014995")
+
+		// remove field
+		collection.Fields.RemoveById("text4266214658")
+
+		// remove field
+		collection.Fields.RemoveById("text557948081")
+
+		// remove field
+		collection.Fields.RemoveById("text3278517032")
+
+		// remove field
+		collection.Fields.RemoveById("bool2841991632")
+
+		return app.Save(collection)
+	})
+}
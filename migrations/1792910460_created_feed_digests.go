@@ -0,0 +1,194 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": "@request.auth.id = user",
+			"deleteRule": "@request.auth.id = user",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation2375276105",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text724990059",
+					"max": 0,
+					"min": 0,
+					"name": "title",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text645904403",
+					"max": 0,
+					"min": 0,
+					"name": "frequency",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "date2932989418",
+					"max": "",
+					"min": "",
+					"name": "period_start",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "date179253131",
+					"max": "",
+					"min": "",
+					"name": "period_end",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "number2944294834",
+					"max": null,
+					"min": null,
+					"name": "item_count",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "json2546982058",
+					"maxSize": 0,
+					"name": "item_ids",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "json"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor410646757",
+					"maxSize": 0,
+					"name": "html",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor2529069283",
+					"maxSize": 0,
+					"name": "markdown",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"hidden": false,
+					"id": "json4078232246",
+					"maxSize": 0,
+					"name": "channels",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "json"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_2222857086",
+			"indexes": [
+				"CREATE INDEX idx_feed_digests_user ON feed_digests (user, created)"
+			],
+			"listRule": "@request.auth.id = user",
+			"name": "feed_digests",
+			"system": false,
+			"type": "base",
+			"updateRule": "@request.auth.id = user",
+			"viewRule": "@request.auth.id = user"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2222857086")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,231 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": "@request.auth.id = user",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation2375276105",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_3928066657",
+					"hidden": false,
+					"id": "relation521872670",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "item",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text4101391790",
+					"max": 0,
+					"min": 0,
+					"name": "url",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text724990059",
+					"max": 0,
+					"min": 0,
+					"name": "title",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text2063623452",
+					"max": 0,
+					"min": 0,
+					"name": "status",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor410646757",
+					"maxSize": 0,
+					"name": "html",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor999008199",
+					"maxSize": 0,
+					"name": "text",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"hidden": false,
+					"id": "file3760176746",
+					"maxSelect": 100,
+					"maxSize": 10485760,
+					"mimeTypes": [],
+					"name": "images",
+					"presentable": false,
+					"protected": false,
+					"required": false,
+					"system": false,
+					"thumbs": [],
+					"type": "file"
+				},
+				{
+					"hidden": false,
+					"id": "number4156564586",
+					"max": null,
+					"min": null,
+					"name": "size",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number3217549156",
+					"max": null,
+					"min": null,
+					"name": "attempts",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1574812785",
+					"max": 0,
+					"min": 0,
+					"name": "error",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "date70013459",
+					"max": "",
+					"min": "",
+					"name": "archived_at",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_3266804638",
+			"indexes": [
+				"CREATE UNIQUE INDEX idx_feed_archives_item ON feed_archives (item)",
+				"CREATE INDEX idx_feed_archives_user_status ON feed_archives (user, status)"
+			],
+			"listRule": "@request.auth.id = user",
+			"name": "feed_archives",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "@request.auth.id = user"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3266804638")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
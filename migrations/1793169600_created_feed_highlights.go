@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": "@request.auth.id = user",
+			"deleteRule": "@request.auth.id = user",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation2375276105",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_3928066657",
+					"hidden": false,
+					"id": "relation521872670",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "item",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "pbc_3266804638",
+					"hidden": false,
+					"id": "relation3590086044",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "archive",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor999008199",
+					"maxSize": 0,
+					"name": "text",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"convertURLs": false,
+					"hidden": false,
+					"id": "editor3485334036",
+					"maxSize": 0,
+					"name": "note",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "editor"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1716930793",
+					"max": 0,
+					"min": 0,
+					"name": "color",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number2479185216",
+					"max": null,
+					"min": null,
+					"name": "start_offset",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number689021208",
+					"max": null,
+					"min": null,
+					"name": "end_offset",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text2477885070",
+					"max": 0,
+					"min": 0,
+					"name": "prefix",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text3048245214",
+					"max": 0,
+					"min": 0,
+					"name": "suffix",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number2522310777",
+					"max": null,
+					"min": null,
+					"name": "review_count",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "date2424154933",
+					"max": "",
+					"min": "",
+					"name": "last_reviewed_at",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_3042881402",
+			"indexes": [
+				"CREATE INDEX idx_feed_highlights_user_item ON feed_highlights (user, item)",
+				"CREATE INDEX idx_feed_highlights_user_reviewed ON feed_highlights (user, last_reviewed_at)"
+			],
+			"listRule": "@request.auth.id = user",
+			"name": "feed_highlights",
+			"system": false,
+			"type": "base",
+			"updateRule": "@request.auth.id = user",
+			"viewRule": "@request.auth.id = user"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3042881402")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// embeddings holds one vector per indexed record. source_updated and
+		// content_hash tell the indexer which records changed since.
+		statements := []string{
+			`CREATE TABLE IF NOT EXISTS embeddings (
+				id             INTEGER PRIMARY KEY,
+				record_id      TEXT NOT NULL,
+				type           TEXT NOT NULL,
+				user           TEXT NOT NULL,
+				model          TEXT NOT NULL,
+				dimensions     INTEGER NOT NULL,
+				vector         BLOB NOT NULL,
+				content_hash   TEXT NOT NULL DEFAULT '',
+				source_updated TEXT NOT NULL DEFAULT '',
+				date           TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_embeddings_record ON embeddings (type, record_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_embeddings_user ON embeddings (user, model, type)`,
+		}
+
+		for _, statement := range statements {
+			if _, err := app.DB().NewQuery(statement).Execute(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(app core.App) error {
+		_, err := app.DB().NewQuery(`DROP TABLE IF EXISTS embeddings`).Execute()
+		return err
+	})
+}